@@ -0,0 +1,162 @@
+package backfill
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/dinerozz/web-behavior-backend/config"
+	"github.com/dinerozz/web-behavior-backend/internal/repository"
+	"github.com/gofrs/uuid"
+	"github.com/spf13/cobra"
+)
+
+// GetBackfillUserNamesCmd заполняет user_name старых событий из extension_users по user_id.
+// Обновление идет пачками с паузой, как в purge, чтобы не держать длинные блокировки user_behaviors
+func GetBackfillUserNamesCmd(cfg *config.Config) *cobra.Command {
+	var userIDStr string
+	var batchSize int
+	var dryRun bool
+
+	backfillCmd := &cobra.Command{
+		Use:   "backfill-user-names",
+		Short: "Fill missing user_name of behaviors from extension users",
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := slog.Default()
+
+			if batchSize < 1 {
+				logger.Error("batch size must be at least 1", slog.Int("batch_size", batchSize))
+				os.Exit(1)
+			}
+
+			var userID *uuid.UUID
+			if userIDStr != "" {
+				parsed, err := uuid.FromString(userIDStr)
+				if err != nil {
+					logger.Error("invalid user id", slog.String("user_id", userIDStr))
+					os.Exit(1)
+				}
+				userID = &parsed
+			}
+
+			db, err := repository.NewRepository(cfg.DB)
+			if err != nil {
+				logger.Error("failed to connect to database", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+			defer db.Close()
+
+			repo := repository.NewUserBehaviorRepository(db)
+			ctx := context.Background()
+
+			total, err := repo.CountMissingUserNames(ctx, userID)
+			if err != nil {
+				logger.Error("failed to count behaviors", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+
+			logger.Info("user name backfill started",
+				slog.String("user_id", userIDStr),
+				slog.Int64("matched", total),
+				slog.Bool("dry_run", dryRun))
+
+			if dryRun || total == 0 {
+				return
+			}
+
+			var updated int64
+			for {
+				affected, err := repo.BackfillUserNamesBatch(ctx, userID, batchSize)
+				if err != nil {
+					logger.Error("backfill batch failed", slog.Int64("updated", updated), slog.String("error", err.Error()))
+					os.Exit(1)
+				}
+
+				updated += affected
+				logger.Info("backfill batch done", slog.Int64("batch", affected), slog.Int64("updated", updated), slog.Int64("matched", total))
+
+				if affected < int64(batchSize) {
+					break
+				}
+
+				time.Sleep(cfg.Retention.PurgeBatchSleep)
+			}
+
+			logger.Info("user name backfill finished", slog.Int64("updated", updated))
+		},
+	}
+
+	backfillCmd.Flags().StringVar(&userIDStr, "user-id", "", "Backfill only events of this extension user")
+	backfillCmd.Flags().IntVar(&batchSize, "batch-size", cfg.Retention.PurgeBatchSize, "Rows per update batch")
+	backfillCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only report how many rows would be updated")
+
+	return backfillCmd
+}
+
+// GetBackfillDomainsCmd заполняет domain событий, сохраненных до миграции 000016. Каждая пачка
+// коммитится отдельно, с паузой между пачками, вместо одного UPDATE на всю таблицу
+func GetBackfillDomainsCmd(cfg *config.Config) *cobra.Command {
+	var batchSize int
+	var dryRun bool
+
+	backfillCmd := &cobra.Command{
+		Use:   "backfill-domains",
+		Short: "Fill missing domain of behaviors from their URL",
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := slog.Default()
+
+			if batchSize < 1 {
+				logger.Error("batch size must be at least 1", slog.Int("batch_size", batchSize))
+				os.Exit(1)
+			}
+
+			db, err := repository.NewRepository(cfg.DB)
+			if err != nil {
+				logger.Error("failed to connect to database", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+			defer db.Close()
+
+			repo := repository.NewUserBehaviorRepository(db)
+			ctx := context.Background()
+
+			total, err := repo.CountMissingDomains(ctx)
+			if err != nil {
+				logger.Error("failed to count behaviors", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+
+			logger.Info("domain backfill started", slog.Int64("matched", total), slog.Bool("dry_run", dryRun))
+
+			if dryRun || total == 0 {
+				return
+			}
+
+			var updated int64
+			for {
+				affected, err := repo.BackfillDomainsBatch(ctx, batchSize)
+				if err != nil {
+					logger.Error("backfill batch failed", slog.Int64("updated", updated), slog.String("error", err.Error()))
+					os.Exit(1)
+				}
+
+				updated += affected
+				logger.Info("backfill batch done", slog.Int64("batch", affected), slog.Int64("updated", updated), slog.Int64("matched", total))
+
+				if affected < int64(batchSize) {
+					break
+				}
+
+				time.Sleep(cfg.Retention.PurgeBatchSleep)
+			}
+
+			logger.Info("domain backfill finished", slog.Int64("updated", updated))
+		},
+	}
+
+	backfillCmd.Flags().IntVar(&batchSize, "batch-size", cfg.Retention.PurgeBatchSize, "Rows per update batch")
+	backfillCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only report how many rows would be updated")
+
+	return backfillCmd
+}
@@ -19,6 +19,8 @@ func main() {
 	cmd := root.GetRootCmd(config)
 
 	logger := setupLogger(config.Env)
+	// CLI команды (purge и др.) пишут через slog.Default
+	slog.SetDefault(logger)
 
 	logger.Info("starting budget app backend", slog.String("env", config.Env))
 
@@ -0,0 +1,93 @@
+package purge
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/dinerozz/web-behavior-backend/config"
+	"github.com/dinerozz/web-behavior-backend/internal/repository"
+	"github.com/spf13/cobra"
+)
+
+// GetPurgeCmd удаляет сырые события старше срока хранения пачками,
+// чтобы не блокировать таблицу user_behaviors на время одного большого DELETE
+func GetPurgeCmd(cfg *config.Config) *cobra.Command {
+	var retentionDays int
+	var batchSize int
+	var dryRun bool
+	var soft bool
+
+	purgeCmd := &cobra.Command{
+		Use:   "purge",
+		Short: "Delete user behaviors older than the retention window",
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := slog.Default()
+
+			if retentionDays < 1 {
+				logger.Error("retention days must be at least 1", slog.Int("retention_days", retentionDays))
+				os.Exit(1)
+			}
+			if batchSize < 1 {
+				logger.Error("batch size must be at least 1", slog.Int("batch_size", batchSize))
+				os.Exit(1)
+			}
+
+			db, err := repository.NewRepository(cfg.DB)
+			if err != nil {
+				logger.Error("failed to connect to database", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+			defer db.Close()
+
+			repo := repository.NewUserBehaviorRepository(db)
+			ctx := context.Background()
+			before := time.Now().UTC().AddDate(0, 0, -retentionDays)
+
+			total, err := repo.CountOlderThan(ctx, before, soft)
+			if err != nil {
+				logger.Error("failed to count behaviors", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+
+			logger.Info("purge started",
+				slog.Time("before", before),
+				slog.Int("retention_days", retentionDays),
+				slog.Int64("matched", total),
+				slog.Bool("soft", soft),
+				slog.Bool("dry_run", dryRun))
+
+			if dryRun || total == 0 {
+				return
+			}
+
+			var purged int64
+			for {
+				affected, err := repo.PurgeBatch(ctx, before, batchSize, soft)
+				if err != nil {
+					logger.Error("purge batch failed", slog.Int64("purged", purged), slog.String("error", err.Error()))
+					os.Exit(1)
+				}
+
+				purged += affected
+				logger.Info("purge batch done", slog.Int64("batch", affected), slog.Int64("purged", purged), slog.Int64("matched", total))
+
+				if affected < int64(batchSize) {
+					break
+				}
+
+				time.Sleep(cfg.Retention.PurgeBatchSleep)
+			}
+
+			logger.Info("purge finished", slog.Int64("purged", purged))
+		},
+	}
+
+	purgeCmd.Flags().IntVar(&retentionDays, "retention-days", cfg.Retention.BehaviorRetentionDays, "Delete behaviors older than this many days (default from BEHAVIOR_RETENTION_DAYS)")
+	purgeCmd.Flags().IntVar(&batchSize, "batch-size", cfg.Retention.PurgeBatchSize, "Rows per delete batch")
+	purgeCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only report how many rows would be purged")
+	purgeCmd.Flags().BoolVar(&soft, "soft", false, "Set deleted_at instead of deleting rows")
+
+	return purgeCmd
+}
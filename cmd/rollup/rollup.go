@@ -0,0 +1,62 @@
+package rollup
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/dinerozz/web-behavior-backend/config"
+	"github.com/dinerozz/web-behavior-backend/internal/repository"
+	"github.com/spf13/cobra"
+)
+
+// GetRollupCmd пересчитывает daily_engagement. Запускается по расписанию (k3s/rollup-cronjob.yaml);
+// по умолчанию пересчитывает сегодня и вчера, чтобы подхватить поздно пришедшие события
+func GetRollupCmd(cfg *config.Config) *cobra.Command {
+	var date string
+	var days int
+
+	rollupCmd := &cobra.Command{
+		Use:   "rollup",
+		Short: "Aggregate daily engagement into the daily_engagement table",
+		Run: func(cmd *cobra.Command, args []string) {
+			endDay := time.Now().UTC().Truncate(24 * time.Hour)
+			if date != "" {
+				parsed, err := time.Parse("2006-01-02", date)
+				if err != nil {
+					log.Fatal("❌ Invalid --date, use YYYY-MM-DD:", err)
+				}
+				endDay = parsed
+			}
+
+			if days < 1 {
+				log.Fatal("❌ --days must be at least 1")
+			}
+
+			db, err := repository.NewRepository(cfg.DB)
+			if err != nil {
+				log.Fatal("❌ Failed to connect to database:", err)
+			}
+			defer db.Close()
+
+			repo := repository.NewDailyEngagementRepository(db, 0)
+			ctx := context.Background()
+
+			for i := days - 1; i >= 0; i-- {
+				day := endDay.AddDate(0, 0, -i)
+
+				rows, err := repo.RollupDay(ctx, day)
+				if err != nil {
+					log.Fatal("❌ Rollup failed:", err)
+				}
+
+				log.Printf("✅ Rolled up %s: %d users", day.Format("2006-01-02"), rows)
+			}
+		},
+	}
+
+	rollupCmd.Flags().StringVar(&date, "date", "", "Last day to aggregate, YYYY-MM-DD in UTC (default: today)")
+	rollupCmd.Flags().IntVar(&days, "days", 2, "Number of days to aggregate, ending at --date")
+
+	return rollupCmd
+}
@@ -2,7 +2,10 @@ package root
 
 import (
 	"fmt"
+	"github.com/dinerozz/web-behavior-backend/cmd/backfill"
 	"github.com/dinerozz/web-behavior-backend/cmd/migrate"
+	"github.com/dinerozz/web-behavior-backend/cmd/purge"
+	"github.com/dinerozz/web-behavior-backend/cmd/rollup"
 	"github.com/dinerozz/web-behavior-backend/config"
 	"github.com/dinerozz/web-behavior-backend/server"
 	"github.com/spf13/cobra"
@@ -31,6 +34,10 @@ func GetRootCmd(config *config.Config) *cobra.Command {
 	})
 
 	rootCmd.AddCommand(migrate.GetMigrateCmd(dbURL))
+	rootCmd.AddCommand(rollup.GetRollupCmd(config))
+	rootCmd.AddCommand(purge.GetPurgeCmd(config))
+	rootCmd.AddCommand(backfill.GetBackfillUserNamesCmd(config))
+	rootCmd.AddCommand(backfill.GetBackfillDomainsCmd(config))
 
 	return rootCmd
 }
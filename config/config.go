@@ -1,9 +1,14 @@
 package config
 
 import (
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/dinerozz/web-behavior-backend/internal/service/ai_analytics"
 	"github.com/dinerozz/web-behavior-backend/internal/service/redis"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -20,13 +25,102 @@ type DatabaseConfig struct {
 	Password string
 	DBName   string
 	SSLMode  string
+
+	// Пул соединений: без лимита под нагрузкой можно исчерпать max_connections Postgres
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+type JWTConfig struct {
+	// Обязателен в prod; вне prod без него используется небезопасный dev секрет
+	Secret string
+	// HS256, HS384 или HS512
+	Algorithm  string
+	TTL        time.Duration // время жизни access токена
+	RefreshTTL time.Duration // время жизни refresh токена и auth cookie
+}
+
+type RateLimitConfig struct {
+	// Лимит запросов в минуту на публичные эндпоинты сбора событий
+	IngestionPerMinute int
+}
+
+// BodyLimitConfig - максимальный размер тела запроса в байтах
+type BodyLimitConfig struct {
+	DefaultBytes int64
+	// Для POST /behaviors/batch, где тело заметно больше остальных запросов
+	IngestionBatchBytes int64
+}
+
+type PrometheusConfig struct {
+	// Эндпоинт /prometheus и сбор HTTP метрик
+	Enabled bool
+	// Внутренний адрес для /prometheus без авторизации (порт не публикуется наружу); пусто - не слушать
+	ListenAddr string
+	// Если задан, /prometheus доступен и на основном порту с Authorization: Bearer <token>
+	Token string
+}
+
+type MetricsConfig struct {
+	// Время жизни кеша engaged time в Redis
+	EngagedTimeCacheTTL time.Duration
+	// Максимальный период запроса engaged time
+	EngagedTimeMaxRange time.Duration
+	// Максимальный период сырой минутной серии (большой объем строк)
+	MinuteActivityMaxRange time.Duration
+	// Лимит времени тяжелых запросов метрик, по истечении запрос отменяется и возвращается 504 (0 - без лимита)
+	QueryTimeout time.Duration
+}
+
+type RetentionConfig struct {
+	// Сколько дней хранить сырые события user_behaviors (команда purge)
+	BehaviorRetentionDays int
+	// Размер пачки удаления и пауза между пачками, чтобы не держать долгие блокировки
+	PurgeBatchSize  int
+	PurgeBatchSleep time.Duration
+}
+
+type PaginationConfig struct {
+	Behaviors        entity.PaginationLimits
+	Sessions         entity.PaginationLimits
+	ExtensionUsers   entity.PaginationLimits
+	OrgAuditLog      entity.PaginationLimits
+	Users            entity.PaginationLimits
+	DeepWorkSessions entity.PaginationLimits
+}
+
+type IngestionConfig struct {
+	BehaviorTimestamps entity.TimestampBounds
+	MaxBatchEvents     int // максимум событий в одном /behaviors/batch
+	// Домены, которые не сохраняются и не учитываются в метриках (дополняют таблицу excluded_domains)
+	ExcludedDomains []string
+}
+
+type CORSConfig struct {
+	// Точные origin или wildcard поддомены вида https://*.inayla.com
+	AllowedOrigins []string
+	// localhost и 127.0.0.1 разрешены только вне prod
+	AllowLocalhost bool
 }
 
 type Config struct {
-	Server ServerConfig
-	DB     DatabaseConfig
-	Env    string
-	Redis  redis.RedisConfig
+	Server      ServerConfig
+	DB          DatabaseConfig
+	Env         string
+	JWT         JWTConfig
+	Redis       redis.RedisConfig
+	RateLimit   RateLimitConfig
+	BodyLimit   BodyLimitConfig
+	OpenAI      ai_analytics.OpenAIConfig
+	AIAnalytics entity.AIAnalyticsConfig
+	Prometheus  PrometheusConfig
+	CORS        CORSConfig
+	Metrics     MetricsConfig
+	Retention   RetentionConfig
+	Pagination  PaginationConfig
+	Ingestion   IngestionConfig
+	Focus       entity.FocusThresholds
 }
 
 func LoadConfig() *Config {
@@ -34,6 +128,10 @@ func LoadConfig() *Config {
 		log.Println("Warning: .env file not found, using environment variables")
 	}
 
+	// Общие модель и температура OpenAI - значения по умолчанию для отдельных запросов
+	openAIModel := getEnv("OPENAI_MODEL", "gpt-4o")
+	openAITemperature := getEnvAsFloat("OPENAI_TEMPERATURE", 0.1)
+
 	return &Config{
 		Server: ServerConfig{
 			Port:    getEnv("PORT", "8080"),
@@ -46,11 +144,115 @@ func LoadConfig() *Config {
 			Password: getEnv("DB_PASS", "test"),
 			DBName:   getEnv("DB_NAME", "expense_tracker_test"),
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+
+			MaxOpenConns:    getEnvAsInt("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:    getEnvAsInt("DB_MAX_IDLE_CONNS", 10),
+			ConnMaxLifetime: time.Duration(getEnvAsInt("DB_CONN_MAX_LIFETIME_SECONDS", 300)) * time.Second,
+		},
+		JWT: JWTConfig{
+			Secret:     getEnv("JWT_SECRET", ""),
+			Algorithm:  getEnv("JWT_ALGORITHM", "HS256"),
+			TTL:        time.Duration(getEnvAsInt("JWT_TTL_HOURS", 24)) * time.Hour,
+			RefreshTTL: time.Duration(getEnvAsInt("JWT_REFRESH_TTL_DAYS", 30)) * 24 * time.Hour,
 		},
 		Redis: redis.RedisConfig{
 			Host:     getEnv("REDIS_HOST", ""),
 			Port:     getEnv("REDIS_PORT", ""),
 			Password: getEnv("REDIS_PASSWORD", ""),
+
+			HealthCheckInterval: time.Duration(getEnvAsInt("REDIS_HEALTH_CHECK_INTERVAL_SECONDS", 15)) * time.Second,
+		},
+		OpenAI: ai_analytics.OpenAIConfig{
+			APIKey:  getEnv("OPENAI_API_KEY", ""),
+			Model:   openAIModel,
+			BaseURL: getEnv("OPENAI_BASE_URL", "https://api.openai.com/v1/chat/completions"),
+			Timeout: time.Duration(getEnvAsInt("OPENAI_TIMEOUT_SECONDS", 30)) * time.Second,
+
+			RetryAttempts:  getEnvAsInt("OPENAI_RETRY_ATTEMPTS", 2),
+			RetryBaseDelay: time.Duration(getEnvAsInt("OPENAI_RETRY_BASE_DELAY_MS", 500)) * time.Millisecond,
+
+			Temperature: openAITemperature,
+			DomainUsage: ai_analytics.CallConfig{
+				Model:       getEnv("OPENAI_DOMAIN_USAGE_MODEL", openAIModel),
+				Temperature: getEnvAsFloat("OPENAI_DOMAIN_USAGE_TEMPERATURE", openAITemperature),
+				MaxTokens:   getEnvAsInt("OPENAI_DOMAIN_USAGE_MAX_TOKENS", 500),
+			},
+			FocusLevel: ai_analytics.CallConfig{
+				Model:       getEnv("OPENAI_FOCUS_LEVEL_MODEL", openAIModel),
+				Temperature: getEnvAsFloat("OPENAI_FOCUS_LEVEL_TEMPERATURE", openAITemperature),
+				MaxTokens:   getEnvAsInt("OPENAI_FOCUS_LEVEL_MAX_TOKENS", 200),
+			},
+		},
+		RateLimit: RateLimitConfig{
+			IngestionPerMinute: getEnvAsInt("INGESTION_RATE_LIMIT_PER_MINUTE", 600),
+		},
+		AIAnalytics: entity.AIAnalyticsConfig{
+			// Лимит AI анализов в час на пользователя (ответы из кеша не считаются), 0 - без лимита
+			RateLimitPerHour: getEnvAsInt("AI_RATE_LIMIT_PER_HOUR", 30),
+		},
+		BodyLimit: BodyLimitConfig{
+			DefaultBytes:        int64(getEnvAsInt("MAX_REQUEST_BODY_BYTES", 1<<20)),
+			IngestionBatchBytes: int64(getEnvAsInt("MAX_BATCH_REQUEST_BODY_BYTES", 8<<20)),
+		},
+		Prometheus: PrometheusConfig{
+			Enabled:    getEnvAsBool("PROMETHEUS_ENABLED", true),
+			ListenAddr: getEnv("PROMETHEUS_LISTEN_ADDR", ":9091"),
+			Token:      getEnv("PROMETHEUS_TOKEN", ""),
+		},
+		Metrics: MetricsConfig{
+			EngagedTimeCacheTTL:    time.Duration(getEnvAsPositiveInt("ENGAGED_TIME_CACHE_TTL_SECONDS", 3600)) * time.Second,
+			EngagedTimeMaxRange:    time.Duration(getEnvAsInt("ENGAGED_TIME_MAX_RANGE_DAYS", 90)) * 24 * time.Hour,
+			MinuteActivityMaxRange: time.Duration(getEnvAsInt("MINUTE_ACTIVITY_MAX_RANGE_HOURS", 24)) * time.Hour,
+			QueryTimeout:           time.Duration(getEnvAsInt("METRICS_QUERY_TIMEOUT_SECONDS", 30)) * time.Second,
+		},
+		Retention: RetentionConfig{
+			BehaviorRetentionDays: getEnvAsInt("BEHAVIOR_RETENTION_DAYS", 365),
+			PurgeBatchSize:        getEnvAsInt("PURGE_BATCH_SIZE", 5000),
+			PurgeBatchSleep:       time.Duration(getEnvAsInt("PURGE_BATCH_SLEEP_MS", 500)) * time.Millisecond,
+		},
+		Pagination: PaginationConfig{
+			Behaviors: entity.PaginationLimits{
+				DefaultPerPage: getEnvAsInt("BEHAVIORS_DEFAULT_PER_PAGE", 20),
+				MaxPerPage:     getEnvAsInt("BEHAVIORS_MAX_PER_PAGE", 1000),
+			},
+			Sessions: entity.PaginationLimits{
+				DefaultPerPage: getEnvAsInt("SESSIONS_DEFAULT_PER_PAGE", 50),
+				MaxPerPage:     getEnvAsInt("SESSIONS_MAX_PER_PAGE", 200),
+			},
+			ExtensionUsers: entity.PaginationLimits{
+				DefaultPerPage: getEnvAsInt("EXTENSION_USERS_DEFAULT_PER_PAGE", 20),
+				MaxPerPage:     getEnvAsInt("EXTENSION_USERS_MAX_PER_PAGE", 200),
+			},
+			OrgAuditLog: entity.PaginationLimits{
+				DefaultPerPage: getEnvAsInt("ORG_AUDIT_LOG_DEFAULT_PER_PAGE", 50),
+				MaxPerPage:     getEnvAsInt("ORG_AUDIT_LOG_MAX_PER_PAGE", 200),
+			},
+			Users: entity.PaginationLimits{
+				DefaultPerPage: getEnvAsInt("USERS_DEFAULT_PER_PAGE", 50),
+				MaxPerPage:     getEnvAsInt("USERS_MAX_PER_PAGE", 200),
+			},
+			DeepWorkSessions: entity.PaginationLimits{
+				DefaultPerPage: getEnvAsInt("DEEP_WORK_SESSIONS_DEFAULT_LIMIT", 100),
+				MaxPerPage:     getEnvAsInt("DEEP_WORK_SESSIONS_MAX_LIMIT", 500),
+			},
+		},
+		Ingestion: IngestionConfig{
+			BehaviorTimestamps: entity.TimestampBounds{
+				MaxFutureSkew: time.Duration(getEnvAsInt("BEHAVIOR_MAX_FUTURE_SKEW_SECONDS", 300)) * time.Second,
+				Floor:         getEnvAsTime("BEHAVIOR_MIN_TIMESTAMP", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+			},
+			MaxBatchEvents:  getEnvAsInt("BEHAVIOR_MAX_BATCH_EVENTS", 1000),
+			ExcludedDomains: getEnvAsSlice("EXCLUDED_DOMAINS", nil),
+		},
+		Focus: entity.FocusThresholds{
+			HighMaxDomains:           getEnvAsInt("FOCUS_HIGH_MAX_DOMAINS", 5),
+			MediumMaxDomains:         getEnvAsInt("FOCUS_MEDIUM_MAX_DOMAINS", 15),
+			HighMaxSwitchesPerHour:   getEnvAsInt("FOCUS_HIGH_MAX_SWITCHES_PER_HOUR", 5),
+			MediumMaxSwitchesPerHour: getEnvAsInt("FOCUS_MEDIUM_MAX_SWITCHES_PER_HOUR", 15),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: getEnvAsSlice("CORS_ALLOWED_ORIGINS", []string{"https://inayla.com"}),
+			AllowLocalhost: getEnv("ENV", "prod") != "prod",
 		},
 		Env: getEnv("ENV", "prod"),
 	}
@@ -62,3 +264,93 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvAsInt(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: invalid value for %s, using default %d", key, defaultValue)
+		return defaultValue
+	}
+
+	return parsed
+}
+
+// getEnvAsPositiveInt как getEnvAsInt, но значения <= 0 заменяются значением по умолчанию
+// (например, нулевой TTL в Redis означает запись без срока жизни)
+func getEnvAsPositiveInt(key string, defaultValue int) int {
+	parsed := getEnvAsInt(key, defaultValue)
+	if parsed <= 0 {
+		log.Printf("Warning: %s must be positive, using default %d", key, defaultValue)
+		return defaultValue
+	}
+
+	return parsed
+}
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Warning: invalid value for %s, using default %g", key, defaultValue)
+		return defaultValue
+	}
+
+	return parsed
+}
+
+// getEnvAsSlice читает список значений через запятую
+func getEnvAsSlice(key string, defaultValue []string) []string {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+// getEnvAsTime читает время в формате RFC3339
+func getEnvAsTime(key string, defaultValue time.Time) time.Time {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		log.Printf("Warning: invalid value for %s, using default %s", key, defaultValue.Format(time.RFC3339))
+		return defaultValue
+	}
+
+	return parsed
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Warning: invalid value for %s, using default %t", key, defaultValue)
+		return defaultValue
+	}
+
+	return parsed
+}
@@ -0,0 +1,26 @@
+package entity
+
+import "time"
+
+// Статусы асинхронной задачи AI анализа: pending -> done | failed
+const (
+	AIJobStatusPending = "pending"
+	AIJobStatusDone    = "done"
+	AIJobStatusFailed  = "failed"
+)
+
+// AIAnalysisJob - асинхронная задача AI анализа, хранится в Redis под своим ID
+type AIAnalysisJob struct {
+	ID          string          `json:"id" example:"5b0c1a7e-2f4d-4e51-9a0b-8c5d3b1e7f21"`
+	Status      string          `json:"status" example:"pending"`
+	UserID      string          `json:"user_id"`
+	Result      *DomainAnalysis `json:"result,omitempty"`
+	Meta        *AnalyticsMeta  `json:"meta,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+}
+
+func (j *AIAnalysisJob) IsFinished() bool {
+	return j.Status == AIJobStatusDone || j.Status == AIJobStatusFailed
+}
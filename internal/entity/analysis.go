@@ -39,8 +39,10 @@ type AIAnalyticsRequest struct {
 	DeepWork       DeepWorkData `json:"deep_work" binding:"required"`
 	EngagementRate float64      `json:"engagement_rate" binding:"required,min=0,max=100"`
 	TrackedHours   float64      `json:"tracked_hours" binding:"required,min=0"`
+	EventsCount    int          `json:"events_count,omitempty"` // общее число событий за период, влияет на оценку качества данных
 	UserID         string       `json:"user_id,omitempty"`
 	Period         string       `json:"period,omitempty"`
+	Lang           string       `json:"lang,omitempty" example:"ru"` // язык инсайтов: ru (по умолчанию), en
 }
 
 // FocusLevelResponse представляет ответ с уровнем фокуса
@@ -61,8 +63,9 @@ type AnalyticsMeta struct {
 	ProcessedAt     time.Time `json:"processed_at"`
 	ProcessingTime  int64     `json:"processing_time_ms"`
 	AIModel         string    `json:"ai_model,omitempty"`
-	DataQuality     string    `json:"data_quality"` // "high", "medium", "low"
-	ConfidenceScore float64   `json:"confidence_score,omitempty"`
+	DataQuality     string    `json:"data_quality"`       // "high", "medium", "low"
+	ConfidenceScore float64   `json:"confidence_score"`   // 0-1
+	Warnings        []string  `json:"warnings,omitempty"` // например, слишком мало отслеженного времени
 }
 
 type EnhancedDomainAnalysis struct {
@@ -128,6 +131,23 @@ type AIAnalyticsHealthCheck struct {
 	RequestsLimit int       `json:"requests_limit"`
 }
 
+// AIUsageTotals - расход токенов OpenAI за период; стоимость оценочная, по прайсу модели на момент запроса
+type AIUsageTotals struct {
+	Requests         int64   `json:"requests" example:"42"`
+	PromptTokens     int64   `json:"prompt_tokens" example:"61200"`
+	CompletionTokens int64   `json:"completion_tokens" example:"14300"`
+	TotalTokens      int64   `json:"total_tokens" example:"75500"`
+	EstimatedCostUSD float64 `json:"estimated_cost_usd" example:"0.2963"`
+}
+
+// AIUsageStats - расход OpenAI за текущий день и месяц (UTC)
+type AIUsageStats struct {
+	Date       string        `json:"date" example:"2026-10-16"`
+	MonthStart string        `json:"month_start" example:"2026-10-01"`
+	Today      AIUsageTotals `json:"today"`
+	Month      AIUsageTotals `json:"month"`
+}
+
 // BatchAnalyticsRequest запрос для пакетного анализа
 type BatchAnalyticsRequest struct {
 	Requests []AIAnalyticsRequest `json:"requests" binding:"required,min=1,max=10"`
@@ -0,0 +1,54 @@
+package entity
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// BehaviorCursor - позиция последнего отданного события для keyset пагинации
+type BehaviorCursor struct {
+	Timestamp time.Time
+	ID        uuid.UUID
+}
+
+// Encode возвращает непрозрачный курсор: base64("<timestamp>|<id>")
+func (c BehaviorCursor) Encode() string {
+	raw := c.Timestamp.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeBehaviorCursor(cursor string) (*BehaviorCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return nil, ErrInvalidCursor
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	id, err := uuid.FromString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &BehaviorCursor{Timestamp: timestamp, ID: id}, nil
+}
+
+type CursorPaginationInfo struct {
+	PerPage    int    `json:"per_page"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
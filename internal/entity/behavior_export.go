@@ -0,0 +1,21 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Форматы выгрузки событий
+const (
+	BehaviorExportFormatNDJSON = "ndjson"
+	BehaviorExportFormatCSV    = "csv"
+)
+
+// BehaviorExportFilter - выгрузка событий пользователя и/или сессии за период (нужен хотя бы один из UserID, SessionID)
+type BehaviorExportFilter struct {
+	UserID    *uuid.UUID
+	SessionID *string
+	StartTime time.Time
+	EndTime   time.Time
+}
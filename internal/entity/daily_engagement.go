@@ -0,0 +1,44 @@
+package entity
+
+import "time"
+
+// DailyEngagement - строка rollup таблицы daily_engagement (день в UTC)
+type DailyEngagement struct {
+	UserID         string    `db:"user_id"`
+	Day            time.Time `db:"day"`
+	ActiveMinutes  int       `db:"active_minutes"`
+	TrackedMinutes int       `db:"tracked_minutes"`
+	UpdatedAt      time.Time `db:"updated_at"`
+}
+
+type EngagedTimeDailyFilter struct {
+	UserID    string    `form:"user_id" json:"user_id" binding:"required"`
+	StartTime time.Time `form:"start_time" json:"start_time" binding:"required"`
+	EndTime   time.Time `form:"end_time" json:"end_time" binding:"required"`
+
+	ActiveEvents []string `form:"-" json:"-"` // набор активных событий организации (nil = по умолчанию)
+}
+
+type EngagedTimeDay struct {
+	Date           string  `json:"date" example:"2025-07-01"`
+	ActiveMinutes  int     `json:"active_minutes" example:"245"`
+	TrackedMinutes int     `json:"tracked_minutes" example:"380"`
+	EngagementRate float64 `json:"engagement_rate" example:"64.47"`
+	Source         string  `json:"source" example:"rollup"` // rollup - из daily_engagement, raw - посчитано по событиям
+}
+
+type EngagedTimeDailyMetric struct {
+	UserID         string           `json:"user_id" example:"39b962b6-d4fa-49a6-8f3e-e4ff9b6bb0df"`
+	StartTime      time.Time        `json:"start_time" example:"2025-07-01T00:00:00Z"`
+	EndTime        time.Time        `json:"end_time" example:"2025-07-31T23:59:59Z"`
+	ActiveMinutes  int              `json:"active_minutes" example:"5230"`
+	TrackedMinutes int              `json:"tracked_minutes" example:"8120"`
+	EngagementRate float64          `json:"engagement_rate" example:"64.41"`
+	Days           []EngagedTimeDay `json:"days"`
+}
+
+type EngagedTimeDailyResponse struct {
+	Data    *EngagedTimeDailyMetric `json:"data"`
+	Success bool                    `json:"success"`
+	Message string                  `json:"message,omitempty"`
+}
@@ -0,0 +1,30 @@
+// internal/entity/domain_category.go
+package entity
+
+import (
+	"github.com/gofrs/uuid"
+	"time"
+)
+
+// Категории доменов, совпадают с полями DomainBreakdown
+const (
+	DomainCategoryWorkTools     = "work_tools"
+	DomainCategoryDevelopment   = "development"
+	DomainCategoryResearch      = "research"
+	DomainCategoryCommunication = "communication"
+	DomainCategoryDistractions  = "distractions"
+)
+
+// DomainCategory - закрепленная админом категория домена для AI анализа
+type DomainCategory struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	Domain    string    `json:"domain" db:"domain" example:"jira.internal.company.com"`
+	Category  string    `json:"category" db:"category" example:"work_tools"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type DomainCategoryRequest struct {
+	Domain   string `json:"domain" binding:"required" example:"jira.internal.company.com"`
+	Category string `json:"category" binding:"required,oneof=work_tools development research communication distractions" example:"work_tools"`
+}
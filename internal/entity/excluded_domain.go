@@ -0,0 +1,19 @@
+// internal/entity/excluded_domain.go
+package entity
+
+import (
+	"github.com/gofrs/uuid"
+	"time"
+)
+
+// ExcludedDomain - домен, исключенный из метрик и приема событий
+type ExcludedDomain struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	Domain    string    `json:"domain" db:"domain" example:"chrome"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type ExcludedDomainRequest struct {
+	Domain string `json:"domain" binding:"required,max=255" example:"chrome-extension"`
+}
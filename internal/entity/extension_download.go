@@ -0,0 +1,13 @@
+// internal/entity/extension_download.go
+package entity
+
+import "time"
+
+type ExtensionDownloadStats struct {
+	TotalDownloads int        `json:"total_downloads" db:"total_downloads"`
+	LastDownload   *time.Time `json:"last_download" db:"last_download"`
+}
+
+type TrackExtensionDownloadRequest struct {
+	UserAgent string `json:"user_agent" example:"Mozilla/5.0 (Windows NT 10.0; Win64; x64)"`
+}
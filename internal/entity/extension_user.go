@@ -2,6 +2,7 @@
 package entity
 
 import (
+	"encoding/json"
 	"github.com/gofrs/uuid"
 	"time"
 )
@@ -9,13 +10,16 @@ import (
 type ExtensionUser struct {
 	ID             uuid.UUID         `json:"id" db:"id"`
 	Username       string            `json:"username" db:"username"`
-	APIKey         string            `json:"apiKey" db:"api_key"`
+	APIKey         string            `json:"apiKey,omitempty" db:"-"` // открытый ключ, заполняется только при создании
+	APIKeyHash     string            `json:"-" db:"api_key"`
 	IsActive       bool              `json:"isActive" db:"is_active"`
 	CreatedAt      time.Time         `json:"createdAt" db:"created_at"`
 	UpdatedAt      time.Time         `json:"updatedAt" db:"updated_at"`
 	LastUsedAt     *time.Time        `json:"lastUsedAt" db:"last_used_at"`
 	OrganizationID uuid.UUID         `json:"organization_id,omitzero" db:"organization_id"`
 	Organization   *OrganizationInfo `json:"organization,omitempty"`
+	// Участник организации (users), которому принадлежит расширение
+	OwnerUserID *uuid.UUID `json:"owner_user_id,omitempty" db:"owner_user_id"`
 }
 
 type ExtensionUserPublic struct {
@@ -36,6 +40,7 @@ type OrganizationInfo struct {
 type CreateExtensionUserRequest struct {
 	Username       string     `json:"username" binding:"required,min=3,max=100"`
 	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	OwnerUserID    *uuid.UUID `json:"owner_user_id,omitempty"`
 }
 
 type UpdateExtensionUserRequest struct {
@@ -43,6 +48,29 @@ type UpdateExtensionUserRequest struct {
 	IsActive       *bool      `json:"isActive,omitempty"`
 	APIKey         *string    `json:"apiKey,omitempty"`
 	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	// null снимает привязку к участнику организации, отсутствие поля оставляет ее без изменений
+	OwnerUserID OptionalUUID `json:"owner_user_id,omitempty" swaggertype:"string" format:"uuid"`
+}
+
+// OptionalUUID отличает отсутствующее в JSON поле (Set = false) от явного null (Set = true, Value = nil)
+type OptionalUUID struct {
+	Set   bool
+	Value *uuid.UUID
+}
+
+func (o *OptionalUUID) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Value = nil
+		return nil
+	}
+
+	var id uuid.UUID
+	if err := json.Unmarshal(data, &id); err != nil {
+		return err
+	}
+	o.Value = &id
+	return nil
 }
 
 type RegenerateAPIKeyResponse struct {
@@ -50,10 +78,22 @@ type RegenerateAPIKeyResponse struct {
 	APIKey string    `json:"apiKey"`
 }
 
+// Допустимые значения sort_by для списка пользователей расширения
+var ExtensionUserSortFields = map[string]bool{
+	"username":     true,
+	"created_at":   true,
+	"updated_at":   true,
+	"last_used_at": true,
+}
+
 type ExtensionUserFilter struct {
 	Username       string     `form:"username" json:"username"`
 	IsActive       *bool      `form:"isActive" json:"is_active"`
 	OrganizationID *uuid.UUID `form:"organization_id" json:"organization_id"`
+	LastUsedBefore *time.Time `form:"last_used_before" json:"last_used_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	LastUsedAfter  *time.Time `form:"last_used_after" json:"last_used_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	SortBy         string     `form:"sort_by" json:"sort_by"`
+	Order          string     `form:"order" json:"order"`
 	Limit          int        `form:"limit" json:"limit"`
 	Offset         int        `form:"offset" json:"offset"`
 	Page           int        `form:"page" json:"page"`
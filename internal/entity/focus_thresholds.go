@@ -0,0 +1,69 @@
+package entity
+
+// Уровни фокуса
+const (
+	FocusLevelHigh   = "high"
+	FocusLevelMedium = "medium"
+	FocusLevelLow    = "low"
+)
+
+// FocusThresholds - единые границы уровня фокуса (задаются в конфиге).
+// Значения включительные: <= High* - high, <= Medium* - medium, выше - low
+type FocusThresholds struct {
+	// По числу уникальных доменов за период (без deep work блоков и в AI fallback)
+	HighMaxDomains   int
+	MediumMaxDomains int
+	// По переключениям контекста в час внутри deep work блоков
+	HighMaxSwitchesPerHour   int
+	MediumMaxSwitchesPerHour int
+}
+
+// DefaultFocusThresholds - значения по умолчанию: 5/15 доменов и 5/15 переключений в час
+func DefaultFocusThresholds() FocusThresholds {
+	return FocusThresholds{
+		HighMaxDomains:           5,
+		MediumMaxDomains:         15,
+		HighMaxSwitchesPerHour:   5,
+		MediumMaxSwitchesPerHour: 15,
+	}
+}
+
+// Normalize заменяет невалидные пары (<= 0 или medium < high) значениями по умолчанию
+func (t FocusThresholds) Normalize() FocusThresholds {
+	defaults := DefaultFocusThresholds()
+
+	if t.HighMaxDomains <= 0 || t.MediumMaxDomains < t.HighMaxDomains {
+		t.HighMaxDomains = defaults.HighMaxDomains
+		t.MediumMaxDomains = defaults.MediumMaxDomains
+	}
+	if t.HighMaxSwitchesPerHour <= 0 || t.MediumMaxSwitchesPerHour < t.HighMaxSwitchesPerHour {
+		t.HighMaxSwitchesPerHour = defaults.HighMaxSwitchesPerHour
+		t.MediumMaxSwitchesPerHour = defaults.MediumMaxSwitchesPerHour
+	}
+
+	return t
+}
+
+// DomainsLevel возвращает уровень фокуса по числу уникальных доменов
+func (t FocusThresholds) DomainsLevel(domainsCount int) string {
+	switch {
+	case domainsCount <= t.HighMaxDomains:
+		return FocusLevelHigh
+	case domainsCount <= t.MediumMaxDomains:
+		return FocusLevelMedium
+	default:
+		return FocusLevelLow
+	}
+}
+
+// SwitchesLevel возвращает уровень фокуса по переключениям контекста в час
+func (t FocusThresholds) SwitchesLevel(switchesPerHour float64) string {
+	switch {
+	case switchesPerHour <= float64(t.HighMaxSwitchesPerHour):
+		return FocusLevelHigh
+	case switchesPerHour <= float64(t.MediumMaxSwitchesPerHour):
+		return FocusLevelMedium
+	default:
+		return FocusLevelLow
+	}
+}
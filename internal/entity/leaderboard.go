@@ -0,0 +1,52 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Метрики, по которым можно построить рейтинг организации
+const (
+	LeaderboardMetricDeepWork       = "deep_work"
+	LeaderboardMetricEngagementRate = "engagement_rate"
+	LeaderboardMetricTrackedHours   = "tracked_hours"
+)
+
+type LeaderboardFilter struct {
+	OrganizationID uuid.UUID
+	StartTime      time.Time
+	EndTime        time.Time
+	Metric         string
+
+	ActiveEvents    []string // набор активных событий организации (nil = по умолчанию)
+	ExcludedDomains []string // домены из списка исключений, не учитываются в рейтинге
+}
+
+// LeaderboardEntry - метрики одного пользователя расширения организации, Value - значение выбранной метрики
+type LeaderboardEntry struct {
+	Rank            int        `json:"rank" example:"1"`
+	UserID          string     `json:"user_id" db:"user_id" example:"39b962b6-d4fa-49a6-8f3e-e4ff9b6bb0df"`
+	Username        string     `json:"username" db:"username" example:"john"`
+	OwnerUserID     *uuid.UUID `json:"owner_user_id" db:"owner_user_id"`
+	Value           float64    `json:"value" example:"312.5"`
+	DeepWorkMinutes float64    `json:"deep_work_minutes" db:"deep_work_minutes" example:"312.5"`
+	ActiveMinutes   int        `json:"active_minutes" db:"active_minutes" example:"245"`
+	TrackedMinutes  int        `json:"tracked_minutes" db:"tracked_minutes" example:"380"`
+	TrackedHours    float64    `json:"tracked_hours" example:"6.33"`
+	EngagementRate  float64    `json:"engagement_rate" example:"64.47"`
+}
+
+type Leaderboard struct {
+	OrganizationID uuid.UUID          `json:"organization_id"`
+	Metric         string             `json:"metric" example:"deep_work"`
+	StartTime      time.Time          `json:"start_time" example:"2025-07-01T00:00:00Z"`
+	EndTime        time.Time          `json:"end_time" example:"2025-07-31T23:59:59Z"`
+	Entries        []LeaderboardEntry `json:"entries"` // по убыванию value
+}
+
+type LeaderboardResponse struct {
+	Data    *Leaderboard `json:"data"`
+	Success bool         `json:"success"`
+	Message string       `json:"message,omitempty"`
+}
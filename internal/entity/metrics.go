@@ -1,7 +1,13 @@
 // entity/metrics_service.go
 package entity
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// ErrQueryTimeout - запрос метрик не уложился в METRICS_QUERY_TIMEOUT_SECONDS, хендлеры отвечают 504
+var ErrQueryTimeout = errors.New("metrics query timed out")
 
 type TrackedTimeMetric struct {
 	UserID       string    `json:"user_id"`
@@ -18,6 +24,23 @@ type TrackedTimeFilter struct {
 	StartTime time.Time `form:"start_time" json:"start_time" binding:"required"`
 	EndTime   time.Time `form:"end_time" json:"end_time" binding:"required"`
 	SessionID *string   `form:"session_id" json:"session_id,omitempty"`
+
+	ExcludedDomains []string `form:"-" json:"-"` // домены из списка исключений, не учитываются в метриках
+}
+
+const MaxTrackedTimeTotalUsers = 50
+
+type TrackedTimeTotalByUsersFilter struct {
+	UserIDs   []string `json:"user_ids"`
+	SessionID *string  `json:"session_id,omitempty"`
+
+	ExcludedDomains []string `json:"-"` // домены из списка исключений, не учитываются в метриках
+}
+
+type TrackedTimeTotalByUsersResponse struct {
+	Data    map[string]*TrackedTimeMetric `json:"data"`
+	Success bool                          `json:"success"`
+	Message string                        `json:"message,omitempty"`
 }
 
 type TrackedTimeResponse struct {
@@ -27,23 +50,61 @@ type TrackedTimeResponse struct {
 }
 
 type EngagedTimeMetric struct {
-	UserID         string    `json:"user_id" db:"user_id"`
-	ActiveMinutes  int       `json:"active_minutes" db:"active_minutes"`
-	ActiveHours    float64   `json:"active_hours" db:"active_hours"`
-	ActiveEvents   int       `json:"active_events" db:"active_events"`
-	Sessions       int       `json:"sessions" db:"sessions"`
-	TrackedMinutes float64   `json:"tracked_minutes" db:"tracked_minutes"`
-	TrackedHours   float64   `json:"tracked_hours" db:"tracked_hours"`
-	EngagementRate float64   `json:"engagement_rate" db:"engagement_rate"`
-	StartTime      time.Time `json:"start_time" db:"start_time"`
-	EndTime        time.Time `json:"end_time" db:"end_time"`
-	Period         string    `json:"period" db:"period"`
+	UserID          string    `json:"user_id" db:"user_id"`
+	ActiveMinutes   int       `json:"active_minutes" db:"active_minutes"`
+	ActiveHours     float64   `json:"active_hours" db:"active_hours"`
+	ActiveEvents    int       `json:"active_events" db:"active_events"`
+	IdleEventsCount int       `json:"idle_events_count" db:"idle_events_count"` // явные idle события, их минуты всегда idle
+	Sessions        int       `json:"sessions" db:"sessions"`
+	TrackedMinutes  float64   `json:"tracked_minutes" db:"tracked_minutes"`
+	TrackedHours    float64   `json:"tracked_hours" db:"tracked_hours"`
+	EngagementRate  float64   `json:"engagement_rate" db:"engagement_rate"`
+	StartTime       time.Time `json:"start_time" db:"start_time"`
+	EndTime         time.Time `json:"end_time" db:"end_time"`
+	Period          string    `json:"period" db:"period"`
 
 	DeepWork DeepWorkData `json:"deep_work"`
 
 	UniqueDomainsCount int          `json:"unique_domains_count" db:"unique_domains_count"`
 	DomainsList        []string     `json:"domains_list" db:"domains_list"`
 	HourlyBreakdown    []HourlyData `json:"hourly_breakdown"`
+
+	IdleIntervals []IdleInterval `json:"idle_intervals"` // топ-100 самых длинных разрывов
+
+	FocusLevel   string `json:"focus_level" example:"high" enums:"high,medium,low"`
+	FocusInsight string `json:"focus_insight"`
+	FocusMethod  string `json:"focus_method" example:"switches" enums:"domains,switches"` // фактически примененный способ
+
+	Comparison *EngagementComparison `json:"comparison,omitempty"`
+
+	Analysis *EngagedTimeAnalysis `json:"analysis,omitempty"`
+}
+
+// Источник productivity score
+const AnalysisSourceRules = "rules" // детерминированная формула на сервере, без AI
+
+// EngagedTimeAnalysis - productivity score, посчитанный сервером; база для сравнения с оценкой AI
+type EngagedTimeAnalysis struct {
+	ProductivityScore ProductivityScore `json:"productivity_score"`
+	Source            string            `json:"source" example:"rules"`
+}
+
+// EngagementComparison - сравнение с предыдущим периодом такой же длины
+type EngagementComparison struct {
+	PreviousStartTime time.Time `json:"previous_start_time"`
+	PreviousEndTime   time.Time `json:"previous_end_time"`
+
+	PreviousEngagementRate float64 `json:"previous_engagement_rate"`
+	EngagementRateDelta    float64 `json:"engagement_rate_delta"`  // в процентных пунктах
+	EngagementRateChange   float64 `json:"engagement_rate_change"` // % изменения
+
+	PreviousDeepWorkMinutes float64 `json:"previous_deep_work_minutes"`
+	DeepWorkMinutesDelta    float64 `json:"deep_work_minutes_delta"`
+	DeepWorkMinutesChange   float64 `json:"deep_work_minutes_change"`
+
+	PreviousActiveMinutes int     `json:"previous_active_minutes"`
+	ActiveMinutesDelta    int     `json:"active_minutes_delta"`
+	ActiveMinutesChange   float64 `json:"active_minutes_change"`
 }
 
 type HourlyData struct {
@@ -58,19 +119,53 @@ type HourlyData struct {
 	Productivity float64 `json:"productivity"` // engaged_mins / total_mins * 100
 }
 
+type IdleInterval struct {
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"`
+	DurationSeconds int       `json:"duration_seconds"`
+}
+
 type EngagedTimeFilter struct {
 	UserID    string    `form:"user_id" json:"user_id" binding:"required"`
 	StartTime time.Time `form:"start_time" json:"start_time" binding:"required"`
 	EndTime   time.Time `form:"end_time" json:"end_time" binding:"required"`
 	SessionID *string   `form:"session_id" json:"session_id,omitempty"`
+
+	// Пороги Deep Work (0 = значение по умолчанию)
+	MinDurationMinutes  int `form:"min_duration" json:"min_duration,omitempty"`
+	GapThresholdSeconds int `form:"gap_threshold" json:"gap_threshold,omitempty"`
+	MinEventsPerBlock   int `form:"min_events" json:"min_events,omitempty"`
+
+	ComparePrevious bool `form:"-" json:"-"` // compare=previous
+
+	FocusMethod string `form:"-" json:"-"` // focus_method=domains|switches, пусто = switches
+
+	ActiveEvents []string `form:"-" json:"-"` // набор активных событий организации (nil = по умолчанию)
+
+	// active_events=click,keydown - набор активных событий только для этого запроса, заменяет набор организации
+	CustomActiveEvents []string `form:"-" json:"-"`
+
+	ExcludedDomains []string `form:"-" json:"-"` // домены из списка исключений, не учитываются в метриках
 }
 
+// Способы расчета уровня фокуса в engaged time
+const (
+	FocusMethodDomains  = "domains"  // по числу уникальных доменов за период
+	FocusMethodSwitches = "switches" // по переключениям контекста в deep work блоках
+)
+
 type EngagedTimeResponse struct {
 	Data    *EngagedTimeMetric `json:"data"`
 	Success bool               `json:"success"`
 	Message string             `json:"message,omitempty"`
 }
 
+// MetricsCacheInvalidation - результат сброса кеша метрик пользователя
+type MetricsCacheInvalidation struct {
+	UserID          string `json:"user_id"`
+	InvalidatedKeys int64  `json:"invalidated_keys" example:"3"`
+}
+
 type DeepWorkData struct {
 	SessionsCount  int              `json:"sessions_count"`        // количество deep work сессий
 	TotalMinutes   float64          `json:"total_minutes"`         // общее время в deep work
@@ -79,6 +174,14 @@ type DeepWorkData struct {
 	LongestMinutes float64          `json:"longest_minutes"`       // самая длинная сессия
 	DeepWorkRate   float64          `json:"deep_work_rate"`        // % от tracked time
 	TopDomains     []DeepWorkDomain `json:"top_domains,omitempty"` // топ доменов для deep work
+
+	ContextSwitches    int     `json:"context_switches"`      // переключения доменов внутри deep work блоков
+	AvgSwitchesPerHour float64 `json:"avg_switches_per_hour"` // среднее по блокам
+
+	// Распределение блоков по focus_level, как в DeepWorkSessionsResponse.ContextSwitches
+	HighFocusBlocks   int `json:"high_focus_blocks"`
+	MediumFocusBlocks int `json:"medium_focus_blocks"`
+	LowFocusBlocks    int `json:"low_focus_blocks"`
 }
 
 type DeepWorkDomain struct {
@@ -92,6 +195,19 @@ type DeepWorkSessionsFilter struct {
 	StartTime time.Time `json:"start_time" validate:"required" example:"2025-07-10T08:00:00Z"`
 	EndTime   time.Time `json:"end_time" validate:"required" example:"2025-07-11T19:59:59Z"`
 	SessionID *string   `json:"session_id,omitempty" example:"session_12345"`
+
+	// Пороги Deep Work (0 = значение по умолчанию)
+	MinDurationMinutes  int `json:"min_duration,omitempty" example:"25"`
+	GapThresholdSeconds int `json:"gap_threshold,omitempty" example:"300"`
+	MinEventsPerBlock   int `json:"min_events,omitempty" example:"10"`
+
+	// Страница списка Sessions (по start_time); агрегаты считаются по всем блокам.
+	// Limit ограничивается сверху конфигом, 0 = значение по умолчанию
+	Limit  int `json:"limit,omitempty" example:"100"`
+	Offset int `json:"offset,omitempty" example:"0"`
+
+	ActiveEvents    []string `json:"-"` // набор активных событий организации (nil = по умолчанию)
+	ExcludedDomains []string `json:"-"` // домены из списка исключений, не учитываются в метриках
 }
 
 type HourlyDeepWorkData struct {
@@ -118,6 +234,37 @@ type DeepWorkSession struct {
 	FocusLevel      string    `json:"focus_level" example:"high" enums:"high,medium,low"`
 }
 
+// SessionDeepWork - deep work блоки одной сессии. Блок строится по активности пользователя без учета
+// session_id и может захватывать несколько сессий: он относится к сессии своего первого события,
+// а SessionsSpanned показывает, сколько сессий он фактически затронул
+type SessionDeepWork struct {
+	SessionID    string                 `json:"session_id" example:"session_1751443200_abc123"`
+	BlocksCount  int                    `json:"blocks_count" example:"2"`
+	TotalMinutes float64                `json:"total_minutes" example:"78.5"`
+	Blocks       []SessionDeepWorkBlock `json:"blocks"`
+}
+
+type SessionDeepWorkBlock struct {
+	DeepWorkSession
+	SessionsSpanned int `json:"sessions_spanned" example:"1"`
+}
+
+type DeepWorkBySessionResponse struct {
+	UserID    string            `json:"user_id" example:"39b962b6-d4fa-49a6-8f3e-e4ff9b6bb0df"`
+	StartTime time.Time         `json:"start_time" example:"2025-07-10T08:00:00Z"`
+	EndTime   time.Time         `json:"end_time" example:"2025-07-11T19:59:59Z"`
+	Sessions  []SessionDeepWork `json:"sessions"` // по времени первого блока
+}
+
+type DeepWorkBlockEventsResponse struct {
+	BlockID     int            `json:"block_id" example:"1"`
+	UserID      string         `json:"user_id" example:"39b962b6-d4fa-49a6-8f3e-e4ff9b6bb0df"`
+	StartTime   time.Time      `json:"start_time" example:"2025-07-10T14:15:00Z"` // начало блока
+	EndTime     time.Time      `json:"end_time" example:"2025-07-10T15:00:00Z"`   // конец блока
+	TotalEvents int            `json:"total_events" example:"342"`
+	Events      []UserBehavior `json:"events"`
+}
+
 type ContextSwitchesStats struct {
 	TotalSwitches      int     `json:"total_switches" example:"8"`
 	AvgSwitchesPerHour float64 `json:"avg_switches_per_hour" example:"3.75"`
@@ -145,11 +292,236 @@ type DeepWorkSessionsResponse struct {
 
 	DeepWorkContextRatio float64 `json:"deep_work_context_ratio" example:"0.375"`
 
-	Sessions []DeepWorkSession `json:"sessions"`
+	Sessions []DeepWorkSession `json:"sessions"` // страница блоков, всего блоков - SessionsCount
+	Limit    int               `json:"limit" example:"100"`
+	Offset   int               `json:"offset" example:"0"`
+	HasMore  bool              `json:"has_more" example:"false"`
 
 	HourlyBreakdown []HourlyDeepWorkData `json:"hourly_breakdown"`
 }
 
+type ActivityHeatmapFilter struct {
+	UserID    string    `form:"user_id" json:"user_id" binding:"required"`
+	StartTime time.Time `form:"start_time" json:"start_time" binding:"required"`
+	EndTime   time.Time `form:"end_time" json:"end_time" binding:"required"`
+	SessionID *string   `form:"session_id" json:"session_id,omitempty"`
+
+	ActiveEvents    []string `form:"-" json:"-"` // набор активных событий организации (nil = по умолчанию)
+	ExcludedDomains []string `form:"-" json:"-"` // домены из списка исключений, не учитываются в метриках
+}
+
+// ActivityHeatmap - сетка активных событий день недели × час.
+// Строки — дни недели как в PostgreSQL DOW (0 = воскресенье), столбцы — часы 0-23 (UTC).
+type ActivityHeatmap struct {
+	UserID      string     `json:"user_id" example:"39b962b6-d4fa-49a6-8f3e-e4ff9b6bb0df"`
+	StartTime   time.Time  `json:"start_time" example:"2025-07-01T00:00:00Z"`
+	EndTime     time.Time  `json:"end_time" example:"2025-07-31T23:59:59Z"`
+	Grid        [7][24]int `json:"grid"`
+	TotalEvents int        `json:"total_events" example:"15230"`
+	MaxEvents   int        `json:"max_events" example:"412"` // максимум в одной ячейке, для нормализации цвета
+}
+
+type ActivityHeatmapResponse struct {
+	Data    *ActivityHeatmap `json:"data"`
+	Success bool             `json:"success"`
+	Message string           `json:"message,omitempty"`
+}
+
+type SessionEngagementFilter struct {
+	UserID    string    `form:"user_id" json:"user_id" binding:"required"`
+	StartTime time.Time `form:"start_time" json:"start_time" binding:"required"`
+	EndTime   time.Time `form:"end_time" json:"end_time" binding:"required"`
+
+	ActiveEvents []string `form:"-" json:"-"` // набор активных событий организации (nil = по умолчанию)
+}
+
+// SessionEngagement - сводка вовлеченности по одной сессии
+type SessionEngagement struct {
+	SessionID      string    `json:"session_id" example:"session_1751443200_abc123"`
+	SessionStart   time.Time `json:"session_start" example:"2025-07-02T09:00:00Z"`
+	SessionEnd     time.Time `json:"session_end" example:"2025-07-02T11:30:00Z"`
+	ActiveMinutes  int       `json:"active_minutes" example:"95"`
+	TrackedMinutes int       `json:"tracked_minutes" example:"140"`
+	EngagementRate float64   `json:"engagement_rate" example:"67.86"` // процент активных минут
+	UniqueDomains  int       `json:"unique_domains" example:"7"`
+}
+
+type SessionEngagementMetric struct {
+	UserID    string              `json:"user_id" example:"39b962b6-d4fa-49a6-8f3e-e4ff9b6bb0df"`
+	StartTime time.Time           `json:"start_time" example:"2025-07-01T00:00:00Z"`
+	EndTime   time.Time           `json:"end_time" example:"2025-07-31T23:59:59Z"`
+	Sessions  []SessionEngagement `json:"sessions"`
+}
+
+type SessionEngagementResponse struct {
+	Data    *SessionEngagementMetric `json:"data"`
+	Success bool                     `json:"success"`
+	Message string                   `json:"message,omitempty"`
+}
+
+type TypingActivityFilter struct {
+	UserID    string    `form:"user_id" json:"user_id" binding:"required"`
+	StartTime time.Time `form:"start_time" json:"start_time" binding:"required"`
+	EndTime   time.Time `form:"end_time" json:"end_time" binding:"required"`
+	SessionID *string   `form:"session_id" json:"session_id,omitempty"`
+}
+
+// TypingStats - интенсивность набора текста. Минута считается "печатной", если в ней есть keydown/keyup,
+// нажатия за минуту = max(keydown, keyup), чтобы пара событий одной клавиши не считалась дважды
+type TypingStats struct {
+	TotalKeystrokes int     `json:"total_keystrokes" example:"8420"`
+	TypingMinutes   int     `json:"typing_minutes" example:"215"`
+	AvgKeysPerMin   float64 `json:"avg_keys_per_minute" example:"39.16"` // среднее по печатным минутам
+	PeakKeysPerMin  int     `json:"peak_keys_per_minute" example:"182"`
+}
+
+type DomainTypingActivity struct {
+	Domain string `json:"domain" example:"docs.google.com"`
+	TypingStats
+}
+
+type TypingActivity struct {
+	UserID    string    `json:"user_id" example:"39b962b6-d4fa-49a6-8f3e-e4ff9b6bb0df"`
+	StartTime time.Time `json:"start_time" example:"2025-07-01T00:00:00Z"`
+	EndTime   time.Time `json:"end_time" example:"2025-07-31T23:59:59Z"`
+	TypingStats
+	Domains []DomainTypingActivity `json:"domains"`
+}
+
+type TypingActivityResponse struct {
+	Data    *TypingActivity `json:"data"`
+	Success bool            `json:"success"`
+	Message string          `json:"message,omitempty"`
+}
+
+type ScrollActivityFilter struct {
+	UserID    string    `form:"user_id" json:"user_id" binding:"required"`
+	StartTime time.Time `form:"start_time" json:"start_time" binding:"required"`
+	EndTime   time.Time `form:"end_time" json:"end_time" binding:"required"`
+}
+
+// Профиль домена по соотношению прокрутки (scrollend) и взаимодействий (click, keydown, keyup)
+const (
+	ScrollProfileReadingHeavy     = "reading_heavy"
+	ScrollProfileInteractionHeavy = "interaction_heavy"
+	ScrollProfileMixed            = "mixed"
+)
+
+type DomainScrollActivity struct {
+	Domain            string  `json:"domain" example:"habr.com"`
+	ScrollEvents      int     `json:"scroll_events" example:"412"`
+	InteractionEvents int     `json:"interaction_events" example:"38"`
+	TotalEvents       int     `json:"total_events" example:"530"`
+	ScrollRatio       float64 `json:"scroll_ratio" example:"77.74"` // процент scrollend от всех событий домена
+	Profile           string  `json:"profile" example:"reading_heavy"`
+}
+
+type SessionScrollActivity struct {
+	SessionID    string  `json:"session_id" example:"session_1751443200_abc123"`
+	ScrollEvents int     `json:"scroll_events" example:"120"`
+	TotalEvents  int     `json:"total_events" example:"300"`
+	ScrollRatio  float64 `json:"scroll_ratio" example:"40"`
+}
+
+type ScrollActivity struct {
+	UserID       string                  `json:"user_id" example:"39b962b6-d4fa-49a6-8f3e-e4ff9b6bb0df"`
+	StartTime    time.Time               `json:"start_time" example:"2025-07-01T00:00:00Z"`
+	EndTime      time.Time               `json:"end_time" example:"2025-07-31T23:59:59Z"`
+	ScrollEvents int                     `json:"scroll_events" example:"1250"`
+	TotalEvents  int                     `json:"total_events" example:"5400"`
+	ScrollRatio  float64                 `json:"scroll_ratio" example:"23.15"`
+	Domains      []DomainScrollActivity  `json:"domains"`  // по убыванию scroll_events
+	Sessions     []SessionScrollActivity `json:"sessions"` // по убыванию scroll_events
+}
+
+type ScrollActivityResponse struct {
+	Data    *ScrollActivity `json:"data"`
+	Success bool            `json:"success"`
+	Message string          `json:"message,omitempty"`
+}
+
+// MinuteActivity - строка сырой минутной серии engaged time; одна минута может повторяться для разных доменов
+type MinuteActivity struct {
+	Minute       time.Time `json:"minute" db:"minute" example:"2025-07-01T10:15:00Z"` // начало минуты (UTC)
+	IsActive     bool      `json:"is_active" db:"is_active" example:"true"`
+	ActiveEvents int       `json:"active_events" db:"active_events" example:"7"`
+	IdleEvents   int       `json:"idle_events" db:"idle_events" example:"0"` // > 0 - минута idle независимо от активных событий
+	Domain       string    `json:"domain" db:"domain" example:"github.com"`
+}
+
+type MinuteActivitySeries struct {
+	UserID    string           `json:"user_id"`
+	StartTime time.Time        `json:"start_time"`
+	EndTime   time.Time        `json:"end_time"`
+	Minutes   []MinuteActivity `json:"minutes"` // по возрастанию minute
+}
+
+type MinuteActivityResponse struct {
+	Data    *MinuteActivitySeries `json:"data"`
+	Success bool                  `json:"success"`
+	Message string                `json:"message,omitempty"`
+}
+
+// ClickHeatmapCell - непустая ячейка сетки кликов; X/Y - номер колонки/строки (координата / cell_size)
+type ClickHeatmapCell struct {
+	X      int `json:"x" db:"cell_x" example:"3"`
+	Y      int `json:"y" db:"cell_y" example:"12"`
+	Clicks int `json:"clicks" db:"clicks" example:"27"`
+}
+
+type ClickHeatmap struct {
+	UserID      string             `json:"user_id"`
+	URL         string             `json:"url"`
+	CellSize    int                `json:"cell_size" example:"50"` // размер ячейки в пикселях
+	Columns     int                `json:"columns" example:"40"`   // ширина сетки: максимальная колонка + 1
+	Rows        int                `json:"rows" example:"120"`     // высота сетки: максимальная строка + 1
+	TotalClicks int                `json:"total_clicks" example:"845"`
+	Cells       []ClickHeatmapCell `json:"cells"` // только непустые ячейки, по строкам и колонкам
+}
+
+type ClickHeatmapResponse struct {
+	Data    *ClickHeatmap `json:"data"`
+	Success bool          `json:"success"`
+	Message string        `json:"message,omitempty"`
+}
+
+type TabSwitchFilter struct {
+	UserID    string    `form:"user_id" json:"user_id" binding:"required"`
+	StartTime time.Time `form:"start_time" json:"start_time" binding:"required"`
+	EndTime   time.Time `form:"end_time" json:"end_time" binding:"required"`
+}
+
+type HourlyTabSwitches struct {
+	Hour      time.Time `json:"hour" example:"2025-07-01T10:00:00Z"` // начало часа (UTC)
+	Switches  int       `json:"switches" example:"42"`
+	HighChurn bool      `json:"high_churn" example:"true"` // переключений не меньше high_churn_threshold
+}
+
+type DomainTabSwitches struct {
+	Domain   string  `json:"domain" example:"youtube.com"`
+	Switches int     `json:"switches" example:"57"`
+	Share    float64 `json:"share" example:"18.5"` // процент от всех переключений за период
+}
+
+type TabSwitchStats struct {
+	UserID             string              `json:"user_id" example:"39b962b6-d4fa-49a6-8f3e-e4ff9b6bb0df"`
+	StartTime          time.Time           `json:"start_time" example:"2025-07-01T00:00:00Z"`
+	EndTime            time.Time           `json:"end_time" example:"2025-07-31T23:59:59Z"`
+	TotalSwitches      int                 `json:"total_switches" example:"308"`
+	ActiveHours        int                 `json:"active_hours" example:"12"` // часы, в которых было хотя бы одно переключение
+	AvgSwitchesPerHour float64             `json:"avg_switches_per_hour" example:"25.67"`
+	HighChurnThreshold int                 `json:"high_churn_threshold" example:"30"`
+	HighChurnHours     int                 `json:"high_churn_hours" example:"3"`
+	Hours              []HourlyTabSwitches `json:"hours"`       // по возрастанию hour
+	TopDomains         []DomainTabSwitches `json:"top_domains"` // по убыванию switches
+}
+
+type TabSwitchStatsResponse struct {
+	Data    *TabSwitchStats `json:"data"`
+	Success bool            `json:"success"`
+	Message string          `json:"message,omitempty"`
+}
+
 //func (e *EngagedTimeMetric) GetFocusLevelDescription() string {
 //	switch e.FocusLevel {
 //	case "high":
@@ -177,3 +549,52 @@ func (e *EngagedTimeMetric) GetTopDomains(limit int) []string {
 //func (e *EngagedTimeMetric) IsLowFocus() bool {
 //	return e.FocusLevel == "low"
 //}
+
+type WeeklyDigestFilter struct {
+	UserID    string
+	WeekStart time.Time // начало недели (00:00 UTC), неделя - 7 суток от него
+}
+
+// WeeklyDigest - готовая к рассылке недельная сводка; внешний mailer только рендерит ее
+type WeeklyDigest struct {
+	UserID    string    `json:"user_id"`
+	WeekStart time.Time `json:"week_start"`
+	WeekEnd   time.Time `json:"week_end"` // включительно
+
+	TrackedHours   float64 `json:"tracked_hours"`
+	ActiveHours    float64 `json:"active_hours"`
+	EngagementRate float64 `json:"engagement_rate"`
+
+	DeepWorkSessions int     `json:"deep_work_sessions"`
+	DeepWorkHours    float64 `json:"deep_work_hours"`
+
+	TopDomains []DomainStats `json:"top_domains"` // топ-3 по числу событий
+
+	FocusLevel string           `json:"focus_level" example:"high" enums:"high,medium,low"`
+	FocusTrend WeeklyFocusTrend `json:"focus_trend"`
+
+	Recommendations []string `json:"recommendations"` // ровно 2 рекомендации
+
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// Направление изменения focus score относительно прошлой недели
+const (
+	FocusTrendUp   = "up"
+	FocusTrendDown = "down"
+	FocusTrendFlat = "flat"
+)
+
+// WeeklyFocusTrend сравнивает focus из серверного productivity score с прошлой неделей
+type WeeklyFocusTrend struct {
+	Focus         int    `json:"focus"`
+	PreviousFocus int    `json:"previous_focus"`
+	Delta         int    `json:"delta"`
+	Direction     string `json:"direction" example:"up" enums:"up,down,flat"`
+}
+
+type WeeklyDigestResponse struct {
+	Data    *WeeklyDigest `json:"data"`
+	Success bool          `json:"success"`
+	Message string        `json:"message,omitempty"`
+}
@@ -12,3 +12,20 @@ type PaginationInfo struct {
 	Total      int `json:"total"`
 	TotalPages int `json:"total_pages"`
 }
+
+// PaginationLimits - размер страницы по умолчанию и максимальный размер для списка (задается в конфиге)
+type PaginationLimits struct {
+	DefaultPerPage int
+	MaxPerPage     int
+}
+
+// Clamp подставляет размер по умолчанию для perPage <= 0 и ограничивает его сверху MaxPerPage
+func (l PaginationLimits) Clamp(perPage int) int {
+	if perPage <= 0 {
+		return l.DefaultPerPage
+	}
+	if perPage > l.MaxPerPage {
+		return l.MaxPerPage
+	}
+	return perPage
+}
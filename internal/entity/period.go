@@ -1,7 +1,11 @@
 package entity
 
+import "time"
+
 type PeriodInfo struct {
-	Key         string `json:"key" db:"key"`
-	Label       string `json:"label" db:"label"`
-	Description string `json:"description" db:"description"`
+	Key         string     `json:"key" db:"key"`
+	Label       string     `json:"label" db:"label"`
+	Description string     `json:"description" db:"description"`
+	StartTime   *time.Time `json:"startTime,omitempty"` // только если передан lang или tz
+	EndTime     *time.Time `json:"endTime,omitempty"`
 }
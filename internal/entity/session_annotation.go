@@ -0,0 +1,21 @@
+// internal/entity/session_annotation.go
+package entity
+
+import (
+	"github.com/gofrs/uuid"
+	"time"
+)
+
+// SessionAnnotation - метка сессии, оставленная админом
+type SessionAnnotation struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	SessionID  string     `json:"sessionId" db:"session_id" example:"session_1751443200_abc123"`
+	Tag        string     `json:"tag" db:"tag" example:"pairing"`
+	AuthorID   *uuid.UUID `json:"authorId" db:"author_id"`     // nil, если автор удален
+	AuthorName *string    `json:"authorName" db:"author_name"` // username автора на момент запроса
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+}
+
+type CreateSessionAnnotationRequest struct {
+	Tag string `json:"tag" binding:"required,max=255" example:"focus block"`
+}
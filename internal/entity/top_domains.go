@@ -4,11 +4,24 @@ package entity
 import "time"
 
 type TopDomainsFilter struct {
-	UserID    string  `json:"user_id"`
-	Limit     int     `json:"limit,omitempty"` // По умолчанию 10
-	SessionID *string `json:"session_id,omitempty"`
+	UserID     string   `json:"user_id"`
+	Limit      int      `json:"limit,omitempty"` // По умолчанию 10
+	SessionID  *string  `json:"session_id,omitempty"`
+	EventTypes []string `json:"event_types,omitempty"` // Пусто = все типы событий
+	Page       int      `json:"page,omitempty"`
+	PerPage    int      `json:"per_page,omitempty"`
+	Categorize bool     `json:"categorize,omitempty"` // добавить категории доменов и сводку по категориям
+
+	ExcludedDomains []string `json:"-"` // домены из списка исключений, не попадают в топ
+
+	// Необязательный период (end включителен); nil - за все время
+	StartTime *time.Time `json:"-"`
+	EndTime   *time.Time `json:"-"`
 }
 
+// Категория доменов, которые не распознаны правилами и не закреплены админом
+const DomainCategoryOther = "other"
+
 type DomainStats struct {
 	Domain        string    `json:"domain"`
 	EventsCount   int       `json:"events_count"`
@@ -16,6 +29,16 @@ type DomainStats struct {
 	Percentage    float64   `json:"percentage"`
 	FirstVisit    time.Time `json:"first_visit"`
 	LastVisit     time.Time `json:"last_visit"`
+	Category      string    `json:"category,omitempty"` // только при categorize=true
+}
+
+// DomainCategoryStats - сводка по категории среди возвращенных доменов
+type DomainCategoryStats struct {
+	Category      string  `json:"category" example:"development"`
+	DomainsCount  int     `json:"domains_count" example:"3"`
+	EventsCount   int     `json:"events_count" example:"1250"`
+	ActiveMinutes int     `json:"active_minutes" example:"184"`
+	Percentage    float64 `json:"percentage" example:"42.5"`
 }
 
 type TopDomainsResponse struct {
@@ -23,4 +46,8 @@ type TopDomainsResponse struct {
 	TotalDomains int           `json:"total_domains"`
 	TotalEvents  int           `json:"total_events"`
 	Domains      []DomainStats `json:"domains"`
+
+	Categories []DomainCategoryStats `json:"categories,omitempty"` // только при categorize=true, по убыванию active_minutes
+
+	Pagination *PaginationInfo `json:"pagination,omitempty"` // только для page/per_page
 }
@@ -7,3 +7,9 @@ type User struct {
 	Username     string    `json:"username"`
 	IsSuperAdmin bool      `json:"is_super_admin,omitempty"`
 }
+
+// UserFilter - фильтр списка пользователей админки
+type UserFilter struct {
+	Search       string // подстрока username без учета регистра
+	IsSuperAdmin *bool
+}
@@ -11,6 +11,7 @@ type UserBehavior struct {
 	Timestamp time.Time  `json:"ts" db:"timestamp" binding:"required"`
 	Type      string     `json:"type" db:"event_type" binding:"required"`
 	URL       string     `json:"url" db:"url" binding:"required"`
+	Domain    string     `json:"domain" db:"domain"` // нормализованный домен, заполняется при вставке
 	UserID    *uuid.UUID `json:"userId" db:"user_id"`
 	UserName  *string    `json:"userName" db:"user_name"`
 	X         *int       `json:"x,omitempty" db:"x"`
@@ -18,6 +19,7 @@ type UserBehavior struct {
 	Key       *string    `json:"key,omitempty" db:"key"`
 	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
 	UpdatedAt time.Time  `json:"updatedAt" db:"updated_at"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
 }
 
 type CreateUserBehaviorRequest struct {
@@ -26,15 +28,43 @@ type CreateUserBehaviorRequest struct {
 	Type      string     `json:"type" binding:"required"`
 	URL       string     `json:"url"`
 	UserID    *uuid.UUID `json:"userId"`
+	UserName  *string    `json:"userName,omitempty"` // если не передан, берется username пользователя расширения из X-API-Key
 	X         *int       `json:"x,omitempty"`
 	Y         *int       `json:"y,omitempty"`
 	Key       *string    `json:"key,omitempty"`
 }
 
+// TimestampBounds - допустимый диапазон времени события от клиента (задается в конфиге)
+type TimestampBounds struct {
+	// Насколько событие может опережать серверное время (расхождение часов клиента)
+	MaxFutureSkew time.Duration
+	// События раньше этой даты отклоняются (epoch-zero и сбитые часы)
+	Floor time.Time
+}
+
 type BatchCreateUserBehaviorRequest struct {
 	Events []CreateUserBehaviorRequest `json:"events" binding:"required,dive"`
 }
 
+type RejectedBehaviorEvent struct {
+	Index  int    `json:"index" example:"3"`
+	Reason string `json:"reason" example:"invalid event type: mousemove"`
+}
+
+type BatchCreateUserBehaviorResult struct {
+	Accepted int `json:"accepted" example:"997"`
+	// Accepted = Inserted + Duplicates: дубликаты уже сохраненных событий не вставляются повторно
+	Inserted       int                     `json:"inserted" example:"990"`
+	Duplicates     int                     `json:"duplicates" example:"7"`
+	Rejected       int                     `json:"rejected" example:"3"`
+	RejectedEvents []RejectedBehaviorEvent `json:"rejected_events"`
+	// События исключенных доменов: валидны, но не сохраняются и не входят в Accepted
+	Excluded int `json:"excluded" example:"12"`
+
+	// Результат взят из кеша Idempotency-Key, события повторно не вставлялись
+	Replayed bool `json:"-"`
+}
+
 type UserBehaviorFilter struct {
 	UserID    *uuid.UUID `json:"user_id"`
 	SessionID *string    `json:"session_id"`
@@ -43,11 +73,24 @@ type UserBehaviorFilter struct {
 	StartTime *time.Time `json:"start_time"`
 	EndTime   *time.Time `json:"end_time"`
 
+	// Несколько типов событий (eventType=click&eventType=keydown или eventType=click,keydown)
+	EventTypes []string `json:"event_types"`
+
 	Limit  int `json:"limit"`
 	Offset int `json:"offset"`
 
 	Page    int `json:"page"`
 	PerPage int `json:"per_page"`
+
+	// Направление сортировки по timestamp: asc или desc (по умолчанию)
+	Order string `json:"order"`
+
+	// Курсорная пагинация: при CursorMode page/offset игнорируются
+	CursorMode bool            `json:"-"`
+	Cursor     *BehaviorCursor `json:"-"`
+
+	// Домены из списка исключений, не учитываются в unique_domains (заполняет сервис)
+	ExcludedDomains []string `json:"-"`
 }
 
 type UserEventsCount struct {
@@ -62,19 +105,56 @@ type UserEventsCountResponse struct {
 	UserID    string       `json:"user_id"`
 	Events    []EventTypes `json:"events"`
 	Total     int          `json:"total"`
+	Sessions  int          `json:"sessions"` // уникальные session_id за период
 }
 
 type EventTypes struct {
-	Event  string `json:"event"`
-	Amount int    `json:"amount"`
+	Event    string `json:"event"`
+	Amount   int    `json:"amount"`
+	Sessions int    `json:"sessions,omitempty"` // сессии с событиями этого типа (заполняется в user-events)
+}
+
+// UserActivitySpan - общий диапазон данных пользователя; для пользователя без событий время равно null
+type UserActivitySpan struct {
+	UserID       string     `json:"user_id"`
+	FirstEventAt *time.Time `json:"first_event_at"`
+	LastEventAt  *time.Time `json:"last_event_at"`
+	TotalEvents  int        `json:"total_events"`
+}
+
+// DistinctEventTypesResponse - типы событий пользователя за период, отсортированные по имени
+type DistinctEventTypesResponse struct {
+	UserID     string       `json:"user_id"`
+	StartTime  *time.Time   `json:"start_time"`
+	EndTime    *time.Time   `json:"end_time"`
+	EventTypes []EventTypes `json:"event_types"`
 }
 
 type UserBehaviorStats struct {
-	TotalEvents    int64            `json:"totalEvents"`
-	UniqueUsers    int64            `json:"uniqueUsers"`
-	UniqueSessions int64            `json:"uniqueSessions"`
-	EventsByType   map[string]int64 `json:"eventsByType"`
-	PopularURLs    []URLStats       `json:"popularUrls"`
+	TotalEvents    int64 `json:"totalEvents"`
+	UniqueUsers    int64 `json:"uniqueUsers"`
+	UniqueSessions int64 `json:"uniqueSessions"`
+	// Число разных доменов без учета исключенных и событий без домена
+	UniqueDomains int64            `json:"uniqueDomains"`
+	EventsByType  map[string]int64 `json:"eventsByType"`
+	PopularURLs   []URLStats       `json:"popularUrls"`
+	// Заполняется только при group_by=day|hour
+	Timeline []StatsBucket `json:"timeline,omitempty"`
+}
+
+// StatsGroupByUnits - допустимые значения group_by и соответствующий шаг бакета
+var StatsGroupByUnits = map[string]time.Duration{
+	"day":  24 * time.Hour,
+	"hour": time.Hour,
+}
+
+type StatsBucket struct {
+	Bucket time.Time        `json:"bucket"`
+	Total  int64            `json:"total"`
+	ByType map[string]int64 `json:"by_type"`
+	// Разные домены в бакете и из них впервые встреченные за период запроса
+	UniqueDomains int64 `json:"unique_domains"`
+	NewDomains    int64 `json:"new_domains"`
 }
 
 type URLStats struct {
@@ -91,4 +171,7 @@ type SessionSummary struct {
 	Duration    float64   `json:"duration"` // в секундах
 	EventsCount int64     `json:"eventsCount"`
 	URLs        []string  `json:"urls"`
+
+	EventsByType map[string]int      `json:"eventsByType,omitempty"` // только в GetSessionSummary
+	Annotations  []SessionAnnotation `json:"annotations,omitempty"`  // только в GetSessionSummary
 }
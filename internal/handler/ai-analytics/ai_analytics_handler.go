@@ -3,54 +3,139 @@ package ai_analytics
 import (
 	"context"
 	"crypto/md5"
+	"errors"
 	"fmt"
 	"github.com/dinerozz/web-behavior-backend/internal/service/redis"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/dinerozz/web-behavior-backend/internal/entity"
 	"github.com/dinerozz/web-behavior-backend/internal/model/response/wrapper"
 	"github.com/dinerozz/web-behavior-backend/internal/service/ai_analytics"
 	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
 )
 
 type AIAnalyticsHandler struct {
-	aiService    *ai_analytics.AIAnalyticsService
+	aiService    AIAnalyticsService
 	redisService redis.ServiceInterface
+	// Используется RateLimitPerHour - лимит AI анализов в час на пользователя
+	config entity.AIAnalyticsConfig
+	// Очередь фоновых задач анализа, читается пулом воркеров
+	jobs chan aiJobTask
 }
 
+// AIAnalyticsService - методы *ai_analytics.AIAnalyticsService, которые использует хендлер;
+// интерфейс позволяет подменить AI клиента в тестах
 type AIAnalyticsService interface {
-	AnalyzeDomainUsage(ctx context.Context, domainsCount int, domains []string, deepWorkData entity.DeepWorkData, engagementRate float64, trackedHours float64) (*entity.DomainAnalysis, error)
+	AnalyzeDomainUsage(ctx context.Context, domainsCount int, domains []string, deepWorkData entity.DeepWorkData, engagementRate float64, trackedHours float64, lang string) (*entity.DomainAnalysis, error)
+	AnalyzeFocusWithAI(ctx context.Context, domainsCount int, lang string) (*entity.FocusLevelResponse, error)
+	FallbackAnalysis(ctx context.Context, req entity.AIAnalyticsRequest) *entity.DomainAnalysis
 	DetermineFocusLevelFallback(domainsCount int) string
+	FallbackFocusInsight(domainsCount int, lang string) string
+	BuildAnalyticsMeta(req entity.AIAnalyticsRequest, startedAt time.Time, usedAI bool) *entity.AnalyticsMeta
+	DomainUsageModel() string
+	FocusLevelModel() string
+
+	GetUsage(ctx context.Context) (*entity.AIUsageStats, error)
+	HealthCheck(ctx context.Context) *entity.AIAnalyticsHealthCheck
+
+	ListDomainCategories(ctx context.Context) ([]entity.DomainCategory, error)
+	CreateDomainCategory(ctx context.Context, req entity.DomainCategoryRequest) (*entity.DomainCategory, error)
+	UpdateDomainCategory(ctx context.Context, id uuid.UUID, req entity.DomainCategoryRequest) (*entity.DomainCategory, error)
+	DeleteDomainCategory(ctx context.Context, id uuid.UUID) error
 }
 
-func NewAIAnalyticsHandler(aiService *ai_analytics.AIAnalyticsService, redisService redis.ServiceInterface) *AIAnalyticsHandler {
-	return &AIAnalyticsHandler{aiService: aiService, redisService: redisService}
+var _ AIAnalyticsService = (*ai_analytics.AIAnalyticsService)(nil)
+
+func NewAIAnalyticsHandler(aiService AIAnalyticsService, redisService redis.ServiceInterface, config entity.AIAnalyticsConfig) *AIAnalyticsHandler {
+	h := &AIAnalyticsHandler{aiService: aiService, redisService: redisService, config: config}
+	h.startAIJobWorkers()
+	return h
 }
 
 func (h *AIAnalyticsHandler) generateCacheKey(req entity.AIAnalyticsRequest) string {
-	params := fmt.Sprintf("domains_count:%d|domains:%v|deep_work:%+v|engagement_rate:%.2f|tracked_hours:%.2f",
+	params := fmt.Sprintf("model:%s|domains_count:%d|domains:%v|deep_work:%+v|engagement_rate:%.2f|tracked_hours:%.2f|lang:%s",
+		h.aiService.DomainUsageModel(),
 		req.DomainsCount,
 		req.Domains,
 		req.DeepWork,
 		req.EngagementRate,
 		req.TrackedHours,
+		ai_analytics.NormalizeLang(req.Lang),
 	)
 
 	hash := md5.Sum([]byte(params))
-	return fmt.Sprintf("ai_analytics:domain_usage:%x", hash)
+	return fmt.Sprintf("ai_analytics:domain_usage:v2:%x", hash)
+}
+
+// cachedDomainAnalysis - запись кеша анализа. used_ai хранится вместе с результатом,
+// чтобы ответ из кеша получал те же метаданные, что и исходный
+type cachedDomainAnalysis struct {
+	Analysis entity.DomainAnalysis `json:"analysis"`
+	UsedAI   bool                  `json:"used_ai"`
+}
+
+// analyzeDomainUsageCached отдает анализ из кеша или запрашивает AI (перед запросом вызывается beforeAI,
+// например для списания лимита). Кешируются только ответы AI: fallback результат не должен
+// подменять AI анализ на час после временного сбоя OpenAI, поэтому при ошибке AI решение за вызывающим
+func (h *AIAnalyticsHandler) analyzeDomainUsageCached(ctx context.Context, req entity.AIAnalyticsRequest, beforeAI func() error) (*cachedDomainAnalysis, bool, error) {
+	cacheKey := h.generateCacheKey(req)
+
+	var cached cachedDomainAnalysis
+	if err := h.redisService.Get(ctx, cacheKey, &cached); err == nil {
+		return &cached, true, nil
+	}
+
+	if beforeAI != nil {
+		if err := beforeAI(); err != nil {
+			return nil, false, err
+		}
+	}
+
+	result, err := h.aiService.AnalyzeDomainUsage(
+		ctx,
+		req.DomainsCount,
+		req.Domains,
+		req.DeepWork,
+		req.EngagementRate,
+		req.TrackedHours,
+		req.Lang,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+
+	entry := &cachedDomainAnalysis{Analysis: *result, UsedAI: true}
+	if cacheErr := h.redisService.Set(ctx, cacheKey, entry, time.Hour); cacheErr != nil {
+		fmt.Printf("Failed to cache AI analysis result: %v\n", cacheErr)
+	}
+
+	return entry, false, nil
+}
+
+// fallbackDomainAnalysis - анализ без AI, когда OpenAI недоступен или не настроен
+func (h *AIAnalyticsHandler) fallbackDomainAnalysis(ctx context.Context, req entity.AIAnalyticsRequest, aiErr error) *cachedDomainAnalysis {
+	fmt.Printf("AI analysis failed, using fallback: %v\n", aiErr)
+	return &cachedDomainAnalysis{Analysis: *h.aiService.FallbackAnalysis(ctx, req), UsedAI: false}
 }
 
 // AnalyzeDomainUsage godoc
 // @Summary      Analyze domain usage with AI
-// @Description  Get AI-powered analysis of user's domain usage patterns, productivity insights, and recommendations
+// @Description  Get AI-powered analysis of user's domain usage patterns, productivity insights, and recommendations. With async=true returns a job immediately (202), poll /ai-analytics/jobs/{id} for the result
 // @Tags         /api/v1/admin/ai-analytics
 // @Accept       json
 // @Produce      json
 // @Param        request  body      entity.AIAnalyticsRequest  true  "Analytics request data"
-// @Success      200      {object}  wrapper.ResponseWrapper{data=entity.DomainAnalysis}
+// @Param        async    query     bool                       false "Run analysis in background"
+// @Param        lang     query     string                     false "Insights language (ru, en), overridden by lang in body"  default(ru)
+// @Success      200      {object}  entity.AIAnalyticsResponse
+// @Success      202      {object}  wrapper.ResponseWrapper{data=entity.AIAnalysisJob}
 // @Failure      400      {object}  wrapper.ErrorWrapper
+// @Failure      429      {object}  wrapper.ErrorWrapper
 // @Failure      500      {object}  wrapper.ErrorWrapper
+// @Failure      503      {object}  wrapper.ErrorWrapper
 // @Router       /ai-analytics/domain-usage [post]
 func (h *AIAnalyticsHandler) AnalyzeDomainUsage(c *gin.Context) {
 	var req entity.AIAnalyticsRequest
@@ -62,6 +147,10 @@ func (h *AIAnalyticsHandler) AnalyzeDomainUsage(c *gin.Context) {
 		return
 	}
 
+	if req.Lang == "" {
+		req.Lang = c.Query("lang")
+	}
+
 	if err := h.validateRequest(req); err != nil {
 		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
 			Message: err.Error(),
@@ -71,42 +160,60 @@ func (h *AIAnalyticsHandler) AnalyzeDomainUsage(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
-	cacheKey := h.generateCacheKey(req)
 
-	var cachedAnalysis entity.DomainAnalysis
-	err := h.redisService.Get(ctx, cacheKey, &cachedAnalysis)
-	if err == nil {
-		c.Header("X-Cache", "HIT")
-		c.JSON(http.StatusOK, wrapper.ResponseWrapper{
-			Data:    &cachedAnalysis,
+	if c.Query("async") == "true" {
+		// Готовый результат в кеше не расходует лимит
+		if cached, _ := h.redisService.Exists(ctx, h.generateCacheKey(req)); !cached {
+			if err := h.consumeAIRateLimit(c); err != nil {
+				c.JSON(http.StatusTooManyRequests, wrapper.ErrorWrapper{
+					Message: err.Error(),
+					Success: false,
+				})
+				return
+			}
+		}
+
+		job, err := h.startDomainUsageJob(ctx, req, currentUserID(c))
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrAIJobStoreUnavailable) || errors.Is(err, ErrAIJobQueueFull) {
+				status = http.StatusServiceUnavailable
+			}
+			c.JSON(status, wrapper.ErrorWrapper{
+				Message: "Failed to start analysis job: " + err.Error(),
+				Success: false,
+			})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, wrapper.ResponseWrapper{
+			Data:    job,
 			Success: true,
 		})
 		return
 	}
 
-	c.Header("X-Cache", "MISS")
+	startedAt := time.Now()
 
-	analysis, err := h.aiService.AnalyzeDomainUsage(
-		ctx,
-		req.DomainsCount,
-		req.Domains,
-		req.DeepWork,
-		req.EngagementRate,
-		req.TrackedHours,
-	)
-
-	if err != nil {
-		analysis = h.generateFallbackAnalysis(req)
+	analysis, hit, err := h.analyzeDomainUsageCached(ctx, req, func() error {
+		return h.consumeAIRateLimit(c)
+	})
+	if errors.Is(err, errAIRateLimited) {
+		c.JSON(http.StatusTooManyRequests, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
 	}
-
-	cacheErr := h.redisService.Set(ctx, cacheKey, analysis, time.Hour)
-	if cacheErr != nil {
-		fmt.Printf("Failed to cache AI analysis result: %v\n", cacheErr)
+	if err != nil {
+		analysis = h.fallbackDomainAnalysis(ctx, req, err)
 	}
 
-	c.JSON(http.StatusOK, wrapper.ResponseWrapper{
-		Data:    analysis,
+	c.Header("X-Cache", cacheStatus(hit))
+	c.JSON(http.StatusOK, entity.AIAnalyticsResponse{
+		Data:    &analysis.Analysis,
 		Success: true,
+		Meta:    h.aiService.BuildAnalyticsMeta(req, startedAt, analysis.UsedAI),
 	})
 }
 
@@ -127,6 +234,10 @@ func (h *AIAnalyticsHandler) validateRequest(req entity.AIAnalyticsRequest) erro
 		return fmt.Errorf("tracked_hours must be non-negative")
 	}
 
+	if !ai_analytics.IsSupportedLang(req.Lang) {
+		return fmt.Errorf("unsupported lang: %s (supported: ru, en)", req.Lang)
+	}
+
 	if req.DeepWork.SessionsCount == 0 && req.EngagementRate == 0 {
 		return fmt.Errorf("insufficient data for analysis - need either deep work sessions or engagement activity")
 	}
@@ -134,8 +245,8 @@ func (h *AIAnalyticsHandler) validateRequest(req entity.AIAnalyticsRequest) erro
 	return nil
 }
 
-func (h *AIAnalyticsHandler) generateFocusLevelCacheKey(domainsCount int) string {
-	return fmt.Sprintf("ai_analytics:focus_level:%d", domainsCount)
+func (h *AIAnalyticsHandler) generateFocusLevelCacheKey(domainsCount int, lang string) string {
+	return fmt.Sprintf("ai_analytics:focus_level:%s:%s:%d", h.aiService.FocusLevelModel(), ai_analytics.NormalizeLang(lang), domainsCount)
 }
 
 // GetFocusLevel godoc
@@ -144,7 +255,8 @@ func (h *AIAnalyticsHandler) generateFocusLevelCacheKey(domainsCount int) string
 // @Tags         /api/v1/admin/ai-analytics
 // @Accept       json
 // @Produce      json
-// @Param        domains_count  query     int  true  "Number of unique domains"
+// @Param        domains_count  query     int     true   "Number of unique domains"
+// @Param        lang           query     string  false  "Insight language (ru, en)"  default(ru)
 // @Success      200            {object}  wrapper.ResponseWrapper{data=entity.FocusLevelResponse}
 // @Failure      400            {object}  wrapper.ErrorWrapper
 // @Router       /ai-analytics/focus-level [get]
@@ -175,87 +287,230 @@ func (h *AIAnalyticsHandler) GetFocusLevel(c *gin.Context) {
 		return
 	}
 
-	ctx := c.Request.Context()
-	cacheKey := h.generateFocusLevelCacheKey(domainsCount)
-
-	var cachedResponse entity.FocusLevelResponse
-	err := h.redisService.Get(ctx, cacheKey, &cachedResponse)
-	if err == nil {
-		c.Header("X-Cache", "HIT")
-		c.Header("X-Cache-Key", cacheKey)
-		c.JSON(http.StatusOK, wrapper.ResponseWrapper{
-			Data:    &cachedResponse,
-			Success: true,
+	lang := c.Query("lang")
+	if !ai_analytics.IsSupportedLang(lang) {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "unsupported lang: " + lang + " (supported: ru, en)",
+			Success: false,
 		})
 		return
 	}
 
-	c.Header("X-Cache", "MISS")
+	ctx := c.Request.Context()
+	cacheKey := h.generateFocusLevelCacheKey(domainsCount, lang)
+
+	var response entity.FocusLevelResponse
+	hit, err := h.redisService.GetOrCompute(ctx, cacheKey, 6*time.Hour, &response, func() (interface{}, error) {
+		focusLevel, err := h.aiService.AnalyzeFocusWithAI(ctx, domainsCount, lang)
+		if err != nil {
+			return nil, err
+		}
+		if focusLevel == nil {
+			return nil, fmt.Errorf("empty focus level response")
+		}
+		return entity.FocusLevelResponse{
+			FocusLevel: focusLevel.FocusLevel,
+			Insight:    focusLevel.Insight,
+			Method:     focusLevel.Method,
+			Timestamp:  focusLevel.Timestamp,
+		}, nil
+	})
+	if err != nil {
+		// Ошибку или пустой ответ AI не кешируем, чтобы следующий запрос снова попробовал AI
+		response = entity.FocusLevelResponse{
+			FocusLevel: h.aiService.DetermineFocusLevelFallback(domainsCount),
+			Insight:    h.aiService.FallbackFocusInsight(domainsCount, lang),
+			Method:     "fallback",
+			Timestamp:  time.Now(),
+		}
+	}
+
+	c.Header("X-Cache", cacheStatus(hit))
 	c.Header("X-Cache-Key", cacheKey)
-	focusLevel, _ := h.aiService.AnalyzeFocusWithAI(ctx, domainsCount)
+	c.JSON(http.StatusOK, wrapper.ResponseWrapper{
+		Data:    &response,
+		Success: true,
+	})
+}
+
+func cacheStatus(hit bool) string {
+	if hit {
+		return "HIT"
+	}
+	return "MISS"
+}
 
-	response := entity.FocusLevelResponse{
-		FocusLevel: focusLevel.FocusLevel,
-		Insight:    focusLevel.Insight,
-		Method:     focusLevel.Method,
-		Timestamp:  focusLevel.Timestamp,
+// Максимум одновременных запросов к OpenAI в параллельном режиме батча
+const batchWorkers = 3
+
+// AnalyzeBatch godoc
+// @Summary      Batch analyze domain usage with AI
+// @Description  Run AI domain usage analysis for up to 10 requests. With options.parallel requests are processed by a bounded worker pool. With options.fail_on_error the batch stops on the first failure. Without an OpenAI API key or when OpenAI fails, items get the fallback analysis like the single request endpoint
+// @Tags         /api/v1/admin/ai-analytics
+// @Accept       json
+// @Produce      json
+// @Param        request  body      entity.BatchAnalyticsRequest  true  "Batch analytics request"
+// @Success      200      {object}  wrapper.ResponseWrapper{data=entity.BatchAnalyticsResponse}
+// @Failure      400      {object}  wrapper.ErrorWrapper
+// @Failure      502      {object}  wrapper.ErrorWrapper
+// @Router       /ai-analytics/batch [post]
+func (h *AIAnalyticsHandler) AnalyzeBatch(c *gin.Context) {
+	var req entity.BatchAnalyticsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "Invalid request body: " + err.Error(),
+			Success: false,
+		})
+		return
 	}
 
-	cacheErr := h.redisService.Set(ctx, cacheKey, &response, 6*time.Hour)
-	if cacheErr != nil {
-		fmt.Printf("Failed to cache focus level result: %v\n", cacheErr)
+	startedAt := time.Now()
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	results := make([]*entity.EnhancedDomainAnalysis, len(req.Requests))
+	errs := make([]*entity.BatchError, len(req.Requests))
+
+	// Первая ошибка, из-за которой батч прерван (fail_on_error)
+	var abortOnce sync.Once
+	var abortErr *entity.BatchError
+
+	process := func(i int) {
+		result, err := h.analyzeBatchItem(ctx, req.Requests[i], req.Options)
+		if err != nil {
+			errs[i] = &entity.BatchError{Index: i, Error: err.Error(), Request: req.Requests[i]}
+			if req.Options.FailOnError {
+				abortOnce.Do(func() {
+					abortErr = errs[i]
+					cancel()
+				})
+			}
+			return
+		}
+		results[i] = result
+	}
+
+	if req.Options.Parallel {
+		jobs := make(chan int)
+		var wg sync.WaitGroup
+
+		for w := 0; w < batchWorkers && w < len(req.Requests); w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range jobs {
+					process(i)
+				}
+			}()
+		}
+
+	dispatch:
+		for i := range req.Requests {
+			select {
+			case <-ctx.Done():
+				break dispatch
+			case jobs <- i:
+			}
+		}
+		close(jobs)
+		wg.Wait()
+	} else {
+		for i := range req.Requests {
+			if ctx.Err() != nil {
+				break
+			}
+			process(i)
+		}
+	}
+
+	response := entity.BatchAnalyticsResponse{
+		Results: []entity.EnhancedDomainAnalysis{},
+		Total:   len(req.Requests),
+	}
+
+	var processingTime int64
+	for i := range req.Requests {
+		if errs[i] != nil {
+			response.Errors = append(response.Errors, *errs[i])
+			continue
+		}
+		if results[i] != nil {
+			response.Results = append(response.Results, *results[i])
+			processingTime += results[i].Meta.ProcessingTime
+		}
+	}
+
+	response.Processed = len(response.Results)
+	response.Failed = len(response.Errors)
+	response.Success = response.Failed == 0
+
+	if abortErr != nil {
+		c.JSON(http.StatusBadGateway, wrapper.ErrorWrapper{
+			Message: fmt.Sprintf("batch aborted: request %d failed: %s", abortErr.Index, abortErr.Error),
+			Success: false,
+		})
+		return
+	}
+
+	response.Meta = entity.BatchMeta{
+		ProcessedAt:  time.Now(),
+		TotalTime:    time.Since(startedAt).Milliseconds(),
+		ParallelMode: req.Options.Parallel,
+	}
+	if response.Processed > 0 {
+		response.Meta.AverageTime = processingTime / int64(response.Processed)
 	}
 
 	c.JSON(http.StatusOK, wrapper.ResponseWrapper{
-		Data:    &response,
+		Data:    response,
 		Success: true,
 	})
 }
 
-func (h *AIAnalyticsHandler) generateFallbackInsight(domainsCount int) string {
-	switch {
-	case domainsCount <= 5:
-		return fmt.Sprintf("Высокая концентрация: работа в %d доменах указывает на фокусированную деятельность", domainsCount)
-	case domainsCount <= 15:
-		return fmt.Sprintf("Средняя концентрация: %d доменов говорит о сбалансированной многозадачности", domainsCount)
-	case domainsCount <= 25:
-		return fmt.Sprintf("Низкая концентрация: %d доменов может указывать на частые переключения контекста", domainsCount)
-	default:
-		return fmt.Sprintf("Очень низкая концентрация: %d доменов указывает на высокую фрагментацию внимания", domainsCount)
+func (h *AIAnalyticsHandler) analyzeBatchItem(ctx context.Context, req entity.AIAnalyticsRequest, options entity.BatchOptions) (*entity.EnhancedDomainAnalysis, error) {
+	if err := h.validateRequest(req); err != nil {
+		return nil, err
 	}
-}
 
-func (h *AIAnalyticsHandler) generateFallbackAnalysis(req entity.AIAnalyticsRequest) *entity.DomainAnalysis {
-	return &entity.DomainAnalysis{
-		FocusLevel:      h.aiService.DetermineFocusLevelFallback(req.DomainsCount),
-		FocusInsight:    h.generateFallbackInsight(req.DomainsCount),
-		WorkPattern:     "unknown",
-		Recommendations: []string{"AI анализ временно недоступен"},
-		Analysis: entity.DetailedAnalysis{
-			DomainBreakdown: entity.DomainBreakdown{
-				WorkTools:     []string{},
-				Development:   []string{},
-				Research:      []string{},
-				Communication: []string{},
-				Distractions:  []string{},
-			},
-			ProductivityScore: entity.ProductivityScore{
-				Overall:     0,
-				Focus:       0,
-				Efficiency:  0,
-				Balance:     0,
-				Explanation: "AI анализ недоступен, используется базовая оценка",
-			},
-			BehaviorInsights: []string{"Базовая оценка без AI анализа"},
-			KeyFindings:      []string{"Детальный анализ требует AI сервиса"},
-		},
+	startedAt := time.Now()
+
+	// Как и одиночный запрос: без API ключа или при сбое OpenAI элемент получает fallback анализ.
+	// Ошибкой элемент становится только при отмене батча (fail_on_error или отключение клиента)
+	analysis, _, err := h.analyzeDomainUsageCached(ctx, req, nil)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		analysis = h.fallbackDomainAnalysis(ctx, req, err)
+	}
+
+	enhanced := &entity.EnhancedDomainAnalysis{
+		DomainAnalysis: analysis.Analysis,
+		Meta:           *h.aiService.BuildAnalyticsMeta(req, startedAt, analysis.UsedAI),
 	}
+
+	if options.IncludeMetadata {
+		enhanced.RequestData = req
+	} else {
+		enhanced.Meta.AIModel = ""
+	}
+
+	return enhanced, nil
 }
 
 func (h *AIAnalyticsHandler) RegisterRoutes(router *gin.RouterGroup) {
 	analytics := router.Group("/ai-analytics")
 	{
 		analytics.POST("/domain-usage", h.AnalyzeDomainUsage)
+		analytics.POST("/batch", h.AnalyzeBatch)
+		analytics.GET("/jobs/:id", h.GetAnalysisJob)
+		analytics.GET("/jobs/:id/events", h.StreamAnalysisJob)
 		analytics.GET("/focus-level", h.GetFocusLevel)
+		analytics.GET("/health", h.GetHealth)
+		analytics.GET("/domain-categories", h.ListDomainCategories)
+		analytics.POST("/domain-categories", h.CreateDomainCategory)
+		analytics.PUT("/domain-categories/:id", h.UpdateDomainCategory)
+		analytics.DELETE("/domain-categories/:id", h.DeleteDomainCategory)
 	}
 }
@@ -0,0 +1,109 @@
+package ai_analytics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/dinerozz/web-behavior-backend/internal/service/redis"
+	"github.com/gin-gonic/gin"
+)
+
+// stubAIService подменяет AI клиента; неиспользуемые методы берутся из встроенного (nil) интерфейса
+type stubAIService struct {
+	AIAnalyticsService
+	focusLevel *entity.FocusLevelResponse
+	err        error
+}
+
+func (s *stubAIService) AnalyzeFocusWithAI(ctx context.Context, domainsCount int, lang string) (*entity.FocusLevelResponse, error) {
+	return s.focusLevel, s.err
+}
+
+func (s *stubAIService) DetermineFocusLevelFallback(domainsCount int) string {
+	return "medium"
+}
+
+func (s *stubAIService) FallbackFocusInsight(domainsCount int, lang string) string {
+	return "fallback insight"
+}
+
+func (s *stubAIService) FocusLevelModel() string {
+	return "test-model"
+}
+
+// newUnavailableRedis - Redis, до которого нельзя достучаться: DegradableService работает как пустой кеш
+func newUnavailableRedis(t *testing.T) redis.ServiceInterface {
+	t.Helper()
+
+	service := redis.NewDegradableRedisService(redis.RedisConfig{Host: "127.0.0.1", Port: "1", HealthCheckInterval: time.Hour})
+	t.Cleanup(func() { service.Close() })
+	return service
+}
+
+func getFocusLevel(t *testing.T, aiService AIAnalyticsService) (int, entity.FocusLevelResponse) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	h := &AIAnalyticsHandler{aiService: aiService, redisService: newUnavailableRedis(t)}
+	router := gin.New()
+	router.GET("/ai-analytics/focus-level", h.GetFocusLevel)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/ai-analytics/focus-level?domains_count=7&lang=en", nil))
+
+	var body struct {
+		Data    entity.FocusLevelResponse `json:"data"`
+		Success bool                      `json:"success"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response %q: %v", recorder.Body.String(), err)
+	}
+	if !body.Success {
+		t.Fatalf("expected success response, got %s", recorder.Body.String())
+	}
+
+	return recorder.Code, body.Data
+}
+
+func TestGetFocusLevelFallback(t *testing.T) {
+	cases := []struct {
+		name      string
+		aiService *stubAIService
+	}{
+		{name: "AI error", aiService: &stubAIService{err: errors.New("openai is down")}},
+		{name: "empty AI response", aiService: &stubAIService{}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, response := getFocusLevel(t, tc.aiService)
+			if status != http.StatusOK {
+				t.Fatalf("expected 200, got %d", status)
+			}
+			if response.Method != "fallback" || response.FocusLevel != "medium" || response.Insight != "fallback insight" {
+				t.Errorf("expected fallback response, got %+v", response)
+			}
+		})
+	}
+}
+
+func TestGetFocusLevelAI(t *testing.T) {
+	status, response := getFocusLevel(t, &stubAIService{focusLevel: &entity.FocusLevelResponse{
+		FocusLevel: "high",
+		Insight:    "ai insight",
+		Method:     "ai",
+		Timestamp:  time.Now(),
+	}})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if response.Method != "ai" || response.FocusLevel != "high" {
+		t.Errorf("expected AI response, got %+v", response)
+	}
+}
@@ -0,0 +1,267 @@
+package ai_analytics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/dinerozz/web-behavior-backend/internal/model/response/wrapper"
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// Сколько хранится задача с результатом после создания
+	aiJobTTL = time.Hour
+	// Максимальное время выполнения анализа в фоне
+	aiJobTimeout = 2 * time.Minute
+	// Время на запись итогового статуса задачи, отдельно от истекшего контекста анализа
+	aiJobStoreTimeout = 5 * time.Second
+
+	// Пул фоновых задач: число воркеров и очередь ожидающих задач
+	aiJobWorkers   = 4
+	aiJobQueueSize = 64
+)
+
+var (
+	// ErrAIJobStoreUnavailable - задачу негде сохранить (Redis недоступен), ее статус нельзя было бы получить
+	ErrAIJobStoreUnavailable = errors.New("analysis jobs are unavailable, retry later or use the synchronous mode")
+	// ErrAIJobQueueFull - все воркеры заняты и очередь заполнена
+	ErrAIJobQueueFull = errors.New("too many analysis jobs in progress, retry later")
+)
+
+type aiJobTask struct {
+	job entity.AIAnalysisJob
+	req entity.AIAnalyticsRequest
+}
+
+// startAIJobWorkers запускает фиксированный пул воркеров фоновых задач
+func (h *AIAnalyticsHandler) startAIJobWorkers() {
+	h.jobs = make(chan aiJobTask, aiJobQueueSize)
+	for i := 0; i < aiJobWorkers; i++ {
+		go func() {
+			for task := range h.jobs {
+				h.runDomainUsageJobSafe(task.job, task.req)
+			}
+		}()
+	}
+}
+
+func aiJobKey(jobID string) string {
+	return "ai_analytics:job:" + jobID
+}
+
+func aiJobChannel(jobID string) string {
+	return "ai_analytics:job_events:" + jobID
+}
+
+// startDomainUsageJob сохраняет задачу в статусе pending и ставит анализ в очередь пула воркеров.
+// Контекст запроса не используется: анализ должен пережить закрытие соединения.
+// Задача записывается через SET NX: без Redis запись не происходит молча, а возвращает ошибку
+func (h *AIAnalyticsHandler) startDomainUsageJob(ctx context.Context, req entity.AIAnalyticsRequest, userID string) (*entity.AIAnalysisJob, error) {
+	jobID, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate job id: %w", err)
+	}
+
+	job := &entity.AIAnalysisJob{
+		ID:        jobID.String(),
+		Status:    entity.AIJobStatusPending,
+		UserID:    userID,
+		CreatedAt: time.Now(),
+	}
+
+	stored, err := h.redisService.SetNX(ctx, aiJobKey(job.ID), job, aiJobTTL)
+	if err != nil || !stored {
+		if err != nil {
+			log.Printf("Failed to store AI job %s: %v", job.ID, err)
+		}
+		return nil, ErrAIJobStoreUnavailable
+	}
+
+	select {
+	case h.jobs <- aiJobTask{job: *job, req: req}:
+	default:
+		if err := h.redisService.Delete(ctx, aiJobKey(job.ID)); err != nil {
+			log.Printf("Failed to delete rejected AI job %s: %v", job.ID, err)
+		}
+		return nil, ErrAIJobQueueFull
+	}
+
+	return job, nil
+}
+
+// runDomainUsageJobSafe не дает панике в анализе уронить сервер: задача помечается failed
+func (h *AIAnalyticsHandler) runDomainUsageJobSafe(job entity.AIAnalysisJob, req entity.AIAnalyticsRequest) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("AI job %s panicked: %v\n%s", job.ID, r, debug.Stack())
+
+			completedAt := time.Now()
+			job.CompletedAt = &completedAt
+			job.Status = entity.AIJobStatusFailed
+			job.Error = "internal error"
+			job.Result = nil
+			job.Meta = nil
+			h.storeJobResult(job)
+		}
+	}()
+
+	h.runDomainUsageJob(job, req)
+}
+
+func (h *AIAnalyticsHandler) runDomainUsageJob(job entity.AIAnalysisJob, req entity.AIAnalyticsRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), aiJobTimeout)
+	defer cancel()
+
+	startedAt := time.Now()
+	analysis, _, err := h.analyzeDomainUsageCached(ctx, req, nil)
+	if err != nil {
+		analysis = h.fallbackDomainAnalysis(ctx, req, err)
+	}
+
+	completedAt := time.Now()
+	job.CompletedAt = &completedAt
+	job.Status = entity.AIJobStatusDone
+	job.Result = &analysis.Analysis
+	job.Meta = h.aiService.BuildAnalyticsMeta(req, startedAt, analysis.UsedAI)
+
+	h.storeJobResult(job)
+}
+
+// storeJobResult сохраняет итоговый статус задачи и оповещает подписчиков. Контекст новый:
+// контекст анализа к этому моменту мог истечь, и задача навсегда осталась бы pending
+func (h *AIAnalyticsHandler) storeJobResult(job entity.AIAnalysisJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), aiJobStoreTimeout)
+	defer cancel()
+
+	// Задача живет aiJobTTL с момента создания
+	ttl := aiJobTTL - time.Since(job.CreatedAt)
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+
+	if err := h.redisService.Set(ctx, aiJobKey(job.ID), job, ttl); err != nil {
+		log.Printf("Failed to store AI job %s result: %v", job.ID, err)
+		return
+	}
+
+	if err := h.redisService.Publish(ctx, aiJobChannel(job.ID), job); err != nil {
+		log.Printf("Failed to publish AI job %s completion: %v", job.ID, err)
+	}
+}
+
+// loadJob читает задачу и проверяет, что она принадлежит текущему пользователю
+func (h *AIAnalyticsHandler) loadJob(c *gin.Context) (*entity.AIAnalysisJob, bool) {
+	jobID, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "Invalid job ID",
+			Success: false,
+		})
+		return nil, false
+	}
+
+	var job entity.AIAnalysisJob
+	if err := h.redisService.Get(c.Request.Context(), aiJobKey(jobID.String()), &job); err != nil || job.UserID != currentUserID(c) {
+		c.JSON(http.StatusNotFound, wrapper.ErrorWrapper{
+			Message: "Job not found",
+			Success: false,
+		})
+		return nil, false
+	}
+
+	return &job, true
+}
+
+func currentUserID(c *gin.Context) string {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return ""
+	}
+	id, _ := userID.(string)
+	return id
+}
+
+// GetAnalysisJob godoc
+// @Summary      Get async AI analysis job
+// @Description  Get status of an AI analysis job started with async=true. Result is present when status is done, error when status is failed. Jobs expire after 1 hour
+// @Tags         /api/v1/admin/ai-analytics
+// @Produce      json
+// @Param        id   path      string  true  "Job ID"
+// @Success      200  {object}  wrapper.ResponseWrapper{data=entity.AIAnalysisJob}
+// @Failure      400  {object}  wrapper.ErrorWrapper
+// @Failure      404  {object}  wrapper.ErrorWrapper
+// @Router       /ai-analytics/jobs/{id} [get]
+func (h *AIAnalyticsHandler) GetAnalysisJob(c *gin.Context) {
+	job, ok := h.loadJob(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapper.ResponseWrapper{
+		Data:    job,
+		Success: true,
+	})
+}
+
+// StreamAnalysisJob godoc
+// @Summary      Stream async AI analysis job completion (SSE)
+// @Description  Server-Sent Events stream that emits a "job" event with the current job state and, if it is still pending, another "job" event once it is done or failed, then closes
+// @Tags         /api/v1/admin/ai-analytics
+// @Produce      text/event-stream
+// @Param        id   path      string  true  "Job ID"
+// @Success      200  {object}  entity.AIAnalysisJob
+// @Failure      400  {object}  wrapper.ErrorWrapper
+// @Failure      404  {object}  wrapper.ErrorWrapper
+// @Router       /ai-analytics/jobs/{id}/events [get]
+func (h *AIAnalyticsHandler) StreamAnalysisJob(c *gin.Context) {
+	job, ok := h.loadJob(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), aiJobTimeout)
+	defer cancel()
+
+	// Подписываемся до повторного чтения задачи, чтобы не пропустить завершение между ними
+	pubsub := h.redisService.Subscribe(ctx, aiJobChannel(job.ID))
+	defer pubsub.Close()
+
+	if !job.IsFinished() {
+		var current entity.AIAnalysisJob
+		if err := h.redisService.Get(ctx, aiJobKey(job.ID), &current); err == nil {
+			job = &current
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	c.SSEvent("job", job)
+	c.Writer.Flush()
+
+	if job.IsFinished() {
+		return
+	}
+
+	select {
+	case msg := <-pubsub.Channel():
+		var finished entity.AIAnalysisJob
+		if err := json.Unmarshal([]byte(msg.Payload), &finished); err != nil {
+			c.SSEvent("error", "failed to decode job event")
+		} else {
+			c.SSEvent("job", finished)
+		}
+	case <-ctx.Done():
+		c.SSEvent("error", "timeout waiting for job completion")
+	}
+	c.Writer.Flush()
+}
@@ -0,0 +1,50 @@
+package ai_analytics
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const aiRateLimitWindow = time.Hour
+
+var errAIRateLimited = errors.New("AI analysis rate limit exceeded, please retry later")
+
+// consumeAIRateLimit списывает один AI анализ из часового лимита пользователя и выставляет
+// X-RateLimit-* заголовки. Вызывается только при промахе кеша. При недоступности Redis запрос пропускается
+func (h *AIAnalyticsHandler) consumeAIRateLimit(c *gin.Context) error {
+	if h.config.RateLimitPerHour <= 0 {
+		return nil
+	}
+
+	key := fmt.Sprintf("rate_limit:ai_analysis:user:%s", currentUserID(c))
+
+	count, ttl, err := h.redisService.IncrementFixedWindow(c.Request.Context(), key, aiRateLimitWindow)
+	if err != nil {
+		log.Printf("AI rate limit check failed for %s: %v", key, err)
+		return nil
+	}
+	if ttl <= 0 {
+		ttl = aiRateLimitWindow
+	}
+
+	remaining := h.config.RateLimitPerHour - int(count)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	c.Header("X-RateLimit-Limit", strconv.Itoa(h.config.RateLimitPerHour))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	c.Header("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(ttl).Unix(), 10))
+
+	if count > int64(h.config.RateLimitPerHour) {
+		c.Header("Retry-After", strconv.Itoa(int(ttl.Seconds())))
+		return errAIRateLimited
+	}
+
+	return nil
+}
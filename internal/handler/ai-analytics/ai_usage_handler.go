@@ -0,0 +1,50 @@
+package ai_analytics
+
+import (
+	"net/http"
+
+	"github.com/dinerozz/web-behavior-backend/internal/model/response/wrapper"
+	"github.com/gin-gonic/gin"
+)
+
+// GetUsage godoc
+// @Summary      Get OpenAI token usage
+// @Description  Token counts and estimated cost of OpenAI calls for today and the current month (UTC). Cost is estimated from the model price list at call time. Super admin only
+// @Tags         /api/v1/admin/ai-analytics
+// @Produce      json
+// @Success      200  {object}  wrapper.ResponseWrapper{data=entity.AIUsageStats}
+// @Failure      403  {object}  wrapper.ErrorWrapper
+// @Failure      500  {object}  wrapper.ErrorWrapper
+// @Router       /ai-analytics/usage [get]
+func (h *AIAnalyticsHandler) GetUsage(c *gin.Context) {
+	usage, err := h.aiService.GetUsage(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapper.ResponseWrapper{
+		Data:    usage,
+		Success: true,
+	})
+}
+
+// GetHealth godoc
+// @Summary      Get AI analytics health
+// @Description  AI availability (API key configured), model and OpenAI requests made today. requests_limit is the per-user hourly AI analysis limit (0 - unlimited)
+// @Tags         /api/v1/admin/ai-analytics
+// @Produce      json
+// @Success      200  {object}  wrapper.ResponseWrapper{data=entity.AIAnalyticsHealthCheck}
+// @Router       /ai-analytics/health [get]
+func (h *AIAnalyticsHandler) GetHealth(c *gin.Context) {
+	health := h.aiService.HealthCheck(c.Request.Context())
+	health.RequestsLimit = h.config.RateLimitPerHour
+
+	c.JSON(http.StatusOK, wrapper.ResponseWrapper{
+		Data:    health,
+		Success: true,
+	})
+}
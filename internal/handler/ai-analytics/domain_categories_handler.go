@@ -0,0 +1,175 @@
+package ai_analytics
+
+import (
+	"net/http"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/dinerozz/web-behavior-backend/internal/model/response/wrapper"
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// ListDomainCategories godoc
+// @Summary      List domain category overrides
+// @Description  Get all pinned domain categories used to override AI categorization
+// @Tags         /api/v1/admin/ai-analytics
+// @Produce      json
+// @Success      200  {object}  wrapper.ResponseWrapper{data=[]entity.DomainCategory}
+// @Failure      500  {object}  wrapper.ErrorWrapper
+// @Router       /ai-analytics/domain-categories [get]
+func (h *AIAnalyticsHandler) ListDomainCategories(c *gin.Context) {
+	categories, err := h.aiService.ListDomainCategories(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapper.ResponseWrapper{
+		Data:    categories,
+		Success: true,
+	})
+}
+
+// CreateDomainCategory godoc
+// @Summary      Create domain category override
+// @Description  Pin a domain to a category; AI analysis will always use this category for the domain. Super admin only
+// @Tags         /api/v1/admin/ai-analytics
+// @Accept       json
+// @Produce      json
+// @Param        request  body      entity.DomainCategoryRequest  true  "Domain category data"
+// @Success      201      {object}  wrapper.ResponseWrapper{data=entity.DomainCategory}
+// @Failure      400      {object}  wrapper.ErrorWrapper
+// @Failure      409      {object}  wrapper.ErrorWrapper
+// @Failure      403      {object}  wrapper.ErrorWrapper
+// @Failure      500      {object}  wrapper.ErrorWrapper
+// @Router       /ai-analytics/domain-categories [post]
+func (h *AIAnalyticsHandler) CreateDomainCategory(c *gin.Context) {
+	var req entity.DomainCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "Invalid request body: " + err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	category, err := h.aiService.CreateDomainCategory(c.Request.Context(), req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == "domain category already exists" {
+			status = http.StatusConflict
+		}
+
+		c.JSON(status, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, wrapper.ResponseWrapper{
+		Data:    category,
+		Success: true,
+	})
+}
+
+// UpdateDomainCategory godoc
+// @Summary      Update domain category override
+// @Description  Change the domain or pinned category of an existing override. Super admin only
+// @Tags         /api/v1/admin/ai-analytics
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                        true  "Domain category ID"
+// @Param        request  body      entity.DomainCategoryRequest  true  "Domain category data"
+// @Success      200      {object}  wrapper.ResponseWrapper{data=entity.DomainCategory}
+// @Failure      400      {object}  wrapper.ErrorWrapper
+// @Failure      404      {object}  wrapper.ErrorWrapper
+// @Failure      409      {object}  wrapper.ErrorWrapper
+// @Failure      403      {object}  wrapper.ErrorWrapper
+// @Failure      500      {object}  wrapper.ErrorWrapper
+// @Router       /ai-analytics/domain-categories/{id} [put]
+func (h *AIAnalyticsHandler) UpdateDomainCategory(c *gin.Context) {
+	id, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "Invalid UUID format",
+			Success: false,
+		})
+		return
+	}
+
+	var req entity.DomainCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "Invalid request body: " + err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	category, err := h.aiService.UpdateDomainCategory(c.Request.Context(), id, req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch err.Error() {
+		case "domain category not found":
+			status = http.StatusNotFound
+		case "domain category already exists":
+			status = http.StatusConflict
+		}
+
+		c.JSON(status, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapper.ResponseWrapper{
+		Data:    category,
+		Success: true,
+	})
+}
+
+// DeleteDomainCategory godoc
+// @Summary      Delete domain category override
+// @Description  Remove a pinned domain category; the domain will be categorized by AI again. Super admin only
+// @Tags         /api/v1/admin/ai-analytics
+// @Produce      json
+// @Param        id   path      string  true  "Domain category ID"
+// @Success      200  {object}  wrapper.SuccessWrapper
+// @Failure      400  {object}  wrapper.ErrorWrapper
+// @Failure      404  {object}  wrapper.ErrorWrapper
+// @Failure      403  {object}  wrapper.ErrorWrapper
+// @Failure      500  {object}  wrapper.ErrorWrapper
+// @Router       /ai-analytics/domain-categories/{id} [delete]
+func (h *AIAnalyticsHandler) DeleteDomainCategory(c *gin.Context) {
+	id, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "Invalid UUID format",
+			Success: false,
+		})
+		return
+	}
+
+	if err := h.aiService.DeleteDomainCategory(c.Request.Context(), id); err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == "domain category not found" {
+			status = http.StatusNotFound
+		}
+
+		c.JSON(status, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapper.SuccessWrapper{
+		Message: "Domain category deleted successfully",
+		Success: true,
+	})
+}
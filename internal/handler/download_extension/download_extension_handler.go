@@ -1,11 +1,14 @@
 package download_extension
 
 import (
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
 	"github.com/dinerozz/web-behavior-backend/internal/model/response/wrapper"
 	"github.com/dinerozz/web-behavior-backend/internal/repository"
+	"github.com/dinerozz/web-behavior-backend/internal/service/redis"
 	"github.com/dinerozz/web-behavior-backend/pkg/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/gofrs/uuid"
@@ -17,9 +20,14 @@ import (
 )
 
 type ExtensionHandler struct {
-	userRepo *repository.UserRepository
+	userRepo     *repository.UserRepository
+	downloadRepo *repository.ExtensionDownloadRepository
+	redisService redis.ServiceInterface
 }
 
+// Повторная загрузка с того же IP и User-Agent в течение окна не учитывается
+const downloadTrackDedupWindow = time.Hour
+
 type ExtensionInfo struct {
 	Version    string `json:"version"`
 	FullCommit string `json:"full_commit"`
@@ -51,9 +59,11 @@ const (
 	ExtensionInfoPath = ExtensionDir + "/info.json"
 )
 
-func NewExtensionHandler(userRepo *repository.UserRepository) *ExtensionHandler {
+func NewExtensionHandler(userRepo *repository.UserRepository, downloadRepo *repository.ExtensionDownloadRepository, redisService redis.ServiceInterface) *ExtensionHandler {
 	return &ExtensionHandler{
-		userRepo: userRepo,
+		userRepo:     userRepo,
+		downloadRepo: downloadRepo,
+		redisService: redisService,
 	}
 }
 
@@ -180,14 +190,29 @@ func (h *ExtensionHandler) GetExtensionInfo(c *gin.Context) {
 // @Failure 500 {object} wrapper.ErrorWrapper
 // @Router /api/extension/stats [get]
 func (h *ExtensionHandler) GetExtensionStats(c *gin.Context) {
+	downloadStats, err := h.downloadRepo.GetDownloadStats(c.Request.Context())
+	if err != nil {
+		log.Printf("Failed to get extension download stats: %v", err)
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{
+			Message: "Cannot get extension download stats",
+			Success: false,
+		})
+		return
+	}
+
 	stats := ExtensionStats{
-		TotalDownloads: 0, // TODO: можно считать из логов nginx
+		TotalDownloads: downloadStats.TotalDownloads,
 		LastDownload:   nil,
 		ExtensionSize:  0,
 		IsAvailable:    false,
 		DeploymentDate: time.Now().UTC().Format(time.RFC3339),
 	}
 
+	if downloadStats.LastDownload != nil {
+		lastDownload := downloadStats.LastDownload.UTC().Format(time.RFC3339)
+		stats.LastDownload = &lastDownload
+	}
+
 	if stat, err := os.Stat(ExtensionZipPath); err == nil {
 		stats.ExtensionSize = stat.Size()
 		stats.IsAvailable = true
@@ -341,6 +366,77 @@ func (h *ExtensionHandler) GetExtensionHealth(c *gin.Context) {
 	}
 }
 
+// TrackDownload - учет загрузок, вызывается nginx (log_by_lua) или sidecar
+// @Summary Track Chrome Extension download
+// @Description Record a single extension download. Called by nginx or a sidecar since the file itself is served by nginx; the caller must forward the client IP (X-Forwarded-For) and User-Agent. Repeated downloads from the same IP and User-Agent within an hour are counted once
+// @Tags Chrome Extension
+// @Accept json
+// @Produce json
+// @Param download body entity.TrackExtensionDownloadRequest false "Download info"
+// @Success 200 {object} wrapper.SuccessWrapper
+// @Failure 400 {object} wrapper.ErrorWrapper
+// @Failure 429 {object} wrapper.ErrorWrapper
+// @Failure 500 {object} wrapper.ErrorWrapper
+// @Router /api/download-extension/track [post]
+func (h *ExtensionHandler) TrackDownload(c *gin.Context) {
+	var req entity.TrackExtensionDownloadRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+				Message: err.Error(),
+				Success: false,
+			})
+			return
+		}
+	}
+
+	if !h.reserveDownloadTrack(c) {
+		c.JSON(http.StatusOK, wrapper.SuccessWrapper{
+			Message: "Download already tracked",
+			Success: true,
+		})
+		return
+	}
+
+	var userAgent *string
+	if req.UserAgent != "" {
+		userAgent = &req.UserAgent
+	}
+
+	if err := h.downloadRepo.RecordDownload(c.Request.Context(), userAgent); err != nil {
+		log.Printf("Failed to track extension download: %v", err)
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{
+			Message: "Cannot track extension download",
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapper.SuccessWrapper{
+		Message: "Download tracked",
+		Success: true,
+	})
+}
+
+// reserveDownloadTrack отмечает загрузку клиента (IP + User-Agent) на окно дедупликации.
+// false - загрузка уже учтена в этом окне. Без Redis загрузка учитывается, как и rate limit пропускает запросы
+func (h *ExtensionHandler) reserveDownloadTrack(c *gin.Context) bool {
+	if h.redisService == nil {
+		return true
+	}
+
+	client := sha256.Sum256([]byte(c.ClientIP() + "|" + c.Request.UserAgent()))
+	key := fmt.Sprintf("download_track:%x", client)
+
+	reserved, err := h.redisService.SetNX(c.Request.Context(), key, time.Now().Unix(), downloadTrackDedupWindow)
+	if err != nil {
+		log.Printf("Failed to dedupe extension download %s: %v", key, err)
+		return true
+	}
+
+	return reserved
+}
+
 // DownloadExtension godoc
 // @Summary Download Chrome extension
 // @Description Download Chrome extension zip file (Super admin only)
@@ -0,0 +1,186 @@
+package excluded_domain
+
+import (
+	"net/http"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/dinerozz/web-behavior-backend/internal/model/response/wrapper"
+	service "github.com/dinerozz/web-behavior-backend/internal/service/excluded_domain"
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+type ExcludedDomainHandler struct {
+	service *service.ExcludedDomainService
+}
+
+func NewExcludedDomainHandler(service *service.ExcludedDomainService) *ExcludedDomainHandler {
+	return &ExcludedDomainHandler{service: service}
+}
+
+// ListExcludedDomains godoc
+// @Summary      List excluded domains
+// @Description  Get domains stored in the exclusion list. Domains from EXCLUDED_DOMAINS are applied too but not listed here
+// @Tags         /api/v1/admin/excluded-domains
+// @Produce      json
+// @Success      200  {object}  wrapper.ResponseWrapper{data=[]entity.ExcludedDomain}
+// @Failure      500  {object}  wrapper.ErrorWrapper
+// @Router       /excluded-domains [get]
+func (h *ExcludedDomainHandler) ListExcludedDomains(c *gin.Context) {
+	domains, err := h.service.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapper.ResponseWrapper{
+		Data:    domains,
+		Success: true,
+	})
+}
+
+// CreateExcludedDomain godoc
+// @Summary      Exclude domain
+// @Description  Add a domain to the exclusion list: its events are no longer stored and it is ignored by metrics
+// @Tags         /api/v1/admin/excluded-domains
+// @Accept       json
+// @Produce      json
+// @Param        request  body      entity.ExcludedDomainRequest  true  "Excluded domain data"
+// @Success      201      {object}  wrapper.ResponseWrapper{data=entity.ExcludedDomain}
+// @Failure      400      {object}  wrapper.ErrorWrapper
+// @Failure      409      {object}  wrapper.ErrorWrapper
+// @Failure      500      {object}  wrapper.ErrorWrapper
+// @Router       /excluded-domains [post]
+func (h *ExcludedDomainHandler) CreateExcludedDomain(c *gin.Context) {
+	var req entity.ExcludedDomainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "Invalid request body: " + err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	excluded, err := h.service.Create(c.Request.Context(), req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch err.Error() {
+		case "domain is required":
+			status = http.StatusBadRequest
+		case "excluded domain already exists":
+			status = http.StatusConflict
+		}
+
+		c.JSON(status, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, wrapper.ResponseWrapper{
+		Data:    excluded,
+		Success: true,
+	})
+}
+
+// UpdateExcludedDomain godoc
+// @Summary      Update excluded domain
+// @Description  Change the domain of an existing exclusion
+// @Tags         /api/v1/admin/excluded-domains
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                        true  "Excluded domain ID"
+// @Param        request  body      entity.ExcludedDomainRequest  true  "Excluded domain data"
+// @Success      200      {object}  wrapper.ResponseWrapper{data=entity.ExcludedDomain}
+// @Failure      400      {object}  wrapper.ErrorWrapper
+// @Failure      404      {object}  wrapper.ErrorWrapper
+// @Failure      409      {object}  wrapper.ErrorWrapper
+// @Failure      500      {object}  wrapper.ErrorWrapper
+// @Router       /excluded-domains/{id} [put]
+func (h *ExcludedDomainHandler) UpdateExcludedDomain(c *gin.Context) {
+	id, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "Invalid UUID format",
+			Success: false,
+		})
+		return
+	}
+
+	var req entity.ExcludedDomainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "Invalid request body: " + err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	excluded, err := h.service.Update(c.Request.Context(), id, req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch err.Error() {
+		case "domain is required":
+			status = http.StatusBadRequest
+		case "excluded domain not found":
+			status = http.StatusNotFound
+		case "excluded domain already exists":
+			status = http.StatusConflict
+		}
+
+		c.JSON(status, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapper.ResponseWrapper{
+		Data:    excluded,
+		Success: true,
+	})
+}
+
+// DeleteExcludedDomain godoc
+// @Summary      Delete excluded domain
+// @Description  Remove a domain from the exclusion list; new events of the domain are stored and counted again
+// @Tags         /api/v1/admin/excluded-domains
+// @Produce      json
+// @Param        id   path      string  true  "Excluded domain ID"
+// @Success      200  {object}  wrapper.SuccessWrapper
+// @Failure      400  {object}  wrapper.ErrorWrapper
+// @Failure      404  {object}  wrapper.ErrorWrapper
+// @Failure      500  {object}  wrapper.ErrorWrapper
+// @Router       /excluded-domains/{id} [delete]
+func (h *ExcludedDomainHandler) DeleteExcludedDomain(c *gin.Context) {
+	id, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "Invalid UUID format",
+			Success: false,
+		})
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == "excluded domain not found" {
+			status = http.StatusNotFound
+		}
+
+		c.JSON(status, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapper.SuccessWrapper{
+		Message: "Excluded domain deleted successfully",
+		Success: true,
+	})
+}
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/dinerozz/web-behavior-backend/internal/entity"
 	"github.com/dinerozz/web-behavior-backend/internal/model/response/wrapper"
@@ -25,7 +27,7 @@ func NewExtensionUserHandler(service service.ExtensionUserService) *ExtensionUse
 
 // CreateExtensionUser godoc
 // @Summary      Create extension user
-// @Description  Create a new extension user with API key
+// @Description  Create a new extension user with API key. owner_user_id must be a member of the organization
 // @Tags         /api/v1/admin/extension
 // @Accept       json
 // @Produce      json
@@ -46,7 +48,7 @@ func (h *ExtensionUserHandler) CreateExtensionUser(c *gin.Context) {
 
 	user, err := h.service.CreateUser(c.Request.Context(), req)
 	if err != nil {
-		if err.Error() == "username already exists" {
+		if err.Error() == "username already exists" || errors.Is(err, service.ErrOwnerNotOrgMember) {
 			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
 				Message: err.Error(),
 				Success: false,
@@ -163,6 +165,11 @@ func (h *ExtensionUserHandler) GetExtensionUserByUsername(c *gin.Context) {
 // @Produce      json
 // @Param        username   query     string  false  "Filter by username"
 // @Param        isActive   query     bool    false  "Filter by active status"
+// @Param        organization_id   query     string  false  "Filter by organization ID"
+// @Param        last_used_before  query     string  false  "Filter by last usage before (RFC3339)"
+// @Param        last_used_after   query     string  false  "Filter by last usage after (RFC3339)"
+// @Param        sort_by    query     string  false  "Sort field: username, created_at, updated_at, last_used_at (default: created_at)"
+// @Param        order      query     string  false  "Sort order: asc or desc (default: desc)"
 // @Param        page       query     int     false  "Page number (starts from 1)"
 // @Param        per_page   query     int     false  "Items per page (default: 20, max: 200)"
 // @Param        limit      query     int     false  "Limit (deprecated, use per_page)"
@@ -181,12 +188,15 @@ func (h *ExtensionUserHandler) GetAllExtensionUsers(c *gin.Context) {
 		return
 	}
 
-	if filter.Page > 0 && filter.PerPage == 0 {
-		filter.PerPage = 20
-	}
-
 	users, paginationInfo, err := h.service.GetAllUsers(c.Request.Context(), filter)
 	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid sort_by") || strings.HasPrefix(err.Error(), "invalid order") {
+			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+				Message: err.Error(),
+				Success: false,
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{
 			Message: err.Error(),
 			Success: false,
@@ -210,7 +220,7 @@ func (h *ExtensionUserHandler) GetAllExtensionUsers(c *gin.Context) {
 
 // UpdateExtensionUser godoc
 // @Summary      Update extension user
-// @Description  Update extension user information
+// @Description  Update extension user information. owner_user_id must be a member of the organization, null unlinks the owner
 // @Tags         /api/v1/admin/extension
 // @Accept       json
 // @Produce      json
@@ -251,7 +261,7 @@ func (h *ExtensionUserHandler) UpdateExtensionUser(c *gin.Context) {
 			return
 		}
 		// todo check api key for unique
-		if err.Error() == "username already exists" {
+		if err.Error() == "username already exists" || errors.Is(err, service.ErrOwnerNotOrgMember) {
 			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
 				Message: err.Error(),
 				Success: false,
@@ -368,6 +378,47 @@ func (h *ExtensionUserHandler) DeleteExtensionUser(c *gin.Context) {
 	})
 }
 
+// DeactivateStaleExtensionUsers godoc
+// @Summary      Deactivate stale extension users
+// @Description  Deactivate all active extension users who haven't used their API key in the given number of days
+// @Tags         /api/v1/admin/extension
+// @Accept       json
+// @Produce      json
+// @Param        days  query     int  false  "Inactivity threshold in days (default: 30)"
+// @Success      200   {object}  wrapper.ResponseWrapper{data=[]string}
+// @Failure      400   {object}  wrapper.ErrorWrapper
+// @Failure      403   {object}  wrapper.ErrorWrapper
+// @Failure      500   {object}  wrapper.ErrorWrapper
+// @Router       /extension/users/deactivate-stale [post]
+func (h *ExtensionUserHandler) DeactivateStaleExtensionUsers(c *gin.Context) {
+	days := 30
+	if daysStr := c.Query("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+				Message: "days must be a positive integer",
+				Success: false,
+			})
+			return
+		}
+		days = parsed
+	}
+
+	ids, err := h.service.DeactivateStaleUsers(c.Request.Context(), days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapper.ResponseWrapper{
+		Data:    ids,
+		Success: true,
+	})
+}
+
 // ValidateAPIKey godoc
 // @Summary      Validate API key
 // @Description  Validate extension user API key
@@ -3,33 +3,117 @@ package metrics
 import (
 	"context"
 	"crypto/md5"
+	"encoding/csv"
+	"errors"
 	"fmt"
 	"github.com/dinerozz/web-behavior-backend/internal/service/redis"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dinerozz/web-behavior-backend/internal/entity"
 	"github.com/dinerozz/web-behavior-backend/internal/model/response/wrapper"
+	metricsService "github.com/dinerozz/web-behavior-backend/internal/service/metrics_service"
+	userBehaviorService "github.com/dinerozz/web-behavior-backend/internal/service/user_behavior"
+	"github.com/dinerozz/web-behavior-backend/pkg/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
 )
 
 type MetricsHandler struct {
-	service      MetricsService
-	redisService redis.ServiceInterface
+	service             MetricsService
+	redisService        redis.ServiceInterface
+	engagedTimeCacheTTL time.Duration
+	engagedTimeMaxRange time.Duration
+	// Максимальный период /metrics/minute-activity
+	minuteActivityMaxRange time.Duration
+	orgAccess              OrganizationAccessChecker
+}
+
+// OrganizationAccessChecker проверяет доступ пользователя к организации (super admin имеет доступ ко всем)
+type OrganizationAccessChecker interface {
+	CheckUserAccess(orgID, userID uuid.UUID) (string, error)
 }
 
 type MetricsService interface {
 	GetTrackedTime(ctx context.Context, filter entity.TrackedTimeFilter) (*entity.TrackedTimeMetric, error)
 	GetTrackedTimeTotal(ctx context.Context, filter entity.TrackedTimeFilter) (*entity.TrackedTimeMetric, error)
+	GetTrackedTimeTotalByUsers(ctx context.Context, filter entity.TrackedTimeTotalByUsersFilter) (map[string]*entity.TrackedTimeMetric, error)
 	GetEngagedTime(ctx context.Context, filter entity.EngagedTimeFilter) (*entity.EngagedTimeMetric, error)
 	GetTopDomains(ctx context.Context, filter entity.TopDomainsFilter) (*entity.TopDomainsResponse, error)
 	GetDeepWorkSessions(ctx context.Context, filter entity.DeepWorkSessionsFilter) (*entity.DeepWorkSessionsResponse, error)
+	GetDeepWorkBySession(ctx context.Context, filter entity.DeepWorkSessionsFilter) (*entity.DeepWorkBySessionResponse, error)
+	GetActivityHeatmap(ctx context.Context, filter entity.ActivityHeatmapFilter) (*entity.ActivityHeatmap, error)
+	GetDeepWorkBlockEvents(ctx context.Context, filter entity.DeepWorkSessionsFilter, blockID int) (*entity.DeepWorkBlockEventsResponse, error)
+	GetSessionEngagement(ctx context.Context, filter entity.SessionEngagementFilter) (*entity.SessionEngagementMetric, error)
+	GetEngagedTimeDaily(ctx context.Context, filter entity.EngagedTimeDailyFilter) (*entity.EngagedTimeDailyMetric, error)
+	GetTypingActivity(ctx context.Context, filter entity.TypingActivityFilter) (*entity.TypingActivity, error)
+	GetScrollActivity(ctx context.Context, filter entity.ScrollActivityFilter) (*entity.ScrollActivity, error)
+	GetTabSwitchStats(ctx context.Context, filter entity.TabSwitchFilter) (*entity.TabSwitchStats, error)
+	GetOrganizationLeaderboard(ctx context.Context, filter entity.LeaderboardFilter) (*entity.Leaderboard, error)
+	GetWeeklyDigest(ctx context.Context, filter entity.WeeklyDigestFilter) (*entity.WeeklyDigest, error)
+	GetMinuteActivity(ctx context.Context, filter entity.EngagedTimeFilter) (*entity.MinuteActivitySeries, error)
+	GetClickHeatmap(ctx context.Context, userID, url string, gridSize int) (*entity.ClickHeatmap, error)
+	ActiveEventsCacheKey(userID string) string
+	ExcludedDomainsCacheKey(ctx context.Context) string
 	//PrepareAIAnalyticsData(ctx context.Context, filter entity.EngagedTimeFilter) (*entity.AIAnalyticsRequest, error)
 }
 
-func NewMetricsHandler(service MetricsService, redisService redis.ServiceInterface) *MetricsHandler {
-	return &MetricsHandler{service: service, redisService: redisService}
+func NewMetricsHandler(service MetricsService, redisService redis.ServiceInterface, engagedTimeCacheTTL, engagedTimeMaxRange, minuteActivityMaxRange time.Duration, orgAccess OrganizationAccessChecker) *MetricsHandler {
+	return &MetricsHandler{
+		service:                service,
+		redisService:           redisService,
+		engagedTimeCacheTTL:    engagedTimeCacheTTL,
+		engagedTimeMaxRange:    engagedTimeMaxRange,
+		minuteActivityMaxRange: minuteActivityMaxRange,
+		orgAccess:              orgAccess,
+	}
+}
+
+// serviceErrorStatus - 504, если запрос метрик не уложился в лимит времени, иначе 500
+func serviceErrorStatus(err error) int {
+	if errors.Is(err, entity.ErrQueryTimeout) {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+const (
+	maxDeepWorkMinDurationMinutes  = 480   // 8 часов
+	maxDeepWorkGapThresholdSeconds = 3600  // 1 час
+	maxDeepWorkMinEventsPerBlock   = 10000 // защита от заведомо пустых выборок
+)
+
+// parseDeepWorkThresholds читает опциональные min_duration, gap_threshold и min_events.
+// Отсутствующие параметры возвращаются как 0 — репозиторий подставит значения по умолчанию.
+func parseDeepWorkThresholds(c *gin.Context) (minDuration, gapThreshold, minEvents int, err error) {
+	parse := func(name string, max int) (int, error) {
+		raw := c.Query(name)
+		if raw == "" {
+			return 0, nil
+		}
+
+		value, err := strconv.Atoi(raw)
+		if err != nil || value <= 0 || value > max {
+			return 0, fmt.Errorf("%s must be an integer between 1 and %d", name, max)
+		}
+
+		return value, nil
+	}
+
+	if minDuration, err = parse("min_duration", maxDeepWorkMinDurationMinutes); err != nil {
+		return 0, 0, 0, err
+	}
+	if gapThreshold, err = parse("gap_threshold", maxDeepWorkGapThresholdSeconds); err != nil {
+		return 0, 0, 0, err
+	}
+	if minEvents, err = parse("min_events", maxDeepWorkMinEventsPerBlock); err != nil {
+		return 0, 0, 0, err
+	}
+
+	return minDuration, gapThreshold, minEvents, nil
 }
 
 func (h *MetricsHandler) GetTrackedTime(c *gin.Context) {
@@ -44,37 +128,166 @@ func (h *MetricsHandler) GetTrackedTime(c *gin.Context) {
 		return
 	}
 
-	startTimeStr := c.Query("start_time")
-	if startTimeStr == "" {
+	startTime, endTime, err := utils.ParseTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	filter.StartTime = startTime
+	filter.EndTime = endTime
+
+	if sessionID := c.Query("session_id"); sessionID != "" {
+		filter.SessionID = &sessionID
+	}
+
+	metric, err := h.service.GetTrackedTime(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(serviceErrorStatus(err), wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.TrackedTimeResponse{
+		Data:    metric,
+		Success: true,
+	})
+}
+
+// parseUserIDs собирает user_id из повторяющихся параметров и списков через запятую
+func parseUserIDs(c *gin.Context) []string {
+	seen := make(map[string]bool)
+	var userIDs []string
+
+	for _, raw := range c.QueryArray("user_id") {
+		for _, userID := range strings.Split(raw, ",") {
+			userID = strings.TrimSpace(userID)
+			if userID == "" || seen[userID] {
+				continue
+			}
+			seen[userID] = true
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	return userIDs
+}
+
+func (h *MetricsHandler) GetTrackedTimeTotal(c *gin.Context) {
+	var filter entity.TrackedTimeFilter
+
+	userIDs := parseUserIDs(c)
+	if len(userIDs) == 0 {
 		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
-			Message: "start_time is required (RFC3339 format)",
+			Message: "user_id is required",
 			Success: false,
 		})
 		return
 	}
 
-	endTimeStr := c.Query("end_time")
-	if endTimeStr == "" {
+	if len(userIDs) > entity.MaxTrackedTimeTotalUsers {
 		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
-			Message: "end_time is required (RFC3339 format)",
+			Message: fmt.Sprintf("no more than %d user_id values are allowed", entity.MaxTrackedTimeTotalUsers),
 			Success: false,
 		})
 		return
 	}
 
-	startTime, err := time.Parse(time.RFC3339, startTimeStr)
+	if sessionID := c.Query("session_id"); sessionID != "" {
+		filter.SessionID = &sessionID
+	}
+
+	if len(userIDs) > 1 {
+		metrics, err := h.service.GetTrackedTimeTotalByUsers(c.Request.Context(), entity.TrackedTimeTotalByUsersFilter{
+			UserIDs:   userIDs,
+			SessionID: filter.SessionID,
+		})
+		if err != nil {
+			c.JSON(serviceErrorStatus(err), wrapper.ErrorWrapper{
+				Message: err.Error(),
+				Success: false,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, entity.TrackedTimeTotalByUsersResponse{
+			Data:    metrics,
+			Success: true,
+		})
+		return
+	}
+
+	filter.UserID = userIDs[0]
+
+	metric, err := h.service.GetTrackedTimeTotal(c.Request.Context(), filter)
 	if err != nil {
+		c.JSON(serviceErrorStatus(err), wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.TrackedTimeResponse{
+		Data:    metric,
+		Success: true,
+	})
+}
+
+func (h *MetricsHandler) generateEngagedTimeCacheKey(ctx context.Context, filter entity.EngagedTimeFilter) string {
+	sessionID := ""
+	if filter.SessionID != nil {
+		sessionID = *filter.SessionID
+	}
+
+	params := fmt.Sprintf("user_id:%s|start_time:%s|end_time:%s|session_id:%s|min_duration:%d|gap_threshold:%d|min_events:%d|compare:%t|focus_method:%s|active_events:%s|org_active_events:%s|excluded_domains:%s",
+		filter.UserID,
+		filter.StartTime.Format(time.RFC3339),
+		filter.EndTime.Format(time.RFC3339),
+		sessionID,
+		filter.MinDurationMinutes,
+		filter.GapThresholdSeconds,
+		filter.MinEventsPerBlock,
+		filter.ComparePrevious,
+		filter.FocusMethod,
+		strings.Join(filter.CustomActiveEvents, ","),
+		h.service.ActiveEventsCacheKey(filter.UserID),
+		h.service.ExcludedDomainsCacheKey(ctx),
+	)
+
+	hash := md5.Sum([]byte(params))
+	return fmt.Sprintf("metrics:engaged_time:%x", hash)
+}
+
+func (h *MetricsHandler) GetEngagedTime(c *gin.Context) {
+	var filter entity.EngagedTimeFilter
+
+	filter.UserID = c.Query("user_id")
+	if filter.UserID == "" {
 		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
-			Message: "Invalid start_time format, use RFC3339",
+			Message: "user_id is required",
 			Success: false,
 		})
 		return
 	}
 
-	endTime, err := time.Parse(time.RFC3339, endTimeStr)
+	startTime, endTime, err := utils.ParseTimeRange(c)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
-			Message: "Invalid end_time format, use RFC3339",
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	if endTime.Sub(startTime) > h.engagedTimeMaxRange {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: fmt.Sprintf("Time range cannot exceed %d days", int(h.engagedTimeMaxRange.Hours()/24)),
 			Success: false,
 		})
 		return
@@ -87,69 +300,699 @@ func (h *MetricsHandler) GetTrackedTime(c *gin.Context) {
 		filter.SessionID = &sessionID
 	}
 
-	metric, err := h.service.GetTrackedTime(c.Request.Context(), filter)
+	filter.MinDurationMinutes, filter.GapThresholdSeconds, filter.MinEventsPerBlock, err = parseDeepWorkThresholds(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
 			Message: err.Error(),
 			Success: false,
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, entity.TrackedTimeResponse{
+	switch c.Query("compare") {
+	case "":
+	case "previous":
+		filter.ComparePrevious = true
+	default:
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "compare must be 'previous'",
+			Success: false,
+		})
+		return
+	}
+
+	switch focusMethod := c.Query("focus_method"); focusMethod {
+	case "", entity.FocusMethodDomains, entity.FocusMethodSwitches:
+		filter.FocusMethod = focusMethod
+	default:
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "focus_method must be 'domains' or 'switches'",
+			Success: false,
+		})
+		return
+	}
+
+	filter.CustomActiveEvents, err = parseActiveEvents(c.Query("active_events"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	cacheKey := h.generateEngagedTimeCacheKey(ctx, filter)
+
+	// no_cache=true сбрасывает запись, свежий результат все равно записывается
+	noCache := c.Query("no_cache") == "true"
+	if noCache {
+		if err := h.redisService.Delete(ctx, cacheKey); err != nil {
+			fmt.Printf("Failed to drop engaged time cache: %v\n", err)
+		}
+	}
+
+	// С compare=previous метрика зависит и от событий предыдущего периода той же длины
+	period := redis.MetricCachePeriod{Start: filter.StartTime, End: filter.EndTime}
+	if filter.ComparePrevious {
+		period.Start = filter.StartTime.Add(-filter.EndTime.Sub(filter.StartTime))
+	}
+
+	var metric entity.EngagedTimeMetric
+	hit, err := h.redisService.GetOrComputeUserMetric(ctx, filter.UserID, cacheKey, period, h.engagedTimeCacheTTL, &metric, func() (interface{}, error) {
+		return h.service.GetEngagedTime(ctx, filter)
+	})
+	if err != nil {
+		c.JSON(serviceErrorStatus(err), wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	switch {
+	case noCache:
+		c.Header("X-Cache", "BYPASS")
+	case hit:
+		c.Header("X-Cache", "HIT")
+		if ttl, ttlErr := h.redisService.GetTTL(ctx, cacheKey); ttlErr == nil && ttl > 0 {
+			c.Header("X-Cache-TTL", strconv.Itoa(int(ttl.Seconds())))
+		}
+	default:
+		c.Header("X-Cache", "MISS")
+	}
+	c.Header("X-Cache-Key", cacheKey) // debug
+
+	c.JSON(http.StatusOK, entity.EngagedTimeResponse{
+		Data:    &metric,
+		Success: true,
+	})
+}
+
+// parseActiveEvents разбирает список активных событий через запятую. Результат без повторов
+// и отсортирован, чтобы одинаковые наборы давали один ключ кеша
+func parseActiveEvents(raw string) ([]string, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool)
+	var events []string
+	for _, eventType := range strings.Split(raw, ",") {
+		eventType = strings.TrimSpace(eventType)
+		if eventType == "" || seen[eventType] {
+			continue
+		}
+		if !userBehaviorService.IsValidEventType(eventType) {
+			return nil, fmt.Errorf("invalid active event type: %s", eventType)
+		}
+		seen[eventType] = true
+		events = append(events, eventType)
+	}
+
+	sort.Strings(events)
+	return events, nil
+}
+
+// requiredUserID читает обязательный user_id
+func requiredUserID(c *gin.Context) (string, error) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		return "", fmt.Errorf("user_id is required")
+	}
+	return userID, nil
+}
+
+func (h *MetricsHandler) generateActivityHeatmapCacheKey(ctx context.Context, filter entity.ActivityHeatmapFilter) string {
+	sessionID := ""
+	if filter.SessionID != nil {
+		sessionID = *filter.SessionID
+	}
+
+	params := fmt.Sprintf("user_id:%s|start_time:%s|end_time:%s|session_id:%s|org_active_events:%s|excluded_domains:%s",
+		filter.UserID,
+		filter.StartTime.Format(time.RFC3339),
+		filter.EndTime.Format(time.RFC3339),
+		sessionID,
+		h.service.ActiveEventsCacheKey(filter.UserID),
+		h.service.ExcludedDomainsCacheKey(ctx),
+	)
+
+	hash := md5.Sum([]byte(params))
+	return fmt.Sprintf("metrics:activity_heatmap:%x", hash)
+}
+
+func (h *MetricsHandler) GetActivityHeatmap(c *gin.Context) {
+	var filter entity.ActivityHeatmapFilter
+
+	userID, err := requiredUserID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	startTime, endTime, err := utils.ParseTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	if endTime.Sub(startTime) > h.engagedTimeMaxRange {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: fmt.Sprintf("Time range cannot exceed %d days", int(h.engagedTimeMaxRange.Hours()/24)),
+			Success: false,
+		})
+		return
+	}
+
+	filter.UserID = userID
+	filter.StartTime = startTime
+	filter.EndTime = endTime
+
+	if sessionID := c.Query("session_id"); sessionID != "" {
+		filter.SessionID = &sessionID
+	}
+
+	ctx := c.Request.Context()
+	cacheKey := h.generateActivityHeatmapCacheKey(ctx, filter)
+
+	var cachedHeatmap entity.ActivityHeatmap
+	err = h.redisService.Get(ctx, cacheKey, &cachedHeatmap)
+	if err == nil {
+		c.Header("X-Cache", "HIT")
+		c.JSON(http.StatusOK, entity.ActivityHeatmapResponse{
+			Data:    &cachedHeatmap,
+			Success: true,
+		})
+		return
+	}
+
+	c.Header("X-Cache", "MISS")
+
+	heatmap, err := h.service.GetActivityHeatmap(ctx, filter)
+	if err != nil {
+		c.JSON(serviceErrorStatus(err), wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	period := redis.MetricCachePeriod{Start: filter.StartTime, End: filter.EndTime}
+	cacheErr := h.redisService.SetUserMetricCache(ctx, filter.UserID, cacheKey, period, heatmap, time.Hour)
+	if cacheErr != nil {
+		fmt.Printf("Failed to cache activity heatmap result: %v\n", cacheErr)
+	}
+
+	c.JSON(http.StatusOK, entity.ActivityHeatmapResponse{
+		Data:    heatmap,
+		Success: true,
+	})
+}
+
+// GetSessionEngagement godoc
+// @Summary      Get per-session engagement
+// @Description  Get active minutes, tracked minutes, engagement rate and unique domains for each session of a user in a time range, ordered by session start
+// @Tags         /api/v1/admin/metrics
+// @Accept       json
+// @Produce      json
+// @Param        user_id     query     string  true   "User ID"
+// @Param        start_time  query     string  false  "Start time (RFC3339), required unless period is set"
+// @Param        end_time    query     string  false  "End time (RFC3339), required unless period is set"
+// @Param        period      query     string  false  "Period preset, ignored when start_time or end_time is set"  Enums(today, week, month, year)
+// @Param        tz          query     string  false  "IANA timezone for period (default: UTC)"
+// @Success      200         {object}  entity.SessionEngagementResponse
+// @Failure      400         {object}  wrapper.ErrorWrapper
+// @Failure      403         {object}  wrapper.ErrorWrapper
+// @Failure      500         {object}  wrapper.ErrorWrapper
+// @Failure      504         {object}  wrapper.ErrorWrapper
+// @Router       /metrics/session-engagement [get]
+func (h *MetricsHandler) GetSessionEngagement(c *gin.Context) {
+	userID, err := requiredUserID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	startTime, endTime, err := utils.ParseTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	filter := entity.SessionEngagementFilter{
+		UserID:    userID,
+		StartTime: startTime,
+		EndTime:   endTime,
+	}
+
+	metric, err := h.service.GetSessionEngagement(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(serviceErrorStatus(err), wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.SessionEngagementResponse{
 		Data:    metric,
 		Success: true,
 	})
 }
 
-func (h *MetricsHandler) GetTrackedTimeTotal(c *gin.Context) {
-	var filter entity.TrackedTimeFilter
+// GetTypingActivity godoc
+// @Summary      Get typing activity
+// @Description  Get keystrokes per minute from keydown/keyup events: total, average and peak keys per minute over the period, broken down by domain. Helps tell reading sessions from writing sessions
+// @Tags         /api/v1/admin/metrics
+// @Accept       json
+// @Produce      json
+// @Param        user_id     query     string  true   "User ID"
+// @Param        start_time  query     string  false  "Start time (RFC3339), required unless period is set"
+// @Param        end_time    query     string  false  "End time (RFC3339), required unless period is set"
+// @Param        period      query     string  false  "Period preset, ignored when start_time or end_time is set"  Enums(today, week, month, year)
+// @Param        tz          query     string  false  "IANA timezone for period (default: UTC)"
+// @Param        session_id  query     string  false  "Session ID"
+// @Success      200         {object}  entity.TypingActivityResponse
+// @Failure      400         {object}  wrapper.ErrorWrapper
+// @Failure      403         {object}  wrapper.ErrorWrapper
+// @Failure      500         {object}  wrapper.ErrorWrapper
+// @Failure      504         {object}  wrapper.ErrorWrapper
+// @Router       /metrics/typing-activity [get]
+func (h *MetricsHandler) GetTypingActivity(c *gin.Context) {
+	userID, err := requiredUserID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	startTime, endTime, err := utils.ParseTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	filter := entity.TypingActivityFilter{
+		UserID:    userID,
+		StartTime: startTime,
+		EndTime:   endTime,
+	}
+
+	if sessionID := c.Query("session_id"); sessionID != "" {
+		filter.SessionID = &sessionID
+	}
+
+	activity, err := h.service.GetTypingActivity(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(serviceErrorStatus(err), wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.TypingActivityResponse{
+		Data:    activity,
+		Success: true,
+	})
+}
+
+// GetScrollActivity godoc
+// @Summary      Get scroll activity
+// @Description  Get scrollend counts per domain and per session with the share of scroll events among all events. Domains are profiled as reading_heavy, interaction_heavy or mixed by comparing scroll events with clicks and key events
+// @Tags         /api/v1/admin/metrics
+// @Accept       json
+// @Produce      json
+// @Param        user_id     query     string  true   "User ID"
+// @Param        start_time  query     string  false  "Start time (RFC3339), required unless period is set"
+// @Param        end_time    query     string  false  "End time (RFC3339), required unless period is set"
+// @Param        period      query     string  false  "Period preset, ignored when start_time or end_time is set"  Enums(today, week, month, year)
+// @Param        tz          query     string  false  "IANA timezone for period (default: UTC)"
+// @Success      200         {object}  entity.ScrollActivityResponse
+// @Failure      400         {object}  wrapper.ErrorWrapper
+// @Failure      403         {object}  wrapper.ErrorWrapper
+// @Failure      500         {object}  wrapper.ErrorWrapper
+// @Failure      504         {object}  wrapper.ErrorWrapper
+// @Router       /metrics/scroll-activity [get]
+func (h *MetricsHandler) GetScrollActivity(c *gin.Context) {
+	userID, err := requiredUserID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	startTime, endTime, err := utils.ParseTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	filter := entity.ScrollActivityFilter{
+		UserID:    userID,
+		StartTime: startTime,
+		EndTime:   endTime,
+	}
+
+	activity, err := h.service.GetScrollActivity(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(serviceErrorStatus(err), wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.ScrollActivityResponse{
+		Data:    activity,
+		Success: true,
+	})
+}
+
+// GetTabSwitchStats godoc
+// @Summary      Get tab switch stats
+// @Description  Get tab switches (visibility_hidden transitions) per hour with high churn hours flagged, and the domains most often left when switching tabs
+// @Tags         /api/v1/admin/metrics
+// @Accept       json
+// @Produce      json
+// @Param        user_id     query     string  true   "User ID"
+// @Param        start_time  query     string  false  "Start time (RFC3339), required unless period is set"
+// @Param        end_time    query     string  false  "End time (RFC3339), required unless period is set"
+// @Param        period      query     string  false  "Period preset, ignored when start_time or end_time is set"  Enums(today, week, month, year)
+// @Param        tz          query     string  false  "IANA timezone for period (default: UTC)"
+// @Success      200         {object}  entity.TabSwitchStatsResponse
+// @Failure      400         {object}  wrapper.ErrorWrapper
+// @Failure      403         {object}  wrapper.ErrorWrapper
+// @Failure      500         {object}  wrapper.ErrorWrapper
+// @Failure      504         {object}  wrapper.ErrorWrapper
+// @Router       /metrics/tab-switches [get]
+func (h *MetricsHandler) GetTabSwitchStats(c *gin.Context) {
+	userID, err := requiredUserID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	startTime, endTime, err := utils.ParseTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	filter := entity.TabSwitchFilter{
+		UserID:    userID,
+		StartTime: startTime,
+		EndTime:   endTime,
+	}
+
+	stats, err := h.service.GetTabSwitchStats(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(serviceErrorStatus(err), wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.TabSwitchStatsResponse{
+		Data:    stats,
+		Success: true,
+	})
+}
+
+// GetMinuteActivity godoc
+// @Summary      Get minute activity series
+// @Description  Get the raw minute-by-minute rows behind engaged time (minute, is_active, active events, domain) for binned activity strips. A minute repeats for each domain visited in it. The range is limited (MINUTE_ACTIVITY_MAX_RANGE_HOURS, 24h by default) because of volume
+// @Tags         /api/v1/admin/metrics
+// @Accept       json
+// @Produce      json
+// @Param        user_id     query     string  true   "User ID"
+// @Param        start_time  query     string  false  "Start time (RFC3339), required unless period is set"
+// @Param        end_time    query     string  false  "End time (RFC3339), required unless period is set"
+// @Param        period      query     string  false  "Period preset, ignored when start_time or end_time is set"  Enums(today, week, month, year)
+// @Param        tz          query     string  false  "IANA timezone for period (default: UTC)"
+// @Param        session_id  query     string  false  "Session ID"
+// @Success      200         {object}  entity.MinuteActivityResponse
+// @Failure      400         {object}  wrapper.ErrorWrapper
+// @Failure      403         {object}  wrapper.ErrorWrapper
+// @Failure      500         {object}  wrapper.ErrorWrapper
+// @Failure      504         {object}  wrapper.ErrorWrapper
+// @Router       /metrics/minute-activity [get]
+func (h *MetricsHandler) GetMinuteActivity(c *gin.Context) {
+	userID, err := requiredUserID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	startTime, endTime, err := utils.ParseTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	if endTime.Sub(startTime) > h.minuteActivityMaxRange {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: fmt.Sprintf("Time range cannot exceed %d hours", int(h.minuteActivityMaxRange.Hours())),
+			Success: false,
+		})
+		return
+	}
+
+	filter := entity.EngagedTimeFilter{
+		UserID:    userID,
+		StartTime: startTime,
+		EndTime:   endTime,
+	}
+
+	if sessionID := c.Query("session_id"); sessionID != "" {
+		filter.SessionID = &sessionID
+	}
+
+	series, err := h.service.GetMinuteActivity(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(serviceErrorStatus(err), wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.MinuteActivityResponse{
+		Data:    series,
+		Success: true,
+	})
+}
+
+// Размер ячейки click heatmap в пикселях
+const (
+	defaultClickHeatmapGridSize = 50
+	minClickHeatmapGridSize     = 10
+	maxClickHeatmapGridSize     = 500
+)
+
+// GetClickHeatmap godoc
+// @Summary      Get click heatmap
+// @Description  Bucket click coordinates on a URL into a grid of grid_size x grid_size pixel cells. Returns grid dimensions and only the non-empty cells
+// @Tags         /api/v1/admin/metrics
+// @Accept       json
+// @Produce      json
+// @Param        user_id    query     string  true   "User ID"
+// @Param        url        query     string  true   "Page URL (exact match)"
+// @Param        grid_size  query     int     false  "Cell size in pixels (10-500)"  default(50)
+// @Success      200        {object}  entity.ClickHeatmapResponse
+// @Failure      400        {object}  wrapper.ErrorWrapper
+// @Failure      403        {object}  wrapper.ErrorWrapper
+// @Failure      500        {object}  wrapper.ErrorWrapper
+// @Failure      504        {object}  wrapper.ErrorWrapper
+// @Router       /metrics/click-heatmap [get]
+func (h *MetricsHandler) GetClickHeatmap(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "user_id is required",
+			Success: false,
+		})
+		return
+	}
+
+	url := c.Query("url")
+	if url == "" {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "url is required",
+			Success: false,
+		})
+		return
+	}
+
+	gridSize := defaultClickHeatmapGridSize
+	if raw := c.Query("grid_size"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < minClickHeatmapGridSize || parsed > maxClickHeatmapGridSize {
+			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+				Message: fmt.Sprintf("grid_size must be an integer between %d and %d", minClickHeatmapGridSize, maxClickHeatmapGridSize),
+				Success: false,
+			})
+			return
+		}
+		gridSize = parsed
+	}
+
+	heatmap, err := h.service.GetClickHeatmap(c.Request.Context(), userID, url, gridSize)
+	if err != nil {
+		c.JSON(serviceErrorStatus(err), wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.ClickHeatmapResponse{
+		Data:    heatmap,
+		Success: true,
+	})
+}
+
+// GetEngagedTimeDaily godoc
+// @Summary      Get daily engaged time
+// @Description  Get active and tracked minutes per day (UTC). Whole past days are read from the daily_engagement rollup, partial edge days and today are computed from raw events
+// @Tags         /api/v1/admin/metrics
+// @Accept       json
+// @Produce      json
+// @Param        user_id     query     string  true   "User ID"
+// @Param        start_time  query     string  false  "Start time (RFC3339), required unless period is set"
+// @Param        end_time    query     string  false  "End time (RFC3339), required unless period is set"
+// @Param        period      query     string  false  "Period preset, ignored when start_time or end_time is set"  Enums(today, week, month, year)
+// @Param        tz          query     string  false  "IANA timezone for period (default: UTC)"
+// @Success      200         {object}  entity.EngagedTimeDailyResponse
+// @Failure      400         {object}  wrapper.ErrorWrapper
+// @Failure      403         {object}  wrapper.ErrorWrapper
+// @Failure      500         {object}  wrapper.ErrorWrapper
+// @Failure      504         {object}  wrapper.ErrorWrapper
+// @Router       /metrics/engaged-time-daily [get]
+func (h *MetricsHandler) GetEngagedTimeDaily(c *gin.Context) {
+	userID, err := requiredUserID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	startTime, endTime, err := utils.ParseTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
 
-	filter.UserID = c.Query("user_id")
-	if filter.UserID == "" {
+	if endTime.Sub(startTime) > h.engagedTimeMaxRange {
 		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
-			Message: "user_id is required",
+			Message: fmt.Sprintf("Time range cannot exceed %d days", int(h.engagedTimeMaxRange.Hours()/24)),
 			Success: false,
 		})
 		return
 	}
 
-	if sessionID := c.Query("session_id"); sessionID != "" {
-		filter.SessionID = &sessionID
+	filter := entity.EngagedTimeDailyFilter{
+		UserID:    userID,
+		StartTime: startTime,
+		EndTime:   endTime,
 	}
 
-	metric, err := h.service.GetTrackedTimeTotal(c.Request.Context(), filter)
+	metric, err := h.service.GetEngagedTimeDaily(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{
+		c.JSON(serviceErrorStatus(err), wrapper.ErrorWrapper{
 			Message: err.Error(),
 			Success: false,
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, entity.TrackedTimeResponse{
+	c.JSON(http.StatusOK, entity.EngagedTimeDailyResponse{
 		Data:    metric,
 		Success: true,
 	})
 }
 
-func (h *MetricsHandler) generateEngagedTimeCacheKey(filter entity.EngagedTimeFilter) string {
-	params := fmt.Sprintf("user_id:%s|start_time:%s|end_time:%s|session_id:%v",
-		filter.UserID,
-		filter.StartTime.Format(time.RFC3339),
-		filter.EndTime.Format(time.RFC3339),
-		filter.SessionID,
-	)
+// Недельная сводка кешируется на неделю; новые события пользователя сбрасывают кеш
+const weeklyDigestCacheTTL = 7 * 24 * time.Hour
 
-	hash := md5.Sum([]byte(params))
-	return fmt.Sprintf("metrics:engaged_time:%x", hash)
-}
+// parseWeekStart читает week_start (YYYY-MM-DD, UTC); по умолчанию - понедельник текущей недели
+func parseWeekStart(raw string) (time.Time, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	if raw == "" {
+		// time.Weekday: воскресенье = 0
+		return today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7)), nil
+	}
 
-func (h *MetricsHandler) GetEngagedTime(c *gin.Context) {
-	var filter entity.EngagedTimeFilter
+	weekStart, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid week_start format, use YYYY-MM-DD")
+	}
 
-	filter.UserID = c.Query("user_id")
-	if filter.UserID == "" {
+	if weekStart.After(today) {
+		return time.Time{}, fmt.Errorf("week_start cannot be in the future")
+	}
+
+	return weekStart, nil
+}
+
+// GetWeeklyDigest godoc
+// @Summary      Get weekly digest
+// @Description  Get a ready-to-send weekly summary: tracked hours, deep work sessions, top 3 domains, focus trend vs the previous week and 2 recommendations. Cached for a week, new events of the user reset the cache
+// @Tags         /api/v1/admin/metrics
+// @Accept       json
+// @Produce      json
+// @Param        user_id     query     string  true   "User ID"
+// @Param        week_start  query     string  false  "Week start date (YYYY-MM-DD, UTC), defaults to Monday of the current week"
+// @Success      200         {object}  entity.WeeklyDigestResponse
+// @Failure      400         {object}  wrapper.ErrorWrapper
+// @Failure      403         {object}  wrapper.ErrorWrapper
+// @Failure      500         {object}  wrapper.ErrorWrapper
+// @Failure      504         {object}  wrapper.ErrorWrapper
+// @Router       /metrics/weekly-digest [get]
+func (h *MetricsHandler) GetWeeklyDigest(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
 		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
 			Message: "user_id is required",
 			Success: false,
@@ -157,83 +1000,129 @@ func (h *MetricsHandler) GetEngagedTime(c *gin.Context) {
 		return
 	}
 
-	startTimeStr := c.Query("start_time")
-	if startTimeStr == "" {
+	weekStart, err := parseWeekStart(c.Query("week_start"))
+	if err != nil {
 		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
-			Message: "start_time is required (RFC3339 format)",
+			Message: err.Error(),
 			Success: false,
 		})
 		return
 	}
 
-	endTimeStr := c.Query("end_time")
-	if endTimeStr == "" {
-		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
-			Message: "end_time is required (RFC3339 format)",
+	filter := entity.WeeklyDigestFilter{
+		UserID:    userID,
+		WeekStart: weekStart,
+	}
+
+	ctx := c.Request.Context()
+	settingsHash := md5.Sum([]byte(h.service.ActiveEventsCacheKey(userID) + "|" + h.service.ExcludedDomainsCacheKey(ctx)))
+	cacheKey := fmt.Sprintf("metrics:weekly_digest:%s:%s:%x", userID, weekStart.Format("2006-01-02"), settingsHash[:4])
+
+	var digest entity.WeeklyDigest
+	// Сводка сравнивает неделю с предыдущей
+	period := redis.MetricCachePeriod{Start: weekStart.AddDate(0, 0, -7), End: weekStart.AddDate(0, 0, 7)}
+	hit, err := h.redisService.GetOrComputeUserMetric(ctx, userID, cacheKey, period, weeklyDigestCacheTTL, &digest, func() (interface{}, error) {
+		return h.service.GetWeeklyDigest(ctx, filter)
+	})
+	if err != nil {
+		c.JSON(serviceErrorStatus(err), wrapper.ErrorWrapper{
+			Message: err.Error(),
 			Success: false,
 		})
 		return
 	}
 
-	startTime, err := time.Parse(time.RFC3339, startTimeStr)
+	if hit {
+		c.Header("X-Cache", "HIT")
+	} else {
+		c.Header("X-Cache", "MISS")
+	}
+
+	c.JSON(http.StatusOK, entity.WeeklyDigestResponse{
+		Data:    &digest,
+		Success: true,
+	})
+}
+
+// GetOrganizationLeaderboard godoc
+// @Summary      Get organization leaderboard
+// @Description  Compare active extension users of the organization by deep work minutes, engagement rate or tracked hours, sorted descending. Requires access to the organization
+// @Tags         /api/v1/admin/organizations
+// @Accept       json
+// @Produce      json
+// @Param        id          path      string  true   "Organization ID"
+// @Param        metric      query     string  false  "Metric to rank by"  Enums(deep_work, engagement_rate, tracked_hours)  default(deep_work)
+// @Param        start_time  query     string  false  "Start time (RFC3339), required unless period is set"
+// @Param        end_time    query     string  false  "End time (RFC3339), required unless period is set"
+// @Param        period      query     string  false  "Period preset, ignored when start_time or end_time is set"  Enums(today, week, month, year)
+// @Param        tz          query     string  false  "IANA timezone for period (default: UTC)"
+// @Success      200         {object}  entity.LeaderboardResponse
+// @Failure      400         {object}  wrapper.ErrorWrapper
+// @Failure      401         {object}  wrapper.ErrorWrapper
+// @Failure      403         {object}  wrapper.ErrorWrapper
+// @Failure      500         {object}  wrapper.ErrorWrapper
+// @Failure      504         {object}  wrapper.ErrorWrapper
+// @Router       /organizations/{id}/metrics/leaderboard [get]
+func (h *MetricsHandler) GetOrganizationLeaderboard(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, wrapper.ErrorWrapper{Message: "User ID not found", Success: false})
+		return
+	}
+
+	userUUID, err := uuid.FromString(userID.(string))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
-			Message: "Invalid start_time format, use RFC3339",
-			Success: false,
-		})
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
 		return
 	}
 
-	endTime, err := time.Parse(time.RFC3339, endTimeStr)
+	orgID, err := uuid.FromString(c.Param("id"))
 	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{Message: "Invalid organization ID", Success: false})
+		return
+	}
+
+	metric := c.DefaultQuery("metric", entity.LeaderboardMetricDeepWork)
+	if !metricsService.IsValidLeaderboardMetric(metric) {
 		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
-			Message: "Invalid end_time format, use RFC3339",
+			Message: "metric must be one of: deep_work, engagement_rate, tracked_hours",
 			Success: false,
 		})
 		return
 	}
 
-	filter.StartTime = startTime
-	filter.EndTime = endTime
-
-	if sessionID := c.Query("session_id"); sessionID != "" {
-		filter.SessionID = &sessionID
+	startTime, endTime, err := utils.ParseTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+		return
 	}
 
-	ctx := c.Request.Context()
-	cacheKey := h.generateEngagedTimeCacheKey(filter)
-
-	var cachedMetric entity.EngagedTimeMetric
-	err = h.redisService.Get(ctx, cacheKey, &cachedMetric)
-	if err == nil {
-		c.Header("X-Cache", "HIT")
-		c.Header("X-Cache-Key", cacheKey) // debug
-		c.JSON(http.StatusOK, entity.EngagedTimeResponse{
-			Data:    &cachedMetric,
-			Success: true,
+	if endTime.Sub(startTime) > h.engagedTimeMaxRange {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: fmt.Sprintf("Time range cannot exceed %d days", int(h.engagedTimeMaxRange.Hours()/24)),
+			Success: false,
 		})
 		return
 	}
 
-	c.Header("X-Cache", "MISS")
-	c.Header("X-Cache-Key", cacheKey) // debug
-
-	metric, err := h.service.GetEngagedTime(ctx, filter)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{
-			Message: err.Error(),
-			Success: false,
-		})
+	if _, err := h.orgAccess.CheckUserAccess(orgID, userUUID); err != nil {
+		c.JSON(http.StatusForbidden, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
 		return
 	}
 
-	cacheErr := h.redisService.Set(ctx, cacheKey, metric, time.Hour)
-	if cacheErr != nil {
-		fmt.Printf("Failed to cache engaged time result: %v\n", cacheErr)
+	leaderboard, err := h.service.GetOrganizationLeaderboard(c.Request.Context(), entity.LeaderboardFilter{
+		OrganizationID: orgID,
+		StartTime:      startTime,
+		EndTime:        endTime,
+		Metric:         metric,
+	})
+	if err != nil {
+		c.JSON(serviceErrorStatus(err), wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+		return
 	}
 
-	c.JSON(http.StatusOK, entity.EngagedTimeResponse{
-		Data:    metric,
+	c.JSON(http.StatusOK, entity.LeaderboardResponse{
+		Data:    leaderboard,
 		Success: true,
 	})
 }
@@ -321,11 +1210,31 @@ func (h *MetricsHandler) GetEngagedTime(c *gin.Context) {
 //	})
 //}
 
-func (h *MetricsHandler) generateTopDomainsCacheKey(filter entity.TopDomainsFilter) string {
-	params := fmt.Sprintf("user_id:%s|limit:%d|session_id:%v",
+func (h *MetricsHandler) generateTopDomainsCacheKey(ctx context.Context, filter entity.TopDomainsFilter) string {
+	sessionID := ""
+	if filter.SessionID != nil {
+		sessionID = *filter.SessionID
+	}
+
+	startTime, endTime := "", ""
+	if filter.StartTime != nil {
+		startTime = filter.StartTime.Format(time.RFC3339)
+	}
+	if filter.EndTime != nil {
+		endTime = filter.EndTime.Format(time.RFC3339)
+	}
+
+	params := fmt.Sprintf("user_id:%s|limit:%d|session_id:%s|event_types:%s|page:%d|per_page:%d|categorize:%t|start_time:%s|end_time:%s|excluded_domains:%s",
 		filter.UserID,
 		filter.Limit,
-		filter.SessionID,
+		sessionID,
+		strings.Join(filter.EventTypes, ","),
+		filter.Page,
+		filter.PerPage,
+		filter.Categorize,
+		startTime,
+		endTime,
+		h.service.ExcludedDomainsCacheKey(ctx),
 	)
 
 	hash := md5.Sum([]byte(params))
@@ -356,15 +1265,75 @@ func (h *MetricsHandler) GetTopDomains(c *gin.Context) {
 		filter.Limit = limit
 	}
 
+	if pageStr := c.Query("page"); pageStr != "" {
+		page, err := strconv.Atoi(pageStr)
+		if err != nil || page <= 0 {
+			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+				Message: "page must be a positive integer",
+				Success: false,
+			})
+			return
+		}
+		filter.Page = page
+		filter.PerPage = 20
+	}
+
+	if perPageStr := c.Query("per_page"); perPageStr != "" {
+		perPage, err := strconv.Atoi(perPageStr)
+		if err != nil || perPage <= 0 || perPage > 100 {
+			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+				Message: "per_page must be an integer between 1 and 100",
+				Success: false,
+			})
+			return
+		}
+		filter.PerPage = perPage
+		if filter.Page == 0 {
+			filter.Page = 1
+		}
+	}
+
 	if sessionID := c.Query("session_id"); sessionID != "" {
 		filter.SessionID = &sessionID
 	}
 
+	if categorizeStr := c.Query("categorize"); categorizeStr != "" {
+		categorize, err := strconv.ParseBool(categorizeStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+				Message: "categorize must be a boolean",
+				Success: false,
+			})
+			return
+		}
+		filter.Categorize = categorize
+	}
+
+	if eventTypes := c.Query("event_types"); eventTypes != "" {
+		for _, eventType := range strings.Split(eventTypes, ",") {
+			if eventType = strings.TrimSpace(eventType); eventType != "" {
+				filter.EventTypes = append(filter.EventTypes, eventType)
+			}
+		}
+	}
+
+	// Период необязателен: без start_time/end_time/period топ считается за все время
+	startTime, endTime, err := utils.ParseOptionalTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+	filter.StartTime = startTime
+	filter.EndTime = endTime
+
 	ctx := c.Request.Context()
-	cacheKey := h.generateTopDomainsCacheKey(filter)
+	cacheKey := h.generateTopDomainsCacheKey(ctx, filter)
 
 	var cachedResult entity.TopDomainsResponse
-	err := h.redisService.Get(ctx, cacheKey, &cachedResult)
+	err = h.redisService.Get(ctx, cacheKey, &cachedResult)
 	if err == nil {
 		c.Header("X-Cache", "HIT")
 		c.Header("X-Cache-Key", cacheKey)
@@ -380,15 +1349,31 @@ func (h *MetricsHandler) GetTopDomains(c *gin.Context) {
 
 	result, err := h.service.GetTopDomains(c.Request.Context(), filter)
 	if err != nil {
+		if errors.Is(err, userBehaviorService.ErrInvalidEventType) {
+			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+				Message: err.Error(),
+				Success: false,
+			})
+			return
+		}
+
 		fmt.Println("Failed to get top domains", "error", err)
-		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{
+		c.JSON(serviceErrorStatus(err), wrapper.ErrorWrapper{
 			Message: "Failed to retrieve top domains",
 			Success: false,
 		})
 		return
 	}
 
-	cacheErr := h.redisService.Set(ctx, cacheKey, result, 30*time.Minute)
+	// Без start_time/end_time топ считается за все время: нулевая граница периода не ограничена
+	var period redis.MetricCachePeriod
+	if filter.StartTime != nil {
+		period.Start = *filter.StartTime
+	}
+	if filter.EndTime != nil {
+		period.End = *filter.EndTime
+	}
+	cacheErr := h.redisService.SetUserMetricCache(ctx, filter.UserID, cacheKey, period, result, 30*time.Minute)
 	if cacheErr != nil {
 		fmt.Printf("Failed to cache top domains result: %v\n", cacheErr)
 	}
@@ -399,85 +1384,178 @@ func (h *MetricsHandler) GetTopDomains(c *gin.Context) {
 	})
 }
 
-func (h *MetricsHandler) GetDeepWorkSessions(c *gin.Context) {
+// parseDeepWorkSessionsFilter читает общие параметры deep work запросов:
+// user_id, start_time, end_time, session_id и пороги блоков
+func parseDeepWorkSessionsFilter(c *gin.Context) (entity.DeepWorkSessionsFilter, error) {
+	var filter entity.DeepWorkSessionsFilter
+
 	userID := c.Query("user_id")
-	startTimeStr := c.Query("start_time")
-	endTimeStr := c.Query("end_time")
 	sessionID := c.Query("session_id")
 
 	if userID == "" {
+		return filter, fmt.Errorf("user_id is required")
+	}
+
+	startTime, endTime, err := utils.ParseTimeRange(c)
+	if err != nil {
+		return filter, err
+	}
+
+	if endTime.Sub(startTime) > 30*24*time.Hour {
+		return filter, fmt.Errorf("Time range cannot exceed 30 days")
+	}
+
+	minDuration, gapThreshold, minEvents, err := parseDeepWorkThresholds(c)
+	if err != nil {
+		return filter, err
+	}
+
+	filter = entity.DeepWorkSessionsFilter{
+		UserID:              userID,
+		StartTime:           startTime,
+		EndTime:             endTime,
+		MinDurationMinutes:  minDuration,
+		GapThresholdSeconds: gapThreshold,
+		MinEventsPerBlock:   minEvents,
+	}
+
+	if sessionID != "" {
+		filter.SessionID = &sessionID
+	}
+
+	return filter, nil
+}
+
+func (h *MetricsHandler) GetDeepWorkSessions(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+
+	if format != "json" && format != "csv" {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"message": "user_id is required",
+			"message": "format must be one of: json, csv",
 		})
 		return
 	}
 
-	if startTimeStr == "" {
+	filter, err := parseDeepWorkSessionsFilter(c)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"message": "start_time is required",
+			"message": err.Error(),
 		})
 		return
 	}
 
-	if endTimeStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
+	// limit выше максимума из конфига обрезается сервисом
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "limit must be a positive integer",
+			})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "offset must be a non-negative integer",
+			})
+			return
+		}
+		filter.Offset = offset
+	}
+
+	result, err := h.service.GetDeepWorkSessions(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(serviceErrorStatus(err), gin.H{
 			"success": false,
-			"message": "end_time is required",
+			"message": "Failed to get deep work sessions",
+			"error":   err.Error(),
 		})
 		return
 	}
 
-	startTime, err := time.Parse(time.RFC3339, startTimeStr)
+	if format == "csv" {
+		h.writeDeepWorkSessionsCSV(c, result)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// GetDeepWorkBySession возвращает deep work блоки, сгруппированные по session_id.
+// Принимает те же параметры, что и /metrics/deep-work-sessions; блок, захвативший несколько сессий,
+// относится к сессии своего первого события
+func (h *MetricsHandler) GetDeepWorkBySession(c *gin.Context) {
+	filter, err := parseDeepWorkSessionsFilter(c)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"message": "Invalid start_time format, use RFC3339 (e.g., 2025-07-10T08:00:00Z)",
+			"message": err.Error(),
 		})
 		return
 	}
 
-	endTime, err := time.Parse(time.RFC3339, endTimeStr)
+	result, err := h.service.GetDeepWorkBySession(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
+		c.JSON(serviceErrorStatus(err), gin.H{
 			"success": false,
-			"message": "Invalid end_time format, use RFC3339 (e.g., 2025-07-11T19:59:59Z)",
+			"message": "Failed to get deep work by session",
+			"error":   err.Error(),
 		})
 		return
 	}
 
-	if endTime.Before(startTime) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// GetDeepWorkBlockEvents возвращает сырые события одного deep work блока.
+// block_id берется из ответа /metrics/deep-work-sessions и воспроизводим только при тех же
+// user_id, start_time, end_time, session_id и порогах: блоки нумеруются заново на каждый запрос.
+func (h *MetricsHandler) GetDeepWorkBlockEvents(c *gin.Context) {
+	blockID, err := strconv.Atoi(c.Param("blockId"))
+	if err != nil || blockID < 1 {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"message": "end_time must be after start_time",
+			"message": "blockId must be a positive integer",
 		})
 		return
 	}
 
-	if endTime.Sub(startTime) > 30*24*time.Hour {
+	filter, err := parseDeepWorkSessionsFilter(c)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"message": "Time range cannot exceed 30 days",
+			"message": err.Error(),
 		})
 		return
 	}
 
-	filter := entity.DeepWorkSessionsFilter{
-		UserID:    userID,
-		StartTime: startTime,
-		EndTime:   endTime,
-	}
-
-	if sessionID != "" {
-		filter.SessionID = &sessionID
-	}
-
-	result, err := h.service.GetDeepWorkSessions(c.Request.Context(), filter)
+	result, err := h.service.GetDeepWorkBlockEvents(c.Request.Context(), filter, blockID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		if err.Error() == "deep work block not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(serviceErrorStatus(err), gin.H{
 			"success": false,
-			"message": "Failed to get deep work sessions",
+			"message": "Failed to get deep work block events",
 			"error":   err.Error(),
 		})
 		return
@@ -489,6 +1567,90 @@ func (h *MetricsHandler) GetDeepWorkSessions(c *gin.Context) {
 	})
 }
 
+// writeDeepWorkSessionsCSV отдает список сессий в виде CSV-файла
+func (h *MetricsHandler) writeDeepWorkSessionsCSV(c *gin.Context, result *entity.DeepWorkSessionsResponse) {
+	filename := fmt.Sprintf("deep_work_sessions_%s_%s_%s.csv",
+		result.UserID,
+		result.StartTime.Format("20060102"),
+		result.EndTime.Format("20060102"),
+	)
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+
+	// Заголовок пишем всегда, даже если сессий нет
+	_ = writer.Write([]string{
+		"block_id", "start_time", "end_time", "duration_minutes",
+		"total_events", "context_switches", "switches_per_hour", "focus_level",
+	})
+
+	for _, session := range result.Sessions {
+		_ = writer.Write([]string{
+			strconv.Itoa(session.BlockID),
+			session.StartTime.Format(time.RFC3339),
+			session.EndTime.Format(time.RFC3339),
+			strconv.FormatFloat(session.DurationMinutes, 'f', 2, 64),
+			strconv.Itoa(session.TotalEvents),
+			strconv.Itoa(session.ContextSwitches),
+			strconv.FormatFloat(session.SwitchesPerHour, 'f', 2, 64),
+			session.FocusLevel,
+		})
+	}
+
+	writer.Flush()
+}
+
+// ClearUserMetricsCache godoc
+// @Summary      Clear user's cached metrics
+// @Description  Delete all cached metrics (engaged time, activity heatmap, top domains) of an extension user, e.g. after a backfill or data correction. Returns the number of invalidated keys (super admin only)
+// @Tags         /api/v1/admin/metrics
+// @Accept       json
+// @Produce      json
+// @Param        user_id  query     string  true  "User ID"
+// @Success      200      {object}  wrapper.ResponseWrapper{data=entity.MetricsCacheInvalidation}
+// @Failure      400      {object}  wrapper.ErrorWrapper
+// @Failure      403      {object}  wrapper.ErrorWrapper
+// @Failure      500      {object}  wrapper.ErrorWrapper
+// @Router       /metrics/cache [delete]
+func (h *MetricsHandler) ClearUserMetricsCache(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "user_id is required",
+			Success: false,
+		})
+		return
+	}
+
+	if _, err := uuid.FromString(userID); err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "Invalid user_id format",
+			Success: false,
+		})
+		return
+	}
+
+	invalidated, err := h.redisService.InvalidateUserMetricCache(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{
+			Message: "Failed to clear metrics cache: " + err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapper.ResponseWrapper{
+		Data: entity.MetricsCacheInvalidation{
+			UserID:          userID,
+			InvalidatedKeys: invalidated,
+		},
+		Success: true,
+	})
+}
+
 func (h *MetricsHandler) RegisterRoutes(router *gin.RouterGroup) {
 	metrics := router.Group("/metrics")
 	{
@@ -498,5 +1660,16 @@ func (h *MetricsHandler) RegisterRoutes(router *gin.RouterGroup) {
 		//metrics.GET("/ai-analytics-data", h.PrepareAIAnalyticsData) // Новый эндпоинт
 		metrics.GET("/top-domains", h.GetTopDomains)
 		metrics.GET("/deep-work-sessions", h.GetDeepWorkSessions)
+		metrics.GET("/deep-work-sessions/:blockId/events", h.GetDeepWorkBlockEvents)
+		metrics.GET("/deep-work-by-session", h.GetDeepWorkBySession)
+		metrics.GET("/activity-heatmap", h.GetActivityHeatmap)
+		metrics.GET("/session-engagement", h.GetSessionEngagement)
+		metrics.GET("/engaged-time-daily", h.GetEngagedTimeDaily)
+		metrics.GET("/typing-activity", h.GetTypingActivity)
+		metrics.GET("/scroll-activity", h.GetScrollActivity)
+		metrics.GET("/tab-switches", h.GetTabSwitchStats)
+		metrics.GET("/weekly-digest", h.GetWeeklyDigest)
+		metrics.GET("/minute-activity", h.GetMinuteActivity)
+		metrics.GET("/click-heatmap", h.GetClickHeatmap)
 	}
 }
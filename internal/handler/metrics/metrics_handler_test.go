@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Диапазон отклоняется до обращения к сервису и кешу, поэтому они не нужны
+func newRangeTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	h := NewMetricsHandler(nil, nil, time.Hour, 90*24*time.Hour, 24*time.Hour, nil)
+
+	router := gin.New()
+	router.GET("/metrics/engaged-time", h.GetEngagedTime)
+	router.GET("/metrics/deep-work-sessions", h.GetDeepWorkSessions)
+	router.GET("/metrics/activity-heatmap", h.GetActivityHeatmap)
+	return router
+}
+
+func TestMetricsTimeRangeValidation(t *testing.T) {
+	start := time.Date(2025, 7, 10, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name    string
+		path    string
+		end     time.Time
+		message string
+	}{
+		{name: "engaged time reversed", path: "/metrics/engaged-time", end: start.Add(-time.Hour), message: "end_time must be after start_time"},
+		{name: "engaged time too long", path: "/metrics/engaged-time", end: start.Add(91 * 24 * time.Hour), message: "Time range cannot exceed 90 days"},
+		{name: "deep work reversed", path: "/metrics/deep-work-sessions", end: start.Add(-time.Hour), message: "end_time must be after start_time"},
+		{name: "activity heatmap too long", path: "/metrics/activity-heatmap", end: start.Add(91 * 24 * time.Hour), message: "Time range cannot exceed 90 days"},
+		{name: "deep work too long", path: "/metrics/deep-work-sessions", end: start.Add(31 * 24 * time.Hour), message: "Time range cannot exceed 30 days"},
+	}
+
+	router := newRangeTestRouter(t)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			query := url.Values{
+				"user_id":    {"39b962b6-d4fa-49a6-8f3e-e4ff9b6bb0df"},
+				"start_time": {start.Format(time.RFC3339)},
+				"end_time":   {tc.end.Format(time.RFC3339)},
+			}
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path+"?"+query.Encode(), nil))
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d: %s", recorder.Code, recorder.Body.String())
+			}
+
+			var body struct {
+				Message string `json:"message"`
+				Success bool   `json:"success"`
+			}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode response %q: %v", recorder.Body.String(), err)
+			}
+			if body.Success || body.Message != tc.message {
+				t.Errorf("expected error %q, got %+v", tc.message, body)
+			}
+		})
+	}
+}
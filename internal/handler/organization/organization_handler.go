@@ -1,12 +1,16 @@
 package organization
 
 import (
+	"errors"
 	"github.com/dinerozz/web-behavior-backend/internal/model/request"
 	"github.com/dinerozz/web-behavior-backend/internal/model/response/wrapper"
+	"github.com/dinerozz/web-behavior-backend/internal/repository"
 	"github.com/dinerozz/web-behavior-backend/internal/service/organization"
 	"github.com/gin-gonic/gin"
 	"github.com/gofrs/uuid"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type OrganizationHandler struct {
@@ -231,16 +235,18 @@ func (h *OrganizationHandler) UpdateOrganization(c *gin.Context) {
 
 // DeleteOrganization godoc
 // @Summary Delete organization
-// @Description Delete organization (admin only)
+// @Description Delete organization (admin only). Fails with 409 while the organization has other members or extension users unless force=true; with force extension users of the organization are deleted and their behaviors are soft-deleted (kept until the retention purge)
 // @Tags /api/v1/admin/organizations
 // @Accept json
 // @Produce json
 // @Param id path string true "Organization ID"
+// @Param force query bool false "Delete even if the organization has members or extension users"
 // @Success 200 {object} wrapper.SuccessWrapper
 // @Failure 400 {object} wrapper.ErrorWrapper
 // @Failure 401 {object} wrapper.ErrorWrapper
 // @Failure 403 {object} wrapper.ErrorWrapper
 // @Failure 404 {object} wrapper.ErrorWrapper
+// @Failure 409 {object} wrapper.ErrorWrapper
 // @Failure 500 {object} wrapper.ErrorWrapper
 // @Router /organizations/{id} [delete]
 func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
@@ -263,12 +269,22 @@ func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
 		return
 	}
 
-	err = h.srv.DeleteOrganization(orgID, userUUID)
+	force := c.Query("force") == "true"
+
+	err = h.srv.DeleteOrganization(orgID, userUUID, force)
 	if err != nil {
 		if err.Error() == "only admins can delete organization" {
 			c.JSON(http.StatusForbidden, wrapper.ErrorWrapper{Message: "Admin access required", Success: false})
 			return
 		}
+		if err.Error() == "organization not found" {
+			c.JSON(http.StatusNotFound, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "organization still has") {
+			c.JSON(http.StatusConflict, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
 		return
 	}
@@ -494,3 +510,185 @@ func (h *OrganizationHandler) UpdateUserRole(c *gin.Context) {
 
 	c.JSON(http.StatusOK, wrapper.SuccessWrapper{Message: "User role updated successfully", Success: true})
 }
+
+// GetAuditLog godoc
+// @Summary Get organization audit log
+// @Description Paginated log of membership changes (member added/removed, role changed, invite accepted), newest first (admin only)
+// @Tags /api/v1/admin/organizations
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page"
+// @Success 200 {object} wrapper.PaginatedResponseWrapper{data=[]response.OrgAuditEntry}
+// @Failure 400 {object} wrapper.ErrorWrapper
+// @Failure 401 {object} wrapper.ErrorWrapper
+// @Failure 403 {object} wrapper.ErrorWrapper
+// @Failure 500 {object} wrapper.ErrorWrapper
+// @Router /organizations/{id}/audit [get]
+func (h *OrganizationHandler) GetAuditLog(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, wrapper.ErrorWrapper{Message: "User ID not found", Success: false})
+		return
+	}
+
+	userUUID, err := uuid.FromString(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+		return
+	}
+
+	orgID, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{Message: "Invalid organization ID", Success: false})
+		return
+	}
+
+	page := 1
+	if pageStr := c.Query("page"); pageStr != "" {
+		page, err = strconv.Atoi(pageStr)
+		if err != nil || page < 1 {
+			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{Message: "Invalid page value", Success: false})
+			return
+		}
+	}
+
+	perPage := 0
+	if perPageStr := c.Query("per_page"); perPageStr != "" {
+		perPage, err = strconv.Atoi(perPageStr)
+		if err != nil || perPage < 1 {
+			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{Message: "Invalid per_page value", Success: false})
+			return
+		}
+	}
+
+	entries, paginationInfo, err := h.srv.GetAuditLog(orgID, userUUID, page, perPage)
+	if err != nil {
+		if err.Error() == "only admins can view audit log" {
+			c.JSON(http.StatusForbidden, wrapper.ErrorWrapper{Message: "Admin access required", Success: false})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapper.PaginatedResponseWrapper{Data: entries, Meta: *paginationInfo, Success: true})
+}
+
+// GetActiveEvents godoc
+// @Summary Get organization active events
+// @Description Get event types counted as "active" for engaged time metrics of organization users
+// @Tags /api/v1/admin/organizations
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Success 200 {object} wrapper.ResponseWrapper{data=response.OrganizationActiveEvents}
+// @Failure 400 {object} wrapper.ErrorWrapper
+// @Failure 401 {object} wrapper.ErrorWrapper
+// @Failure 403 {object} wrapper.ErrorWrapper
+// @Failure 404 {object} wrapper.ErrorWrapper
+// @Failure 500 {object} wrapper.ErrorWrapper
+// @Router /organizations/{id}/active-events [get]
+func (h *OrganizationHandler) GetActiveEvents(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, wrapper.ErrorWrapper{Message: "User ID not found", Success: false})
+		return
+	}
+
+	userUUID, err := uuid.FromString(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+		return
+	}
+
+	orgIDStr := c.Param("id")
+	orgID, err := uuid.FromString(orgIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{Message: "Invalid organization ID", Success: false})
+		return
+	}
+
+	activeEvents, err := h.srv.GetActiveEvents(orgID, userUUID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNoOrganizationAccess) {
+			c.JSON(http.StatusForbidden, wrapper.ErrorWrapper{Message: "Access denied", Success: false})
+			return
+		}
+		if errors.Is(err, repository.ErrOrganizationNotFound) {
+			c.JSON(http.StatusNotFound, wrapper.ErrorWrapper{Message: "Organization not found", Success: false})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapper.ResponseWrapper{Data: activeEvents, Success: true})
+}
+
+// UpdateActiveEvents godoc
+// @Summary Update organization active events
+// @Description Set event types counted as "active" for organization users (admin only). Empty list resets to default
+// @Tags /api/v1/admin/organizations
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param request body request.UpdateOrganizationActiveEvents true "Active events"
+// @Success 200 {object} wrapper.ResponseWrapper{data=response.OrganizationActiveEvents}
+// @Failure 400 {object} wrapper.ErrorWrapper
+// @Failure 401 {object} wrapper.ErrorWrapper
+// @Failure 403 {object} wrapper.ErrorWrapper
+// @Failure 404 {object} wrapper.ErrorWrapper
+// @Failure 500 {object} wrapper.ErrorWrapper
+// @Router /organizations/{id}/active-events [put]
+func (h *OrganizationHandler) UpdateActiveEvents(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, wrapper.ErrorWrapper{Message: "User ID not found", Success: false})
+		return
+	}
+
+	userUUID, err := uuid.FromString(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+		return
+	}
+
+	orgIDStr := c.Param("id")
+	orgID, err := uuid.FromString(orgIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{Message: "Invalid organization ID", Success: false})
+		return
+	}
+
+	var updateRequest request.UpdateOrganizationActiveEvents
+	if err := c.ShouldBindJSON(&updateRequest); err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+		return
+	}
+
+	activeEvents, err := h.srv.UpdateActiveEvents(orgID, &updateRequest, userUUID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNoOrganizationAccess) {
+			c.JSON(http.StatusForbidden, wrapper.ErrorWrapper{Message: "Access denied", Success: false})
+			return
+		}
+		if errors.Is(err, organization.ErrActiveEventsAdminOnly) {
+			c.JSON(http.StatusForbidden, wrapper.ErrorWrapper{Message: "Admin access required", Success: false})
+			return
+		}
+		if errors.Is(err, organization.ErrInvalidActiveEventType) {
+			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+			return
+		}
+		if errors.Is(err, repository.ErrOrganizationNotFound) {
+			c.JSON(http.StatusNotFound, wrapper.ErrorWrapper{Message: "Organization not found", Success: false})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapper.ResponseWrapper{Data: activeEvents, Success: true})
+}
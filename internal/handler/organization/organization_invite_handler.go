@@ -0,0 +1,209 @@
+package organization
+
+import (
+	"net/http"
+
+	"github.com/dinerozz/web-behavior-backend/internal/model/request"
+	"github.com/dinerozz/web-behavior-backend/internal/model/response/wrapper"
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// inviteErrorStatus сопоставляет ошибки приглашений с HTTP статусами
+func inviteErrorStatus(err error) int {
+	switch err.Error() {
+	case "only admins can manage invites", "invite was issued to another user":
+		return http.StatusForbidden
+	case "invite not found":
+		return http.StatusNotFound
+	case "invite already pending for this email":
+		return http.StatusConflict
+	case "invite has expired", "invite is no longer valid":
+		return http.StatusGone
+	case "user is already in this organization", "username is required", "invalid role: must be 'admin', 'member', or 'viewer'":
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// CreateInvite godoc
+// @Summary Invite user to organization by email
+// @Description Create a pending invite (admin only) bound to the invitee's username. The token is returned only once and must be delivered to the invitee; invites expire after 7 days
+// @Tags /api/v1/admin/organizations
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param invite body request.CreateOrganizationInvite true "Invite"
+// @Success 201 {object} wrapper.ResponseWrapper{data=response.OrganizationInvite}
+// @Failure 400 {object} wrapper.ErrorWrapper
+// @Failure 401 {object} wrapper.ErrorWrapper
+// @Failure 403 {object} wrapper.ErrorWrapper
+// @Failure 409 {object} wrapper.ErrorWrapper
+// @Failure 500 {object} wrapper.ErrorWrapper
+// @Router /organizations/{id}/invites [post]
+func (h *OrganizationHandler) CreateInvite(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, wrapper.ErrorWrapper{Message: "User ID not found", Success: false})
+		return
+	}
+
+	userUUID, err := uuid.FromString(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+		return
+	}
+
+	orgID, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{Message: "Invalid organization ID", Success: false})
+		return
+	}
+
+	var inviteRequest request.CreateOrganizationInvite
+	if err := c.ShouldBindJSON(&inviteRequest); err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+		return
+	}
+
+	invite, err := h.srv.CreateInvite(orgID, &inviteRequest, userUUID)
+	if err != nil {
+		c.JSON(inviteErrorStatus(err), wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+		return
+	}
+
+	c.JSON(http.StatusCreated, wrapper.ResponseWrapper{Data: invite, Success: true})
+}
+
+// GetPendingInvites godoc
+// @Summary List pending organization invites
+// @Description List invites that are not accepted, revoked or expired (admin only)
+// @Tags /api/v1/admin/organizations
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Success 200 {object} wrapper.ResponseWrapper{data=[]response.OrganizationInvite}
+// @Failure 400 {object} wrapper.ErrorWrapper
+// @Failure 401 {object} wrapper.ErrorWrapper
+// @Failure 403 {object} wrapper.ErrorWrapper
+// @Failure 500 {object} wrapper.ErrorWrapper
+// @Router /organizations/{id}/invites [get]
+func (h *OrganizationHandler) GetPendingInvites(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, wrapper.ErrorWrapper{Message: "User ID not found", Success: false})
+		return
+	}
+
+	userUUID, err := uuid.FromString(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+		return
+	}
+
+	orgID, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{Message: "Invalid organization ID", Success: false})
+		return
+	}
+
+	invites, err := h.srv.GetPendingInvites(orgID, userUUID)
+	if err != nil {
+		c.JSON(inviteErrorStatus(err), wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapper.ResponseWrapper{Data: invites, Success: true})
+}
+
+// RevokeInvite godoc
+// @Summary Revoke organization invite
+// @Description Revoke a pending invite (admin only)
+// @Tags /api/v1/admin/organizations
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param invite_id path string true "Invite ID"
+// @Success 200 {object} wrapper.SuccessWrapper
+// @Failure 400 {object} wrapper.ErrorWrapper
+// @Failure 401 {object} wrapper.ErrorWrapper
+// @Failure 403 {object} wrapper.ErrorWrapper
+// @Failure 404 {object} wrapper.ErrorWrapper
+// @Failure 500 {object} wrapper.ErrorWrapper
+// @Router /organizations/{id}/invites/{invite_id} [delete]
+func (h *OrganizationHandler) RevokeInvite(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, wrapper.ErrorWrapper{Message: "User ID not found", Success: false})
+		return
+	}
+
+	userUUID, err := uuid.FromString(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+		return
+	}
+
+	orgID, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{Message: "Invalid organization ID", Success: false})
+		return
+	}
+
+	inviteID, err := uuid.FromString(c.Param("invite_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{Message: "Invalid invite ID", Success: false})
+		return
+	}
+
+	if err := h.srv.RevokeInvite(orgID, inviteID, userUUID); err != nil {
+		c.JSON(inviteErrorStatus(err), wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapper.SuccessWrapper{Message: "Invite revoked successfully", Success: true})
+}
+
+// AcceptInvite godoc
+// @Summary Accept organization invite
+// @Description Join the organization from the invite token as the authenticated user. Only the user whose username the invite was issued to can accept it (403 otherwise)
+// @Tags /api/v1/admin/organizations
+// @Accept json
+// @Produce json
+// @Param invite body request.AcceptOrganizationInvite true "Invite token"
+// @Success 200 {object} wrapper.ResponseWrapper{data=response.OrganizationInvite}
+// @Failure 400 {object} wrapper.ErrorWrapper
+// @Failure 401 {object} wrapper.ErrorWrapper
+// @Failure 403 {object} wrapper.ErrorWrapper
+// @Failure 404 {object} wrapper.ErrorWrapper
+// @Failure 410 {object} wrapper.ErrorWrapper
+// @Failure 500 {object} wrapper.ErrorWrapper
+// @Router /organizations/invites/accept [post]
+func (h *OrganizationHandler) AcceptInvite(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, wrapper.ErrorWrapper{Message: "User ID not found", Success: false})
+		return
+	}
+
+	userUUID, err := uuid.FromString(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+		return
+	}
+
+	var acceptRequest request.AcceptOrganizationInvite
+	if err := c.ShouldBindJSON(&acceptRequest); err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+		return
+	}
+
+	invite, err := h.srv.AcceptInvite(&acceptRequest, userUUID)
+	if err != nil {
+		c.JSON(inviteErrorStatus(err), wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapper.ResponseWrapper{Data: invite, Success: true})
+}
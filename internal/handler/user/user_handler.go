@@ -1,8 +1,12 @@
 package user
 
 import (
+	"errors"
+	"fmt"
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
 	"github.com/dinerozz/web-behavior-backend/internal/model/request"
 	"github.com/dinerozz/web-behavior-backend/internal/model/response/wrapper"
+	"github.com/dinerozz/web-behavior-backend/internal/repository"
 	"github.com/dinerozz/web-behavior-backend/internal/service/organization"
 	"github.com/dinerozz/web-behavior-backend/internal/service/user"
 	"github.com/dinerozz/web-behavior-backend/pkg/utils"
@@ -10,6 +14,8 @@ import (
 	"github.com/gofrs/uuid"
 	"golang.org/x/crypto/bcrypt"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type UserHandler struct {
@@ -114,7 +120,68 @@ func (h *UserHandler) AuthenticateUserWithPassword(c *gin.Context) {
 		return
 	}
 
-	c.SetCookie("token", token, 3600*24, "/", "", false, true)
+	refreshToken, err := h.srv.IssueRefreshToken(c.Request.Context(), existingUser.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{Message: "Failed to generate refresh token", Success: false})
+		return
+	}
+
+	setAuthCookies(c, token, refreshToken)
+	c.JSON(http.StatusOK, wrapper.ResponseWrapper{Data: token, Success: true})
+}
+
+// Refresh токен отправляется браузером только на маршрут обновления (и его DELETE для logout),
+// а не с каждым запросом к API
+const refreshTokenCookiePath = "/api/v1/admin/users/refresh"
+
+// Cookie access токена живет столько же, сколько refresh токен, чтобы middleware
+// могло отличить просроченный токен от отсутствующего
+func setAuthCookies(c *gin.Context, token, refreshToken string) {
+	c.SetCookie("token", token, int(utils.RefreshTokenTTL.Seconds()), "/", "", false, true)
+	c.SetCookie("refresh_token", refreshToken, int(utils.RefreshTokenTTL.Seconds()), refreshTokenCookiePath, "", false, true)
+	// refresh cookie старых сессий с путем "/" больше не нужен
+	c.SetCookie("refresh_token", "", -1, "/", "", false, true)
+}
+
+func clearAuthCookies(c *gin.Context) {
+	c.SetCookie("token", "", -1, "/", "", false, true)
+	c.SetCookie("refresh_token", "", -1, refreshTokenCookiePath, "", false, true)
+	c.SetCookie("refresh_token", "", -1, "/", "", false, true)
+}
+
+// RefreshToken godoc
+// @Summary Refresh access token
+// @Description Issue a new access token using the refresh_token cookie. The refresh token is rotated on each use
+// @Tags /api/v1/admin/users
+// @Accept json
+// @Produce json
+// @Success 200 {object} wrapper.ResponseWrapper{data=string}
+// @Failure 401 {object} wrapper.ErrorWrapper
+// @Failure 500 {object} wrapper.ErrorWrapper
+// @Failure 503 {object} wrapper.ErrorWrapper
+// @Router /admin/users/refresh [post]
+func (h *UserHandler) RefreshToken(c *gin.Context) {
+	refreshToken, err := c.Cookie("refresh_token")
+	if err != nil || refreshToken == "" {
+		c.JSON(http.StatusUnauthorized, wrapper.ErrorWrapper{Message: "Missing refresh token", Success: false})
+		return
+	}
+
+	token, newRefreshToken, err := h.srv.RotateRefreshToken(c.Request.Context(), refreshToken)
+	if err != nil {
+		if errors.Is(err, user.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusUnauthorized, wrapper.ErrorWrapper{Message: "Invalid or expired refresh token", Success: false})
+			return
+		}
+		if errors.Is(err, user.ErrRefreshUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, wrapper.ErrorWrapper{Message: "Cannot refresh session right now, retry later", Success: false})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+		return
+	}
+
+	setAuthCookies(c, token, newRefreshToken)
 	c.JSON(http.StatusOK, wrapper.ResponseWrapper{Data: token, Success: true})
 }
 
@@ -195,35 +262,131 @@ func (h *UserHandler) GetUserWithOrganizations(c *gin.Context) {
 
 // GetAllUsers godoc
 // @Summary Get all users
-// @Description Get list of all users (Super admin only)
+// @Description Paginated list of users, newest first, with optional username search and super admin filter (Super admin only)
 // @Tags /api/v1/admin/users
 // @Accept json
 // @Produce json
-// @Success 200 {object} wrapper.ResponseWrapper{data=[]response.User}
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page"
+// @Param search query string false "Username substring (case-insensitive)"
+// @Param is_super_admin query bool false "Filter by super admin flag"
+// @Success 200 {object} wrapper.PaginatedResponseWrapper{data=[]response.User}
+// @Failure 400 {object} wrapper.ErrorWrapper
 // @Failure 401 {object} wrapper.ErrorWrapper
 // @Failure 403 {object} wrapper.ErrorWrapper
 // @Failure 500 {object} wrapper.ErrorWrapper
 // @Router /admin/users [get]
 func (h *UserHandler) GetAllUsers(c *gin.Context) {
-	users, err := h.srv.GetAllUsers()
+	var err error
+
+	page := 1
+	if pageStr := c.Query("page"); pageStr != "" {
+		page, err = strconv.Atoi(pageStr)
+		if err != nil || page < 1 {
+			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{Message: "Invalid page value", Success: false})
+			return
+		}
+	}
+
+	perPage := 0
+	if perPageStr := c.Query("per_page"); perPageStr != "" {
+		perPage, err = strconv.Atoi(perPageStr)
+		if err != nil || perPage < 1 {
+			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{Message: "Invalid per_page value", Success: false})
+			return
+		}
+	}
+
+	filter := entity.UserFilter{Search: strings.TrimSpace(c.Query("search"))}
+
+	if isSuperAdminStr := c.Query("is_super_admin"); isSuperAdminStr != "" {
+		isSuperAdmin, err := strconv.ParseBool(isSuperAdminStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{Message: "Invalid is_super_admin value", Success: false})
+			return
+		}
+		filter.IsSuperAdmin = &isSuperAdmin
+	}
+
+	users, paginationInfo, err := h.srv.GetAllUsers(filter, page, perPage)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapper.PaginatedResponseWrapper{Data: users, Meta: *paginationInfo, Success: true})
+}
+
+// SetSuperAdmin godoc
+// @Summary Promote or demote super admin
+// @Description Set is_super_admin for a user. The last remaining super admin cannot be demoted (Super admin only)
+// @Tags /api/v1/admin/users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body request.UpdateSuperAdmin true "Super admin flag"
+// @Success 200 {object} wrapper.SuccessWrapper{message=string}
+// @Failure 400 {object} wrapper.ErrorWrapper
+// @Failure 401 {object} wrapper.ErrorWrapper
+// @Failure 403 {object} wrapper.ErrorWrapper
+// @Failure 404 {object} wrapper.ErrorWrapper
+// @Failure 500 {object} wrapper.ErrorWrapper
+// @Router /admin/users/{id}/super-admin [put]
+func (h *UserHandler) SetSuperAdmin(c *gin.Context) {
+	userToUpdateID, err := uuid.FromString(c.Param("id"))
 	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{Message: "Invalid user ID", Success: false})
+		return
+	}
+
+	var req request.UpdateSuperAdmin
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{Message: "Invalid request body: " + err.Error(), Success: false})
+		return
+	}
+
+	err = h.srv.SetSuperAdmin(userToUpdateID, *req.IsSuperAdmin)
+	if err != nil {
+		if errors.Is(err, repository.ErrLastSuperAdmin) {
+			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{Message: "Cannot demote the only super admin", Success: false})
+			return
+		}
+		if errors.Is(err, repository.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, wrapper.ErrorWrapper{Message: "User not found", Success: false})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
 		return
 	}
 
-	c.JSON(http.StatusOK, wrapper.ResponseWrapper{Data: users, Success: true})
+	c.JSON(http.StatusOK, wrapper.SuccessWrapper{Message: "Super admin status updated successfully", Success: true})
 }
 
 // Logout godoc
 // @Summary Logout user
-// @Description Logout user by clearing authentication cookie
+// @Description Logout user by revoking the refresh token and clearing authentication cookies. The refresh_token cookie is scoped to /api/v1/admin/users/refresh, so browsers send it only to DELETE /admin/users/refresh. Returns 503 and keeps the cookies when the token cannot be revoked
 // @Tags /api/v1/admin/users
 // @Accept json
 // @Produce json
 // @Success 200 {object} wrapper.SuccessWrapper{message=string}
+// @Failure 503 {object} wrapper.ErrorWrapper
 // @Router /users/logout [post]
+// @Router /admin/users/refresh [delete]
 func (h *UserHandler) Logout(c *gin.Context) {
-	c.SetCookie("token", "", -1, "/", "", false, true)
+	if refreshToken, err := c.Cookie("refresh_token"); err == nil && refreshToken != "" {
+		// Неотозванный токен снова станет валиден, поэтому без отзыва logout не подтверждается,
+		// а cookie остаются, чтобы запрос можно было повторить
+		if err := h.srv.RevokeRefreshToken(c.Request.Context(), refreshToken); err != nil {
+			fmt.Printf("Failed to revoke refresh token: %v\n", err)
+			c.JSON(http.StatusServiceUnavailable, wrapper.ErrorWrapper{
+				Message: "Cannot revoke session right now, retry logout later",
+				Success: false,
+			})
+			return
+		}
+	}
+
+	clearAuthCookies(c)
 
 	c.JSON(http.StatusOK, wrapper.SuccessWrapper{
 		Message: "Successfully logged out",
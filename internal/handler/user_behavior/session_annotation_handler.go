@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/dinerozz/web-behavior-backend/internal/model/response/wrapper"
+	service "github.com/dinerozz/web-behavior-backend/internal/service/user_behavior"
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// CreateSessionAnnotation godoc
+// @Summary      Annotate session
+// @Description  Add a free-text tag ("pairing", "meeting", "focus block") to a session. The current admin is stored as the author
+// @Tags         /api/v1/admin/behaviors
+// @Accept       json
+// @Produce      json
+// @Param        sessionId   path      string                                 true  "Session ID"
+// @Param        annotation  body      entity.CreateSessionAnnotationRequest  true  "Annotation"
+// @Success      201         {object}  wrapper.ResponseWrapper{data=entity.SessionAnnotation}
+// @Failure      400         {object}  wrapper.ErrorWrapper
+// @Failure      401         {object}  wrapper.ErrorWrapper
+// @Failure      404         {object}  wrapper.ErrorWrapper
+// @Failure      500         {object}  wrapper.ErrorWrapper
+// @Router       /behaviors/sessions/{sessionId}/annotations [post]
+func (h *UserBehaviorHandler) CreateSessionAnnotation(c *gin.Context) {
+	userUUID, ok := currentAdminID(c)
+	if !ok {
+		return
+	}
+
+	var req entity.CreateSessionAnnotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+
+	annotation, err := h.annotationService.CreateAnnotation(c.Request.Context(), c.Param("sessionId"), userUUID, req)
+	if err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, wrapper.ErrorWrapper{
+				Message: "Session not found",
+			})
+			return
+		}
+		if err.Error() == "tag is required" {
+			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, wrapper.ResponseWrapper{
+		Data:    annotation,
+		Success: true,
+	})
+}
+
+// GetSessionAnnotations godoc
+// @Summary      Get session annotations
+// @Description  Get all annotations of a session in creation order
+// @Tags         /api/v1/admin/behaviors
+// @Accept       json
+// @Produce      json
+// @Param        sessionId  path      string  true  "Session ID"
+// @Success      200        {object}  wrapper.ResponseWrapper{data=[]entity.SessionAnnotation}
+// @Failure      500        {object}  wrapper.ErrorWrapper
+// @Router       /behaviors/sessions/{sessionId}/annotations [get]
+func (h *UserBehaviorHandler) GetSessionAnnotations(c *gin.Context) {
+	annotations, err := h.annotationService.GetAnnotations(c.Request.Context(), c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapper.ResponseWrapper{
+		Data:    annotations,
+		Success: true,
+	})
+}
+
+// DeleteSessionAnnotation godoc
+// @Summary      Delete session annotation
+// @Description  Delete a session annotation. Only its author or a super admin can delete it
+// @Tags         /api/v1/admin/behaviors
+// @Accept       json
+// @Produce      json
+// @Param        sessionId     path      string  true  "Session ID"
+// @Param        annotationId  path      string  true  "Annotation ID"
+// @Success      200           {object}  wrapper.ResponseWrapper{data=string}
+// @Failure      400           {object}  wrapper.ErrorWrapper
+// @Failure      401           {object}  wrapper.ErrorWrapper
+// @Failure      403           {object}  wrapper.ErrorWrapper
+// @Failure      404           {object}  wrapper.ErrorWrapper
+// @Failure      500           {object}  wrapper.ErrorWrapper
+// @Router       /behaviors/sessions/{sessionId}/annotations/{annotationId} [delete]
+func (h *UserBehaviorHandler) DeleteSessionAnnotation(c *gin.Context) {
+	userUUID, ok := currentAdminID(c)
+	if !ok {
+		return
+	}
+
+	annotationID, err := uuid.FromString(c.Param("annotationId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "Invalid UUID format",
+		})
+		return
+	}
+
+	err = h.annotationService.DeleteAnnotation(c.Request.Context(), c.Param("sessionId"), annotationID, userUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrSessionAnnotationNotFound):
+			c.JSON(http.StatusNotFound, wrapper.ErrorWrapper{
+				Message: "Annotation not found",
+			})
+		case errors.Is(err, service.ErrSessionAnnotationForbidden):
+			c.JSON(http.StatusForbidden, wrapper.ErrorWrapper{
+				Message: err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapper.ResponseWrapper{
+		Data:    "Annotation deleted successfully",
+		Success: true,
+	})
+}
+
+// currentAdminID читает id админа, выставленный AuthenticationMiddleware; при ошибке ответ уже записан
+func currentAdminID(c *gin.Context) (uuid.UUID, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, wrapper.ErrorWrapper{Message: "User ID not found", Success: false})
+		return uuid.Nil, false
+	}
+
+	userUUID, err := uuid.FromString(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{Message: err.Error(), Success: false})
+		return uuid.Nil, false
+	}
+
+	return userUUID, true
+}
@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/dinerozz/web-behavior-backend/internal/model/response/wrapper"
+	service "github.com/dinerozz/web-behavior-backend/internal/service/user_behavior"
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// Через сколько строк выгрузки сбрасывать буфер клиенту
+const behaviorExportFlushEvery = 500
+
+var behaviorExportCSVHeader = []string{
+	"id", "session_id", "user_id", "user_name", "event_type", "url", "domain", "x", "y", "key", "timestamp",
+}
+
+// ExportBehaviors godoc
+// @Summary      Export behavior events
+// @Description  Stream all events of a user and/or session in the time range as NDJSON (one entity.UserBehavior per line) or CSV, ordered by timestamp. The range cannot exceed 31 days. Admins can export only users of their organizations and must pass userId; session-only export is super admin only. CSV cells starting with = + - @ are prefixed with ' to prevent formula injection
+// @Tags         /api/v1/admin/behaviors
+// @Produce      plain
+// @Param        userId     query     string  false  "User ID (userId or sessionId is required)"
+// @Param        sessionId  query     string  false  "Session ID"
+// @Param        start      query     string  true   "Start time (RFC3339 format)"
+// @Param        end        query     string  true   "End time (RFC3339 format)"
+// @Param        format     query     string  false  "Export format" Enums(ndjson, csv) default(ndjson)
+// @Success      200        {string}  string  "NDJSON or CSV stream"
+// @Failure      400        {object}  wrapper.ErrorWrapper
+// @Failure      403        {object}  wrapper.ErrorWrapper
+// @Router       /behaviors/export [get]
+func (h *UserBehaviorHandler) ExportBehaviors(c *gin.Context) {
+	format := c.DefaultQuery("format", entity.BehaviorExportFormatNDJSON)
+	if format != entity.BehaviorExportFormatNDJSON && format != entity.BehaviorExportFormatCSV {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "format must be one of: ndjson, csv",
+			Success: false,
+		})
+		return
+	}
+
+	var filter entity.BehaviorExportFilter
+
+	if userIDStr := c.Query("userId"); userIDStr != "" {
+		userID, err := uuid.FromString(userIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+				Message: "Invalid UUID format for userId",
+				Success: false,
+			})
+			return
+		}
+		filter.UserID = &userID
+	}
+
+	if sessionID := c.Query("sessionId"); sessionID != "" {
+		filter.SessionID = &sessionID
+	}
+
+	if startStr := c.Query("start"); startStr != "" {
+		start, err := time.Parse(time.RFC3339, startStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+				Message: "Invalid start format, use RFC3339",
+				Success: false,
+			})
+			return
+		}
+		filter.StartTime = start
+	}
+
+	if endStr := c.Query("end"); endStr != "" {
+		end, err := time.Parse(time.RFC3339, endStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+				Message: "Invalid end format, use RFC3339",
+				Success: false,
+			})
+			return
+		}
+		filter.EndTime = end
+	}
+
+	// После начала записи статус уже не поменять, поэтому фильтр проверяется заранее
+	if err := service.ValidateBehaviorExport(filter); err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("behaviors_%s_%s.%s",
+		filter.StartTime.Format("20060102"),
+		filter.EndTime.Format("20060102"),
+		format,
+	)
+
+	contentType := "application/x-ndjson"
+	if format == entity.BehaviorExportFormatCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	var write func(entity.UserBehavior) error
+	var flush func()
+
+	if format == entity.BehaviorExportFormatCSV {
+		writer := csv.NewWriter(c.Writer)
+		_ = writer.Write(behaviorExportCSVHeader)
+
+		write = func(behavior entity.UserBehavior) error {
+			return writer.Write(behaviorCSVRecord(behavior))
+		}
+		flush = func() {
+			writer.Flush()
+			c.Writer.Flush()
+		}
+	} else {
+		encoder := json.NewEncoder(c.Writer)
+
+		// Encode дописывает перевод строки после каждого объекта
+		write = func(behavior entity.UserBehavior) error {
+			return encoder.Encode(behavior)
+		}
+		flush = c.Writer.Flush
+	}
+
+	rowsWritten := 0
+	err := h.service.ExportBehaviors(c.Request.Context(), filter, func(behavior entity.UserBehavior) error {
+		if err := write(behavior); err != nil {
+			return err
+		}
+
+		rowsWritten++
+		if rowsWritten%behaviorExportFlushEvery == 0 {
+			flush()
+		}
+
+		return nil
+	})
+	flush()
+
+	// Ответ уже частично отправлен, клиент увидит обрезанный файл
+	if err != nil {
+		log.Printf("Behavior export interrupted after %d rows: %v", rowsWritten, err)
+	}
+}
+
+func behaviorCSVRecord(behavior entity.UserBehavior) []string {
+	userID := ""
+	if behavior.UserID != nil {
+		userID = behavior.UserID.String()
+	}
+
+	userName := ""
+	if behavior.UserName != nil {
+		userName = *behavior.UserName
+	}
+
+	x := ""
+	if behavior.X != nil {
+		x = strconv.Itoa(*behavior.X)
+	}
+
+	y := ""
+	if behavior.Y != nil {
+		y = strconv.Itoa(*behavior.Y)
+	}
+
+	key := ""
+	if behavior.Key != nil {
+		key = *behavior.Key
+	}
+
+	return []string{
+		behavior.ID.String(),
+		csvSafeCell(behavior.SessionID),
+		userID,
+		csvSafeCell(userName),
+		csvSafeCell(behavior.Type),
+		csvSafeCell(behavior.URL),
+		csvSafeCell(behavior.Domain),
+		x,
+		y,
+		csvSafeCell(key),
+		behavior.Timestamp.Format(time.RFC3339Nano),
+	}
+}
+
+// csvSafeCell экранирует значения, которые табличные редакторы выполнят как формулу
+func csvSafeCell(value string) string {
+	if value == "" {
+		return value
+	}
+
+	switch value[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + value
+	}
+
+	return value
+}
@@ -2,7 +2,9 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
+	"github.com/dinerozz/web-behavior-backend/internal/service/redis"
 	service "github.com/dinerozz/web-behavior-backend/internal/service/user_behavior"
 	"github.com/dinerozz/web-behavior-backend/pkg/utils"
 	"net/http"
@@ -17,15 +19,30 @@ import (
 )
 
 type UserBehaviorHandler struct {
-	service service.UserBehaviorService
+	service           service.UserBehaviorService
+	annotationService service.SessionAnnotationService
+	redisService      redis.ServiceInterface
+	maxBatchEvents    int
+	// Проверка Origin для WebSocket стрима (список CORS)
+	allowOrigin func(origin string) bool
+	// Текущее время для period фильтров; подменяется в тестах
+	now func() time.Time
 }
 
-func NewUserBehaviorHandler(service service.UserBehaviorService) *UserBehaviorHandler {
+func NewUserBehaviorHandler(service service.UserBehaviorService, annotationService service.SessionAnnotationService, redisService redis.ServiceInterface, maxBatchEvents int, allowOrigin func(origin string) bool) *UserBehaviorHandler {
 	return &UserBehaviorHandler{
-		service: service,
+		service:           service,
+		annotationService: annotationService,
+		redisService:      redisService,
+		maxBatchEvents:    maxBatchEvents,
+		allowOrigin:       allowOrigin,
+		now:               time.Now,
 	}
 }
 
+// Запас размера тела batch запроса на одно событие (URL может быть длинным)
+const batchEventMaxBytes = 8 << 10
+
 // CreateBehavior godoc
 // @Summary      Create user behavior event
 // @Description  Create a single user behavior event
@@ -34,6 +51,7 @@ func NewUserBehaviorHandler(service service.UserBehaviorService) *UserBehaviorHa
 // @Produce      json
 // @Param        behavior  body      entity.CreateUserBehaviorRequest  true  "Behavior data"
 // @Success      201       {object}  wrapper.ResponseWrapper{data=entity.UserBehavior}
+// @Success      202       {object}  wrapper.SuccessWrapper  "Domain is excluded, event is not stored"
 // @Failure      400       {object}  wrapper.ErrorWrapper
 // @Failure      500       {object}  wrapper.ErrorWrapper
 // @Router       /behaviors [post]
@@ -46,7 +64,16 @@ func (h *UserBehaviorHandler) CreateBehavior(c *gin.Context) {
 		return
 	}
 
+	fillExtensionUserName(c, &req)
+
 	behavior, err := h.service.CreateBehavior(c.Request.Context(), req)
+	if errors.Is(err, service.ErrExcludedDomain) {
+		c.JSON(http.StatusAccepted, wrapper.SuccessWrapper{
+			Message: err.Error(),
+			Success: true,
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
 			Message: err.Error(),
@@ -62,37 +89,113 @@ func (h *UserBehaviorHandler) CreateBehavior(c *gin.Context) {
 
 // BatchCreateBehaviors godoc
 // @Summary      Batch create user behavior events
-// @Description  Create multiple user behavior events in one request
+// @Description  Create multiple user behavior events in one request. With partial=true invalid events are skipped and reported instead of failing the whole batch. Events already stored (same session, timestamp, type and URL) are counted as duplicates and not inserted again.
+// @Description  With an Idempotency-Key header the result is remembered for 24h: a retry with the same key and payload returns the original response (Idempotent-Replayed: true) without inserting, the same key with a different payload or while the first request is still processed returns 409. Keys are scoped to the API key user (or client IP without X-API-Key)
 // @Tags         /api/v1/inayla/behaviors
 // @Accept       json
 // @Produce      json
-// @Param        behaviors  body      entity.BatchCreateUserBehaviorRequest  true  "Behaviors data"
+// @Param        behaviors  body      entity.BatchCreateUserBehaviorRequest  true   "Behaviors data"
+// @Param        partial    query     bool                                   false  "Accept valid events and report rejected ones"
+// @Param        Idempotency-Key  header  string                             false  "Client generated key for safe retries (max 255 chars)"
 // @Success      201        {object}  wrapper.ResponseWrapper{data=string}
+// @Success      207        {object}  wrapper.ResponseWrapper{data=entity.BatchCreateUserBehaviorResult}
 // @Failure      400        {object}  wrapper.ErrorWrapper
+// @Failure      409        {object}  wrapper.ErrorWrapper
+// @Failure      413        {object}  wrapper.ErrorWrapper
 // @Failure      500        {object}  wrapper.ErrorWrapper
 // @Router       /behaviors/batch [post]
 func (h *UserBehaviorHandler) BatchCreateBehaviors(c *gin.Context) {
+	// Тело заведомо больше допустимого батча не дочитываем и не разбираем целиком
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(h.maxBatchEvents)*batchEventMaxBytes)
+
 	var req entity.BatchCreateUserBehaviorRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, wrapper.ErrorWrapper{
+				Message: fmt.Sprintf("Request body too large, maximum is %d events", h.maxBatchEvents),
+			})
+			return
+		}
+
 		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
 			Message: "Invalid request body: " + err.Error(),
 		})
 		return
 	}
 
-	err := h.service.BatchCreateBehaviors(c.Request.Context(), req)
+	for i := range req.Events {
+		fillExtensionUserName(c, &req.Events[i])
+	}
+
+	partial := c.Query("partial") == "true"
+
+	result, err := h.service.BatchCreateBehaviorsIdempotent(c.Request.Context(), req, partial, idempotencyScope(c), c.GetHeader("Idempotency-Key"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrIdempotencyKeyMismatch) || errors.Is(err, service.ErrIdempotencyKeyInProgress) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, wrapper.ErrorWrapper{
 			Message: err.Error(),
 		})
 		return
 	}
 
-	c.JSON(http.StatusCreated, wrapper.ResponseWrapper{
-		Data: "Successfully created " + strconv.Itoa(len(req.Events)) + " behavior events",
+	if result.Replayed {
+		c.Header("Idempotent-Replayed", "true")
+	}
+
+	if !partial {
+		c.JSON(http.StatusCreated, wrapper.ResponseWrapper{
+			Data: "Successfully created " + strconv.Itoa(result.Inserted) + " behavior events, skipped " + strconv.Itoa(result.Duplicates) + " duplicates",
+		})
+		return
+	}
+
+	status := http.StatusCreated
+	if result.Rejected > 0 {
+		status = http.StatusMultiStatus
+	}
+
+	c.JSON(status, wrapper.ResponseWrapper{
+		Data:    result,
+		Success: true,
 	})
 }
 
+// idempotencyScope - пространство Idempotency-Key клиента: пользователь расширения по X-API-Key,
+// для запросов без ключа - IP (как в RateLimitMiddleware)
+func idempotencyScope(c *gin.Context) string {
+	if extensionUserID := c.GetString("extension_user_id"); extensionUserID != "" {
+		return "user:" + extensionUserID
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// fillExtensionUserName подставляет пользователя расширения, определенного по X-API-Key, в события
+// без userName: без userId заполняются и userId, и userName, чтобы событие не было атрибутировано
+// наполовину. События с чужим userId не трогаем
+func fillExtensionUserName(c *gin.Context, req *entity.CreateUserBehaviorRequest) {
+	if req.UserName != nil {
+		return
+	}
+
+	username := c.GetString("extension_username")
+	extensionUserID, err := uuid.FromString(c.GetString("extension_user_id"))
+	if username == "" || err != nil {
+		return
+	}
+
+	if req.UserID == nil {
+		req.UserID = &extensionUserID
+	} else if *req.UserID != extensionUserID {
+		return
+	}
+
+	req.UserName = &username
+}
+
 // GetBehaviorByID godoc
 // @Summary      Get behavior by ID
 // @Description  Get a specific user behavior event by ID
@@ -142,15 +245,18 @@ func (h *UserBehaviorHandler) GetBehaviorByID(c *gin.Context) {
 // @Produce      json
 // @Param        userId     query     string  false  "User ID"
 // @Param        sessionId  query     string  false  "Session ID"
-// @Param        eventType  query     string  false  "Event type"
+// @Param        eventType  query     []string  false  "Event types (repeat the param or use a comma separated list)"  collectionFormat(multi)
 // @Param        url        query     string  false  "URL (partial match)"
 // @Param        startTime  query     string  false  "Start time (RFC3339 format)"
 // @Param        endTime    query     string  false  "End time (RFC3339 format)"
 // @Param        period     query     string  false  "Time period filter: 'today', 'week', 'month', 'year'"
+// @Param        tz         query     string  false  "IANA timezone for period boundaries (default: UTC)"
 // @Param        page       query     int     false  "Page number (starts from 1)"
 // @Param        per_page   query     int     false  "Items per page (default: 20, max: 1000)"
 // @Param        limit      query     int     false  "Limit (deprecated, use per_page)"
 // @Param        offset     query     int     false  "Offset (deprecated, use page)"
+// @Param        cursor     query     string  false  "Cursor from meta.next_cursor; pass an empty value to start cursor pagination (page/offset are ignored)"
+// @Param        order      query     string  false  "Sort order by timestamp: asc or desc (default: desc)"
 // @Success      200        {object}  entity.PaginatedResponse{data=[]entity.UserBehavior}
 // @Failure      400        {object}  wrapper.ErrorWrapper
 // @Failure      500        {object}  wrapper.ErrorWrapper
@@ -176,19 +282,32 @@ func (h *UserBehaviorHandler) GetBehaviors(c *gin.Context) {
 		filter.SessionID = &sessionID
 	}
 
-	if eventType := c.Query("eventType"); eventType != "" {
-		filter.EventType = &eventType
+	eventTypes, err := parseEventTypes(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: err.Error(),
+		})
+		return
 	}
+	filter.EventTypes = eventTypes
 
 	if url := c.Query("url"); url != "" {
 		filter.URL = &url
 	}
 
 	if period := c.Query("period"); period != "" {
-		startTime, endTime, err := h.getPeriodTimeRange(period)
+		loc, err := utils.LoadTimezone(c.Query("tz"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+				Message: err.Error(),
+			})
+			return
+		}
+
+		startTime, endTime, err := utils.PeriodTimeRangeAt(period, loc, h.now())
 		if err != nil {
 			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
-				Message: fmt.Sprintf("Invalid period '%s'. Valid values: today, week, month, year", period),
+				Message: err.Error(),
 			})
 			return
 		}
@@ -218,6 +337,8 @@ func (h *UserBehaviorHandler) GetBehaviors(c *gin.Context) {
 		}
 	}
 
+	warnDeprecatedPagination(c)
+
 	if pageStr := c.Query("page"); pageStr != "" {
 		page, err := strconv.Atoi(pageStr)
 		if err != nil || page < 1 {
@@ -238,8 +359,6 @@ func (h *UserBehaviorHandler) GetBehaviors(c *gin.Context) {
 			return
 		}
 		filter.PerPage = perPage
-	} else if filter.Page > 0 {
-		filter.PerPage = 20
 	}
 
 	if limitStr := c.Query("limit"); limitStr != "" {
@@ -264,9 +383,47 @@ func (h *UserBehaviorHandler) GetBehaviors(c *gin.Context) {
 		filter.Offset = offset
 	}
 
+	filter.Order = c.Query("order")
+
+	if cursor, ok := c.GetQuery("cursor"); ok {
+		if cursor != "" {
+			decoded, err := entity.DecodeBehaviorCursor(cursor)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+					Message: "Invalid cursor value",
+				})
+				return
+			}
+			filter.Cursor = decoded
+		}
+
+		behaviors, cursorInfo, err := h.service.GetBehaviorsByCursor(c.Request.Context(), filter)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, service.ErrInvalidBehaviorOrder) {
+				status = http.StatusBadRequest
+			}
+			c.JSON(status, wrapper.ErrorWrapper{
+				Message: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, wrapper.CursorPaginatedResponseWrapper{
+			Data:    behaviors,
+			Meta:    *cursorInfo,
+			Success: true,
+		})
+		return
+	}
+
 	behaviors, paginationInfo, err := h.service.GetBehaviors(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidBehaviorOrder) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, wrapper.ErrorWrapper{
 			Message: err.Error(),
 		})
 		return
@@ -291,80 +448,88 @@ func (h *UserBehaviorHandler) GetBehaviors(c *gin.Context) {
 	}
 }
 
-func (h *UserBehaviorHandler) getPeriodTimeRange(period string) (time.Time, time.Time, error) {
-	now := time.Now()
-	var startTime, endTime time.Time
-
-	switch strings.ToLower(period) {
-	case "today":
-		// С начала дня до конца дня
-		startTime = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-		endTime = time.Date(now.Year(), now.Month(), now.Day(), 23, 59, 59, 999999999, now.Location())
-
-	case "week":
-		// С начала недели (понедельник) до конца недели (воскресенье)
-		weekday := int(now.Weekday())
-		if weekday == 0 { // воскресенье в Go = 0, делаем его 7
-			weekday = 7
-		}
-
-		// Начало недели (понедельник)
-		daysFromMonday := weekday - 1
-		monday := now.AddDate(0, 0, -daysFromMonday)
-		startTime = time.Date(monday.Year(), monday.Month(), monday.Day(), 0, 0, 0, 0, monday.Location())
+// Порядок пресетов в ответе GetBehaviorsPeriods
+var periodKeys = []string{"today", "week", "month", "year"}
 
-		// Конец недели (воскресенье)
-		sunday := monday.AddDate(0, 0, 6)
-		endTime = time.Date(sunday.Year(), sunday.Month(), sunday.Day(), 23, 59, 59, 999999999, sunday.Location())
-
-	case "month":
-		// С начала месяца до конца месяца
-		startTime = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-		endTime = startTime.AddDate(0, 1, -1) // последний день месяца
-		endTime = time.Date(endTime.Year(), endTime.Month(), endTime.Day(), 23, 59, 59, 999999999, endTime.Location())
-
-	case "year":
-		// С начала года до конца года
-		startTime = time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
-		endTime = time.Date(now.Year(), 12, 31, 23, 59, 59, 999999999, now.Location())
-
-	default:
-		return time.Time{}, time.Time{}, fmt.Errorf("unsupported period: %s", period)
-	}
+type periodText struct {
+	label       string
+	description string
+}
 
-	return startTime, endTime, nil
+// Подписи пресетов по языкам; русский - по умолчанию
+var periodLabels = map[string]map[string]periodText{
+	"ru": {
+		"today": {"Сегодня", "События за текущий день"},
+		"week":  {"Эта неделя", "События за текущую неделю (понедельник - воскресенье)"},
+		"month": {"Этот месяц", "События за текущий месяц"},
+		"year":  {"Этот год", "События за текущий год"},
+	},
+	"en": {
+		"today": {"Today", "Events for the current day"},
+		"week":  {"This week", "Events for the current week (Monday - Sunday)"},
+		"month": {"This month", "Events for the current month"},
+		"year":  {"This year", "Events for the current year"},
+	},
 }
 
 // GetBehaviorsPeriods godoc
 // @Summary      Get available time periods
-// @Description  Get list of available time period filters
+// @Description  Get list of available time period filters. Without params labels are in Russian and no dates are resolved. With lang and/or tz the labels are localized and each preset gets its startTime/endTime computed in the given timezone (UTC by default)
 // @Tags         /api/v1/admin/behaviors
 // @Accept       json
 // @Produce      json
+// @Param        lang       query     string  false  "Label language"  Enums(ru, en)  default(ru)
+// @Param        tz         query     string  false  "IANA timezone for resolved dates, e.g. Asia/Almaty (default: UTC)"
 // @Success      200        {object}  wrapper.ResponseWrapper{data=[]entity.PeriodInfo}
+// @Failure      400        {object}  wrapper.ErrorWrapper
 // @Router       /behaviors/periods [get]
 func (h *UserBehaviorHandler) GetBehaviorsPeriods(c *gin.Context) {
-	periods := []entity.PeriodInfo{
-		{
-			Key:         "today",
-			Label:       "Сегодня",
-			Description: "События за текущий день",
-		},
-		{
-			Key:         "week",
-			Label:       "Эта неделя",
-			Description: "События за текущую неделю (понедельник - воскресенье)",
-		},
-		{
-			Key:         "month",
-			Label:       "Этот месяц",
-			Description: "События за текущий месяц",
-		},
-		{
-			Key:         "year",
-			Label:       "Этот год",
-			Description: "События за текущий год",
-		},
+	lang := c.Query("lang")
+	tz := c.Query("tz")
+
+	labels, ok := periodLabels["ru"]
+	if lang != "" {
+		labels, ok = periodLabels[lang]
+		if !ok {
+			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+				Message: "unsupported lang: " + lang + " (supported: ru, en)",
+			})
+			return
+		}
+	}
+
+	loc, err := utils.LoadTimezone(tz)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Без параметров - прежний ответ без дат
+	resolveDates := lang != "" || tz != ""
+
+	periods := make([]entity.PeriodInfo, 0, len(periodKeys))
+	for _, key := range periodKeys {
+		period := entity.PeriodInfo{
+			Key:         key,
+			Label:       labels[key].label,
+			Description: labels[key].description,
+		}
+
+		if resolveDates {
+			startTime, endTime, err := utils.PeriodTimeRangeAt(key, loc, h.now())
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{
+					Message: err.Error(),
+				})
+				return
+			}
+			period.StartTime = &startTime
+			period.EndTime = &endTime
+		}
+
+		periods = append(periods, period)
 	}
 
 	c.JSON(http.StatusOK, wrapper.ResponseWrapper{
@@ -375,16 +540,17 @@ func (h *UserBehaviorHandler) GetBehaviorsPeriods(c *gin.Context) {
 
 // GetStats godoc
 // @Summary      Get behavior statistics
-// @Description  Get statistics about user behaviors
+// @Description  Get statistics about user behaviors, including uniqueDomains (excluded domains are not counted). With group_by each timeline bucket also has unique_domains and new_domains (first seen in the requested period)
 // @Tags         /api/v1/admin/behaviors
 // @Accept       json
 // @Produce      json
 // @Param        userId     query     string  false  "User ID"
 // @Param        sessionId  query     string  false  "Session ID"
-// @Param        eventType  query     string  false  "Event type"
+// @Param        eventType  query     []string  false  "Event types (repeat the param or use a comma separated list)"  collectionFormat(multi)
 // @Param        url        query     string  false  "URL (partial match)"
 // @Param        startTime  query     string  false  "Start time (RFC3339 format)"
 // @Param        endTime    query     string  false  "End time (RFC3339 format)"
+// @Param        group_by   query     string  false  "Add timeline bucketed by day or hour (requires startTime and endTime)"  Enums(day, hour)
 // @Success      200        {object}  wrapper.ResponseWrapper{data=entity.UserBehaviorStats}
 // @Failure      400        {object}  wrapper.ErrorWrapper
 // @Failure      500        {object}  wrapper.ErrorWrapper
@@ -410,9 +576,14 @@ func (h *UserBehaviorHandler) GetStats(c *gin.Context) {
 		filter.SessionID = &sessionID
 	}
 
-	if eventType := c.Query("eventType"); eventType != "" {
-		filter.EventType = &eventType
+	eventTypes, err := parseEventTypes(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: err.Error(),
+		})
+		return
 	}
+	filter.EventTypes = eventTypes
 
 	if url := c.Query("url"); url != "" {
 		filter.URL = &url
@@ -448,6 +619,22 @@ func (h *UserBehaviorHandler) GetStats(c *gin.Context) {
 		return
 	}
 
+	if groupBy := c.Query("group_by"); groupBy != "" {
+		timeline, err := h.service.GetStatsTimeline(c.Request.Context(), filter, groupBy)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, service.ErrInvalidStatsGroupBy) {
+				status = http.StatusBadRequest
+			}
+			c.JSON(status, wrapper.ErrorWrapper{
+				Message: err.Error(),
+				Success: false,
+			})
+			return
+		}
+		stats.Timeline = timeline
+	}
+
 	c.JSON(http.StatusOK, wrapper.ResponseWrapper{
 		Data:    stats,
 		Success: true,
@@ -456,7 +643,7 @@ func (h *UserBehaviorHandler) GetStats(c *gin.Context) {
 
 // GetSessionSummary godoc
 // @Summary      Get session summary
-// @Description  Get summary information about a specific session
+// @Description  Get summary information about a specific session, including event counts by type (eventsByType) and analyst annotations
 // @Tags         /api/v1/admin/behaviors
 // @Accept       json
 // @Produce      json
@@ -489,6 +676,15 @@ func (h *UserBehaviorHandler) GetSessionSummary(c *gin.Context) {
 		return
 	}
 
+	annotations, err := h.annotationService.GetAnnotations(c.Request.Context(), sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{
+			Message: err.Error(),
+		})
+		return
+	}
+	summary.Annotations = annotations
+
 	c.JSON(http.StatusOK, wrapper.ResponseWrapper{
 		Data:    summary,
 		Success: true,
@@ -519,8 +715,11 @@ func (h *UserBehaviorHandler) GetUserSessions(c *gin.Context) {
 		return
 	}
 
+	// perPage = 0: размер страницы по умолчанию задается в сервисе (SESSIONS_DEFAULT_PER_PAGE)
 	page := 1
-	perPage := 50
+	perPage := 0
+
+	warnDeprecatedPagination(c)
 
 	if pageStr := c.Query("page"); pageStr != "" {
 		var err error
@@ -544,7 +743,7 @@ func (h *UserBehaviorHandler) GetUserSessions(c *gin.Context) {
 		}
 	}
 
-	if page == 1 && perPage == 50 {
+	if page == 1 && perPage == 0 {
 		if limitStr := c.Query("limit"); limitStr != "" {
 			var err error
 			limit, err := strconv.Atoi(limitStr)
@@ -634,18 +833,70 @@ func (h *UserBehaviorHandler) DeleteBehavior(c *gin.Context) {
 	})
 }
 
+// RestoreBehavior godoc
+// @Summary      Restore behavior
+// @Description  Restore a soft-deleted user behavior event (super admin only)
+// @Tags         /api/v1/admin/behaviors
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Behavior ID"
+// @Success      200  {object}  wrapper.ResponseWrapper{data=string}
+// @Failure      400  {object}  wrapper.ErrorWrapper
+// @Failure      403  {object}  wrapper.ErrorWrapper
+// @Failure      404  {object}  wrapper.ErrorWrapper
+// @Failure      409  {object}  wrapper.ErrorWrapper
+// @Failure      500  {object}  wrapper.ErrorWrapper
+// @Router       /behaviors/{id}/restore [post]
+func (h *UserBehaviorHandler) RestoreBehavior(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.FromString(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "Invalid UUID format",
+		})
+		return
+	}
+
+	err = h.service.RestoreBehavior(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrDeletedBehaviorNotFound) {
+			c.JSON(http.StatusNotFound, wrapper.ErrorWrapper{
+				Message: "Deleted behavior not found",
+			})
+			return
+		}
+		if errors.Is(err, service.ErrBehaviorRestoreConflict) {
+			c.JSON(http.StatusConflict, wrapper.ErrorWrapper{
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapper.ResponseWrapper{
+		Data:    "Behavior restored successfully",
+		Success: true,
+	})
+}
+
 // GetStats godoc
 // @Summary      Get behavior statistics
 // @Description  Get statistics about user behaviors
 // @Tags         /api/v1/admin/behaviors
 // @Accept       json
 // @Produce      json
-// @Param        userId     query     string  false  "User ID"
-// @Param        startTime  query     string  false  "Start time (RFC3339 format)"
-// @Param        endTime    query     string  false  "End time (RFC3339 format)"
-// @Success      200        {object}  wrapper.ResponseWrapper{data=entity.UserEventsCountResponse}
-// @Failure      400        {object}  wrapper.ErrorWrapper
-// @Failure      500        {object}  wrapper.ErrorWrapper
+// @Param        user_id     query     string  false  "User ID"
+// @Param        start_time  query     string  false  "Start time (RFC3339 format)"
+// @Param        end_time    query     string  false  "End time (RFC3339 format)"
+// @Param        period      query     string  false  "Period preset, ignored when start_time or end_time is set"  Enums(today, week, month, year)
+// @Param        tz          query     string  false  "IANA timezone for period (default: UTC)"
+// @Success      200         {object}  wrapper.ResponseWrapper{data=entity.UserEventsCountResponse}
+// @Failure      400         {object}  wrapper.ErrorWrapper
+// @Failure      500         {object}  wrapper.ErrorWrapper
 // @Router       /behaviors/user-events [get]
 func (h *UserBehaviorHandler) GetUserEventsCount(c *gin.Context) {
 	var filter entity.UserEventsCount
@@ -654,22 +905,82 @@ func (h *UserBehaviorHandler) GetUserEventsCount(c *gin.Context) {
 		filter.UserID = userID
 	}
 
-	if startTimeStr := c.Query("start_time"); startTimeStr != "" {
+	startTime, endTime, err := utils.ParseOptionalTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: err.Error(),
+		})
+		return
+	}
+	filter.StartTime = startTime
+	filter.EndTime = endTime
+
+	result, err := h.service.GetUserEventsCount(c.Request.Context(), filter)
+	if err != nil {
+
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{
+			Message: "Failed to retrieve user events count",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapper.ResponseWrapper{
+		Data:    result,
+		Success: true,
+	})
+}
+
+// GetDistinctEventTypes godoc
+// @Summary      Get distinct event types for a user
+// @Description  List event types seen for a user in the time range, sorted by name, with event counts. Used to build event type filters
+// @Tags         /api/v1/admin/behaviors
+// @Accept       json
+// @Produce      json
+// @Param        userId     query     string  true   "User ID"
+// @Param        startTime  query     string  false  "Start time (RFC3339 format)"
+// @Param        endTime    query     string  false  "End time (RFC3339 format)"
+// @Success      200        {object}  wrapper.ResponseWrapper{data=entity.DistinctEventTypesResponse}
+// @Failure      400        {object}  wrapper.ErrorWrapper
+// @Failure      500        {object}  wrapper.ErrorWrapper
+// @Router       /behaviors/event-types [get]
+func (h *UserBehaviorHandler) GetDistinctEventTypes(c *gin.Context) {
+	var filter entity.UserEventsCount
+
+	filter.UserID = c.Query("userId")
+	if filter.UserID == "" {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "userId is required",
+			Success: false,
+		})
+		return
+	}
+
+	if !utils.ValidateUUID(filter.UserID) {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "Invalid UUID format for userId",
+			Success: false,
+		})
+		return
+	}
+
+	if startTimeStr := c.Query("startTime"); startTimeStr != "" {
 		startTime, err := time.Parse(time.RFC3339, startTimeStr)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
-				Message: "Invalid start_time format, use RFC3339",
+				Message: "Invalid startTime format, use RFC3339",
+				Success: false,
 			})
 			return
 		}
 		filter.StartTime = &startTime
 	}
 
-	if endTimeStr := c.Query("end_time"); endTimeStr != "" {
+	if endTimeStr := c.Query("endTime"); endTimeStr != "" {
 		endTime, err := time.Parse(time.RFC3339, endTimeStr)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
-				Message: "Invalid end_time format, use RFC3339",
+				Message: "Invalid endTime format, use RFC3339",
+				Success: false,
 			})
 			return
 		}
@@ -679,15 +990,16 @@ func (h *UserBehaviorHandler) GetUserEventsCount(c *gin.Context) {
 	if filter.StartTime != nil && filter.EndTime != nil && filter.StartTime.After(*filter.EndTime) {
 		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
 			Message: "startTime cannot be after endTime",
+			Success: false,
 		})
 		return
 	}
 
-	result, err := h.service.GetUserEventsCount(c.Request.Context(), filter)
+	result, err := h.service.GetDistinctEventTypes(c.Request.Context(), filter)
 	if err != nil {
-
 		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{
-			Message: "Failed to retrieve user events count",
+			Message: err.Error(),
+			Success: false,
 		})
 		return
 	}
@@ -698,6 +1010,42 @@ func (h *UserBehaviorHandler) GetUserEventsCount(c *gin.Context) {
 	})
 }
 
+// GetUserActivitySpan godoc
+// @Summary      Get user activity span
+// @Description  Get the earliest and latest event timestamps and the total event count for a user, to pick default date ranges. Timestamps are null when the user has no data
+// @Tags         /api/v1/admin/behaviors
+// @Accept       json
+// @Produce      json
+// @Param        userId  path      string  true  "User ID"
+// @Success      200     {object}  wrapper.ResponseWrapper{data=entity.UserActivitySpan}
+// @Failure      400     {object}  wrapper.ErrorWrapper
+// @Failure      500     {object}  wrapper.ErrorWrapper
+// @Router       /behaviors/users/{userId}/span [get]
+func (h *UserBehaviorHandler) GetUserActivitySpan(c *gin.Context) {
+	userID := c.Param("userId")
+	if !utils.ValidateUUID(userID) {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "Invalid UUID format for userId",
+			Success: false,
+		})
+		return
+	}
+
+	span, err := h.service.GetUserActivitySpan(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{
+			Message: err.Error(),
+			Success: false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapper.ResponseWrapper{
+		Data:    span,
+		Success: true,
+	})
+}
+
 func (h *UserBehaviorHandler) RegisterRoutes(router *gin.RouterGroup) {
 	behaviors := router.Group("/behaviors")
 	{
@@ -705,11 +1053,63 @@ func (h *UserBehaviorHandler) RegisterRoutes(router *gin.RouterGroup) {
 		behaviors.POST("/batch", h.BatchCreateBehaviors)
 		behaviors.GET("", h.GetBehaviors)
 		behaviors.GET("/stats", h.GetStats)
+		behaviors.GET("/event-types", h.GetDistinctEventTypes)
+		behaviors.GET("/export", h.ExportBehaviors)
 		behaviors.GET("/:id", h.GetBehaviorByID)
 		behaviors.DELETE("/:id", h.DeleteBehavior)
 
 		// Session routes
 		behaviors.GET("/sessions/:sessionId", h.GetSessionSummary)
+		behaviors.GET("/sessions/:sessionId/stream", h.StreamSessionEvents)
+		behaviors.GET("/sessions/:sessionId/annotations", h.GetSessionAnnotations)
+		behaviors.POST("/sessions/:sessionId/annotations", h.CreateSessionAnnotation)
+		behaviors.DELETE("/sessions/:sessionId/annotations/:annotationId", h.DeleteSessionAnnotation)
 		behaviors.GET("/users/:userId/sessions", h.GetUserSessions)
+		behaviors.GET("/users/:userId/span", h.GetUserActivitySpan)
+	}
+}
+
+// parseEventTypes собирает eventType из повторяющихся параметров и списков через запятую
+func parseEventTypes(c *gin.Context) ([]string, error) {
+	var eventTypes []string
+	for _, value := range c.QueryArray("eventType") {
+		for _, eventType := range strings.Split(value, ",") {
+			eventType = strings.TrimSpace(eventType)
+			if eventType == "" {
+				continue
+			}
+			if !service.IsValidEventType(eventType) {
+				return nil, fmt.Errorf("Invalid eventType: %s", eventType)
+			}
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	return eventTypes, nil
+}
+
+// Устаревшие параметры пагинации и их замены
+var deprecatedPaginationParams = []struct {
+	name        string
+	replacement string
+}{
+	{name: "limit", replacement: "per_page"},
+	{name: "offset", replacement: "page"},
+}
+
+// warnDeprecatedPagination ставит заголовки Warning и X-API-Deprecation, если клиент передал
+// limit/offset. Параметры продолжают работать, заголовки - сигнал к переходу на page/per_page
+func warnDeprecatedPagination(c *gin.Context) {
+	var used []string
+	for _, param := range deprecatedPaginationParams {
+		if _, ok := c.GetQuery(param.name); ok {
+			used = append(used, fmt.Sprintf("%s (use %s)", param.name, param.replacement))
+		}
+	}
+	if len(used) == 0 {
+		return
 	}
+
+	note := "Deprecated query parameters: " + strings.Join(used, ", ")
+	c.Header("Warning", fmt.Sprintf("299 - %q", note))
+	c.Header("X-API-Deprecation", note)
 }
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	service "github.com/dinerozz/web-behavior-backend/internal/service/user_behavior"
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// stubBehaviorService запоминает фильтр; неиспользуемые методы берутся из встроенного (nil) интерфейса
+type stubBehaviorService struct {
+	service.UserBehaviorService
+	filter *entity.UserBehaviorFilter
+}
+
+func (s *stubBehaviorService) GetBehaviors(ctx context.Context, filter entity.UserBehaviorFilter) ([]entity.UserBehavior, *entity.PaginationInfo, error) {
+	s.filter = &filter
+	return []entity.UserBehavior{}, nil, nil
+}
+
+func newPeriodTestRouter(stub *stubBehaviorService, now time.Time) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	h := &UserBehaviorHandler{service: stub, now: func() time.Time { return now }}
+	router := gin.New()
+	router.GET("/behaviors", h.GetBehaviors)
+	router.GET("/behaviors/periods", h.GetBehaviorsPeriods)
+	return router
+}
+
+func TestFillExtensionUserName(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	extensionUserID := uuid.Must(uuid.NewV4())
+	otherUserID := uuid.Must(uuid.NewV4())
+
+	newContext := func() *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Set("extension_user_id", extensionUserID.String())
+		c.Set("extension_username", "alice")
+		return c
+	}
+
+	// Без userId заполняются и userId, и userName
+	var req entity.CreateUserBehaviorRequest
+	fillExtensionUserName(newContext(), &req)
+	if req.UserID == nil || *req.UserID != extensionUserID || req.UserName == nil || *req.UserName != "alice" {
+		t.Fatalf("expected user %s named alice, got %v %v", extensionUserID, req.UserID, req.UserName)
+	}
+
+	// Свой userId - подставляется только имя
+	req = entity.CreateUserBehaviorRequest{UserID: &extensionUserID}
+	fillExtensionUserName(newContext(), &req)
+	if req.UserName == nil || *req.UserName != "alice" {
+		t.Fatalf("expected user name alice, got %v", req.UserName)
+	}
+
+	// Чужой userId не трогаем
+	req = entity.CreateUserBehaviorRequest{UserID: &otherUserID}
+	fillExtensionUserName(newContext(), &req)
+	if *req.UserID != otherUserID || req.UserName != nil {
+		t.Fatalf("expected foreign event untouched, got %v %v", req.UserID, req.UserName)
+	}
+
+	// Без пользователя расширения событие остается как есть
+	req = entity.CreateUserBehaviorRequest{}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	fillExtensionUserName(c, &req)
+	if req.UserID != nil || req.UserName != nil {
+		t.Fatalf("expected event untouched without extension user, got %v %v", req.UserID, req.UserName)
+	}
+}
+
+func TestGetBehaviorsPeriodInTimezone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("failed to load Asia/Tokyo: %v", err)
+	}
+
+	// 2025-03-31 20:30 UTC - в Токио уже 1 апреля
+	stub := &stubBehaviorService{}
+	router := newPeriodTestRouter(stub, time.Date(2025, 3, 31, 20, 30, 0, 0, time.UTC))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/behaviors?period=today&tz=Asia/Tokyo", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if stub.filter == nil || stub.filter.StartTime == nil || stub.filter.EndTime == nil {
+		t.Fatalf("expected filter with time range, got %+v", stub.filter)
+	}
+
+	wantStart := time.Date(2025, 4, 1, 0, 0, 0, 0, tokyo)
+	wantEnd := time.Date(2025, 4, 2, 0, 0, 0, 0, tokyo).Add(-time.Nanosecond)
+	if !stub.filter.StartTime.Equal(wantStart) || !stub.filter.EndTime.Equal(wantEnd) {
+		t.Errorf("expected range %s - %s, got %s - %s", wantStart, wantEnd, stub.filter.StartTime, stub.filter.EndTime)
+	}
+}
+
+func TestInvalidTimezone(t *testing.T) {
+	paths := []string{
+		"/behaviors?period=today&tz=Mars/Olympus",
+		"/behaviors/periods?tz=Mars/Olympus",
+	}
+
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			stub := &stubBehaviorService{}
+			router := newPeriodTestRouter(stub, time.Now())
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d: %s", recorder.Code, recorder.Body.String())
+			}
+
+			var body struct {
+				Message string `json:"message"`
+			}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode response %q: %v", recorder.Body.String(), err)
+			}
+			if body.Message != "Invalid timezone 'Mars/Olympus'" {
+				t.Errorf("unexpected message %q", body.Message)
+			}
+			if stub.filter != nil {
+				t.Errorf("service should not be called on invalid timezone")
+			}
+		})
+	}
+}
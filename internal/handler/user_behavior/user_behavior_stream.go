@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/dinerozz/web-behavior-backend/internal/model/response/wrapper"
+	service "github.com/dinerozz/web-behavior-backend/internal/service/user_behavior"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// Интервал ping-фреймов, чтобы прокси не закрывали простаивающее соединение
+const sessionStreamHeartbeatInterval = 30 * time.Second
+
+// Буфер сообщений одного подписчика: медленный клиент теряет сообщения сверх буфера,
+// а не задерживает чтение подписки
+const sessionStreamBufferSize = 256
+
+var errSessionStreamOrigin = errors.New("websocket origin is not allowed")
+
+// StreamSessionEvents godoc
+// @Summary      Stream session events
+// @Description  WebSocket stream of new behavior events for a session. Each frame is a JSON-encoded entity.UserBehavior
+// @Tags         /api/v1/admin/behaviors
+// @Param        sessionId  path      string  true  "Session ID"
+// @Success      101        {string}  string  "Switching Protocols"
+// @Failure      400        {object}  wrapper.ErrorWrapper
+// @Failure      403        {string}  string  "Origin is not in the CORS allow-list"
+// @Failure      503        {object}  wrapper.ErrorWrapper
+// @Router       /behaviors/sessions/{sessionId}/stream [get]
+func (h *UserBehaviorHandler) StreamSessionEvents(c *gin.Context) {
+	sessionID := c.Param("sessionId")
+	if sessionID == "" {
+		c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{
+			Message: "Session ID is required",
+		})
+		return
+	}
+
+	if h.redisService == nil {
+		c.JSON(http.StatusServiceUnavailable, wrapper.ErrorWrapper{
+			Message: "Session streaming is unavailable",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	server := websocket.Server{
+		// Cookie авторизация уходит с любого сайта, поэтому браузерный Origin должен быть
+		// из списка CORS, иначе чужая страница прочитает стрим (cross-site WebSocket hijacking).
+		// Запросы без Origin приходят не из браузера и cookie жертвы не несут
+		Handshake: func(_ *websocket.Config, req *http.Request) error {
+			origin := req.Header.Get("Origin")
+			if origin == "" || (h.allowOrigin != nil && h.allowOrigin(origin)) {
+				return nil
+			}
+			return errSessionStreamOrigin
+		},
+		Handler: func(ws *websocket.Conn) {
+			h.streamSessionEvents(ctx, ws, sessionID)
+		},
+	}
+
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+func (h *UserBehaviorHandler) streamSessionEvents(ctx context.Context, ws *websocket.Conn, sessionID string) {
+	defer ws.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pubsub := h.redisService.Subscribe(ctx, service.SessionEventsChannel(sessionID))
+	defer pubsub.Close()
+
+	// Входящие сообщения не нужны, читаем только чтобы заметить отключение клиента
+	go func() {
+		defer cancel()
+
+		var discard []byte
+		for {
+			if err := websocket.Message.Receive(ws, &discard); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Подписка читается отдельно от записи в сокет: отправка в буфер не блокируется
+	messages := make(chan string, sessionStreamBufferSize)
+	go func() {
+		defer cancel()
+
+		for msg := range pubsub.Channel() {
+			select {
+			case messages <- msg.Payload:
+			default:
+				// клиент не успевает читать, сообщение пропускается
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(sessionStreamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case payload := <-messages:
+			if err := websocket.Message.Send(ws, payload); err != nil {
+				return
+			}
+
+		case <-heartbeat.C:
+			// Message.Send сам выставляет тип фрейма, поэтому PayloadType можно не восстанавливать
+			ws.PayloadType = websocket.PingFrame
+			if _, err := ws.Write(nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -14,3 +14,18 @@ type AddUserToOrganization struct {
 	UserID string `json:"user_id" binding:"required"`
 	Role   string `json:"role" binding:"required" validate:"oneof=admin member viewer"`
 }
+
+type UpdateOrganizationActiveEvents struct {
+	ActiveEvents []string `json:"active_events"` // пустой список - сброс на набор по умолчанию
+}
+
+type CreateOrganizationInvite struct {
+	Email string `json:"email" binding:"required,email"`
+	// Username приглашенного: принять приглашение может только этот пользователь
+	Username string `json:"username" binding:"required"`
+	Role     string `json:"role" binding:"required" validate:"oneof=admin member viewer"`
+}
+
+type AcceptOrganizationInvite struct {
+	Token string `json:"token" binding:"required"`
+}
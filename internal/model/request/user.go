@@ -4,3 +4,7 @@ type CreateUserWithPassword struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
+
+type UpdateSuperAdmin struct {
+	IsSuperAdmin *bool `json:"is_super_admin" binding:"required"`
+}
@@ -11,6 +11,8 @@ type Organization struct {
 	Description *string   `json:"description" db:"description"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	// Число участников (user_organization_access), заполняется только в списках организаций
+	MemberCount *int `json:"member_count,omitempty" db:"member_count"`
 }
 
 type OrganizationWithMembers struct {
@@ -27,6 +29,8 @@ type OrganizationMember struct {
 	Username string    `json:"username" db:"username"`
 	Role     string    `json:"role" db:"role"`
 	JoinedAt time.Time `json:"joined_at" db:"created_at"`
+	// Последнее использование привязанных расширений (extension_users.owner_user_id), null без привязки
+	LastActiveAt *time.Time `json:"last_active_at" db:"last_active_at"`
 }
 
 type UserOrganizations struct {
@@ -40,4 +44,37 @@ type UserOrgAccess struct {
 	Description *string   `json:"description" db:"description"`
 	Role        string    `json:"role" db:"role"`
 	JoinedAt    time.Time `json:"joined_at" db:"created_at"`
+	MemberCount int       `json:"member_count" db:"member_count"`
+}
+
+type OrganizationActiveEvents struct {
+	OrganizationID uuid.UUID `json:"organization_id"`
+	ActiveEvents   []string  `json:"active_events"`
+	IsDefault      bool      `json:"is_default"`
+}
+
+type OrganizationInvite struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id" db:"organization_id"`
+	Email          string     `json:"email" db:"email"`
+	Username       string     `json:"username" db:"username"`
+	Role           string     `json:"role" db:"role"`
+	InvitedBy      *uuid.UUID `json:"invited_by" db:"invited_by"`
+	ExpiresAt      time.Time  `json:"expires_at" db:"expires_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	// Токен возвращается только при создании приглашения, в БД хранится его хэш
+	Token string `json:"token,omitempty" db:"-"`
+}
+
+type OrgAuditEntry struct {
+	ID             uuid.UUID `json:"id" db:"id"`
+	OrganizationID uuid.UUID `json:"organization_id" db:"organization_id"`
+	ActorID        uuid.UUID `json:"actor_id" db:"actor_id"`
+	ActorUsername  *string   `json:"actor_username" db:"actor_username"`
+	TargetUserID   uuid.UUID `json:"target_user_id" db:"target_user_id"`
+	TargetUsername *string   `json:"target_username" db:"target_username"`
+	Action         string    `json:"action" db:"action"` // member_added, member_removed, role_changed, invite_accepted
+	OldRole        *string   `json:"old_role" db:"old_role"`
+	NewRole        *string   `json:"new_role" db:"new_role"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
 }
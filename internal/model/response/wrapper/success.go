@@ -19,3 +19,9 @@ type SuccessWrapper struct {
 	Message string `json:"message"`
 	Success bool   `json:"success"`
 }
+
+type CursorPaginatedResponseWrapper struct {
+	Data    interface{}                 `json:"data"`
+	Meta    entity.CursorPaginationInfo `json:"meta"`
+	Success bool                        `json:"success"`
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/dinerozz/web-behavior-backend/pkg/telemetry"
+	"github.com/jmoiron/sqlx"
+)
+
+type DailyEngagementRepository interface {
+	RollupDay(ctx context.Context, day time.Time) (int64, error)
+	GetRange(ctx context.Context, userID string, startDay, endDay time.Time) ([]entity.DailyEngagement, error)
+	ComputeRaw(ctx context.Context, userID string, start, end time.Time, activeEvents []string) (activeMinutes int, trackedMinutes int, err error)
+}
+
+type dailyEngagementRepository struct {
+	db *sqlx.DB
+	// Чтения для /metrics/engaged-time-daily ограничены лимитом запросов метрик; пересчет (RollupDay) - нет
+	reads *queryTimeoutDB
+}
+
+// queryTimeout - лимит каждого запроса чтения (0 - без лимита)
+func NewDailyEngagementRepository(db *sqlx.DB, queryTimeout time.Duration) DailyEngagementRepository {
+	return &dailyEngagementRepository{db: db, reads: newQueryTimeoutDB(db, queryTimeout)}
+}
+
+// Пересчет одного дня для всех пользователей. Набор активных событий берется из организации
+// пользователя расширения, иначе ActiveEvents по умолчанию; минута с idle событием всегда неактивна
+// (см. IdleEventType). Повторный запуск перезаписывает строки
+const rollupDailyEngagementQuery = `
+INSERT INTO daily_engagement (user_id, day, active_minutes, tracked_minutes, updated_at)
+SELECT user_id, $4::date, SUM(is_active), COUNT(*), CURRENT_TIMESTAMP
+FROM (
+    SELECT
+        ub.user_id,
+        DATE_TRUNC('minute', ub.timestamp) AS minute,
+        CASE
+            WHEN COUNT(*) FILTER (WHERE ub.event_type = 'idle') > 0 THEN 0
+            ELSE MAX(CASE WHEN ub.event_type = ANY(COALESCE(o.active_events, $3::text[])) THEN 1 ELSE 0 END)
+        END AS is_active
+    FROM user_behaviors ub
+    LEFT JOIN extension_users eu ON eu.id = ub.user_id
+    LEFT JOIN organizations o ON o.id = eu.organization_id
+    WHERE ub.deleted_at IS NULL
+        AND ub.user_id IS NOT NULL
+        AND ub.timestamp >= $1
+        AND ub.timestamp < $2
+    GROUP BY ub.user_id, DATE_TRUNC('minute', ub.timestamp)
+) minute_activity
+GROUP BY user_id
+ON CONFLICT (user_id, day) DO UPDATE SET
+    active_minutes = EXCLUDED.active_minutes,
+    tracked_minutes = EXCLUDED.tracked_minutes,
+    updated_at = EXCLUDED.updated_at`
+
+// Активные/отслеживаемые минуты по сырым событиям для неполных дней, интервал [start, end)
+const rawEngagementQuery = `
+SELECT 
+    COALESCE(SUM(is_active), 0)::integer as active_minutes,
+    COUNT(*)::integer as tracked_minutes
+FROM (
+    SELECT
+        DATE_TRUNC('minute', timestamp) AS minute,
+        CASE
+            WHEN COUNT(*) FILTER (WHERE event_type = 'idle') > 0 THEN 0
+            ELSE MAX(CASE WHEN event_type = ANY($4::text[]) THEN 1 ELSE 0 END)
+        END AS is_active
+    FROM user_behaviors 
+    WHERE user_id = $1 AND deleted_at IS NULL 
+        AND timestamp >= $2 
+        AND timestamp < $3
+    GROUP BY DATE_TRUNC('minute', timestamp)
+) minute_activity`
+
+// RollupDay пересчитывает день (UTC), в который попадает day, и возвращает количество upsert-нутых строк
+func (r *dailyEngagementRepository) RollupDay(ctx context.Context, day time.Time) (int64, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "daily_engagement_rollup")
+
+	dayStart := day.UTC().Truncate(24 * time.Hour)
+	dayEnd := dayStart.Add(24 * time.Hour)
+
+	result, err := r.db.ExecContext(ctx, rollupDailyEngagementQuery,
+		dayStart, dayEnd, activeEventsArg(nil), dayStart.Format("2006-01-02"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to rollup daily engagement for %s: %w", dayStart.Format("2006-01-02"), err)
+	}
+
+	return result.RowsAffected()
+}
+
+// GetRange возвращает строки rollup за дни [startDay, endDay)
+func (r *dailyEngagementRepository) GetRange(ctx context.Context, userID string, startDay, endDay time.Time) ([]entity.DailyEngagement, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "daily_engagement_range")
+
+	query := `
+		SELECT user_id, day, active_minutes, tracked_minutes, updated_at
+		FROM daily_engagement
+		WHERE user_id = $1 AND day >= $2::date AND day < $3::date
+		ORDER BY day`
+
+	var rows []entity.DailyEngagement
+	err := r.reads.SelectContext(ctx, &rows, query, userID, startDay.Format("2006-01-02"), endDay.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily engagement: %w", err)
+	}
+
+	return rows, nil
+}
+
+func (r *dailyEngagementRepository) ComputeRaw(ctx context.Context, userID string, start, end time.Time, activeEvents []string) (int, int, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "daily_engagement_raw")
+
+	var result struct {
+		ActiveMinutes  int `db:"active_minutes"`
+		TrackedMinutes int `db:"tracked_minutes"`
+	}
+
+	err := r.reads.GetContext(ctx, &result, rawEngagementQuery, userID, start, end, activeEventsArg(activeEvents))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to compute raw engagement: %w", err)
+	}
+
+	return result.ActiveMinutes, result.TrackedMinutes, nil
+}
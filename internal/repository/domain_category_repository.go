@@ -0,0 +1,110 @@
+// internal/repository/domain_category_repository.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type DomainCategoryRepository interface {
+	GetAll(ctx context.Context) ([]entity.DomainCategory, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.DomainCategory, error)
+	ExistsByDomain(ctx context.Context, domain string, excludeID *uuid.UUID) (bool, error)
+	Create(ctx context.Context, category *entity.DomainCategory) error
+	Update(ctx context.Context, category *entity.DomainCategory) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type domainCategoryRepository struct {
+	db *sqlx.DB
+}
+
+func NewDomainCategoryRepository(db *sqlx.DB) DomainCategoryRepository {
+	return &domainCategoryRepository{db: db}
+}
+
+func (r *domainCategoryRepository) GetAll(ctx context.Context) ([]entity.DomainCategory, error) {
+	query := `SELECT id, domain, category, created_at, updated_at FROM domain_categories ORDER BY domain`
+
+	categories := []entity.DomainCategory{}
+	if err := r.db.SelectContext(ctx, &categories, query); err != nil {
+		return nil, fmt.Errorf("failed to get domain categories: %w", err)
+	}
+
+	return categories, nil
+}
+
+func (r *domainCategoryRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.DomainCategory, error) {
+	query := `SELECT id, domain, category, created_at, updated_at FROM domain_categories WHERE id = $1`
+
+	var category entity.DomainCategory
+	if err := r.db.GetContext(ctx, &category, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get domain category: %w", err)
+	}
+
+	return &category, nil
+}
+
+func (r *domainCategoryRepository) ExistsByDomain(ctx context.Context, domain string, excludeID *uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM domain_categories WHERE domain = $1 AND ($2::uuid IS NULL OR id <> $2))`
+
+	var exists bool
+	if err := r.db.GetContext(ctx, &exists, query, domain, excludeID); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (r *domainCategoryRepository) Create(ctx context.Context, category *entity.DomainCategory) error {
+	query := `
+		INSERT INTO domain_categories (id, domain, category)
+		VALUES ($1, $2, $3)
+		RETURNING created_at, updated_at`
+
+	return r.db.QueryRowContext(ctx, query, category.ID, category.Domain, category.Category).
+		Scan(&category.CreatedAt, &category.UpdatedAt)
+}
+
+func (r *domainCategoryRepository) Update(ctx context.Context, category *entity.DomainCategory) error {
+	query := `
+		UPDATE domain_categories
+		SET domain = $2, category = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING created_at, updated_at`
+
+	err := r.db.QueryRowContext(ctx, query, category.ID, category.Domain, category.Category).
+		Scan(&category.CreatedAt, &category.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sql.ErrNoRows
+	}
+
+	return err
+}
+
+func (r *domainCategoryRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM domain_categories WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
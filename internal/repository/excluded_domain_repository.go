@@ -0,0 +1,95 @@
+// internal/repository/excluded_domain_repository.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type ExcludedDomainRepository interface {
+	GetAll(ctx context.Context) ([]entity.ExcludedDomain, error)
+	ExistsByDomain(ctx context.Context, domain string, excludeID *uuid.UUID) (bool, error)
+	Create(ctx context.Context, excluded *entity.ExcludedDomain) error
+	Update(ctx context.Context, excluded *entity.ExcludedDomain) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type excludedDomainRepository struct {
+	db *sqlx.DB
+}
+
+func NewExcludedDomainRepository(db *sqlx.DB) ExcludedDomainRepository {
+	return &excludedDomainRepository{db: db}
+}
+
+func (r *excludedDomainRepository) GetAll(ctx context.Context) ([]entity.ExcludedDomain, error) {
+	query := `SELECT id, domain, created_at, updated_at FROM excluded_domains ORDER BY domain`
+
+	domains := []entity.ExcludedDomain{}
+	if err := r.db.SelectContext(ctx, &domains, query); err != nil {
+		return nil, fmt.Errorf("failed to get excluded domains: %w", err)
+	}
+
+	return domains, nil
+}
+
+func (r *excludedDomainRepository) ExistsByDomain(ctx context.Context, domain string, excludeID *uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM excluded_domains WHERE domain = $1 AND ($2::uuid IS NULL OR id <> $2))`
+
+	var exists bool
+	if err := r.db.GetContext(ctx, &exists, query, domain, excludeID); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (r *excludedDomainRepository) Create(ctx context.Context, excluded *entity.ExcludedDomain) error {
+	query := `
+		INSERT INTO excluded_domains (id, domain)
+		VALUES ($1, $2)
+		RETURNING created_at, updated_at`
+
+	return r.db.QueryRowContext(ctx, query, excluded.ID, excluded.Domain).
+		Scan(&excluded.CreatedAt, &excluded.UpdatedAt)
+}
+
+func (r *excludedDomainRepository) Update(ctx context.Context, excluded *entity.ExcludedDomain) error {
+	query := `
+		UPDATE excluded_domains
+		SET domain = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING created_at, updated_at`
+
+	err := r.db.QueryRowContext(ctx, query, excluded.ID, excluded.Domain).
+		Scan(&excluded.CreatedAt, &excluded.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sql.ErrNoRows
+	}
+
+	return err
+}
+
+func (r *excludedDomainRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM excluded_domains WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
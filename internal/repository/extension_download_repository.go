@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/jmoiron/sqlx"
+)
+
+type ExtensionDownloadRepository struct {
+	db *sqlx.DB
+}
+
+func NewExtensionDownloadRepository(db *sqlx.DB) *ExtensionDownloadRepository {
+	return &ExtensionDownloadRepository{db: db}
+}
+
+// RecordDownload фиксирует одну загрузку расширения
+func (r *ExtensionDownloadRepository) RecordDownload(ctx context.Context, userAgent *string) error {
+	query := `INSERT INTO extension_downloads (user_agent) VALUES ($1)`
+
+	if _, err := r.db.ExecContext(ctx, query, userAgent); err != nil {
+		return fmt.Errorf("failed to record extension download: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ExtensionDownloadRepository) GetDownloadStats(ctx context.Context) (*entity.ExtensionDownloadStats, error) {
+	query := `
+		SELECT 
+			COUNT(*) as total_downloads,
+			MAX(downloaded_at) as last_download
+		FROM extension_downloads`
+
+	var stats entity.ExtensionDownloadStats
+	if err := r.db.GetContext(ctx, &stats, query); err != nil {
+		return nil, fmt.Errorf("failed to get extension download stats: %w", err)
+	}
+
+	return &stats, nil
+}
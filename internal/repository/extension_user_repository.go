@@ -4,6 +4,7 @@ package repository
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
 	"errors"
@@ -24,6 +25,7 @@ type ExtensionUserRepository interface {
 	Update(ctx context.Context, id uuid.UUID, req entity.UpdateExtensionUserRequest) (*entity.ExtensionUser, error)
 	RegenerateAPIKey(ctx context.Context, id uuid.UUID) (string, error)
 	Delete(ctx context.Context, id uuid.UUID) error
+	DeactivateStaleUsers(ctx context.Context, olderThan time.Duration) ([]uuid.UUID, error)
 	UpdateLastUsed(ctx context.Context, apiKey string) error
 	GetStats(ctx context.Context) (*entity.ExtensionUserStats, error)
 	IsAPIKeyValid(ctx context.Context, apiKey string) bool
@@ -41,17 +43,26 @@ func NewExtensionUserRepository(db *sqlx.DB) ExtensionUserRepository {
 	return &extensionUserRepository{db: db}
 }
 
+// HashAPIKey возвращает SHA-256 хэш ключа; в БД хранится только он
+func HashAPIKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
 func (r *extensionUserRepository) Create(ctx context.Context, user *entity.ExtensionUser) error {
+	user.APIKeyHash = HashAPIKey(user.APIKey)
+
 	query := `
-		INSERT INTO extension_users (id, username, api_key, is_active, organization_id, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO extension_users (id, username, api_key, is_active, organization_id, owner_user_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`
 	_, err := r.db.ExecContext(ctx, query,
 		user.ID,
 		user.Username,
-		user.APIKey,
+		user.APIKeyHash,
 		user.IsActive,
 		user.OrganizationID,
+		user.OwnerUserID,
 		user.CreatedAt,
 		user.UpdatedAt,
 	)
@@ -62,7 +73,7 @@ func (r *extensionUserRepository) GetByID(ctx context.Context, id uuid.UUID) (*e
 	query := `
        SELECT 
           eu.id, eu.username, eu.is_active, eu.created_at, eu.updated_at, 
-          eu.last_used_at, eu.organization_id, eu.api_key,
+          eu.last_used_at, eu.organization_id, eu.api_key, eu.owner_user_id,
           o.id as org_id, o.name as organization_name
        FROM extension_users eu
        LEFT JOIN organizations o ON eu.organization_id = o.id
@@ -75,7 +86,7 @@ func (r *extensionUserRepository) GetByID(ctx context.Context, id uuid.UUID) (*e
 	var organizationID sql.NullString
 	var orgID sql.NullString
 	var orgName sql.NullString
-	var apiKey string
+	var apiKeyHash string
 
 	err := row.Scan(
 		&user.ID,
@@ -85,7 +96,8 @@ func (r *extensionUserRepository) GetByID(ctx context.Context, id uuid.UUID) (*e
 		&user.UpdatedAt,
 		&user.LastUsedAt,
 		&organizationID,
-		&apiKey,
+		&apiKeyHash,
+		&user.OwnerUserID,
 		&orgID,
 		&orgName,
 	)
@@ -96,7 +108,7 @@ func (r *extensionUserRepository) GetByID(ctx context.Context, id uuid.UUID) (*e
 		return nil, fmt.Errorf("failed to scan extension user: %w", err)
 	}
 
-	user.APIKey = apiKey
+	user.APIKeyHash = apiKeyHash
 	user.Organization = &entity.OrganizationInfo{
 		ID:   nil,
 		Name: "",
@@ -117,7 +129,7 @@ func (r *extensionUserRepository) GetByAPIKey(ctx context.Context, apiKey string
 	var user entity.ExtensionUser
 	query := `SELECT * FROM extension_users WHERE api_key = $1 AND is_active = true`
 
-	err := r.db.GetContext(ctx, &user, query, apiKey)
+	err := r.db.GetContext(ctx, &user, query, HashAPIKey(apiKey))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -147,32 +159,15 @@ func (r *extensionUserRepository) GetAll(ctx context.Context, filter entity.Exte
 	var users []entity.ExtensionUser
 
 	query := `
-		SELECT id, username, api_key, is_active, created_at, updated_at, last_used_at, organization_id
+		SELECT id, username, api_key, is_active, created_at, updated_at, last_used_at, organization_id, owner_user_id
 		FROM extension_users 
 		WHERE 1=1
 	`
-	args := []interface{}{}
-	argIndex := 1
-
-	if filter.Username != "" {
-		query += fmt.Sprintf(" AND username ILIKE $%d", argIndex)
-		args = append(args, "%"+filter.Username+"%")
-		argIndex++
-	}
-
-	if filter.IsActive != nil {
-		query += fmt.Sprintf(" AND is_active = $%d", argIndex)
-		args = append(args, *filter.IsActive)
-		argIndex++
-	}
-
-	if filter.OrganizationID != nil {
-		query += fmt.Sprintf(" AND organization_id = $%d", argIndex)
-		args = append(args, *filter.OrganizationID)
-		argIndex++
-	}
+	conditions, args := buildExtensionUserConditions(filter, "")
+	query += conditions
+	argIndex := len(args) + 1
 
-	query += " ORDER BY created_at DESC"
+	query += buildExtensionUserOrderBy(filter, "")
 
 	if filter.Page > 0 && filter.PerPage > 0 {
 		offset := (filter.Page - 1) * filter.PerPage
@@ -198,38 +193,74 @@ func (r *extensionUserRepository) GetAll(ctx context.Context, filter entity.Exte
 	return users, nil
 }
 
-func (r *extensionUserRepository) GetAllWithOrganization(ctx context.Context, filter entity.ExtensionUserFilter) ([]entity.ExtensionUserPublic, error) {
-	query := `
-       SELECT 
-          eu.id, eu.username, eu.is_active, eu.created_at, eu.updated_at, 
-          eu.last_used_at, eu.organization_id,
-          o.id as org_id, o.name as organization_name
-       FROM extension_users eu
-       LEFT JOIN organizations o ON eu.organization_id = o.id
-       WHERE 1=1
-    `
+// buildExtensionUserConditions собирает общие условия фильтра; prefix - алиас таблицы ("eu." или "")
+func buildExtensionUserConditions(filter entity.ExtensionUserFilter, prefix string) (string, []interface{}) {
+	var conditions strings.Builder
 	args := []interface{}{}
 	argIndex := 1
 
 	if filter.Username != "" {
-		query += fmt.Sprintf(" AND eu.username ILIKE $%d", argIndex)
+		conditions.WriteString(fmt.Sprintf(" AND %susername ILIKE $%d", prefix, argIndex))
 		args = append(args, "%"+filter.Username+"%")
 		argIndex++
 	}
 
 	if filter.IsActive != nil {
-		query += fmt.Sprintf(" AND eu.is_active = $%d", argIndex)
+		conditions.WriteString(fmt.Sprintf(" AND %sis_active = $%d", prefix, argIndex))
 		args = append(args, *filter.IsActive)
 		argIndex++
 	}
 
 	if filter.OrganizationID != nil {
-		query += fmt.Sprintf(" AND eu.organization_id = $%d", argIndex)
+		conditions.WriteString(fmt.Sprintf(" AND %sorganization_id = $%d", prefix, argIndex))
 		args = append(args, *filter.OrganizationID)
 		argIndex++
 	}
 
-	query += " ORDER BY eu.created_at DESC"
+	if filter.LastUsedBefore != nil {
+		conditions.WriteString(fmt.Sprintf(" AND %slast_used_at < $%d", prefix, argIndex))
+		args = append(args, *filter.LastUsedBefore)
+		argIndex++
+	}
+
+	if filter.LastUsedAfter != nil {
+		conditions.WriteString(fmt.Sprintf(" AND %slast_used_at > $%d", prefix, argIndex))
+		args = append(args, *filter.LastUsedAfter)
+	}
+
+	return conditions.String(), args
+}
+
+// buildExtensionUserOrderBy подставляет только колонки из белого списка entity.ExtensionUserSortFields
+func buildExtensionUserOrderBy(filter entity.ExtensionUserFilter, prefix string) string {
+	sortBy := "created_at"
+	if entity.ExtensionUserSortFields[filter.SortBy] {
+		sortBy = filter.SortBy
+	}
+
+	order := "DESC"
+	if strings.EqualFold(filter.Order, "asc") {
+		order = "ASC"
+	}
+
+	return fmt.Sprintf(" ORDER BY %s%s %s NULLS LAST, %sid", prefix, sortBy, order, prefix)
+}
+
+func (r *extensionUserRepository) GetAllWithOrganization(ctx context.Context, filter entity.ExtensionUserFilter) ([]entity.ExtensionUserPublic, error) {
+	query := `
+       SELECT 
+          eu.id, eu.username, eu.is_active, eu.created_at, eu.updated_at, 
+          eu.last_used_at, eu.organization_id,
+          o.id as org_id, o.name as organization_name
+       FROM extension_users eu
+       LEFT JOIN organizations o ON eu.organization_id = o.id
+       WHERE 1=1
+    `
+	conditions, args := buildExtensionUserConditions(filter, "eu.")
+	query += conditions
+	argIndex := len(args) + 1
+
+	query += buildExtensionUserOrderBy(filter, "eu.")
 
 	if filter.Page > 0 && filter.PerPage > 0 {
 		offset := (filter.Page - 1) * filter.PerPage
@@ -295,21 +326,8 @@ func (r *extensionUserRepository) GetAllWithOrganization(ctx context.Context, fi
 }
 
 func (r *extensionUserRepository) CountByFilter(ctx context.Context, filter entity.ExtensionUserFilter) (int, error) {
-	query := "SELECT COUNT(*) FROM extension_users WHERE 1=1"
-	args := []interface{}{}
-	argIndex := 1
-
-	if filter.Username != "" {
-		query += fmt.Sprintf(" AND username ILIKE $%d", argIndex)
-		args = append(args, "%"+filter.Username+"%")
-		argIndex++
-	}
-
-	if filter.IsActive != nil {
-		query += fmt.Sprintf(" AND is_active = $%d", argIndex)
-		args = append(args, *filter.IsActive)
-		argIndex++
-	}
+	conditions, args := buildExtensionUserConditions(filter, "")
+	query := "SELECT COUNT(*) FROM extension_users WHERE 1=1" + conditions
 
 	var count int
 	err := r.db.GetContext(ctx, &count, query, args...)
@@ -342,8 +360,8 @@ func (r *extensionUserRepository) Update(ctx context.Context, id uuid.UUID, req
 
 	if req.APIKey != nil {
 		setParts = append(setParts, fmt.Sprintf("api_key = $%d", argIndex))
-		args = append(args, *req.APIKey)
-		existingUser.APIKey = *req.APIKey
+		existingUser.APIKeyHash = HashAPIKey(*req.APIKey)
+		args = append(args, existingUser.APIKeyHash)
 		argIndex++
 	}
 
@@ -354,6 +372,13 @@ func (r *extensionUserRepository) Update(ctx context.Context, id uuid.UUID, req
 		argIndex++
 	}
 
+	if req.OwnerUserID.Set {
+		setParts = append(setParts, fmt.Sprintf("owner_user_id = $%d", argIndex))
+		args = append(args, req.OwnerUserID.Value)
+		existingUser.OwnerUserID = req.OwnerUserID.Value
+		argIndex++
+	}
+
 	query := fmt.Sprintf("UPDATE extension_users SET %s WHERE id = $%d",
 		strings.Join(setParts, ", "), argIndex)
 	args = append(args, id)
@@ -387,7 +412,7 @@ func (r *extensionUserRepository) RegenerateAPIKey(ctx context.Context, id uuid.
 		SET api_key = $1, updated_at = CURRENT_TIMESTAMP
 		WHERE id = $2 AND is_active = true`
 
-	result, err := r.db.ExecContext(ctx, query, newAPIKey, id)
+	result, err := r.db.ExecContext(ctx, query, HashAPIKey(newAPIKey), id)
 	if err != nil {
 		return "", fmt.Errorf("failed to update API key: %w", err)
 	}
@@ -424,13 +449,30 @@ func (r *extensionUserRepository) Delete(ctx context.Context, id uuid.UUID) erro
 	return nil
 }
 
+// DeactivateStaleUsers деактивирует пользователей, не использовавших ключ дольше olderThan
+func (r *extensionUserRepository) DeactivateStaleUsers(ctx context.Context, olderThan time.Duration) ([]uuid.UUID, error) {
+	query := `
+		UPDATE extension_users
+		SET is_active = false, updated_at = CURRENT_TIMESTAMP
+		WHERE last_used_at < $1 AND is_active = true
+		RETURNING id`
+
+	ids := []uuid.UUID{}
+	err := r.db.SelectContext(ctx, &ids, query, time.Now().Add(-olderThan))
+	if err != nil {
+		return nil, fmt.Errorf("failed to deactivate stale extension users: %w", err)
+	}
+
+	return ids, nil
+}
+
 func (r *extensionUserRepository) UpdateLastUsed(ctx context.Context, apiKey string) error {
 	query := `
 		UPDATE extension_users 
 		SET last_used_at = CURRENT_TIMESTAMP
 		WHERE api_key = $1 AND is_active = true`
 
-	_, err := r.db.ExecContext(ctx, query, apiKey)
+	_, err := r.db.ExecContext(ctx, query, HashAPIKey(apiKey))
 	if err != nil {
 		return fmt.Errorf("failed to update last used: %w", err)
 	}
@@ -494,7 +536,7 @@ func (r *extensionUserRepository) IsAPIKeyValid(ctx context.Context, apiKey stri
 	var count int
 	query := `SELECT COUNT(*) FROM extension_users WHERE api_key = $1 AND is_active = true`
 
-	err := r.db.QueryRowContext(ctx, query, apiKey).Scan(&count)
+	err := r.db.QueryRowContext(ctx, query, HashAPIKey(apiKey)).Scan(&count)
 	if err != nil {
 		return false
 	}
@@ -512,7 +554,7 @@ func (r *extensionUserRepository) GenerateAPIKey() (string, error) {
 
 	var count int
 	query := `SELECT COUNT(*) FROM extension_users WHERE api_key = $1`
-	err := r.db.QueryRow(query, apiKey).Scan(&count)
+	err := r.db.QueryRow(query, HashAPIKey(apiKey)).Scan(&count)
 	if err != nil {
 		return "", err
 	}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/dinerozz/web-behavior-backend/pkg/telemetry"
 	"github.com/dinerozz/web-behavior-backend/pkg/utils"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
@@ -18,21 +19,59 @@ var ActiveEvents = []string{
 	"scrollend", "pagehide", "visibility_visible",
 }
 
+// IdleEventType - явная отметка простоя от расширения. Приоритет выше активных событий: минута
+// с idle событием всегда idle (во всех доменах этой минуты), даже если в ней есть активные события
+// или idle добавлен в active_events организации. Активные события такой минуты продолжают
+// учитываться в active_events, но не дают активных минут. Правило действует и в лидерборде,
+// и в deep work (события idle минуты не входят в блоки). Значение вписано в SQL запросов как 'idle'
+const IdleEventType = "idle"
+
 const (
 	DeepWorkMinDurationMinutes  = 25  // Минимальная длительность Deep Work блока (25 минут)
 	ActivityGapThresholdSeconds = 300 // Максимальный разрыв между событиями (5 минут)
 	MinEventsPerBlock           = 10  // Минимальное количество событий в блоке
-
-	// Пороги для определения уровня фокуса (переключения контекста в час)
-	HighFocusThreshold   = 5  // <= 5 переключений/час = высокий фокус
-	MediumFocusThreshold = 15 // <= 15 переключений/час = средний фокус
-	// > 15 переключений/час = низкий фокус
 )
 
+// activeEventsArg возвращает параметр $4 для запросов: набор организации или ActiveEvents по умолчанию
+func activeEventsArg(activeEvents []string) interface{} {
+	if len(activeEvents) == 0 {
+		return pq.Array(ActiveEvents)
+	}
+	return pq.Array(activeEvents)
+}
+
+// engagedTimeArgs строит параметры $1-$4 и дополнительные условия engaged time запросов:
+// фильтр по сессии и исключение доменов из списка excluded_domains
+func engagedTimeArgs(filter entity.EngagedTimeFilter) (string, []interface{}) {
+	conditions := ""
+	args := []interface{}{filter.UserID, filter.StartTime, filter.EndTime, activeEventsArg(filter.ActiveEvents)}
+
+	if filter.SessionID != nil {
+		args = append(args, *filter.SessionID)
+		conditions = fmt.Sprintf(" AND session_id = $%d", len(args))
+	}
+
+	condition, args := excludedDomainsCondition(filter.ExcludedDomains, args)
+
+	return conditions + condition, args
+}
+
+// excludedDomainsCondition добавляет в args список исключенных доменов и возвращает условие для него;
+// пустой список - без условия
+func excludedDomainsCondition(excluded []string, args []interface{}) (string, []interface{}) {
+	if len(excluded) == 0 {
+		return "", args
+	}
+
+	args = append(args, pq.Array(excluded))
+	return fmt.Sprintf(" AND COALESCE(domain, '') <> ALL($%d::text[])", len(args)), args
+}
+
 // Структуры результатов запросов
 type engagedTimeResult struct {
 	ActiveMinutes       int            `db:"active_minutes"`
 	ActiveEventsCount   int            `db:"active_events_count"`
+	IdleEventsCount     int            `db:"idle_events_count"`
 	TotalTrackedMinutes int            `db:"total_tracked_minutes"`
 	IdleMinutes         int            `db:"idle_minutes"`
 	SessionsCount       int            `db:"sessions_count"`
@@ -63,6 +102,71 @@ type deepWorkStatsResult struct {
 	TotalDeepMinutes  float64 `db:"total_deep_minutes"`
 	AvgDeepMinutes    float64 `db:"avg_deep_minutes"`
 	MaxDeepMinutes    float64 `db:"max_deep_minutes"`
+
+	TotalContextSwitches int     `db:"total_context_switches"`
+	AvgSwitchesPerHour   float64 `db:"avg_switches_per_hour"`
+
+	HighFocusBlocks   int `db:"high_focus_blocks"`
+	MediumFocusBlocks int `db:"medium_focus_blocks"`
+	LowFocusBlocks    int `db:"low_focus_blocks"`
+}
+
+type sessionEngagementResult struct {
+	SessionID      string    `db:"session_id"`
+	SessionStart   time.Time `db:"session_start"`
+	SessionEnd     time.Time `db:"session_end"`
+	ActiveMinutes  int       `db:"active_minutes"`
+	TrackedMinutes int       `db:"tracked_minutes"`
+	UniqueDomains  int       `db:"unique_domains"`
+}
+
+type typingActivityResult struct {
+	Domain          string `db:"domain"`
+	TotalKeystrokes int    `db:"total_keystrokes"`
+	TypingMinutes   int    `db:"typing_minutes"`
+	PeakKeysPerMin  int    `db:"peak_keys_per_minute"`
+}
+
+type scrollActivityResult struct {
+	GroupKey          string `db:"group_key"`
+	ScrollEvents      int    `db:"scroll_events"`
+	InteractionEvents int    `db:"interaction_events"`
+	TotalEvents       int    `db:"total_events"`
+}
+
+type hourlyTabSwitchResult struct {
+	Hour     time.Time `db:"hour"`
+	Switches int       `db:"switches"`
+}
+
+type domainTabSwitchResult struct {
+	Domain   string `db:"domain"`
+	Switches int    `db:"switches"`
+}
+
+type idleIntervalResult struct {
+	Start           time.Time `db:"start"`
+	End             time.Time `db:"end"`
+	DurationSeconds float64   `db:"duration_seconds"`
+}
+
+type activityHeatmapResult struct {
+	DayOfWeek int `db:"day_of_week"`
+	Hour      int `db:"hour"`
+	Events    int `db:"events"`
+}
+
+type deepWorkBySessionResult struct {
+	BlockID         int       `db:"block_id"`
+	SessionID       string    `db:"session_id"`
+	SessionsSpanned int       `db:"sessions_spanned"`
+	StartTime       time.Time `db:"start_time"`
+	EndTime         time.Time `db:"end_time"`
+	DurationMinutes float64   `db:"duration_minutes"`
+	TotalEvents     int       `db:"total_events"`
+	ContextSwitches int       `db:"context_switches"`
+	SwitchesPerHour float64   `db:"switches_per_hour"`
+	FocusLevel      string    `db:"focus_level"`
 }
 
 type deepWorkSessionsResult struct {
@@ -86,48 +190,63 @@ type deepWorkSessionsResult struct {
 type UserMetricsRepository interface {
 	GetTrackedTime(ctx context.Context, filter entity.TrackedTimeFilter) (*entity.TrackedTimeMetric, error)
 	GetTrackedTimeTotal(ctx context.Context, filter entity.TrackedTimeFilter) (*entity.TrackedTimeMetric, error)
+	GetTrackedTimeTotalByUsers(ctx context.Context, filter entity.TrackedTimeTotalByUsersFilter) (map[string]*entity.TrackedTimeMetric, error)
 	GetEngagedTime(ctx context.Context, filter entity.EngagedTimeFilter) (*entity.EngagedTimeMetric, error)
 	GetTopDomains(ctx context.Context, filter entity.TopDomainsFilter) (*entity.TopDomainsResponse, error)
 	GetDeepWorkSessions(ctx context.Context, filter entity.DeepWorkSessionsFilter) (*entity.DeepWorkSessionsResponse, error)
+	GetDeepWorkBySession(ctx context.Context, filter entity.DeepWorkSessionsFilter) (*entity.DeepWorkBySessionResponse, error)
+	GetIdleIntervals(ctx context.Context, filter entity.EngagedTimeFilter) ([]entity.IdleInterval, error)
+	GetActivityHeatmap(ctx context.Context, filter entity.ActivityHeatmapFilter) (*entity.ActivityHeatmap, error)
+	GetDeepWorkBlockEvents(ctx context.Context, filter entity.DeepWorkSessionsFilter, blockID int) ([]entity.UserBehavior, error)
+	GetSessionEngagement(ctx context.Context, filter entity.SessionEngagementFilter) (*entity.SessionEngagementMetric, error)
+	GetTypingActivity(ctx context.Context, filter entity.TypingActivityFilter) (*entity.TypingActivity, error)
+	GetScrollActivity(ctx context.Context, filter entity.ScrollActivityFilter) (*entity.ScrollActivity, error)
+	GetTabSwitchStats(ctx context.Context, filter entity.TabSwitchFilter) (*entity.TabSwitchStats, error)
+	GetMinuteActivity(ctx context.Context, filter entity.EngagedTimeFilter) ([]entity.MinuteActivity, error)
+	GetClickHeatmap(ctx context.Context, userID, url string, gridSize int) (*entity.ClickHeatmap, error)
+	GetLeaderboard(ctx context.Context, filter entity.LeaderboardFilter) ([]entity.LeaderboardEntry, error)
 }
 
 type metricsRepository struct {
-	db *sqlx.DB
+	// Каждый запрос ограничен METRICS_QUERY_TIMEOUT_SECONDS (см. queryTimeoutDB)
+	db *queryTimeoutDB
+	// Пороги переключений в час для focus_level deep work блоков
+	focusThresholds entity.FocusThresholds
 }
 
-func NewMetricsRepository(db *sqlx.DB) *metricsRepository {
-	return &metricsRepository{db: db}
+func NewMetricsRepository(db *sqlx.DB, focusThresholds entity.FocusThresholds, queryTimeout time.Duration) *metricsRepository {
+	return &metricsRepository{db: newQueryTimeoutDB(db, queryTimeout), focusThresholds: focusThresholds.Normalize()}
 }
 
 // Единая CTE для Deep Work анализа - используется в обоих эндпоинтах
 const deepWorkCoreCTE = `
 WITH active_events_filtered AS (
 	SELECT
+		id,
 		user_id,
 		timestamp,
 		event_type,
 		url,
 		session_id,
-		CASE 
-			WHEN url ~ '^https?://' THEN 
-				split_part(split_part(url, '://', 2), '/', 1)
-			ELSE 
-				split_part(url, '/', 1)
-		END as domain,
+		domain,
 		LAG(timestamp) OVER (PARTITION BY user_id ORDER BY timestamp) AS prev_timestamp,
-		LAG(
-			CASE 
-				WHEN url ~ '^https?://' THEN 
-					split_part(split_part(url, '://', 2), '/', 1)
-				ELSE 
-					split_part(url, '/', 1)
-			END
-		) OVER (PARTITION BY user_id ORDER BY timestamp) AS prev_domain
+		LAG(domain) OVER (PARTITION BY user_id ORDER BY timestamp) AS prev_domain
 	FROM user_behaviors 
-	WHERE user_id = $1 
+	WHERE %s AND deleted_at IS NULL 
 		AND timestamp >= $2 
 		AND timestamp <= $3
 		AND event_type = ANY($4::text[]) %s
+		-- Idle приоритетнее активных событий (см. IdleEventType): события минуты с idle отметкой
+		-- не считаются активными и не продлевают deep work блок
+		AND NOT EXISTS (
+			SELECT 1
+			FROM user_behaviors idle_ub
+			WHERE idle_ub.user_id = user_behaviors.user_id
+				AND idle_ub.event_type = 'idle'
+				AND idle_ub.deleted_at IS NULL
+				AND idle_ub.timestamp >= DATE_TRUNC('minute', user_behaviors.timestamp)
+				AND idle_ub.timestamp < DATE_TRUNC('minute', user_behaviors.timestamp) + INTERVAL '1 minute'
+		)
 ),
 activity_gaps AS (
 	SELECT *,
@@ -177,39 +296,36 @@ deep_work_blocks AS (
 		AND total_events >= %d     -- Минимальное количество событий
 )`
 
-// Основной запрос для базовых метрик engaged time (без deep work)
-const optimizedEngagedTimeQuery = `
+// Активность по минутам и доменам: общая часть engaged time и сырой минутной серии
+const minuteActivityCTE = `
 WITH minute_activity AS (
     SELECT
         DATE_TRUNC('minute', timestamp) AS minute,
-        MAX(CASE WHEN event_type = ANY($4::text[]) THEN 1 ELSE 0 END) AS is_active,
+        CASE
+            WHEN SUM(COUNT(*) FILTER (WHERE event_type = 'idle')) OVER (PARTITION BY DATE_TRUNC('minute', timestamp)) > 0 THEN 0
+            ELSE MAX(CASE WHEN event_type = ANY($4::text[]) THEN 1 ELSE 0 END)
+        END AS is_active,
         1 AS is_tracked,
         COUNT(CASE WHEN event_type = ANY($4::text[]) THEN 1 END) AS active_events_in_minute,
+        COUNT(*) FILTER (WHERE event_type = 'idle') AS idle_events_in_minute,
         COUNT(DISTINCT session_id) AS sessions_in_minute,
-        CASE 
-            WHEN url ~ '^https?://' THEN 
-                split_part(split_part(url, '://', 2), '/', 1)
-            ELSE 
-                split_part(url, '/', 1)
-        END as domain
+        domain
     FROM user_behaviors 
-    WHERE user_id = $1 
+    WHERE user_id = $1 AND deleted_at IS NULL 
         AND timestamp >= $2 
         AND timestamp <= $3 %s
-    GROUP BY DATE_TRUNC('minute', timestamp), 
-             CASE 
-                 WHEN url ~ '^https?://' THEN 
-                     split_part(split_part(url, '://', 2), '/', 1)
-                 ELSE 
-                     split_part(url, '/', 1)
-             END
-),
+    GROUP BY DATE_TRUNC('minute', timestamp), domain
+)`
+
+// Основной запрос для базовых метрик engaged time (без deep work)
+const optimizedEngagedTimeQuery = minuteActivityCTE + `,
 base_stats AS (
     SELECT 
         COALESCE(SUM(is_active), 0) as active_minutes,
         COALESCE(SUM(is_tracked), 0) as total_tracked_minutes,
         COALESCE(SUM(CASE WHEN is_active = 0 THEN 1 ELSE 0 END), 0) as idle_minutes,
         COALESCE(SUM(active_events_in_minute), 0) as active_events_count,
+        COALESCE(SUM(idle_events_in_minute), 0) as idle_events_count,
         COALESCE(COUNT(DISTINCT CASE WHEN sessions_in_minute > 0 THEN minute END), 0) as sessions_count,
         COALESCE(MIN(minute), $2::timestamp) as period_start,
         COALESCE(MAX(minute), $3::timestamp) as period_end,
@@ -222,6 +338,7 @@ SELECT
     bs.total_tracked_minutes,
     bs.idle_minutes,
     bs.active_events_count,
+    bs.idle_events_count,
     bs.sessions_count,
     bs.period_start,
     bs.period_end,
@@ -229,6 +346,17 @@ SELECT
     bs.domains_list
 FROM base_stats bs`
 
+// Сырые строки minute_activity для графиков: минута может повторяться для разных доменов
+const minuteActivityQuery = minuteActivityCTE + `
+SELECT
+    minute,
+    is_active = 1 AS is_active,
+    active_events_in_minute AS active_events,
+    idle_events_in_minute AS idle_events,
+    COALESCE(domain, '') AS domain
+FROM minute_activity
+ORDER BY minute, domain`
+
 // Запрос для hourly breakdown
 const hourlyBreakdownQuery = `
 WITH hourly_minute_activity AS (
@@ -236,11 +364,14 @@ WITH hourly_minute_activity AS (
         EXTRACT(HOUR FROM timestamp)::integer as hour,
         DATE(timestamp)::text as date,
         DATE_TRUNC('minute', timestamp) AS minute,
-        MAX(CASE WHEN event_type = ANY($4::text[]) THEN 1 ELSE 0 END) AS is_active,
+        CASE
+            WHEN COUNT(*) FILTER (WHERE event_type = 'idle') > 0 THEN 0
+            ELSE MAX(CASE WHEN event_type = ANY($4::text[]) THEN 1 ELSE 0 END)
+        END AS is_active,
         COUNT(CASE WHEN event_type = ANY($4::text[]) THEN 1 END) AS active_events_in_minute,
         COUNT(DISTINCT session_id) AS sessions_in_minute
     FROM user_behaviors 
-    WHERE user_id = $1 
+    WHERE user_id = $1 AND deleted_at IS NULL 
         AND timestamp >= $2 
         AND timestamp <= $3 %s
     GROUP BY EXTRACT(HOUR FROM timestamp), DATE(timestamp), DATE_TRUNC('minute', timestamp)
@@ -257,35 +388,279 @@ FROM hourly_minute_activity
 GROUP BY hour, date
 ORDER BY date, hour`
 
-// Функции-билдеры для Deep Work запросов
-func buildDeepWorkStatsQuery(sessionFilter string) string {
-	cte := fmt.Sprintf(deepWorkCoreCTE,
+// Максимальное количество idle-интервалов в ответе
+const MaxIdleIntervals = 100
+
+// Запрос idle-интервалов: разрывы между активными событиями длиннее порога
+const idleIntervalsQuery = `
+WITH active_events_filtered AS (
+	SELECT
+		timestamp,
+		LAG(timestamp) OVER (PARTITION BY user_id ORDER BY timestamp) AS prev_timestamp
+	FROM user_behaviors 
+	WHERE user_id = $1 AND deleted_at IS NULL 
+		AND timestamp >= $2 
+		AND timestamp <= $3
+		AND event_type = ANY($4::text[]) %s
+),
+longest_gaps AS (
+	SELECT
+		prev_timestamp AS start,
+		timestamp AS "end",
+		EXTRACT(EPOCH FROM (timestamp - prev_timestamp)) AS duration_seconds
+	FROM active_events_filtered
+	WHERE prev_timestamp IS NOT NULL
+		AND EXTRACT(EPOCH FROM (timestamp - prev_timestamp)) > %d
+	ORDER BY duration_seconds DESC
+	LIMIT %d
+)
+SELECT start, "end", duration_seconds
+FROM longest_gaps
+ORDER BY start`
+
+// Вовлеченность по сессиям: та же minute_activity, что и в engaged time, но с группировкой по session_id
+const sessionEngagementQuery = `
+WITH session_events AS (
+    SELECT
+        session_id,
+        timestamp,
+        event_type,
+        domain
+    FROM user_behaviors 
+    WHERE user_id = $1 AND deleted_at IS NULL 
+        AND timestamp >= $2 
+        AND timestamp <= $3
+),
+minute_activity AS (
+    SELECT
+        session_id,
+        DATE_TRUNC('minute', timestamp) AS minute,
+        CASE
+            WHEN COUNT(*) FILTER (WHERE event_type = 'idle') > 0 THEN 0
+            ELSE MAX(CASE WHEN event_type = ANY($4::text[]) THEN 1 ELSE 0 END)
+        END AS is_active
+    FROM session_events
+    GROUP BY session_id, DATE_TRUNC('minute', timestamp)
+),
+session_minutes AS (
+    SELECT
+        session_id,
+        COALESCE(SUM(is_active), 0)::integer as active_minutes,
+        COUNT(*)::integer as tracked_minutes
+    FROM minute_activity
+    GROUP BY session_id
+),
+session_bounds AS (
+    SELECT
+        session_id,
+        MIN(timestamp) as session_start,
+        MAX(timestamp) as session_end,
+        COUNT(DISTINCT domain) FILTER (WHERE domain IS NOT NULL AND domain != '')::integer as unique_domains
+    FROM session_events
+    GROUP BY session_id
+)
+SELECT 
+    sb.session_id,
+    sb.session_start,
+    sb.session_end,
+    sm.active_minutes,
+    sm.tracked_minutes,
+    sb.unique_domains
+FROM session_bounds sb
+JOIN session_minutes sm ON sm.session_id = sb.session_id
+ORDER BY sb.session_start`
+
+// Активные события по дням недели и часам для heatmap
+const activityHeatmapQuery = `
+SELECT 
+    EXTRACT(DOW FROM timestamp)::integer as day_of_week,
+    EXTRACT(HOUR FROM timestamp)::integer as hour,
+    COUNT(*)::integer as events
+FROM user_behaviors 
+WHERE user_id = $1 AND deleted_at IS NULL 
+    AND timestamp >= $2 
+    AND timestamp <= $3
+    AND event_type = ANY($4::text[]) %s
+GROUP BY EXTRACT(DOW FROM timestamp), EXTRACT(HOUR FROM timestamp)`
+
+// Нажатия клавиш по минутам: тот же DATE_TRUNC('minute'), что и в engaged time, но только keydown/keyup.
+// Параметры: фильтр по сессии, колонка домена для key_minutes, выражение домена и GROUP BY итогового SELECT
+const typingActivityQuery = `
+WITH key_minutes AS (
+    SELECT
+        %[2]s
+        DATE_TRUNC('minute', timestamp) AS minute,
+        GREATEST(
+            COUNT(*) FILTER (WHERE event_type = 'keydown'),
+            COUNT(*) FILTER (WHERE event_type = 'keyup')
+        )::integer AS keys
+    FROM user_behaviors
+    WHERE user_id = $1 AND deleted_at IS NULL
+        AND timestamp >= $2
+        AND timestamp <= $3
+        AND event_type IN ('keydown', 'keyup') %[1]s
+    GROUP BY %[2]s DATE_TRUNC('minute', timestamp)
+)
+SELECT
+    %[3]s AS domain,
+    COALESCE(SUM(keys), 0)::integer AS total_keystrokes,
+    COUNT(*)::integer AS typing_minutes,
+    COALESCE(MAX(keys), 0)::integer AS peak_keys_per_minute
+FROM key_minutes
+%[4]s`
+
+// Прокрутка относительно всех событий и взаимодействий. Параметры: ключ группы и GROUP BY (пустой для итогов)
+const scrollActivityQuery = `
+SELECT
+    %[1]s AS group_key,
+    COUNT(*) FILTER (WHERE event_type = 'scrollend')::integer AS scroll_events,
+    COUNT(*) FILTER (WHERE event_type IN ('click', 'keydown', 'keyup'))::integer AS interaction_events,
+    COUNT(*)::integer AS total_events
+FROM user_behaviors
+WHERE user_id = $1 AND deleted_at IS NULL
+    AND timestamp >= $2
+    AND timestamp <= $3
+%[2]s
+HAVING COUNT(*) FILTER (WHERE event_type = 'scrollend') > 0
+ORDER BY scroll_events DESC`
+
+// Клики страницы по ячейкам сетки: $3 - размер ячейки в пикселях, отрицательные координаты отбрасываются
+const clickHeatmapQuery = `
+SELECT
+    x / $3 AS cell_x,
+    y / $3 AS cell_y,
+    COUNT(*)::integer AS clicks
+FROM user_behaviors
+WHERE user_id = $1 AND deleted_at IS NULL
+    AND event_type = 'click'
+    AND url = $2
+    AND x >= 0 AND y >= 0
+GROUP BY cell_x, cell_y
+ORDER BY cell_y, cell_x`
+
+// Во сколько раз одна сторона должна перевешивать другую, чтобы домен получил профиль reading/interaction heavy
+const scrollProfileDominance = 2
+
+// Переключением вкладки считается visibility_hidden, перед которым в сессии не было другого visibility_hidden:
+// повторные события скрытия без возврата на вкладку не учитываются. Параметр: итоговый SELECT по switches
+const tabSwitchesQuery = `
+WITH visibility_events AS (
+    SELECT
+        domain,
+        timestamp,
+        event_type,
+        LAG(event_type) OVER (PARTITION BY session_id ORDER BY timestamp) AS prev_event_type
+    FROM user_behaviors
+    WHERE user_id = $1 AND deleted_at IS NULL
+        AND timestamp >= $2
+        AND timestamp <= $3
+        AND event_type IN ('visibility_hidden', 'visibility_visible')
+),
+switches AS (
+    SELECT domain, timestamp
+    FROM visibility_events
+    WHERE event_type = 'visibility_hidden'
+        AND prev_event_type IS DISTINCT FROM 'visibility_hidden'
+)
+%s`
+
+const tabSwitchesHourlySelect = `
+SELECT date_trunc('hour', timestamp) AS hour, COUNT(*)::integer AS switches
+FROM switches
+GROUP BY 1
+ORDER BY 1`
+
+const tabSwitchesDomainsSelect = `
+SELECT domain, COUNT(*)::integer AS switches
+FROM switches
+GROUP BY domain
+ORDER BY switches DESC, domain
+LIMIT %d`
+
+// Сколько переключений за час считается высокой частотой (high churn)
+const tabSwitchHighChurnPerHour = 30
+
+// Сколько доменов с наибольшим числом переключений возвращать
+const tabSwitchTopDomainsLimit = 10
+
+// Пороги Deep Work для конкретного запроса
+type deepWorkThresholds struct {
+	MinDurationMinutes  int
+	GapThresholdSeconds int
+	MinEventsPerBlock   int
+
+	HighFocusSwitchesPerHour   int
+	MediumFocusSwitchesPerHour int
+}
+
+// Нулевые значения заменяются дефолтными константами пакета, пороги фокуса берутся из конфига
+func (r *metricsRepository) newDeepWorkThresholds(minDurationMinutes, gapThresholdSeconds, minEventsPerBlock int) deepWorkThresholds {
+	thresholds := deepWorkThresholds{
+		MinDurationMinutes:  DeepWorkMinDurationMinutes,
+		GapThresholdSeconds: ActivityGapThresholdSeconds,
+		MinEventsPerBlock:   MinEventsPerBlock,
+
+		HighFocusSwitchesPerHour:   r.focusThresholds.HighMaxSwitchesPerHour,
+		MediumFocusSwitchesPerHour: r.focusThresholds.MediumMaxSwitchesPerHour,
+	}
+
+	if minDurationMinutes > 0 {
+		thresholds.MinDurationMinutes = minDurationMinutes
+	}
+	if gapThresholdSeconds > 0 {
+		thresholds.GapThresholdSeconds = gapThresholdSeconds
+	}
+	if minEventsPerBlock > 0 {
+		thresholds.MinEventsPerBlock = minEventsPerBlock
+	}
+
+	return thresholds
+}
+
+// Фильтр по одному пользователю ($1 = user_id) для deep work запросов
+const deepWorkSingleUserFilter = "user_id = $1"
+
+// Фильтр по активным пользователям расширения организации ($1 = organization_id)
+const deepWorkOrganizationFilter = "user_id IN (SELECT id FROM extension_users WHERE organization_id = $1 AND is_active = true)"
+
+func buildDeepWorkCoreCTE(sessionFilter string, thresholds deepWorkThresholds) string {
+	return buildDeepWorkCoreCTEForUsers(deepWorkSingleUserFilter, sessionFilter, thresholds)
+}
+
+// buildDeepWorkCoreCTEForUsers строит CTE для произвольного набора пользователей:
+// блоки считаются отдельно по каждому user_id (PARTITION BY user_id)
+func buildDeepWorkCoreCTEForUsers(userFilter, sessionFilter string, thresholds deepWorkThresholds) string {
+	return fmt.Sprintf(deepWorkCoreCTE,
+		userFilter,
 		sessionFilter,
-		ActivityGapThresholdSeconds,
-		HighFocusThreshold,
-		MediumFocusThreshold,
-		DeepWorkMinDurationMinutes,
-		MinEventsPerBlock,
+		thresholds.GapThresholdSeconds,
+		thresholds.HighFocusSwitchesPerHour,
+		thresholds.MediumFocusSwitchesPerHour,
+		thresholds.MinDurationMinutes,
+		thresholds.MinEventsPerBlock,
 	)
+}
+
+// Функции-билдеры для Deep Work запросов
+func buildDeepWorkStatsQuery(sessionFilter string, thresholds deepWorkThresholds) string {
+	cte := buildDeepWorkCoreCTE(sessionFilter, thresholds)
 
 	return fmt.Sprintf(`%s
 	SELECT 
 		COALESCE(COUNT(*), 0) as deep_sessions_count,
 		COALESCE(SUM(duration_minutes), 0) as total_deep_minutes,
 		COALESCE(AVG(duration_minutes), 0) as avg_deep_minutes,
-		COALESCE(MAX(duration_minutes), 0) as max_deep_minutes
+		COALESCE(MAX(duration_minutes), 0) as max_deep_minutes,
+		COALESCE(SUM(context_switches), 0)::integer as total_context_switches,
+		COALESCE(AVG(switches_per_hour), 0) as avg_switches_per_hour,
+		COUNT(CASE WHEN focus_level = 'high' THEN 1 END)::integer as high_focus_blocks,
+		COUNT(CASE WHEN focus_level = 'medium' THEN 1 END)::integer as medium_focus_blocks,
+		COUNT(CASE WHEN focus_level = 'low' THEN 1 END)::integer as low_focus_blocks
 	FROM deep_work_blocks`, cte)
 }
 
-func buildDeepWorkTopDomainsQuery(sessionFilter string) string {
-	cte := fmt.Sprintf(deepWorkCoreCTE,
-		sessionFilter,
-		ActivityGapThresholdSeconds,
-		HighFocusThreshold,
-		MediumFocusThreshold,
-		DeepWorkMinDurationMinutes,
-		MinEventsPerBlock,
-	)
+func buildDeepWorkTopDomainsQuery(sessionFilter string, thresholds deepWorkThresholds) string {
+	cte := buildDeepWorkCoreCTE(sessionFilter, thresholds)
 
 	return fmt.Sprintf(`%s,
 	domain_stats AS (
@@ -307,18 +682,61 @@ func buildDeepWorkTopDomainsQuery(sessionFilter string) string {
 	LIMIT 3`, cte)
 }
 
-func buildDeepWorkSessionsQuery(sessionFilter string) string {
-	cte := fmt.Sprintf(deepWorkCoreCTE,
-		sessionFilter,
-		ActivityGapThresholdSeconds,
-		HighFocusThreshold,
-		MediumFocusThreshold,
-		DeepWorkMinDurationMinutes,
-		MinEventsPerBlock,
+// События одного deep work блока. block_id детерминирован для одинаковых
+// user_id/периода/session_id/gap_threshold: нумерация идет по timestamp внутри выборки
+func buildDeepWorkBlockEventsQuery(sessionFilter string, blockIDPlaceholder int, thresholds deepWorkThresholds) string {
+	cte := buildDeepWorkCoreCTE(sessionFilter, thresholds)
+
+	return fmt.Sprintf(`%s
+	SELECT 
+		ub.id, ub.session_id, ub.event_type, ub.url, ub.domain, ub.user_id, ub.x, ub.y, ub.key,
+		ub.timestamp, ub.created_at, ub.updated_at
+	FROM numbered_blocks nb
+	JOIN deep_work_blocks dwb ON dwb.block_id = nb.block_id
+	JOIN user_behaviors ub ON ub.id = nb.id
+	WHERE nb.block_id = $%d
+	ORDER BY ub.timestamp, ub.id`, cte, blockIDPlaceholder)
+}
+
+// Блок относится к сессии своего первого события (DISTINCT ON по времени внутри блока)
+func buildDeepWorkBySessionQuery(sessionFilter string, thresholds deepWorkThresholds) string {
+	cte := buildDeepWorkCoreCTE(sessionFilter, thresholds)
+
+	return fmt.Sprintf(`%s,
+	block_first_session AS (
+		SELECT DISTINCT ON (block_id) block_id, session_id
+		FROM numbered_blocks
+		ORDER BY block_id, timestamp
+	),
+	block_sessions_spanned AS (
+		SELECT block_id, COUNT(DISTINCT session_id)::integer AS sessions_spanned
+		FROM numbered_blocks
+		GROUP BY block_id
 	)
+	SELECT
+		dwb.block_id,
+		bfs.session_id,
+		bss.sessions_spanned,
+		dwb.start_time,
+		dwb.end_time,
+		dwb.duration_minutes,
+		dwb.total_events,
+		dwb.context_switches,
+		dwb.switches_per_hour,
+		dwb.focus_level
+	FROM deep_work_blocks dwb
+	JOIN block_first_session bfs ON bfs.block_id = dwb.block_id
+	JOIN block_sessions_spanned bss ON bss.block_id = dwb.block_id
+	ORDER BY dwb.start_time`, cte)
+}
+
+// sessions_json отдает только страницу блоков (LIMIT/OFFSET с limitPlaceholder), остальные CTE
+// считаются по всем блокам периода
+func buildDeepWorkSessionsQuery(sessionFilter string, limitPlaceholder int, thresholds deepWorkThresholds) string {
+	cte := buildDeepWorkCoreCTE(sessionFilter, thresholds)
 
 	return fmt.Sprintf(`%s,
-	-- Упрощенная hourly статистика (ИСПРАВЛЕНО)
+	-- Hourly статистика по часам периода
 	hours_series AS (
 		SELECT generate_series(
 			date_trunc('hour', $2::timestamp),
@@ -326,8 +744,61 @@ func buildDeepWorkSessionsQuery(sessionFilter string) string {
 			'1 hour'::interval
 		) as hour_start
 	),
+	-- Отслеживаемые минуты (минуты с активными событиями) по часам, знаменатель hourly deep_work_rate
+	hourly_tracked AS (
+		SELECT 
+			date_trunc('hour', timestamp) as hour_start,
+			COUNT(DISTINCT DATE_TRUNC('minute', timestamp)) as tracked_minutes
+		FROM user_behaviors 
+		WHERE user_id = $1 AND deleted_at IS NULL 
+			AND timestamp >= $2 
+			AND timestamp <= $3
+			AND event_type = ANY($4::text[]) %s
+		GROUP BY date_trunc('hour', timestamp)
+	),
+	-- Часы, которые блок реально пересекает; блок нулевой длительности относится к часу своего начала
+	block_hours AS (
+		SELECT 
+			dwb.block_id,
+			hs.hour_start,
+			dwb.context_switches,
+			EXTRACT(EPOCH FROM (LEAST(dwb.end_time, hs.hour_start + INTERVAL '1 hour') - GREATEST(dwb.start_time, hs.hour_start))) as overlap_seconds,
+			EXTRACT(EPOCH FROM (dwb.end_time - dwb.start_time)) as block_seconds,
+			ROW_NUMBER() OVER (PARTITION BY dwb.block_id ORDER BY hs.hour_start DESC) as hour_rank_desc
+		FROM deep_work_blocks dwb
+		JOIN hours_series hs ON dwb.start_time < hs.hour_start + INTERVAL '1 hour'
+			AND (dwb.end_time > hs.hour_start 
+				OR (dwb.end_time = dwb.start_time AND dwb.start_time >= hs.hour_start))
+	),
+	block_hour_shares AS (
+		SELECT 
+			block_id,
+			hour_start,
+			context_switches,
+			hour_rank_desc,
+			CASE 
+				WHEN block_seconds > 0 
+				THEN FLOOR(context_switches * overlap_seconds / block_seconds)::integer
+				ELSE 0
+			END as floor_share
+		FROM block_hours
+	),
+	-- Переключения блока делятся по часам пропорционально пересечению с округлением вниз,
+	-- остаток уходит в последний час блока: сумма по часам равна context_switches блока
+	block_hour_switches AS (
+		SELECT 
+			block_id,
+			hour_start,
+			CASE 
+				WHEN hour_rank_desc = 1 
+				THEN context_switches - (SUM(floor_share) OVER (PARTITION BY block_id) - floor_share)
+				ELSE floor_share
+			END as context_switches
+		FROM block_hour_shares
+	),
 	hourly_stats AS (
 		SELECT 
+			hs.hour_start,
 			EXTRACT(HOUR FROM hs.hour_start)::integer as hour,
 			DATE(hs.hour_start)::text as date,
 			COALESCE(SUM(
@@ -343,18 +814,10 @@ func buildDeepWorkSessionsQuery(sessionFilter string) string {
 			), 0) as deep_work_minutes,
 			COUNT(DISTINCT CASE WHEN dwb.start_time <= hs.hour_start + INTERVAL '1 hour' 
 								AND dwb.end_time >= hs.hour_start THEN dwb.block_id END) as sessions_count,
-			COALESCE(SUM(
-				CASE 
-					WHEN dwb.start_time <= hs.hour_start + INTERVAL '1 hour' 
-						AND dwb.end_time >= hs.hour_start
-					THEN dwb.context_switches * (
-						LEAST(
-							EXTRACT(EPOCH FROM (hs.hour_start + INTERVAL '1 hour' - GREATEST(dwb.start_time, hs.hour_start))),
-							EXTRACT(EPOCH FROM (LEAST(dwb.end_time, hs.hour_start + INTERVAL '1 hour') - GREATEST(dwb.start_time, hs.hour_start)))
-						) / EXTRACT(EPOCH FROM (dwb.end_time - dwb.start_time))
-					)
-					ELSE 0
-				END
+			COALESCE((
+				SELECT SUM(bhs.context_switches) 
+				FROM block_hour_switches bhs 
+				WHERE bhs.hour_start = hs.hour_start
 			), 0)::integer as context_switches,
 			COALESCE(AVG(CASE WHEN dwb.start_time <= hs.hour_start + INTERVAL '1 hour' 
 							AND dwb.end_time >= hs.hour_start THEN dwb.switches_per_hour END), 0) as avg_switches_per_hour
@@ -375,7 +838,7 @@ func buildDeepWorkSessionsQuery(sessionFilter string) string {
 		SELECT 
 			COUNT(DISTINCT DATE_TRUNC('minute', timestamp)) as total_minutes
 		FROM user_behaviors 
-		WHERE user_id = $1 
+		WHERE user_id = $1 AND deleted_at IS NULL 
 			AND timestamp >= $2 
 			AND timestamp <= $3
 			AND event_type = ANY($4::text[]) %s  -- Только активные события
@@ -423,7 +886,11 @@ func buildDeepWorkSessionsQuery(sessionFilter string) string {
 				),
 				'[]'::json
 			) as sessions_data
-		FROM deep_work_blocks
+		FROM (
+			SELECT * FROM deep_work_blocks
+			ORDER BY start_time, block_id
+			LIMIT $%d OFFSET $%d
+		) paged_blocks
 	),
 	-- JSON данные hourly breakdown
 	hourly_json AS (
@@ -439,17 +906,24 @@ func buildDeepWorkSessionsQuery(sessionFilter string) string {
 							WHEN hour < 12 THEN hour || ':00 AM'
 							ELSE (hour - 12) || ':00 PM'
 						END,
-						'total_mins', ROUND(deep_work_minutes::numeric, 0),
+						'total_mins', COALESCE(ht.tracked_minutes, 0),
 						'sessions', sessions_count,
 						'deep_work_mins', ROUND(deep_work_minutes::numeric, 0),
 						'context_switches', context_switches,
 						'switches_per_hour', ROUND(avg_switches_per_hour::numeric, 2),
-						'deep_work_rate', 100.0  -- В hourly мы показываем только deep work время
+						-- Блок включает паузы до gap_threshold, поэтому deep work минуты часа
+						-- могут превышать отслеживаемые - ограничиваем 100
+						'deep_work_rate', CASE 
+							WHEN COALESCE(ht.tracked_minutes, 0) > 0 
+							THEN ROUND(LEAST(deep_work_minutes / ht.tracked_minutes * 100.0, 100.0)::numeric, 2)
+							ELSE 0.0
+						END
 					) ORDER BY date, hour
 				),
 				'[]'::json
 			) as hourly_data
-		FROM hourly_stats
+		FROM hourly_stats hst
+		LEFT JOIN hourly_tracked ht ON ht.hour_start = hst.hour_start
 	)
 	SELECT 
 		COALESCE(ag.sessions_count, 0) as sessions_count,
@@ -470,19 +944,14 @@ func buildDeepWorkSessionsQuery(sessionFilter string) string {
 	FROM aggregated_stats ag
 	CROSS JOIN sessions_json sj
 	CROSS JOIN total_tracked tt
-	CROSS JOIN hourly_json hj`, cte, sessionFilter)
+	CROSS JOIN hourly_json hj`, cte, sessionFilter, sessionFilter, limitPlaceholder, limitPlaceholder+1)
 }
 
 func (r *metricsRepository) getDeepWorkStats(ctx context.Context, filter entity.EngagedTimeFilter) (*deepWorkStatsResult, error) {
-	sessionFilter := ""
-	args := []interface{}{filter.UserID, filter.StartTime, filter.EndTime, pq.Array(ActiveEvents)}
+	sessionFilter, args := engagedTimeArgs(filter)
 
-	if filter.SessionID != nil {
-		sessionFilter = " AND session_id = $5"
-		args = append(args, *filter.SessionID)
-	}
-
-	query := buildDeepWorkStatsQuery(sessionFilter)
+	thresholds := r.newDeepWorkThresholds(filter.MinDurationMinutes, filter.GapThresholdSeconds, filter.MinEventsPerBlock)
+	query := buildDeepWorkStatsQuery(sessionFilter, thresholds)
 
 	var result deepWorkStatsResult
 	err := r.db.GetContext(ctx, &result, query, args...)
@@ -497,15 +966,10 @@ func (r *metricsRepository) getDeepWorkStats(ctx context.Context, filter entity.
 }
 
 func (r *metricsRepository) getDeepWorkTopDomains(ctx context.Context, filter entity.EngagedTimeFilter) ([]deepWorkDomainResult, error) {
-	sessionFilter := ""
-	args := []interface{}{filter.UserID, filter.StartTime, filter.EndTime, pq.Array(ActiveEvents)}
-
-	if filter.SessionID != nil {
-		sessionFilter = " AND session_id = $5"
-		args = append(args, *filter.SessionID)
-	}
+	sessionFilter, args := engagedTimeArgs(filter)
 
-	query := buildDeepWorkTopDomainsQuery(sessionFilter)
+	thresholds := r.newDeepWorkThresholds(filter.MinDurationMinutes, filter.GapThresholdSeconds, filter.MinEventsPerBlock)
+	query := buildDeepWorkTopDomainsQuery(sessionFilter, thresholds)
 
 	var results []deepWorkDomainResult
 	err := r.db.SelectContext(ctx, &results, query, args...)
@@ -517,13 +981,9 @@ func (r *metricsRepository) getDeepWorkTopDomains(ctx context.Context, filter en
 }
 
 func (r *metricsRepository) GetEngagedTime(ctx context.Context, filter entity.EngagedTimeFilter) (*entity.EngagedTimeMetric, error) {
-	sessionFilter := ""
-	args := []interface{}{filter.UserID, filter.StartTime, filter.EndTime, pq.Array(ActiveEvents)}
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "engaged_time")
 
-	if filter.SessionID != nil {
-		sessionFilter = " AND session_id = $5"
-		args = append(args, *filter.SessionID)
-	}
+	sessionFilter, args := engagedTimeArgs(filter)
 
 	mainQuery := fmt.Sprintf(optimizedEngagedTimeQuery, sessionFilter)
 
@@ -559,19 +1019,439 @@ func (r *metricsRepository) GetEngagedTime(ctx context.Context, filter entity.En
 		}
 	}
 
-	return r.buildEngagedTimeMetricWithDeepWork(filter, result, deepWorkStats, hourlyResults, topDomains), nil
+	// 5. Idle-интервалы для таймлайна
+	idleIntervals, err := r.GetIdleIntervals(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idle intervals: %w", err)
+	}
+
+	return r.buildEngagedTimeMetricWithDeepWork(filter, result, deepWorkStats, hourlyResults, topDomains, idleIntervals), nil
+}
+
+func (r *metricsRepository) GetIdleIntervals(ctx context.Context, filter entity.EngagedTimeFilter) ([]entity.IdleInterval, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "idle_intervals")
+
+	sessionFilter, args := engagedTimeArgs(filter)
+
+	thresholds := r.newDeepWorkThresholds(filter.MinDurationMinutes, filter.GapThresholdSeconds, filter.MinEventsPerBlock)
+	query := fmt.Sprintf(idleIntervalsQuery, sessionFilter, thresholds.GapThresholdSeconds, MaxIdleIntervals)
+
+	var results []idleIntervalResult
+	if err := r.db.SelectContext(ctx, &results, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get idle intervals: %w", err)
+	}
+
+	intervals := make([]entity.IdleInterval, len(results))
+	for i, interval := range results {
+		intervals[i] = entity.IdleInterval{
+			Start:           interval.Start,
+			End:             interval.End,
+			DurationSeconds: int(interval.DurationSeconds),
+		}
+	}
+
+	return intervals, nil
+}
+
+func (r *metricsRepository) GetMinuteActivity(ctx context.Context, filter entity.EngagedTimeFilter) ([]entity.MinuteActivity, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "minute_activity")
+
+	conditions, args := engagedTimeArgs(filter)
+	query := fmt.Sprintf(minuteActivityQuery, conditions)
+
+	minutes := []entity.MinuteActivity{}
+	if err := r.db.SelectContext(ctx, &minutes, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get minute activity: %w", err)
+	}
+
+	return minutes, nil
+}
+
+func (r *metricsRepository) GetActivityHeatmap(ctx context.Context, filter entity.ActivityHeatmapFilter) (*entity.ActivityHeatmap, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "activity_heatmap")
+
+	sessionFilter := ""
+	args := []interface{}{filter.UserID, filter.StartTime, filter.EndTime, activeEventsArg(filter.ActiveEvents)}
+
+	if filter.SessionID != nil {
+		sessionFilter = " AND session_id = $5"
+		args = append(args, *filter.SessionID)
+	}
+
+	excludedFilter, args := excludedDomainsCondition(filter.ExcludedDomains, args)
+	query := fmt.Sprintf(activityHeatmapQuery, sessionFilter+excludedFilter)
+
+	var results []activityHeatmapResult
+	if err := r.db.SelectContext(ctx, &results, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get activity heatmap: %w", err)
+	}
+
+	heatmap := &entity.ActivityHeatmap{
+		UserID:    filter.UserID,
+		StartTime: filter.StartTime,
+		EndTime:   filter.EndTime,
+	}
+
+	for _, cell := range results {
+		if cell.DayOfWeek < 0 || cell.DayOfWeek > 6 || cell.Hour < 0 || cell.Hour > 23 {
+			continue
+		}
+
+		heatmap.Grid[cell.DayOfWeek][cell.Hour] = cell.Events
+		heatmap.TotalEvents += cell.Events
+		if cell.Events > heatmap.MaxEvents {
+			heatmap.MaxEvents = cell.Events
+		}
+	}
+
+	return heatmap, nil
+}
+
+func (r *metricsRepository) GetTypingActivity(ctx context.Context, filter entity.TypingActivityFilter) (*entity.TypingActivity, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "typing_activity")
+
+	sessionFilter := ""
+	args := []interface{}{filter.UserID, filter.StartTime, filter.EndTime}
+
+	if filter.SessionID != nil {
+		sessionFilter = " AND session_id = $4"
+		args = append(args, *filter.SessionID)
+	}
+
+	// Пик считается по всем доменам вместе, поэтому итог - отдельный запрос без группировки по домену
+	totalQuery := fmt.Sprintf(typingActivityQuery, sessionFilter, "", "''", "")
+	domainsQuery := fmt.Sprintf(typingActivityQuery, sessionFilter, "domain,", "domain", "GROUP BY domain ORDER BY total_keystrokes DESC")
+
+	var total typingActivityResult
+	if err := r.db.GetContext(ctx, &total, totalQuery, args...); err != nil {
+		return nil, fmt.Errorf("failed to get typing activity: %w", err)
+	}
+
+	var domains []typingActivityResult
+	if err := r.db.SelectContext(ctx, &domains, domainsQuery, args...); err != nil {
+		return nil, fmt.Errorf("failed to get typing activity by domain: %w", err)
+	}
+
+	activity := &entity.TypingActivity{
+		UserID:      filter.UserID,
+		StartTime:   filter.StartTime,
+		EndTime:     filter.EndTime,
+		TypingStats: buildTypingStats(total),
+		Domains:     make([]entity.DomainTypingActivity, len(domains)),
+	}
+
+	for i, domain := range domains {
+		activity.Domains[i] = entity.DomainTypingActivity{
+			Domain:      domain.Domain,
+			TypingStats: buildTypingStats(domain),
+		}
+	}
+
+	return activity, nil
+}
+
+func buildTypingStats(result typingActivityResult) entity.TypingStats {
+	stats := entity.TypingStats{
+		TotalKeystrokes: result.TotalKeystrokes,
+		TypingMinutes:   result.TypingMinutes,
+		PeakKeysPerMin:  result.PeakKeysPerMin,
+	}
+
+	if result.TypingMinutes > 0 {
+		stats.AvgKeysPerMin = utils.RoundToTwoDecimals(float64(result.TotalKeystrokes) / float64(result.TypingMinutes))
+	}
+
+	return stats
+}
+
+func (r *metricsRepository) GetScrollActivity(ctx context.Context, filter entity.ScrollActivityFilter) (*entity.ScrollActivity, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "scroll_activity")
+
+	args := []interface{}{filter.UserID, filter.StartTime, filter.EndTime}
+
+	var domains []scrollActivityResult
+	if err := r.db.SelectContext(ctx, &domains, fmt.Sprintf(scrollActivityQuery, "domain", "GROUP BY domain"), args...); err != nil {
+		return nil, fmt.Errorf("failed to get scroll activity by domain: %w", err)
+	}
+
+	var sessions []scrollActivityResult
+	if err := r.db.SelectContext(ctx, &sessions, fmt.Sprintf(scrollActivityQuery, "session_id", "GROUP BY session_id"), args...); err != nil {
+		return nil, fmt.Errorf("failed to get scroll activity by session: %w", err)
+	}
+
+	// Без группировки: одна строка итогов или ни одной, если скролла за период не было
+	var totals []scrollActivityResult
+	if err := r.db.SelectContext(ctx, &totals, fmt.Sprintf(scrollActivityQuery, "''", ""), args...); err != nil {
+		return nil, fmt.Errorf("failed to get scroll activity totals: %w", err)
+	}
+
+	activity := &entity.ScrollActivity{
+		UserID:    filter.UserID,
+		StartTime: filter.StartTime,
+		EndTime:   filter.EndTime,
+		Domains:   make([]entity.DomainScrollActivity, len(domains)),
+		Sessions:  make([]entity.SessionScrollActivity, len(sessions)),
+	}
+
+	for i, domain := range domains {
+		profile := entity.ScrollProfileMixed
+		switch {
+		case domain.ScrollEvents >= scrollProfileDominance*domain.InteractionEvents:
+			profile = entity.ScrollProfileReadingHeavy
+		case domain.InteractionEvents >= scrollProfileDominance*domain.ScrollEvents:
+			profile = entity.ScrollProfileInteractionHeavy
+		}
+
+		activity.Domains[i] = entity.DomainScrollActivity{
+			Domain:            domain.GroupKey,
+			ScrollEvents:      domain.ScrollEvents,
+			InteractionEvents: domain.InteractionEvents,
+			TotalEvents:       domain.TotalEvents,
+			ScrollRatio:       calculateEngagementRate(domain.ScrollEvents, domain.TotalEvents),
+			Profile:           profile,
+		}
+	}
+
+	for i, session := range sessions {
+		activity.Sessions[i] = entity.SessionScrollActivity{
+			SessionID:    session.GroupKey,
+			ScrollEvents: session.ScrollEvents,
+			TotalEvents:  session.TotalEvents,
+			ScrollRatio:  calculateEngagementRate(session.ScrollEvents, session.TotalEvents),
+		}
+	}
+
+	if len(totals) > 0 {
+		activity.ScrollEvents = totals[0].ScrollEvents
+		activity.TotalEvents = totals[0].TotalEvents
+		activity.ScrollRatio = calculateEngagementRate(totals[0].ScrollEvents, totals[0].TotalEvents)
+	}
+
+	return activity, nil
+}
+
+// GetClickHeatmap раскладывает клики по url в сетку ячеек gridSize x gridSize пикселей
+func (r *metricsRepository) GetClickHeatmap(ctx context.Context, userID, url string, gridSize int) (*entity.ClickHeatmap, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "click_heatmap")
+
+	cells := []entity.ClickHeatmapCell{}
+	if err := r.db.SelectContext(ctx, &cells, clickHeatmapQuery, userID, url, gridSize); err != nil {
+		return nil, fmt.Errorf("failed to get click heatmap: %w", err)
+	}
+
+	heatmap := &entity.ClickHeatmap{
+		UserID:   userID,
+		URL:      url,
+		CellSize: gridSize,
+		Cells:    cells,
+	}
+
+	for _, cell := range cells {
+		heatmap.TotalClicks += cell.Clicks
+		if cell.X+1 > heatmap.Columns {
+			heatmap.Columns = cell.X + 1
+		}
+		if cell.Y+1 > heatmap.Rows {
+			heatmap.Rows = cell.Y + 1
+		}
+	}
+
+	return heatmap, nil
+}
+
+func (r *metricsRepository) GetTabSwitchStats(ctx context.Context, filter entity.TabSwitchFilter) (*entity.TabSwitchStats, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "tab_switch_stats")
+
+	args := []interface{}{filter.UserID, filter.StartTime, filter.EndTime}
+
+	var hours []hourlyTabSwitchResult
+	if err := r.db.SelectContext(ctx, &hours, fmt.Sprintf(tabSwitchesQuery, tabSwitchesHourlySelect), args...); err != nil {
+		return nil, fmt.Errorf("failed to get tab switches by hour: %w", err)
+	}
+
+	domainsSelect := fmt.Sprintf(tabSwitchesDomainsSelect, tabSwitchTopDomainsLimit)
+
+	var domains []domainTabSwitchResult
+	if err := r.db.SelectContext(ctx, &domains, fmt.Sprintf(tabSwitchesQuery, domainsSelect), args...); err != nil {
+		return nil, fmt.Errorf("failed to get tab switches by domain: %w", err)
+	}
+
+	stats := &entity.TabSwitchStats{
+		UserID:             filter.UserID,
+		StartTime:          filter.StartTime,
+		EndTime:            filter.EndTime,
+		ActiveHours:        len(hours),
+		HighChurnThreshold: tabSwitchHighChurnPerHour,
+		Hours:              make([]entity.HourlyTabSwitches, len(hours)),
+		TopDomains:         make([]entity.DomainTabSwitches, len(domains)),
+	}
+
+	for i, hour := range hours {
+		highChurn := hour.Switches >= tabSwitchHighChurnPerHour
+		if highChurn {
+			stats.HighChurnHours++
+		}
+		stats.TotalSwitches += hour.Switches
+
+		stats.Hours[i] = entity.HourlyTabSwitches{
+			Hour:      hour.Hour.UTC(),
+			Switches:  hour.Switches,
+			HighChurn: highChurn,
+		}
+	}
+
+	if stats.ActiveHours > 0 {
+		stats.AvgSwitchesPerHour = utils.RoundToTwoDecimals(float64(stats.TotalSwitches) / float64(stats.ActiveHours))
+	}
+
+	for i, domain := range domains {
+		stats.TopDomains[i] = entity.DomainTabSwitches{
+			Domain:   domain.Domain,
+			Switches: domain.Switches,
+			Share:    calculateEngagementRate(domain.Switches, stats.TotalSwitches),
+		}
+	}
+
+	return stats, nil
+}
+
+func (r *metricsRepository) GetSessionEngagement(ctx context.Context, filter entity.SessionEngagementFilter) (*entity.SessionEngagementMetric, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "session_engagement")
+
+	args := []interface{}{filter.UserID, filter.StartTime, filter.EndTime, activeEventsArg(filter.ActiveEvents)}
+
+	var results []sessionEngagementResult
+	if err := r.db.SelectContext(ctx, &results, sessionEngagementQuery, args...); err != nil {
+		return nil, fmt.Errorf("failed to get session engagement: %w", err)
+	}
+
+	metric := &entity.SessionEngagementMetric{
+		UserID:    filter.UserID,
+		StartTime: filter.StartTime,
+		EndTime:   filter.EndTime,
+		Sessions:  make([]entity.SessionEngagement, len(results)),
+	}
+
+	for i, session := range results {
+		metric.Sessions[i] = entity.SessionEngagement{
+			SessionID:      session.SessionID,
+			SessionStart:   session.SessionStart,
+			SessionEnd:     session.SessionEnd,
+			ActiveMinutes:  session.ActiveMinutes,
+			TrackedMinutes: session.TrackedMinutes,
+			EngagementRate: calculateEngagementRate(session.ActiveMinutes, session.TrackedMinutes),
+			UniqueDomains:  session.UniqueDomains,
+		}
+	}
+
+	return metric, nil
+}
+
+func (r *metricsRepository) GetDeepWorkBlockEvents(ctx context.Context, filter entity.DeepWorkSessionsFilter, blockID int) ([]entity.UserBehavior, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "deep_work_block_events")
+
+	sessionFilter := ""
+	args := []interface{}{filter.UserID, filter.StartTime, filter.EndTime, activeEventsArg(filter.ActiveEvents)}
+
+	if filter.SessionID != nil {
+		sessionFilter = " AND session_id = $5"
+		args = append(args, *filter.SessionID)
+	}
+
+	excludedFilter, args := excludedDomainsCondition(filter.ExcludedDomains, args)
+	sessionFilter += excludedFilter
+	args = append(args, blockID)
+
+	thresholds := r.newDeepWorkThresholds(filter.MinDurationMinutes, filter.GapThresholdSeconds, filter.MinEventsPerBlock)
+	query := buildDeepWorkBlockEventsQuery(sessionFilter, len(args), thresholds)
+
+	events := []entity.UserBehavior{}
+	if err := r.db.SelectContext(ctx, &events, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get deep work block events: %w", err)
+	}
+
+	return events, nil
+}
+
+func (r *metricsRepository) GetDeepWorkBySession(ctx context.Context, filter entity.DeepWorkSessionsFilter) (*entity.DeepWorkBySessionResponse, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "deep_work_by_session")
+
+	sessionFilter := ""
+	args := []interface{}{filter.UserID, filter.StartTime, filter.EndTime, activeEventsArg(filter.ActiveEvents)}
+
+	if filter.SessionID != nil {
+		sessionFilter = " AND session_id = $5"
+		args = append(args, *filter.SessionID)
+	}
+
+	excludedFilter, args := excludedDomainsCondition(filter.ExcludedDomains, args)
+	sessionFilter += excludedFilter
+
+	thresholds := r.newDeepWorkThresholds(filter.MinDurationMinutes, filter.GapThresholdSeconds, filter.MinEventsPerBlock)
+	query := buildDeepWorkBySessionQuery(sessionFilter, thresholds)
+
+	var results []deepWorkBySessionResult
+	if err := r.db.SelectContext(ctx, &results, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get deep work by session: %w", err)
+	}
+
+	response := &entity.DeepWorkBySessionResponse{
+		UserID:    filter.UserID,
+		StartTime: filter.StartTime,
+		EndTime:   filter.EndTime,
+		Sessions:  []entity.SessionDeepWork{},
+	}
+
+	// Блоки отсортированы по времени, поэтому сессии идут в порядке своего первого блока
+	sessionIndex := make(map[string]int)
+	for _, block := range results {
+		idx, ok := sessionIndex[block.SessionID]
+		if !ok {
+			idx = len(response.Sessions)
+			sessionIndex[block.SessionID] = idx
+			response.Sessions = append(response.Sessions, entity.SessionDeepWork{SessionID: block.SessionID})
+		}
+
+		session := &response.Sessions[idx]
+		session.BlocksCount++
+		session.TotalMinutes = utils.RoundToTwoDecimals(session.TotalMinutes + block.DurationMinutes)
+		session.Blocks = append(session.Blocks, entity.SessionDeepWorkBlock{
+			DeepWorkSession: entity.DeepWorkSession{
+				BlockID:         block.BlockID,
+				StartTime:       block.StartTime,
+				EndTime:         block.EndTime,
+				DurationMinutes: utils.RoundToTwoDecimals(block.DurationMinutes),
+				TotalEvents:     block.TotalEvents,
+				ContextSwitches: block.ContextSwitches,
+				SwitchesPerHour: utils.RoundToTwoDecimals(block.SwitchesPerHour),
+				FocusLevel:      block.FocusLevel,
+			},
+			SessionsSpanned: block.SessionsSpanned,
+		})
+	}
+
+	return response, nil
 }
 
 func (r *metricsRepository) GetDeepWorkSessions(ctx context.Context, filter entity.DeepWorkSessionsFilter) (*entity.DeepWorkSessionsResponse, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "deep_work_sessions")
+
 	sessionFilter := ""
-	args := []interface{}{filter.UserID, filter.StartTime, filter.EndTime, pq.Array(ActiveEvents)}
+	args := []interface{}{filter.UserID, filter.StartTime, filter.EndTime, activeEventsArg(filter.ActiveEvents)}
 
 	if filter.SessionID != nil {
 		sessionFilter = " AND session_id = $5"
 		args = append(args, *filter.SessionID)
 	}
 
-	query := buildDeepWorkSessionsQuery(sessionFilter)
+	excludedFilter, args := excludedDomainsCondition(filter.ExcludedDomains, args)
+	sessionFilter += excludedFilter
+
+	limitPlaceholder := len(args) + 1
+	args = append(args, filter.Limit, filter.Offset)
+
+	thresholds := r.newDeepWorkThresholds(filter.MinDurationMinutes, filter.GapThresholdSeconds, filter.MinEventsPerBlock)
+	query := buildDeepWorkSessionsQuery(sessionFilter, limitPlaceholder, thresholds)
 
 	var result deepWorkSessionsResult
 	err := r.db.GetContext(ctx, &result, query, args...)
@@ -586,6 +1466,8 @@ func (r *metricsRepository) GetDeepWorkSessions(ctx context.Context, filter enti
 }
 
 func (r *metricsRepository) GetTrackedTime(ctx context.Context, filter entity.TrackedTimeFilter) (*entity.TrackedTimeMetric, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "tracked_time")
+
 	query := `
 		SELECT 
 			user_id,
@@ -594,7 +1476,7 @@ func (r *metricsRepository) GetTrackedTime(ctx context.Context, filter entity.Tr
 			MAX(timestamp) as session_end,
 			EXTRACT(EPOCH FROM (MAX(timestamp) - MIN(timestamp))) / 60 as duration_minutes
 		FROM user_behaviors 
-		WHERE user_id = $1 
+		WHERE user_id = $1 AND deleted_at IS NULL 
 			AND timestamp >= $2 
 			AND timestamp <= $3`
 
@@ -606,6 +1488,9 @@ func (r *metricsRepository) GetTrackedTime(ctx context.Context, filter entity.Tr
 		args = append(args, *filter.SessionID)
 	}
 
+	excludedFilter, args := excludedDomainsCondition(filter.ExcludedDomains, args)
+	query += excludedFilter
+
 	query += `
         GROUP BY user_id, session_id
         HAVING COUNT(*) > 1`
@@ -667,6 +1552,8 @@ func (r *metricsRepository) GetTrackedTime(ctx context.Context, filter entity.Tr
 }
 
 func (r *metricsRepository) GetTrackedTimeTotal(ctx context.Context, filter entity.TrackedTimeFilter) (*entity.TrackedTimeMetric, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "tracked_time_total")
+
 	query := `
         SELECT 
             user_id,
@@ -675,7 +1562,7 @@ func (r *metricsRepository) GetTrackedTimeTotal(ctx context.Context, filter enti
             EXTRACT(EPOCH FROM (MAX(timestamp) - MIN(timestamp))) / 60 as total_minutes,
             COUNT(DISTINCT session_id) as sessions_count
         FROM user_behaviors 
-        WHERE user_id = $1`
+        WHERE user_id = $1 AND deleted_at IS NULL`
 
 	args := []interface{}{filter.UserID}
 	argIndex := 2
@@ -685,6 +1572,9 @@ func (r *metricsRepository) GetTrackedTimeTotal(ctx context.Context, filter enti
 		args = append(args, *filter.SessionID)
 	}
 
+	excludedFilter, args := excludedDomainsCondition(filter.ExcludedDomains, args)
+	query += excludedFilter
+
 	query += " GROUP BY user_id"
 
 	type result struct {
@@ -721,50 +1611,202 @@ func (r *metricsRepository) GetTrackedTimeTotal(ctx context.Context, filter enti
 	}, nil
 }
 
+func (r *metricsRepository) GetTrackedTimeTotalByUsers(ctx context.Context, filter entity.TrackedTimeTotalByUsersFilter) (map[string]*entity.TrackedTimeMetric, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "tracked_time_total_by_users")
+
+	query := `
+        SELECT 
+            user_id,
+            MIN(timestamp) as actual_start,
+            MAX(timestamp) as actual_end,
+            EXTRACT(EPOCH FROM (MAX(timestamp) - MIN(timestamp))) / 60 as total_minutes,
+            COUNT(DISTINCT session_id) as sessions_count
+        FROM user_behaviors 
+        WHERE user_id = ANY($1::text[]) AND deleted_at IS NULL`
+
+	args := []interface{}{pq.Array(filter.UserIDs)}
+
+	if filter.SessionID != nil {
+		query += " AND session_id = $2"
+		args = append(args, *filter.SessionID)
+	}
+
+	excludedFilter, args := excludedDomainsCondition(filter.ExcludedDomains, args)
+	query += excludedFilter
+
+	query += " GROUP BY user_id"
+
+	type result struct {
+		UserID        string    `db:"user_id"`
+		ActualStart   time.Time `db:"actual_start"`
+		ActualEnd     time.Time `db:"actual_end"`
+		TotalMinutes  float64   `db:"total_minutes"`
+		SessionsCount int       `db:"sessions_count"`
+	}
+
+	var rows []result
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get total tracked time by users: %w", err)
+	}
+
+	metrics := make(map[string]*entity.TrackedTimeMetric, len(filter.UserIDs))
+
+	// Пользователи без событий получают нулевую метрику
+	for _, userID := range filter.UserIDs {
+		metrics[userID] = &entity.TrackedTimeMetric{
+			UserID: userID,
+			Period: utils.FormatPeriod(time.Time{}, time.Time{}),
+		}
+	}
+
+	for _, res := range rows {
+		metrics[res.UserID] = &entity.TrackedTimeMetric{
+			UserID:       res.UserID,
+			TotalMinutes: utils.RoundToTwoDecimals(res.TotalMinutes),
+			TotalHours:   utils.RoundToTwoDecimals(res.TotalMinutes / 60),
+			Sessions:     res.SessionsCount,
+			StartTime:    res.ActualStart,
+			EndTime:      res.ActualEnd,
+			Period:       utils.FormatPeriod(res.ActualStart, res.ActualEnd),
+		}
+	}
+
+	return metrics, nil
+}
+
+// Запрос метрик рейтинга по всем активным пользователям расширения организации.
+// Минуты считаются так же, как в optimizedEngagedTimeQuery (минута x домен, idle отметка в минуте
+// делает неактивными все ее домены), deep work - по общей CTE. Оба %s после CTE - условие исключенных доменов
+const leaderboardQuery = `%s,
+minute_activity AS (
+	SELECT
+		user_id,
+		CASE
+			WHEN SUM(COUNT(*) FILTER (WHERE event_type = 'idle')) OVER (PARTITION BY user_id, DATE_TRUNC('minute', timestamp)) > 0 THEN 0
+			ELSE MAX(CASE WHEN event_type = ANY($4::text[]) THEN 1 ELSE 0 END)
+		END AS is_active
+	FROM user_behaviors
+	WHERE user_id IN (SELECT id FROM extension_users WHERE organization_id = $1 AND is_active = true)
+		AND deleted_at IS NULL
+		AND timestamp >= $2
+		AND timestamp <= $3 %s
+	GROUP BY user_id, DATE_TRUNC('minute', timestamp), domain
+),
+engagement AS (
+	SELECT user_id, SUM(is_active) AS active_minutes, COUNT(*) AS tracked_minutes
+	FROM minute_activity
+	GROUP BY user_id
+),
+deep_work AS (
+	SELECT user_id, SUM(duration_minutes) AS deep_work_minutes
+	FROM deep_work_blocks
+	GROUP BY user_id
+)
+SELECT
+	eu.id::text AS user_id,
+	eu.username,
+	eu.owner_user_id,
+	COALESCE(e.active_minutes, 0)::integer AS active_minutes,
+	COALESCE(e.tracked_minutes, 0)::integer AS tracked_minutes,
+	COALESCE(dw.deep_work_minutes, 0)::float8 AS deep_work_minutes
+FROM extension_users eu
+LEFT JOIN engagement e ON e.user_id = eu.id
+LEFT JOIN deep_work dw ON dw.user_id = eu.id
+WHERE eu.organization_id = $1 AND eu.is_active = true`
+
+// GetLeaderboard возвращает метрики пользователей расширения организации без сортировки;
+// пользователи без событий за период получают нулевые значения
+func (r *metricsRepository) GetLeaderboard(ctx context.Context, filter entity.LeaderboardFilter) ([]entity.LeaderboardEntry, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "leaderboard")
+
+	thresholds := r.newDeepWorkThresholds(0, 0, 0)
+	args := []interface{}{filter.OrganizationID, filter.StartTime, filter.EndTime, activeEventsArg(filter.ActiveEvents)}
+	excludedFilter, args := excludedDomainsCondition(filter.ExcludedDomains, args)
+	query := fmt.Sprintf(leaderboardQuery, buildDeepWorkCoreCTEForUsers(deepWorkOrganizationFilter, excludedFilter, thresholds), excludedFilter)
+
+	var entries []entity.LeaderboardEntry
+	if err := r.db.SelectContext(ctx, &entries, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get leaderboard: %w", err)
+	}
+
+	for i := range entries {
+		entries[i].DeepWorkMinutes = utils.RoundToTwoDecimals(entries[i].DeepWorkMinutes)
+		entries[i].TrackedHours = utils.RoundToTwoDecimals(float64(entries[i].TrackedMinutes) / 60)
+		entries[i].EngagementRate = calculateEngagementRate(entries[i].ActiveMinutes, entries[i].TrackedMinutes)
+	}
+
+	return entries, nil
+}
+
 func (r *metricsRepository) GetTopDomains(ctx context.Context, filter entity.TopDomainsFilter) (*entity.TopDomainsResponse, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "top_domains")
+
 	limit := filter.Limit
 	if limit <= 0 || limit > 50 {
 		limit = 10
 	}
 
-	sessionCondition := ""
-	args := []interface{}{filter.UserID, limit}
+	// Постраничный режим: page/per_page имеют приоритет над legacy limit
+	offset := 0
+	paginated := filter.Page > 0 && filter.PerPage > 0
+	if paginated {
+		limit = filter.PerPage
+		if limit > 100 {
+			limit = 100
+		}
+		offset = (filter.Page - 1) * limit
+	}
+
+	extraConditions := ""
+	args := []interface{}{filter.UserID}
 
 	if filter.SessionID != nil {
-		sessionCondition = " AND session_id = $3"
 		args = append(args, *filter.SessionID)
+		extraConditions = fmt.Sprintf(" AND session_id = $%d", len(args))
+	}
+
+	if len(filter.EventTypes) > 0 {
+		args = append(args, pq.Array(filter.EventTypes))
+		extraConditions += fmt.Sprintf(" AND event_type = ANY($%d::text[])", len(args))
+	}
+
+	excludedFilter, args := excludedDomainsCondition(filter.ExcludedDomains, args)
+	extraConditions += excludedFilter
+
+	if filter.StartTime != nil {
+		args = append(args, *filter.StartTime)
+		extraConditions += fmt.Sprintf(" AND timestamp >= $%d", len(args))
+	}
+
+	if filter.EndTime != nil {
+		args = append(args, *filter.EndTime)
+		extraConditions += fmt.Sprintf(" AND timestamp <= $%d", len(args))
 	}
 
-	query := fmt.Sprintf(`
+	domainStatsCTE := fmt.Sprintf(`
 		WITH domain_stats AS (
 			SELECT 
-				CASE 
-					WHEN url ~ '^https?://' THEN 
-						split_part(split_part(url, '://', 2), '/', 1)
-					ELSE 
-						split_part(url, '/', 1)
-				END as domain,
+				domain,
 				COUNT(*) as events_count,
 				COUNT(DISTINCT DATE_TRUNC('minute', timestamp)) as active_minutes,
 				MIN(timestamp) as first_visit,
 				MAX(timestamp) as last_visit
 			FROM user_behaviors 
-			WHERE user_id = $1 
+			WHERE user_id = $1 AND deleted_at IS NULL 
 				AND url IS NOT NULL 
 				AND url != '' %s
-			GROUP BY CASE 
-						WHEN url ~ '^https?://' THEN 
-							split_part(split_part(url, '://', 2), '/', 1)
-						ELSE 
-							split_part(url, '/', 1)
-					END
+			GROUP BY domain
 		),
 		total_stats AS (
 			SELECT 
 				COUNT(DISTINCT domain) as total_domains,
-				SUM(events_count) as total_events
+				COALESCE(SUM(events_count), 0) as total_events
 			FROM domain_stats
-		)
+		)`, extraConditions)
+	filterArgs := args
+
+	args = append(args, limit, offset)
+	query := domainStatsCTE + fmt.Sprintf(`
 		SELECT 
 			ds.domain,
 			ds.events_count,
@@ -776,8 +1818,8 @@ func (r *metricsRepository) GetTopDomains(ctx context.Context, filter entity.Top
 			ts.total_events
 		FROM domain_stats ds
 		CROSS JOIN total_stats ts
-		ORDER BY ds.events_count DESC, ds.active_minutes DESC
-		LIMIT $2`, sessionCondition)
+		ORDER BY ds.events_count DESC, ds.active_minutes DESC, ds.domain ASC
+		LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
 
 	type queryResult struct {
 		Domain        string    `db:"domain"`
@@ -790,31 +1832,15 @@ func (r *metricsRepository) GetTopDomains(ctx context.Context, filter entity.Top
 		TotalEvents   int       `db:"total_events"`
 	}
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
-	if err != nil {
+	var results []queryResult
+	if err := r.db.SelectContext(ctx, &results, query, args...); err != nil {
 		return nil, fmt.Errorf("failed to get top domains: %w", err)
 	}
-	defer rows.Close()
 
 	var domains []entity.DomainStats
 	var totalDomains, totalEvents int
 
-	for rows.Next() {
-		var result queryResult
-		err := rows.Scan(
-			&result.Domain,
-			&result.EventsCount,
-			&result.ActiveMinutes,
-			&result.Percentage,
-			&result.FirstVisit,
-			&result.LastVisit,
-			&result.TotalDomains,
-			&result.TotalEvents,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan domain stats: %w", err)
-		}
-
+	for _, result := range results {
 		domains = append(domains, entity.DomainStats{
 			Domain:        result.Domain,
 			EventsCount:   result.EventsCount,
@@ -830,16 +1856,41 @@ func (r *metricsRepository) GetTopDomains(ctx context.Context, filter entity.Top
 		}
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating domain stats: %w", err)
-	}
-
-	return &entity.TopDomainsResponse{
+	response := &entity.TopDomainsResponse{
 		UserID:       filter.UserID,
 		TotalDomains: totalDomains,
 		TotalEvents:  totalEvents,
 		Domains:      domains,
-	}, nil
+	}
+
+	if !paginated {
+		return response, nil
+	}
+
+	// Страница за пределами выборки не содержит строк - итоги берем отдельно
+	if len(domains) == 0 && offset > 0 {
+		totalsQuery := domainStatsCTE + `
+		SELECT total_domains, total_events FROM total_stats`
+
+		var totals struct {
+			TotalDomains int `db:"total_domains"`
+			TotalEvents  int `db:"total_events"`
+		}
+		if err := r.db.GetContext(ctx, &totals, totalsQuery, filterArgs...); err != nil {
+			return nil, fmt.Errorf("failed to count top domains: %w", err)
+		}
+		response.TotalDomains = totals.TotalDomains
+		response.TotalEvents = totals.TotalEvents
+	}
+
+	response.Pagination = &entity.PaginationInfo{
+		Page:       filter.Page,
+		PerPage:    limit,
+		Total:      response.TotalDomains,
+		TotalPages: (response.TotalDomains + limit - 1) / limit,
+	}
+
+	return response, nil
 }
 
 // Билдеры результатов
@@ -849,6 +1900,7 @@ func (r *metricsRepository) buildEngagedTimeMetricWithDeepWork(
 	deepWorkStats *deepWorkStatsResult,
 	hourlyResults []hourlyBreakdownResult,
 	topDomainsResults []deepWorkDomainResult,
+	idleIntervals []entity.IdleInterval,
 ) *entity.EngagedTimeMetric {
 
 	engagementRate := calculateEngagementRate(result.ActiveMinutes, result.TotalTrackedMinutes)
@@ -903,6 +1955,7 @@ func (r *metricsRepository) buildEngagedTimeMetricWithDeepWork(
 		ActiveMinutes:      result.ActiveMinutes,
 		ActiveHours:        utils.RoundToTwoDecimals(float64(result.ActiveMinutes) / 60),
 		ActiveEvents:       result.ActiveEventsCount,
+		IdleEventsCount:    result.IdleEventsCount,
 		Sessions:           result.SessionsCount,
 		TrackedMinutes:     utils.RoundToTwoDecimals(float64(result.TotalTrackedMinutes)),
 		TrackedHours:       utils.RoundToTwoDecimals(float64(result.TotalTrackedMinutes) / 60),
@@ -920,8 +1973,16 @@ func (r *metricsRepository) buildEngagedTimeMetricWithDeepWork(
 			LongestMinutes: utils.RoundToTwoDecimals(deepWorkStats.MaxDeepMinutes),
 			DeepWorkRate:   deepWorkRate,
 			TopDomains:     topDomains,
+
+			ContextSwitches:    deepWorkStats.TotalContextSwitches,
+			AvgSwitchesPerHour: utils.RoundToTwoDecimals(deepWorkStats.AvgSwitchesPerHour),
+
+			HighFocusBlocks:   deepWorkStats.HighFocusBlocks,
+			MediumFocusBlocks: deepWorkStats.MediumFocusBlocks,
+			LowFocusBlocks:    deepWorkStats.LowFocusBlocks,
 		},
 		HourlyBreakdown: hourlyBreakdown,
+		IdleIntervals:   idleIntervals,
 	}
 }
 
@@ -932,6 +1993,8 @@ func (r *metricsRepository) buildEmptyDeepWorkSessionsResponse(filter entity.Dee
 		EndTime:   filter.EndTime,
 		Period:    utils.FormatPeriod(filter.StartTime, filter.EndTime),
 		Sessions:  []entity.DeepWorkSession{},
+		Limit:     filter.Limit,
+		Offset:    filter.Offset,
 		ContextSwitches: entity.ContextSwitchesStats{
 			TotalSwitches:      0,
 			AvgSwitchesPerHour: 0,
@@ -984,6 +2047,11 @@ func (r *metricsRepository) buildDeepWorkSessionsResponse(filter entity.DeepWork
 		}
 	}
 
+	// Страница за пределами списка блоков
+	if sessions == nil {
+		sessions = []entity.DeepWorkSession{}
+	}
+
 	var deepWorkRate float64
 	if result.TotalTrackedMinutes > 0 {
 		deepWorkRate = utils.RoundToTwoDecimals((result.TotalMinutes / float64(result.TotalTrackedMinutes)) * 100)
@@ -1014,6 +2082,9 @@ func (r *metricsRepository) buildDeepWorkSessionsResponse(filter entity.DeepWork
 
 		DeepWorkContextRatio: utils.RoundToTwoDecimals(result.DeepWorkContextRatio),
 		Sessions:             sessions,
+		Limit:                filter.Limit,
+		Offset:               filter.Offset,
+		HasMore:              filter.Offset+len(sessions) < result.SessionsCount,
 		HourlyBreakdown:      hourlyBreakdown,
 	}
 }
@@ -1043,6 +2114,7 @@ func (r *metricsRepository) buildEmptyEngagedTimeMetric(filter entity.EngagedTim
 			TopDomains:     []entity.DeepWorkDomain{},
 		},
 		HourlyBreakdown: []entity.HourlyData{},
+		IdleIntervals:   []entity.IdleInterval{},
 	}
 }
 
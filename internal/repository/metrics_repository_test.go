@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/jmoiron/sqlx"
+)
+
+const testMetricsUserID = "39b962b6-d4fa-49a6-8f3e-e4ff9b6bb0df"
+
+// openBehaviorsTestDB возвращает соединение с временной таблицей user_behaviors: временная таблица
+// видна только своему соединению и перекрывает основную, поэтому пул ограничен одним соединением
+func openBehaviorsTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	db := openTestDB(t)
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+
+	statements := []string{
+		`SET TIME ZONE 'UTC'`,
+		`CREATE TEMP TABLE user_behaviors (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			session_id VARCHAR(255) NOT NULL,
+			timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+			event_type VARCHAR(50) NOT NULL,
+			url TEXT NOT NULL,
+			user_id UUID,
+			domain VARCHAR(255) NOT NULL DEFAULT '',
+			deleted_at TIMESTAMP NULL
+		)`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("failed to prepare test table: %v", err)
+		}
+	}
+
+	return db
+}
+
+func insertTestBehavior(t *testing.T, db *sqlx.DB, ts time.Time, eventType, domain string) {
+	t.Helper()
+
+	_, err := db.Exec(`
+		INSERT INTO user_behaviors (session_id, timestamp, event_type, url, user_id, domain)
+		VALUES ('session_1', $1, $2, $3, $4, $5)`,
+		ts, eventType, "https://"+domain+"/", testMetricsUserID, domain)
+	if err != nil {
+		t.Fatalf("failed to insert behavior: %v", err)
+	}
+}
+
+func findHour(t *testing.T, hourly []entity.HourlyDeepWorkData, hour int) entity.HourlyDeepWorkData {
+	t.Helper()
+
+	for _, h := range hourly {
+		if h.Hour == hour {
+			return h
+		}
+	}
+	t.Fatalf("hour %d not found in hourly breakdown %+v", hour, hourly)
+	return entity.HourlyDeepWorkData{}
+}
+
+func TestDeepWorkSessionsHourlyRate(t *testing.T) {
+	db := openBehaviorsTestDB(t)
+	repo := NewMetricsRepository(db, entity.FocusThresholds{}, 0)
+
+	// Блок 10:50-11:20 с событием каждую минуту: в 10-м часу 10 отслеживаемых минут и 10 минут deep work,
+	// в 11-м - 21 и 20. Домены чередуются: 4 переключения, 1/3 блока в 10-м часу, 2/3 - в 11-м
+	day := time.Date(2025, 7, 10, 0, 0, 0, 0, time.UTC)
+	for minute := 0; minute <= 30; minute++ {
+		ts := day.Add(10*time.Hour + 50*time.Minute + time.Duration(minute)*time.Minute)
+
+		domain := "a.com"
+		switch {
+		case ts.Hour() == 11 && ts.Minute() < 5, ts.Hour() == 11 && ts.Minute() >= 10 && ts.Minute() < 15:
+			domain = "b.com"
+		}
+		insertTestBehavior(t, db, ts, "click", domain)
+	}
+
+	result, err := repo.GetDeepWorkSessions(context.Background(), entity.DeepWorkSessionsFilter{
+		UserID:              testMetricsUserID,
+		StartTime:           day.Add(10 * time.Hour),
+		EndTime:             day.Add(13 * time.Hour),
+		MinDurationMinutes:  20,
+		GapThresholdSeconds: 300,
+		MinEventsPerBlock:   5,
+		Limit:               100,
+	})
+	if err != nil {
+		t.Fatalf("GetDeepWorkSessions: %v", err)
+	}
+
+	if result.SessionsCount != 1 || result.ContextSwitches.TotalSwitches != 4 {
+		t.Fatalf("expected 1 block with 4 switches, got %d blocks, %d switches", result.SessionsCount, result.ContextSwitches.TotalSwitches)
+	}
+	if len(result.HourlyBreakdown) != 2 {
+		t.Fatalf("expected 2 hours, got %+v", result.HourlyBreakdown)
+	}
+
+	first := findHour(t, result.HourlyBreakdown, 10)
+	if first.TotalMins != 10 || first.DeepWorkMins != 10 || first.DeepWorkRate != 100 {
+		t.Errorf("hour 10: expected 10 tracked, 10 deep work, rate 100, got %+v", first)
+	}
+
+	second := findHour(t, result.HourlyBreakdown, 11)
+	if second.TotalMins != 21 || second.DeepWorkMins != 20 || second.DeepWorkRate != 95.24 {
+		t.Errorf("hour 11: expected 21 tracked, 20 deep work, rate 95.24, got %+v", second)
+	}
+
+	// floor(4 * 1/3) = 1 в первом часу, остаток 3 = 4 - 1 уходит в последний час блока
+	if first.ContextSwitches != 1 || second.ContextSwitches != 3 {
+		t.Errorf("expected switches 1 and 3, got %d and %d", first.ContextSwitches, second.ContextSwitches)
+	}
+}
+
+func TestDeepWorkSessionsHourlyZeroDurationBlock(t *testing.T) {
+	db := openBehaviorsTestDB(t)
+	repo := NewMetricsRepository(db, entity.FocusThresholds{}, 0)
+
+	// Два события с одним временем на разных доменах: блок нулевой длительности с одним переключением
+	ts := time.Date(2025, 7, 10, 12, 30, 0, 0, time.UTC)
+	insertTestBehavior(t, db, ts, "click", "a.com")
+	insertTestBehavior(t, db, ts, "click", "b.com")
+
+	// Нулевая минимальная длительность недоступна через фильтр (0 = значение по умолчанию), поэтому запрос строится напрямую
+	thresholds := repo.newDeepWorkThresholds(0, 300, 1)
+	thresholds.MinDurationMinutes = 0
+
+	filter := entity.DeepWorkSessionsFilter{
+		UserID:    testMetricsUserID,
+		StartTime: ts.Add(-30 * time.Minute),
+		EndTime:   ts.Add(30 * time.Minute),
+		Limit:     100,
+	}
+	args := []interface{}{filter.UserID, filter.StartTime, filter.EndTime, activeEventsArg(nil), filter.Limit, filter.Offset}
+
+	var result deepWorkSessionsResult
+	if err := repo.db.GetContext(context.Background(), &result, buildDeepWorkSessionsQuery("", 5, thresholds), args...); err != nil {
+		t.Fatalf("deep work sessions query: %v", err)
+	}
+	response := repo.buildDeepWorkSessionsResponse(filter, result)
+
+	if response.SessionsCount != 1 || response.ContextSwitches.TotalSwitches != 1 {
+		t.Fatalf("expected 1 block with 1 switch, got %d blocks, %d switches", response.SessionsCount, response.ContextSwitches.TotalSwitches)
+	}
+
+	// Без деления на нулевую длительность блок целиком относится к часу своего начала
+	hour := findHour(t, response.HourlyBreakdown, 12)
+	if hour.ContextSwitches != 1 || hour.DeepWorkMins != 0 || hour.TotalMins != 1 || hour.DeepWorkRate != 0 {
+		t.Errorf("hour 12: expected 1 switch, 0 deep work of 1 tracked minute, got %+v", hour)
+	}
+}
+
+func TestDeepWorkSessionsHourlySwitchesSumToTotal(t *testing.T) {
+	db := openBehaviorsTestDB(t)
+	repo := NewMetricsRepository(db, entity.FocusThresholds{}, 0)
+
+	day := time.Date(2025, 7, 10, 0, 0, 0, 0, time.UTC)
+
+	// Первый блок 09:40-11:10 (20, 60 и 10 минут в трех часах), событие раз в 2 минуты,
+	// домен меняется каждые 7 событий: 6 переключений
+	for i := 0; i <= 45; i++ {
+		ts := day.Add(9*time.Hour + 40*time.Minute + time.Duration(2*i)*time.Minute)
+
+		domain := "a.com"
+		if (i/7)%2 == 1 {
+			domain = "b.com"
+		}
+		insertTestBehavior(t, db, ts, "click", domain)
+	}
+
+	// Второй блок 11:30-11:55 целиком в 11-м часу: начинается с домена, на котором закончился первый,
+	// и переключается один раз в 11:40
+	for minute := 0; minute <= 25; minute++ {
+		ts := day.Add(11*time.Hour + 30*time.Minute + time.Duration(minute)*time.Minute)
+
+		domain := "a.com"
+		if minute >= 10 {
+			domain = "d.com"
+		}
+		insertTestBehavior(t, db, ts, "click", domain)
+	}
+
+	result, err := repo.GetDeepWorkSessions(context.Background(), entity.DeepWorkSessionsFilter{
+		UserID:              testMetricsUserID,
+		StartTime:           day.Add(9 * time.Hour),
+		EndTime:             day.Add(12 * time.Hour),
+		MinDurationMinutes:  20,
+		GapThresholdSeconds: 300,
+		MinEventsPerBlock:   5,
+		Limit:               100,
+	})
+	if err != nil {
+		t.Fatalf("GetDeepWorkSessions: %v", err)
+	}
+
+	if result.SessionsCount != 2 || result.ContextSwitches.TotalSwitches != 7 {
+		t.Fatalf("expected 2 blocks with 7 switches, got %d blocks, %d switches", result.SessionsCount, result.ContextSwitches.TotalSwitches)
+	}
+
+	// Первый блок: floor(6 * 20/90) = 1, floor(6 * 60/90) = 4, последнему часу остаток 6 - 5 = 1
+	// (а не floor(6 * 10/90) = 0); второй блок добавляет в 11-й час свое переключение
+	expected := map[int]int{9: 1, 10: 4, 11: 2}
+	for hour, switches := range expected {
+		if got := findHour(t, result.HourlyBreakdown, hour).ContextSwitches; got != switches {
+			t.Errorf("hour %d: expected %d switches, got %d", hour, switches, got)
+		}
+	}
+	sum := 0
+	for _, h := range result.HourlyBreakdown {
+		sum += h.ContextSwitches
+	}
+	if sum != result.ContextSwitches.TotalSwitches {
+		t.Errorf("hourly switches sum to %d, block total is %d", sum, result.ContextSwitches.TotalSwitches)
+	}
+}
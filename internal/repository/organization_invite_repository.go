@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dinerozz/web-behavior-backend/internal/model/response"
+	"github.com/gofrs/uuid"
+)
+
+const organizationInviteColumns = `id, organization_id, email, COALESCE(username, '') AS username, role, invited_by, expires_at, created_at`
+
+func (r *OrganizationRepository) CreateInvite(orgID uuid.UUID, email, username, role, tokenHash string, invitedBy uuid.UUID, expiresAt time.Time) (response.OrganizationInvite, error) {
+	query := `INSERT INTO organization_invites (organization_id, email, username, role, token_hash, invited_by, expires_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7)
+              RETURNING ` + organizationInviteColumns
+
+	var invite response.OrganizationInvite
+	err := r.db.QueryRowx(query, orgID, email, username, role, tokenHash, invitedBy, expiresAt).StructScan(&invite)
+	if err != nil {
+		return response.OrganizationInvite{}, err
+	}
+
+	return invite, nil
+}
+
+// HasPendingInvite проверяет, есть ли непринятое, неотозванное и неистекшее приглашение на email
+func (r *OrganizationRepository) HasPendingInvite(orgID uuid.UUID, email string) (bool, error) {
+	query := `SELECT EXISTS (
+                  SELECT 1 FROM organization_invites
+                  WHERE organization_id = $1 AND email = $2
+                    AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > NOW())`
+
+	var exists bool
+	if err := r.db.QueryRow(query, orgID, email).Scan(&exists); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (r *OrganizationRepository) GetPendingInvites(orgID uuid.UUID) ([]response.OrganizationInvite, error) {
+	query := `SELECT ` + organizationInviteColumns + `
+              FROM organization_invites
+              WHERE organization_id = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()
+              ORDER BY created_at DESC`
+
+	invites := make([]response.OrganizationInvite, 0)
+	if err := r.db.Select(&invites, query, orgID); err != nil {
+		return nil, err
+	}
+
+	return invites, nil
+}
+
+func (r *OrganizationRepository) RevokeInvite(orgID, inviteID uuid.UUID) error {
+	query := `UPDATE organization_invites SET revoked_at = NOW()
+              WHERE id = $1 AND organization_id = $2 AND accepted_at IS NULL AND revoked_at IS NULL`
+	result, err := r.db.Exec(query, inviteID, orgID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("invite not found")
+	}
+
+	return nil
+}
+
+// AcceptInvite в одной транзакции блокирует приглашение, добавляет пользователя в организацию
+// и помечает приглашение принятым, чтобы токен нельзя было использовать дважды. Принять приглашение может
+// только пользователь с username из приглашения, иначе утекший токен давал бы доступ любому аккаунту
+func (r *OrganizationRepository) AcceptInvite(tokenHash string, userID uuid.UUID) (response.OrganizationInvite, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return response.OrganizationInvite{}, err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + organizationInviteColumns + `, accepted_at, revoked_at
+              FROM organization_invites
+              WHERE token_hash = $1
+              FOR UPDATE`
+
+	var invite response.OrganizationInvite
+	var acceptedAt, revokedAt sql.NullTime
+	err = tx.QueryRow(query, tokenHash).Scan(
+		&invite.ID,
+		&invite.OrganizationID,
+		&invite.Email,
+		&invite.Username,
+		&invite.Role,
+		&invite.InvitedBy,
+		&invite.ExpiresAt,
+		&invite.CreatedAt,
+		&acceptedAt,
+		&revokedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return response.OrganizationInvite{}, fmt.Errorf("invite not found")
+		}
+		return response.OrganizationInvite{}, err
+	}
+
+	if acceptedAt.Valid || revokedAt.Valid {
+		return response.OrganizationInvite{}, fmt.Errorf("invite is no longer valid")
+	}
+
+	if time.Now().After(invite.ExpiresAt) {
+		return response.OrganizationInvite{}, fmt.Errorf("invite has expired")
+	}
+
+	var username string
+	if err = tx.QueryRow(`SELECT username FROM users WHERE id = $1`, userID).Scan(&username); err != nil {
+		return response.OrganizationInvite{}, err
+	}
+	if invite.Username == "" || invite.Username != username {
+		return response.OrganizationInvite{}, fmt.Errorf("invite was issued to another user")
+	}
+
+	var alreadyMember bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM user_organization_access WHERE organization_id = $1 AND user_id = $2)`,
+		invite.OrganizationID, userID).Scan(&alreadyMember)
+	if err != nil {
+		return response.OrganizationInvite{}, err
+	}
+	if alreadyMember {
+		return response.OrganizationInvite{}, fmt.Errorf("user is already in this organization")
+	}
+
+	_, err = tx.Exec(`INSERT INTO user_organization_access (user_id, organization_id, role) VALUES ($1, $2, $3)`,
+		userID, invite.OrganizationID, invite.Role)
+	if err != nil {
+		return response.OrganizationInvite{}, err
+	}
+
+	_, err = tx.Exec(`UPDATE organization_invites SET accepted_at = NOW(), accepted_by = $1 WHERE id = $2`, userID, invite.ID)
+	if err != nil {
+		return response.OrganizationInvite{}, err
+	}
+
+	if err = insertOrgAudit(tx, invite.OrganizationID, userID, userID, AuditActionInviteAccepted, nil, &invite.Role); err != nil {
+		return response.OrganizationInvite{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return response.OrganizationInvite{}, err
+	}
+
+	return invite, nil
+}
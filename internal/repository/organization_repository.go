@@ -2,11 +2,19 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/dinerozz/web-behavior-backend/internal/model/request"
 	"github.com/dinerozz/web-behavior-backend/internal/model/response"
 	"github.com/gofrs/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNoOrganizationAccess - пользователь не состоит в организации
+	ErrNoOrganizationAccess = errors.New("user does not have access to this organization")
+	ErrOrganizationNotFound = errors.New("organization not found")
 )
 
 type OrganizationRepository struct {
@@ -61,9 +69,11 @@ func (r *OrganizationRepository) CreateOrganization(org *request.CreateOrganizat
 
 func (r *OrganizationRepository) GetAll() (*[]response.Organization, error) {
 	var organizations = make([]response.Organization, 0)
-	query := `SELECT id, name, description, created_at, updated_at 
-              FROM organizations 
-              ORDER BY created_at DESC`
+	query := `SELECT o.id, o.name, o.description, o.created_at, o.updated_at, COUNT(uoa.user_id)
+              FROM organizations o
+              LEFT JOIN user_organization_access uoa ON uoa.organization_id = o.id
+              GROUP BY o.id
+              ORDER BY o.created_at DESC`
 
 	rows, err := r.db.Query(query)
 	if err != nil {
@@ -74,6 +84,7 @@ func (r *OrganizationRepository) GetAll() (*[]response.Organization, error) {
 	for rows.Next() {
 		var org response.Organization
 		var description sql.NullString
+		var memberCount int
 
 		err = rows.Scan(
 			&org.ID,
@@ -81,6 +92,7 @@ func (r *OrganizationRepository) GetAll() (*[]response.Organization, error) {
 			&description,
 			&org.CreatedAt,
 			&org.UpdatedAt,
+			&memberCount,
 		)
 		if err != nil {
 			return nil, err
@@ -89,6 +101,7 @@ func (r *OrganizationRepository) GetAll() (*[]response.Organization, error) {
 		if description.Valid {
 			org.Description = &description.String
 		}
+		org.MemberCount = &memberCount
 
 		organizations = append(organizations, org)
 	}
@@ -133,7 +146,8 @@ func (r *OrganizationRepository) GetOrganizationWithMembers(orgID uuid.UUID) (re
 
 	// Get members
 	membersQuery := `
-		SELECT uoa.user_id, u.username, uoa.role, uoa.created_at
+		SELECT uoa.user_id, u.username, uoa.role, uoa.created_at,
+		       (SELECT MAX(eu.last_used_at) FROM extension_users eu WHERE eu.owner_user_id = uoa.user_id) AS last_active_at
 		FROM user_organization_access uoa
 		JOIN users u ON u.id = uoa.user_id
 		WHERE uoa.organization_id = $1
@@ -148,7 +162,7 @@ func (r *OrganizationRepository) GetOrganizationWithMembers(orgID uuid.UUID) (re
 	var members []response.OrganizationMember
 	for rows.Next() {
 		var member response.OrganizationMember
-		err := rows.Scan(&member.UserID, &member.Username, &member.Role, &member.JoinedAt)
+		err := rows.Scan(&member.UserID, &member.Username, &member.Role, &member.JoinedAt, &member.LastActiveAt)
 		if err != nil {
 			return response.OrganizationWithMembers{}, err
 		}
@@ -192,9 +206,43 @@ func (r *OrganizationRepository) UpdateOrganization(orgID uuid.UUID, org *reques
 	return organization, nil
 }
 
-func (r *OrganizationRepository) DeleteOrganization(orgID uuid.UUID) error {
+// CountOrganizationDependents считает участников организации (кроме excludeUserID) и привязанных пользователей расширения
+func (r *OrganizationRepository) CountOrganizationDependents(orgID, excludeUserID uuid.UUID) (int, int, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM user_organization_access WHERE organization_id = $1 AND user_id <> $2),
+			(SELECT COUNT(*) FROM extension_users WHERE organization_id = $1)`
+
+	var members, extensionUsers int
+	if err := r.db.QueryRow(query, orgID, excludeUserID).Scan(&members, &extensionUsers); err != nil {
+		return 0, 0, err
+	}
+
+	return members, extensionUsers, nil
+}
+
+// DeleteOrganization с force в той же транзакции мягко удаляет (deleted_at) события пользователей
+// расширения организации: каскад удалит extension_users, а внешний ключ user_behaviors (ON DELETE SET NULL)
+// оставил бы их события активными без пользователя. Сами строки остаются до очистки по retention
+func (r *OrganizationRepository) DeleteOrganization(orgID uuid.UUID, force bool) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if force {
+		_, err = tx.Exec(`
+			UPDATE user_behaviors SET deleted_at = CURRENT_TIMESTAMP
+			WHERE deleted_at IS NULL
+				AND user_id IN (SELECT id FROM extension_users WHERE organization_id = $1)`, orgID)
+		if err != nil {
+			return err
+		}
+	}
+
 	query := `DELETE FROM organizations WHERE id = $1`
-	result, err := r.db.Exec(query, orgID)
+	result, err := tx.Exec(query, orgID)
 	if err != nil {
 		return err
 	}
@@ -208,15 +256,17 @@ func (r *OrganizationRepository) DeleteOrganization(orgID uuid.UUID) error {
 		return fmt.Errorf("organization not found")
 	}
 
-	return nil
+	return tx.Commit()
 }
 
 func (r *OrganizationRepository) GetUserOrganizations(userID uuid.UUID) (response.UserOrganizations, error) {
 	query := `
-		SELECT o.id, o.name, o.description, uoa.role, uoa.created_at
+		SELECT o.id, o.name, o.description, uoa.role, uoa.created_at, COUNT(m.user_id)
 		FROM user_organization_access uoa
 		JOIN organizations o ON o.id = uoa.organization_id
+		LEFT JOIN user_organization_access m ON m.organization_id = o.id
 		WHERE uoa.user_id = $1
+		GROUP BY o.id, uoa.role, uoa.created_at
 		ORDER BY uoa.created_at ASC`
 
 	rows, err := r.db.Query(query, userID)
@@ -229,7 +279,7 @@ func (r *OrganizationRepository) GetUserOrganizations(userID uuid.UUID) (respons
 	for rows.Next() {
 		var org response.UserOrgAccess
 		var description sql.NullString
-		err := rows.Scan(&org.ID, &org.Name, &description, &org.Role, &org.JoinedAt)
+		err := rows.Scan(&org.ID, &org.Name, &description, &org.Role, &org.JoinedAt, &org.MemberCount)
 		if err != nil {
 			return response.UserOrganizations{}, err
 		}
@@ -247,48 +297,118 @@ func (r *OrganizationRepository) GetUserOrganizations(userID uuid.UUID) (respons
 	}, nil
 }
 
-func (r *OrganizationRepository) AddUserToOrganization(orgID, userID uuid.UUID, role string) error {
-	query := `INSERT INTO user_organization_access (user_id, organization_id, role) VALUES ($1, $2, $3)`
-	_, err := r.db.Exec(query, userID, orgID, role)
+// Действия в журнале org_audit_log
+const (
+	AuditActionMemberAdded    = "member_added"
+	AuditActionMemberRemoved  = "member_removed"
+	AuditActionRoleChanged    = "role_changed"
+	AuditActionInviteAccepted = "invite_accepted"
+)
+
+// insertOrgAudit пишет запись журнала в транзакции изменения, чтобы журнал не расходился с составом организации
+func insertOrgAudit(tx *sqlx.Tx, orgID, actorID, targetUserID uuid.UUID, action string, oldRole, newRole *string) error {
+	query := `INSERT INTO org_audit_log (organization_id, actor_id, target_user_id, action, old_role, new_role)
+              VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := tx.Exec(query, orgID, actorID, targetUserID, action, oldRole, newRole)
 	return err
 }
 
-func (r *OrganizationRepository) RemoveUserFromOrganization(orgID, userID uuid.UUID) error {
-	query := `DELETE FROM user_organization_access WHERE organization_id = $1 AND user_id = $2`
-	result, err := r.db.Exec(query, orgID, userID)
+func (r *OrganizationRepository) AddUserToOrganization(orgID, userID uuid.UUID, role string, actorID uuid.UUID) error {
+	tx, err := r.db.Beginx()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	query := `INSERT INTO user_organization_access (user_id, organization_id, role) VALUES ($1, $2, $3)`
+	if _, err = tx.Exec(query, userID, orgID, role); err != nil {
+		return err
+	}
+
+	if err = insertOrgAudit(tx, orgID, actorID, userID, AuditActionMemberAdded, nil, &role); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *OrganizationRepository) RemoveUserFromOrganization(orgID, userID uuid.UUID, actorID uuid.UUID) error {
+	tx, err := r.db.Beginx()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("user access not found")
+	query := `DELETE FROM user_organization_access WHERE organization_id = $1 AND user_id = $2 RETURNING role`
+	var oldRole string
+	err = tx.QueryRow(query, orgID, userID).Scan(&oldRole)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("user access not found")
+		}
+		return err
 	}
 
-	return nil
+	if err = insertOrgAudit(tx, orgID, actorID, userID, AuditActionMemberRemoved, &oldRole, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (r *OrganizationRepository) UpdateUserRole(orgID, userID uuid.UUID, role string) error {
-	query := `UPDATE user_organization_access SET role = $1 WHERE organization_id = $2 AND user_id = $3`
-	result, err := r.db.Exec(query, role, orgID, userID)
+func (r *OrganizationRepository) UpdateUserRole(orgID, userID uuid.UUID, role string, actorID uuid.UUID) error {
+	tx, err := r.db.Beginx()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	var oldRole string
+	err = tx.QueryRow(`SELECT role FROM user_organization_access WHERE organization_id = $1 AND user_id = $2 FOR UPDATE`,
+		orgID, userID).Scan(&oldRole)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("user access not found")
+		}
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("user access not found")
+	query := `UPDATE user_organization_access SET role = $1 WHERE organization_id = $2 AND user_id = $3`
+	if _, err = tx.Exec(query, role, orgID, userID); err != nil {
+		return err
 	}
 
-	return nil
+	if err = insertOrgAudit(tx, orgID, actorID, userID, AuditActionRoleChanged, &oldRole, &role); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *OrganizationRepository) GetAuditLog(orgID uuid.UUID, limit, offset int) ([]response.OrgAuditEntry, error) {
+	query := `
+		SELECT l.id, l.organization_id, l.actor_id, actor.username AS actor_username,
+		       l.target_user_id, target.username AS target_username,
+		       l.action, l.old_role, l.new_role, l.created_at
+		FROM org_audit_log l
+		LEFT JOIN users actor ON actor.id = l.actor_id
+		LEFT JOIN users target ON target.id = l.target_user_id
+		WHERE l.organization_id = $1
+		ORDER BY l.created_at DESC, l.id
+		LIMIT $2 OFFSET $3`
+
+	entries := make([]response.OrgAuditEntry, 0)
+	if err := r.db.Select(&entries, query, orgID, limit, offset); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (r *OrganizationRepository) CountAuditLog(orgID uuid.UUID) (int, error) {
+	var total int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM org_audit_log WHERE organization_id = $1`, orgID).Scan(&total)
+	return total, err
 }
 
 func (r *OrganizationRepository) CheckUserAccess(orgID, userID uuid.UUID) (string, error) {
@@ -297,13 +417,28 @@ func (r *OrganizationRepository) CheckUserAccess(orgID, userID uuid.UUID) (strin
 	err := r.db.QueryRow(query, orgID, userID).Scan(&role)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return "", fmt.Errorf("user does not have access to this organization")
+			return "", ErrNoOrganizationAccess
 		}
 		return "", err
 	}
 	return role, nil
 }
 
+// CanAccessExtensionUser проверяет, что пользователь админки состоит в организации пользователя расширения
+func (r *OrganizationRepository) CanAccessExtensionUser(userID uuid.UUID, extensionUserID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1
+			FROM extension_users eu
+			JOIN user_organization_access uoa ON uoa.organization_id = eu.organization_id
+			WHERE eu.id = $1 AND uoa.user_id = $2
+		)`
+
+	var allowed bool
+	err := r.db.QueryRow(query, extensionUserID, userID).Scan(&allowed)
+	return allowed, err
+}
+
 func (r *OrganizationRepository) IsUserOrgAdmin(orgID, userID uuid.UUID) (bool, error) {
 	role, err := r.CheckUserAccess(orgID, userID)
 	if err != nil {
@@ -311,3 +446,64 @@ func (r *OrganizationRepository) IsUserOrgAdmin(orgID, userID uuid.UUID) (bool,
 	}
 	return role == "admin", nil
 }
+
+// GetActiveEvents возвращает nil, если для организации используется набор по умолчанию
+func (r *OrganizationRepository) GetActiveEvents(orgID uuid.UUID) ([]string, error) {
+	query := `SELECT active_events FROM organizations WHERE id = $1`
+
+	var activeEvents pq.StringArray
+	err := r.db.QueryRow(query, orgID).Scan(&activeEvents)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+
+	return activeEvents, nil
+}
+
+// UpdateActiveEvents сохраняет набор активных событий; пустой набор сбрасывает на значение по умолчанию
+func (r *OrganizationRepository) UpdateActiveEvents(orgID uuid.UUID, activeEvents []string) error {
+	var value interface{}
+	if len(activeEvents) > 0 {
+		value = pq.Array(activeEvents)
+	}
+
+	query := `UPDATE organizations SET active_events = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	result, err := r.db.Exec(query, value, orgID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrOrganizationNotFound
+	}
+
+	return nil
+}
+
+// GetActiveEventsByExtensionUserID возвращает набор активных событий организации пользователя расширения
+func (r *OrganizationRepository) GetActiveEventsByExtensionUserID(extensionUserID string) ([]string, error) {
+	query := `
+		SELECT o.active_events
+		FROM extension_users eu
+		JOIN organizations o ON o.id = eu.organization_id
+		WHERE eu.id = $1`
+
+	var activeEvents pq.StringArray
+	err := r.db.QueryRow(query, extensionUserID).Scan(&activeEvents)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return activeEvents, nil
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/jmoiron/sqlx"
+)
+
+// queryTimeoutDB ограничивает каждый запрос чтения метрик лимитом timeout. Лимит действует на каждый
+// запрос отдельно, поэтому в методах из нескольких запросов ограничен любой из них, а не только первый.
+// По дедлайну lib/pq отменяет запрос на сервере и освобождает соединение, а ошибка заменяется
+// на entity.ErrQueryTimeout (хендлеры отвечают 504). timeout <= 0 - без лимита
+type queryTimeoutDB struct {
+	db      *sqlx.DB
+	timeout time.Duration
+}
+
+func newQueryTimeoutDB(db *sqlx.DB, timeout time.Duration) *queryTimeoutDB {
+	return &queryTimeoutDB{db: db, timeout: timeout}
+}
+
+func (q *queryTimeoutDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return q.run(ctx, func(ctx context.Context) error {
+		return q.db.GetContext(ctx, dest, query, args...)
+	})
+}
+
+func (q *queryTimeoutDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return q.run(ctx, func(ctx context.Context) error {
+		return q.db.SelectContext(ctx, dest, query, args...)
+	})
+}
+
+func (q *queryTimeoutDB) run(ctx context.Context, query func(ctx context.Context) error) error {
+	if q.timeout <= 0 {
+		return query(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+
+	err := query(ctx)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w (limit %s): %v", entity.ErrQueryTimeout, q.timeout, err)
+	}
+
+	return err
+}
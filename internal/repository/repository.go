@@ -5,7 +5,6 @@ import (
 	"github.com/dinerozz/web-behavior-backend/config"
 	"github.com/jmoiron/sqlx"
 	"log"
-	"time"
 )
 
 func NewRepository(cfg config.DatabaseConfig) (*sqlx.DB, error) {
@@ -24,12 +23,17 @@ func NewRepository(cfg config.DatabaseConfig) (*sqlx.DB, error) {
 		return nil, err
 	}
 
-	// Настройка пула соединений
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(10)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	// Настройка пула соединений: 0 для open/lifetime - без лимита, idle не может превышать open
+	maxIdleConns := cfg.MaxIdleConns
+	if cfg.MaxOpenConns > 0 && maxIdleConns > cfg.MaxOpenConns {
+		maxIdleConns = cfg.MaxOpenConns
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 
-	log.Println("✅ Connected to database")
+	log.Printf("✅ Connected to database (pool: max_open=%d, max_idle=%d, conn_max_lifetime=%s)",
+		cfg.MaxOpenConns, maxIdleConns, cfg.ConnMaxLifetime)
 
 	return db, nil
 }
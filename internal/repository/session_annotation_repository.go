@@ -0,0 +1,89 @@
+// internal/repository/session_annotation_repository.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type SessionAnnotationRepository interface {
+	GetBySession(ctx context.Context, sessionID string) ([]entity.SessionAnnotation, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.SessionAnnotation, error)
+	Create(ctx context.Context, annotation *entity.SessionAnnotation) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type sessionAnnotationRepository struct {
+	db *sqlx.DB
+}
+
+func NewSessionAnnotationRepository(db *sqlx.DB) SessionAnnotationRepository {
+	return &sessionAnnotationRepository{db: db}
+}
+
+const sessionAnnotationSelect = `
+		SELECT a.id, a.session_id, a.tag, a.author_id, u.username AS author_name, a.created_at
+		FROM session_annotations a
+		LEFT JOIN users u ON u.id = a.author_id`
+
+func (r *sessionAnnotationRepository) GetBySession(ctx context.Context, sessionID string) ([]entity.SessionAnnotation, error) {
+	query := sessionAnnotationSelect + `
+		WHERE a.session_id = $1
+		ORDER BY a.created_at, a.id`
+
+	annotations := []entity.SessionAnnotation{}
+	if err := r.db.SelectContext(ctx, &annotations, query, sessionID); err != nil {
+		return nil, fmt.Errorf("failed to get session annotations: %w", err)
+	}
+
+	return annotations, nil
+}
+
+func (r *sessionAnnotationRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.SessionAnnotation, error) {
+	query := sessionAnnotationSelect + `
+		WHERE a.id = $1`
+
+	var annotation entity.SessionAnnotation
+	if err := r.db.GetContext(ctx, &annotation, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get session annotation: %w", err)
+	}
+
+	return &annotation, nil
+}
+
+func (r *sessionAnnotationRepository) Create(ctx context.Context, annotation *entity.SessionAnnotation) error {
+	query := `
+		INSERT INTO session_annotations (id, session_id, tag, author_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at`
+
+	return r.db.QueryRowContext(ctx, query, annotation.ID, annotation.SessionID, annotation.Tag, annotation.AuthorID).
+		Scan(&annotation.CreatedAt)
+}
+
+func (r *sessionAnnotationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM session_annotations WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -7,25 +7,40 @@ import (
 	"errors"
 	"fmt"
 	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/dinerozz/web-behavior-backend/pkg/logging"
+	"github.com/dinerozz/web-behavior-backend/pkg/telemetry"
 	"github.com/gofrs/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
+	"log/slog"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type UserBehaviorRepository interface {
-	Create(ctx context.Context, behavior *entity.UserBehavior) error
-	BatchCreate(ctx context.Context, behaviors []entity.UserBehavior) error
+	Create(ctx context.Context, behavior *entity.UserBehavior) (int64, error)
+	BatchCreate(ctx context.Context, behaviors []entity.UserBehavior) ([]entity.UserBehavior, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*entity.UserBehavior, error)
 	GetByFilter(ctx context.Context, filter entity.UserBehaviorFilter) ([]entity.UserBehavior, error)
 	GetStats(ctx context.Context, filter entity.UserBehaviorFilter) (*entity.UserBehaviorStats, error)
+	GetStatsTimeline(ctx context.Context, filter entity.UserBehaviorFilter, groupBy string) ([]entity.StatsBucket, error)
 	GetSessionSummary(ctx context.Context, sessionID string) (*entity.SessionSummary, error)
 	GetUserSessions(ctx context.Context, userID string, limit, offset int) ([]entity.SessionSummary, error)
 	Delete(ctx context.Context, id uuid.UUID) error
+	Restore(ctx context.Context, id uuid.UUID) error
 	CountByFilter(ctx context.Context, filter entity.UserBehaviorFilter) (int, error)
 	CountUserSessions(ctx context.Context, userID string) (int, error)
 	GetUserEventsCount(ctx context.Context, filter entity.UserEventsCount) (*entity.UserEventsCountResponse, error)
+	GetDistinctEventTypes(ctx context.Context, filter entity.UserEventsCount) ([]entity.EventTypes, error)
+	GetUserActivitySpan(ctx context.Context, userID string) (*entity.UserActivitySpan, error)
+	StreamForExport(ctx context.Context, filter entity.BehaviorExportFilter, fn func(entity.UserBehavior) error) error
+	CountOlderThan(ctx context.Context, before time.Time, soft bool) (int64, error)
+	PurgeBatch(ctx context.Context, before time.Time, batchSize int, soft bool) (int64, error)
+	CountMissingUserNames(ctx context.Context, userID *uuid.UUID) (int64, error)
+	BackfillUserNamesBatch(ctx context.Context, userID *uuid.UUID, batchSize int) (int64, error)
+	CountMissingDomains(ctx context.Context) (int64, error)
+	BackfillDomainsBatch(ctx context.Context, batchSize int) (int64, error)
 }
 
 type userBehaviorRepository struct {
@@ -36,41 +51,94 @@ func NewUserBehaviorRepository(db *sqlx.DB) UserBehaviorRepository {
 	return &userBehaviorRepository{db: db}
 }
 
-func (r *userBehaviorRepository) Create(ctx context.Context, behavior *entity.UserBehavior) error {
+// ErrDuplicateBehavior - восстановление события нарушает idx_user_behaviors_dedup:
+// такое же событие уже сохранено повторно после удаления
+var ErrDuplicateBehavior = errors.New("an identical behavior event already exists")
+
+// Код ошибки PostgreSQL unique_violation
+const pqUniqueViolation = "23505"
+
+// Повторно отправленные расширением события (та же сессия, время, тип и URL) пропускаются
+// по уникальному индексу idx_user_behaviors_dedup
+const behaviorOnConflict = `
+		ON CONFLICT (session_id, timestamp, event_type, md5(url)) WHERE deleted_at IS NULL DO NOTHING`
+
+// Create возвращает число вставленных строк: 0, если событие - дубликат
+func (r *userBehaviorRepository) Create(ctx context.Context, behavior *entity.UserBehavior) (int64, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "behavior_create")
+
 	query := `
-		INSERT INTO user_behaviors (id, session_id, timestamp, event_type, url, user_id, x, y, key, created_at, updated_at)
-		VALUES (:id, :session_id, :timestamp, :event_type, :url, :user_id, :x, :y, :key, :created_at, :updated_at)`
+		INSERT INTO user_behaviors (id, session_id, timestamp, event_type, url, domain, user_id, user_name, x, y, key, created_at, updated_at)
+		VALUES (:id, :session_id, :timestamp, :event_type, :url, :domain, :user_id, :user_name, :x, :y, :key, :created_at, :updated_at)` + behaviorOnConflict
 
-	_, err := r.db.NamedExecContext(ctx, query, behavior)
-	return err
+	result, err := r.db.NamedExecContext(ctx, query, behavior)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to insert behavior", slog.String("error", err.Error()))
+		return 0, err
+	}
+
+	return result.RowsAffected()
 }
 
-func (r *userBehaviorRepository) BatchCreate(ctx context.Context, behaviors []entity.UserBehavior) error {
+// Строк в одном INSERT батча: 12 параметров на строку, Postgres допускает не больше 65535 параметров
+const behaviorBatchInsertChunk = 1000
+
+// BatchCreate вставляет события многострочными INSERT ... VALUES (по behaviorBatchInsertChunk строк)
+// в одной транзакции и возвращает вставленные строки с id; дубликаты, в том числе внутри батча,
+// пропускаются и в результат не попадают
+func (r *userBehaviorRepository) BatchCreate(ctx context.Context, behaviors []entity.UserBehavior) ([]entity.UserBehavior, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "behavior_batch_create")
+
 	if len(behaviors) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer tx.Rollback()
 
-	query := `
-		INSERT INTO user_behaviors (session_id, timestamp, event_type, url, user_id, x, y, key, created_at, updated_at)
-		VALUES (:session_id, :timestamp, :event_type, :url, :user_id, :x, :y, :key, :created_at, :updated_at)`
+	var inserted []entity.UserBehavior
+	for start := 0; start < len(behaviors); start += behaviorBatchInsertChunk {
+		end := min(start+behaviorBatchInsertChunk, len(behaviors))
+		chunk := behaviors[start:end]
+
+		const columns = 12
+		values := make([]string, 0, len(chunk))
+		args := make([]interface{}, 0, len(chunk)*columns)
+		for i, b := range chunk {
+			placeholders := make([]string, columns)
+			for j := range placeholders {
+				placeholders[j] = fmt.Sprintf("$%d", i*columns+j+1)
+			}
+			values = append(values, "("+strings.Join(placeholders, ", ")+")")
+			args = append(args, b.SessionID, b.Timestamp, b.Type, b.URL, b.Domain, b.UserID, b.UserName, b.X, b.Y, b.Key, b.CreatedAt, b.UpdatedAt)
+		}
 
-	_, err = tx.NamedExecContext(ctx, query, behaviors)
-	if err != nil {
-		return err
+		query := `
+		INSERT INTO user_behaviors (session_id, timestamp, event_type, url, domain, user_id, user_name, x, y, key, created_at, updated_at)
+		VALUES ` + strings.Join(values, ", ") + behaviorOnConflict + `
+		RETURNING id, session_id, timestamp, event_type, url, domain, user_id, user_name, x, y, key, created_at, updated_at`
+
+		var rows []entity.UserBehavior
+		if err := tx.SelectContext(ctx, &rows, query, args...); err != nil {
+			logging.FromContext(ctx).Error("failed to batch insert behaviors", slog.Int("count", len(behaviors)), slog.String("error", err.Error()))
+			return nil, err
+		}
+		inserted = append(inserted, rows...)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
 	}
 
-	return tx.Commit()
+	return inserted, nil
 }
 
 func (r *userBehaviorRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.UserBehavior, error) {
 	var behavior entity.UserBehavior
-	query := `SELECT * FROM user_behaviors WHERE id = $1`
+	query := `SELECT * FROM user_behaviors WHERE id = $1 AND deleted_at IS NULL`
 
 	err := r.db.GetContext(ctx, &behavior, query, id)
 	if err != nil {
@@ -84,17 +152,19 @@ func (r *userBehaviorRepository) GetByID(ctx context.Context, id uuid.UUID) (*en
 }
 
 func (r *userBehaviorRepository) GetByFilter(ctx context.Context, filter entity.UserBehaviorFilter) ([]entity.UserBehavior, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "behavior_get_by_filter")
+
 	var behaviors []entity.UserBehavior
 
 	query := `SELECT 
-    ub.id, ub.session_id, ub.event_type, ub.url, ub.user_id, ub.x, ub.y, ub.key,
+    ub.id, ub.session_id, ub.event_type, ub.url, ub.domain, ub.user_id, ub.x, ub.y, ub.key,
     ub.timestamp,
     ub.created_at AT TIME ZONE 'UTC' AT TIME ZONE 'Asia/Almaty' as created_at,
     ub.updated_at AT TIME ZONE 'UTC' AT TIME ZONE 'Asia/Almaty' as updated_at,
     eu.username as user_name
 FROM user_behaviors ub
 LEFT JOIN extension_users eu ON ub.user_id = eu.id
-WHERE 1=1`
+WHERE ub.deleted_at IS NULL`
 
 	var args []interface{}
 	argIndex := 1
@@ -117,6 +187,12 @@ WHERE 1=1`
 		argIndex++
 	}
 
+	if len(filter.EventTypes) > 0 {
+		query += fmt.Sprintf(" AND ub.event_type = ANY($%d)", argIndex)
+		args = append(args, pq.Array(filter.EventTypes))
+		argIndex++
+	}
+
 	if filter.URL != nil {
 		query += fmt.Sprintf(" AND ub.url ILIKE $%d", argIndex)
 		args = append(args, "%"+*filter.URL+"%")
@@ -135,7 +211,28 @@ WHERE 1=1`
 		argIndex++
 	}
 
-	query += " ORDER BY ub.timestamp DESC"
+	// Оба столбца сортируются в одном направлении, чтобы индекс по (timestamp, id) читался в любую сторону,
+	// id делает порядок стабильным при одинаковом timestamp
+	order, comparison := "DESC", "<"
+	if strings.EqualFold(filter.Order, "asc") {
+		order, comparison = "ASC", ">"
+	}
+
+	if filter.CursorMode {
+		if filter.Cursor != nil {
+			query += fmt.Sprintf(" AND (ub.timestamp, ub.id) %s ($%d, $%d)", comparison, argIndex, argIndex+1)
+			args = append(args, filter.Cursor.Timestamp, filter.Cursor.ID)
+			argIndex += 2
+		}
+
+		query += fmt.Sprintf(" ORDER BY ub.timestamp %s, ub.id %s LIMIT $%d", order, order, argIndex)
+		args = append(args, filter.Limit)
+
+		err := r.db.SelectContext(ctx, &behaviors, query, args...)
+		return behaviors, err
+	}
+
+	query += fmt.Sprintf(" ORDER BY ub.timestamp %s, ub.id %s", order, order)
 
 	if filter.Page > 0 && filter.PerPage > 0 {
 		offset := (filter.Page - 1) * filter.PerPage
@@ -158,12 +255,14 @@ WHERE 1=1`
 }
 
 func (r *userBehaviorRepository) CountByFilter(ctx context.Context, filter entity.UserBehaviorFilter) (int, error) {
-	query := "SELECT COUNT(*) FROM user_behaviors WHERE 1=1"
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "behavior_count_by_filter")
+
+	query := "SELECT COUNT(*) FROM user_behaviors WHERE deleted_at IS NULL"
 	var args []interface{}
 	argIndex := 1
 
 	if filter.UserID != nil {
-		query += fmt.Sprintf(" AND ub.user_id = $%d", argIndex)
+		query += fmt.Sprintf(" AND user_id = $%d", argIndex)
 		args = append(args, filter.UserID)
 		argIndex++
 	}
@@ -180,6 +279,12 @@ func (r *userBehaviorRepository) CountByFilter(ctx context.Context, filter entit
 		argIndex++
 	}
 
+	if len(filter.EventTypes) > 0 {
+		query += fmt.Sprintf(" AND event_type = ANY($%d)", argIndex)
+		args = append(args, pq.Array(filter.EventTypes))
+		argIndex++
+	}
+
 	if filter.URL != nil {
 		query += fmt.Sprintf(" AND url ILIKE $%d", argIndex)
 		args = append(args, "%"+*filter.URL+"%")
@@ -229,6 +334,13 @@ func (r *userBehaviorRepository) GetStats(ctx context.Context, filter entity.Use
 		return nil, err
 	}
 
+	domainsWhereClause, domainsArgs := r.buildDomainsWhereClause(filter)
+	uniqueDomainsQuery := "SELECT COUNT(DISTINCT domain) FROM user_behaviors" + domainsWhereClause
+	err = r.db.GetContext(ctx, &stats.UniqueDomains, uniqueDomainsQuery, domainsArgs...)
+	if err != nil {
+		return nil, err
+	}
+
 	eventTypesQuery := "SELECT event_type, COUNT(*) FROM user_behaviors" + whereClause + " GROUP BY event_type"
 	rows, err := r.db.QueryContext(ctx, eventTypesQuery, args...)
 	if err != nil {
@@ -259,18 +371,17 @@ func (r *userBehaviorRepository) GetSessionSummary(ctx context.Context, sessionI
 		SELECT 
 			session_id,
 			user_id,
-			user_name,
+			MAX(user_name) as user_name,
 			MIN(timestamp) as start_time,
 			MAX(timestamp) as end_time,
 			EXTRACT(EPOCH FROM (MAX(timestamp) - MIN(timestamp))) as duration,
 			COUNT(*) as events_count,
 			array_agg(DISTINCT url) as urls
 		FROM user_behaviors 
-		WHERE session_id = $1 
-		GROUP BY session_id, user_id, user_name`
+		WHERE session_id = $1 AND deleted_at IS NULL
+		GROUP BY session_id, user_id`
 
 	var summary entity.SessionSummary
-	var urls []string
 
 	err := r.db.QueryRowContext(ctx, query, sessionID).Scan(
 		&summary.SessionID,
@@ -290,7 +401,32 @@ func (r *userBehaviorRepository) GetSessionSummary(ctx context.Context, sessionI
 		return nil, err
 	}
 
-	summary.URLs = urls
+	eventsByTypeQuery := `
+		SELECT event_type, COUNT(*)
+		FROM user_behaviors 
+		WHERE session_id = $1 AND deleted_at IS NULL
+		GROUP BY event_type`
+
+	rows, err := r.db.QueryContext(ctx, eventsByTypeQuery, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summary.EventsByType = make(map[string]int)
+	for rows.Next() {
+		var eventType string
+		var count int
+		if err := rows.Scan(&eventType, &count); err != nil {
+			return nil, err
+		}
+		summary.EventsByType[eventType] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
 	return &summary, nil
 }
 
@@ -301,15 +437,15 @@ func (r *userBehaviorRepository) GetUserSessions(ctx context.Context, userID str
         SELECT 
             session_id,
             user_id,
-            user_name,
+            MAX(user_name) as user_name,
             MIN(timestamp AT TIME ZONE 'UTC' AT TIME ZONE 'Asia/Almaty') as start_time,
             MAX(timestamp AT TIME ZONE 'UTC' AT TIME ZONE 'Asia/Almaty') as end_time,
             EXTRACT(EPOCH FROM (MAX(timestamp) - MIN(timestamp))) as duration,
             COUNT(*) as events_count,
             array_agg(DISTINCT url) as urls
         FROM user_behaviors 
-        WHERE user_id = $1
-        GROUP BY session_id, user_id, user_name
+        WHERE user_id = $1 AND deleted_at IS NULL
+        GROUP BY session_id, user_id
         ORDER BY MIN(timestamp) DESC
         LIMIT $2 OFFSET $3`
 
@@ -355,17 +491,41 @@ func (r *userBehaviorRepository) CountUserSessions(ctx context.Context, userID s
 	query := `
         SELECT COUNT(DISTINCT session_id)
         FROM user_behaviors 
-        WHERE user_id = $1`
+        WHERE user_id = $1 AND deleted_at IS NULL`
 
 	var count int
 	err := r.db.QueryRowContext(ctx, query, userID).Scan(&count)
 	return count, err
 }
 
+// Delete выполняет мягкое удаление: событие скрывается из выборок, но остается в БД
 func (r *userBehaviorRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	query := "DELETE FROM user_behaviors WHERE id = $1"
+	query := "UPDATE user_behaviors SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL"
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *userBehaviorRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	query := "UPDATE user_behaviors SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL"
 	result, err := r.db.ExecContext(ctx, query, id)
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation {
+			return ErrDuplicateBehavior
+		}
 		return err
 	}
 
@@ -381,8 +541,248 @@ func (r *userBehaviorRepository) Delete(ctx context.Context, id uuid.UUID) error
 	return nil
 }
 
+// GetStatsTimeline считает события по бакетам DATE_TRUNC (UTC). groupBy должен быть провалидирован
+// по entity.StatsGroupByUnits, так как подставляется в запрос
+func (r *userBehaviorRepository) GetStatsTimeline(ctx context.Context, filter entity.UserBehaviorFilter, groupBy string) ([]entity.StatsBucket, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "behavior_stats_timeline")
+
+	if _, ok := entity.StatsGroupByUnits[groupBy]; !ok {
+		return nil, fmt.Errorf("invalid group_by: %s", groupBy)
+	}
+
+	whereClause, args := r.buildWhereClause(filter)
+
+	query := fmt.Sprintf(`
+		SELECT DATE_TRUNC('%s', timestamp AT TIME ZONE 'UTC') AS bucket, event_type, COUNT(*)
+		FROM user_behaviors%s
+		GROUP BY bucket, event_type
+		ORDER BY bucket`, groupBy, whereClause)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := []entity.StatsBucket{}
+	for rows.Next() {
+		var bucket time.Time
+		var eventType string
+		var count int64
+		if err := rows.Scan(&bucket, &eventType, &count); err != nil {
+			return nil, err
+		}
+
+		bucket = time.Date(bucket.Year(), bucket.Month(), bucket.Day(), bucket.Hour(), 0, 0, 0, time.UTC)
+		if len(buckets) == 0 || !buckets[len(buckets)-1].Bucket.Equal(bucket) {
+			buckets = append(buckets, entity.StatsBucket{Bucket: bucket, ByType: make(map[string]int64)})
+		}
+
+		last := &buckets[len(buckets)-1]
+		last.Total += count
+		last.ByType[eventType] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.fillTimelineDomains(ctx, filter, groupBy, buckets); err != nil {
+		return nil, err
+	}
+
+	return buckets, nil
+}
+
+// fillTimelineDomains дописывает в бакеты число разных доменов и доменов, впервые встреченных
+// за период запроса (первый бакет домена), - по ним видно, как меняется широта браузинга
+func (r *userBehaviorRepository) fillTimelineDomains(ctx context.Context, filter entity.UserBehaviorFilter, groupBy string, buckets []entity.StatsBucket) error {
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	whereClause, args := r.buildDomainsWhereClause(filter)
+
+	query := fmt.Sprintf(`
+		WITH bucket_domains AS (
+			SELECT DATE_TRUNC('%s', timestamp AT TIME ZONE 'UTC') AS bucket, domain
+			FROM user_behaviors%s
+			GROUP BY bucket, domain
+		),
+		first_seen AS (
+			SELECT bucket, MIN(bucket) OVER (PARTITION BY domain) AS first_bucket
+			FROM bucket_domains
+		)
+		SELECT bucket, COUNT(*), COUNT(*) FILTER (WHERE bucket = first_bucket)
+		FROM first_seen
+		GROUP BY bucket`, groupBy, whereClause)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	index := make(map[time.Time]int, len(buckets))
+	for i, bucket := range buckets {
+		index[bucket.Bucket] = i
+	}
+
+	for rows.Next() {
+		var bucket time.Time
+		var uniqueDomains, newDomains int64
+		if err := rows.Scan(&bucket, &uniqueDomains, &newDomains); err != nil {
+			return err
+		}
+
+		bucket = time.Date(bucket.Year(), bucket.Month(), bucket.Day(), bucket.Hour(), 0, 0, 0, time.UTC)
+		if i, ok := index[bucket]; ok {
+			buckets[i].UniqueDomains = uniqueDomains
+			buckets[i].NewDomains = newDomains
+		}
+	}
+
+	return rows.Err()
+}
+
+// CountOlderThan считает события старше before, которые затронет очистка.
+// При soft=true уже помеченные deleted_at не учитываются
+func (r *userBehaviorRepository) CountOlderThan(ctx context.Context, before time.Time, soft bool) (int64, error) {
+	query := "SELECT COUNT(*) FROM user_behaviors WHERE timestamp < $1"
+	if soft {
+		query += " AND deleted_at IS NULL"
+	}
+
+	var count int64
+	err := r.db.GetContext(ctx, &count, query, before)
+	return count, err
+}
+
+// PurgeBatch удаляет (или помечает deleted_at при soft=true) не более batchSize событий старше before
+func (r *userBehaviorRepository) PurgeBatch(ctx context.Context, before time.Time, batchSize int, soft bool) (int64, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "behavior_purge_batch")
+
+	query := `
+		DELETE FROM user_behaviors
+		WHERE id IN (
+			SELECT id FROM user_behaviors
+			WHERE timestamp < $1
+			LIMIT $2
+		)`
+	if soft {
+		query = `
+		UPDATE user_behaviors SET deleted_at = CURRENT_TIMESTAMP
+		WHERE id IN (
+			SELECT id FROM user_behaviors
+			WHERE timestamp < $1 AND deleted_at IS NULL
+			LIMIT $2
+		)`
+	}
+
+	result, err := r.db.ExecContext(ctx, query, before, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// CountMissingUserNames - число событий без user_name, которые можно заполнить из extension_users
+func (r *userBehaviorRepository) CountMissingUserNames(ctx context.Context, userID *uuid.UUID) (int64, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM user_behaviors ub
+		JOIN extension_users eu ON eu.id = ub.user_id
+		WHERE ub.user_name IS NULL AND ub.deleted_at IS NULL`
+
+	var args []interface{}
+	if userID != nil {
+		query += " AND ub.user_id = $1"
+		args = append(args, *userID)
+	}
+
+	var count int64
+	if err := r.db.GetContext(ctx, &count, query, args...); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// BackfillUserNamesBatch заполняет user_name из extension_users по user_id для одной пачки событий
+func (r *userBehaviorRepository) BackfillUserNamesBatch(ctx context.Context, userID *uuid.UUID, batchSize int) (int64, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "behavior_backfill_user_names")
+
+	userFilter := ""
+	args := []interface{}{batchSize}
+	if userID != nil {
+		userFilter = " AND b.user_id = $2"
+		args = append(args, *userID)
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE user_behaviors ub SET user_name = eu.username
+		FROM extension_users eu
+		WHERE eu.id = ub.user_id AND ub.id IN (
+			SELECT b.id FROM user_behaviors b
+			JOIN extension_users e ON e.id = b.user_id
+			WHERE b.user_name IS NULL AND b.deleted_at IS NULL%s
+			LIMIT $1
+		)`, userFilter)
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// domainFromURLExpr - SQL-версия utils.NormalizeDomain для заполнения domain старых событий
+const domainFromURLExpr = `regexp_replace(
+	lower(split_part(
+		CASE
+			WHEN url ~ '^https?://' THEN split_part(split_part(url, '://', 2), '/', 1)
+			ELSE split_part(url, '/', 1)
+		END,
+		':', 1
+	)),
+	'^www\.', ''
+)`
+
+// missingDomainCondition - события с пустым domain, для которых url дает непустой домен.
+// Строки, из url которых домен не извлекается, не выбираются, иначе пачки не заканчивались бы
+const missingDomainCondition = `domain = '' AND url IS NOT NULL AND url != '' AND ` + domainFromURLExpr + ` != ''`
+
+// CountMissingDomains - число событий с пустым domain, добавленных до миграции 000016
+func (r *userBehaviorRepository) CountMissingDomains(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM user_behaviors WHERE `+missingDomainCondition)
+	return count, err
+}
+
+// BackfillDomainsBatch заполняет domain по url для одной пачки событий. Каждый вызов - отдельная
+// транзакция, поэтому блокировки держатся только на строках пачки
+func (r *userBehaviorRepository) BackfillDomainsBatch(ctx context.Context, batchSize int) (int64, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "behavior_backfill_domains")
+
+	query := `
+		UPDATE user_behaviors SET domain = ` + domainFromURLExpr + `
+		WHERE id IN (
+			SELECT id FROM user_behaviors
+			WHERE ` + missingDomainCondition + `
+			LIMIT $1
+		)`
+
+	result, err := r.db.ExecContext(ctx, query, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
 func (r *userBehaviorRepository) buildWhereClause(filter entity.UserBehaviorFilter) (string, []interface{}) {
-	var conditions []string
+	conditions := []string{"deleted_at IS NULL"}
 	var args []interface{}
 	argIndex := 1
 
@@ -404,6 +804,12 @@ func (r *userBehaviorRepository) buildWhereClause(filter entity.UserBehaviorFilt
 		argIndex++
 	}
 
+	if len(filter.EventTypes) > 0 {
+		conditions = append(conditions, fmt.Sprintf("event_type = ANY($%d)", argIndex))
+		args = append(args, pq.Array(filter.EventTypes))
+		argIndex++
+	}
+
 	if filter.URL != nil {
 		conditions = append(conditions, fmt.Sprintf("url ILIKE $%d", argIndex))
 		args = append(args, "%"+*filter.URL+"%")
@@ -429,8 +835,22 @@ func (r *userBehaviorRepository) buildWhereClause(filter entity.UserBehaviorFilt
 	return " WHERE " + strings.Join(conditions, " AND "), args
 }
 
+// buildDomainsWhereClause - условия GetStats для подсчета доменов: события без домена
+// и домены из списка исключений не учитываются
+func (r *userBehaviorRepository) buildDomainsWhereClause(filter entity.UserBehaviorFilter) (string, []interface{}) {
+	whereClause, args := r.buildWhereClause(filter)
+	whereClause += " AND domain <> ''"
+
+	if len(filter.ExcludedDomains) > 0 {
+		args = append(args, pq.Array(filter.ExcludedDomains))
+		whereClause += fmt.Sprintf(" AND domain <> ALL($%d::text[])", len(args))
+	}
+
+	return whereClause, args
+}
+
 func (r *userBehaviorRepository) buildWhereClauseWithExtra(filter entity.UserBehaviorFilter, extraConditions ...string) (string, []interface{}) {
-	var conditions []string
+	conditions := []string{"deleted_at IS NULL"}
 	var args []interface{}
 	argIndex := 1
 
@@ -452,6 +872,12 @@ func (r *userBehaviorRepository) buildWhereClauseWithExtra(filter entity.UserBeh
 		argIndex++
 	}
 
+	if len(filter.EventTypes) > 0 {
+		conditions = append(conditions, fmt.Sprintf("event_type = ANY($%d)", argIndex))
+		args = append(args, pq.Array(filter.EventTypes))
+		argIndex++
+	}
+
 	if filter.URL != nil {
 		conditions = append(conditions, fmt.Sprintf("url ILIKE $%d", argIndex))
 		args = append(args, "%"+*filter.URL+"%")
@@ -480,33 +906,32 @@ func (r *userBehaviorRepository) buildWhereClauseWithExtra(filter entity.UserBeh
 }
 
 func (r *userBehaviorRepository) GetUserEventsCount(ctx context.Context, filter entity.UserEventsCount) (*entity.UserEventsCountResponse, error) {
-	// Базовый запрос
-	query := `SELECT event_type, COUNT(*) as event_count FROM user_behaviors WHERE 1=1`
+	// Общие условия для счетчиков событий и сессий
+	whereClause := " WHERE deleted_at IS NULL"
 	var args []interface{}
 	argIndex := 1
 
 	// Динамически добавляем условия
 	if filter.UserID != "" {
-		query += fmt.Sprintf(" AND user_id = $%d", argIndex)
+		whereClause += fmt.Sprintf(" AND user_id = $%d", argIndex)
 		args = append(args, filter.UserID)
 		argIndex++
 	}
 
 	if filter.StartTime != nil {
-		query += fmt.Sprintf(" AND timestamp >= $%d", argIndex)
+		whereClause += fmt.Sprintf(" AND timestamp >= $%d", argIndex)
 		args = append(args, *filter.StartTime)
 		argIndex++
 	}
 
 	if filter.EndTime != nil {
-		query += fmt.Sprintf(" AND timestamp <= $%d", argIndex)
+		whereClause += fmt.Sprintf(" AND timestamp <= $%d", argIndex)
 		args = append(args, *filter.EndTime)
 		argIndex++
 	}
 
-	query += " GROUP BY event_type ORDER BY event_count DESC"
-
-	fmt.Printf("Executing query: %s\nWith args: %+v\n", query, args)
+	query := `SELECT event_type, COUNT(*) as event_count, COUNT(DISTINCT session_id) as session_count FROM user_behaviors` +
+		whereClause + " GROUP BY event_type ORDER BY event_count DESC"
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -519,17 +944,16 @@ func (r *userBehaviorRepository) GetUserEventsCount(ctx context.Context, filter
 
 	for rows.Next() {
 		var eventType string
-		var count int
+		var count, sessions int
 
-		if err = rows.Scan(&eventType, &count); err != nil {
+		if err = rows.Scan(&eventType, &count, &sessions); err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
 
-		fmt.Printf("Scanned event: %s, count: %d\n", eventType, count)
-
 		events = append(events, entity.EventTypes{
-			Event:  eventType,
-			Amount: count,
+			Event:    eventType,
+			Amount:   count,
+			Sessions: sessions,
 		})
 
 		totalEvents += count
@@ -539,7 +963,13 @@ func (r *userBehaviorRepository) GetUserEventsCount(ctx context.Context, filter
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
 
-	fmt.Printf("Final events slice: %+v, total: %d\n", events, totalEvents)
+	// Сессии считаются отдельно: одна сессия может содержать события разных типов,
+	// поэтому сумма по типам больше числа уникальных сессий
+	var totalSessions int
+	sessionsQuery := `SELECT COUNT(DISTINCT session_id) FROM user_behaviors` + whereClause
+	if err = r.db.GetContext(ctx, &totalSessions, sessionsQuery, args...); err != nil {
+		return nil, fmt.Errorf("failed to count sessions: %w", err)
+	}
 
 	return &entity.UserEventsCountResponse{
 		StartTime: filter.StartTime,
@@ -547,9 +977,121 @@ func (r *userBehaviorRepository) GetUserEventsCount(ctx context.Context, filter
 		UserID:    filter.UserID,
 		Events:    events,
 		Total:     totalEvents,
+		Sessions:  totalSessions,
 	}, nil
 }
 
+// GetDistinctEventTypes возвращает встречавшиеся у пользователя типы событий с количеством,
+// запрос покрывается индексом idx_user_behaviors_main_query (user_id, timestamp, event_type)
+func (r *userBehaviorRepository) GetDistinctEventTypes(ctx context.Context, filter entity.UserEventsCount) ([]entity.EventTypes, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "distinct_event_types")
+
+	query := `SELECT event_type, COUNT(*) FROM user_behaviors WHERE deleted_at IS NULL AND user_id = $1`
+	args := []interface{}{filter.UserID}
+
+	if filter.StartTime != nil {
+		args = append(args, *filter.StartTime)
+		query += fmt.Sprintf(" AND timestamp >= $%d", len(args))
+	}
+
+	if filter.EndTime != nil {
+		args = append(args, *filter.EndTime)
+		query += fmt.Sprintf(" AND timestamp <= $%d", len(args))
+	}
+
+	query += " GROUP BY event_type ORDER BY event_type"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query distinct event types: %w", err)
+	}
+	defer rows.Close()
+
+	eventTypes := []entity.EventTypes{}
+	for rows.Next() {
+		var eventType entity.EventTypes
+		if err := rows.Scan(&eventType.Event, &eventType.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan event type: %w", err)
+		}
+		eventTypes = append(eventTypes, eventType)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return eventTypes, nil
+}
+
+// GetUserActivitySpan возвращает первое и последнее событие пользователя и их общее число,
+// MIN/MAX читаются из индекса idx_user_behaviors_main_query (user_id, timestamp, event_type)
+func (r *userBehaviorRepository) GetUserActivitySpan(ctx context.Context, userID string) (*entity.UserActivitySpan, error) {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "user_activity_span")
+
+	query := `SELECT MIN(timestamp), MAX(timestamp), COUNT(*)
+FROM user_behaviors
+WHERE deleted_at IS NULL AND user_id = $1`
+
+	span := &entity.UserActivitySpan{UserID: userID}
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&span.FirstEventAt, &span.LastEventAt, &span.TotalEvents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user activity span: %w", err)
+	}
+
+	return span, nil
+}
+
+// StreamForExport читает события построчно через курсор rows и передает каждое в fn, не собирая выборку в память.
+// Ошибка fn (например, клиент отключился) прерывает чтение
+func (r *userBehaviorRepository) StreamForExport(ctx context.Context, filter entity.BehaviorExportFilter, fn func(entity.UserBehavior) error) error {
+	defer telemetry.DBQueryDuration.ObserveSince(time.Now(), "behavior_export")
+
+	query := `SELECT 
+    ub.id, ub.session_id, ub.event_type, ub.url, ub.domain, ub.user_id, ub.x, ub.y, ub.key,
+    ub.timestamp, ub.created_at, ub.updated_at,
+    eu.username as user_name
+FROM user_behaviors ub
+LEFT JOIN extension_users eu ON ub.user_id = eu.id
+WHERE ub.deleted_at IS NULL AND ub.timestamp >= $1 AND ub.timestamp <= $2`
+
+	args := []interface{}{filter.StartTime, filter.EndTime}
+
+	if filter.UserID != nil {
+		args = append(args, *filter.UserID)
+		query += fmt.Sprintf(" AND ub.user_id = $%d", len(args))
+	}
+
+	if filter.SessionID != nil {
+		args = append(args, *filter.SessionID)
+		query += fmt.Sprintf(" AND ub.session_id = $%d", len(args))
+	}
+
+	query += " ORDER BY ub.timestamp, ub.id"
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query behaviors for export: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var behavior entity.UserBehavior
+		if err := rows.StructScan(&behavior); err != nil {
+			return fmt.Errorf("failed to scan behavior: %w", err)
+		}
+
+		if err := fn(behavior); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return nil
+}
+
 type StringSlice []string
 
 func (s *StringSlice) Scan(value interface{}) error {
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/gofrs/uuid"
+)
+
+func TestUserBehaviorRestoreConflict(t *testing.T) {
+	db := openBehaviorsTestDB(t)
+	repo := NewUserBehaviorRepository(db)
+
+	if _, err := db.Exec(`
+		CREATE UNIQUE INDEX idx_user_behaviors_dedup
+		ON user_behaviors (session_id, timestamp, event_type, md5(url))
+		WHERE deleted_at IS NULL`); err != nil {
+		t.Fatalf("failed to create dedup index: %v", err)
+	}
+
+	// Событие удалено, после чего расширение прислало его повторно
+	ts := time.Date(2025, 7, 10, 12, 0, 0, 0, time.UTC)
+	insertTestBehavior(t, db, ts, "click", "a.com")
+
+	var deletedID uuid.UUID
+	if err := db.Get(&deletedID, `UPDATE user_behaviors SET deleted_at = NOW() RETURNING id`); err != nil {
+		t.Fatalf("failed to delete behavior: %v", err)
+	}
+	insertTestBehavior(t, db, ts, "click", "a.com")
+
+	if err := repo.Restore(context.Background(), deletedID); !errors.Is(err, ErrDuplicateBehavior) {
+		t.Fatalf("expected ErrDuplicateBehavior, got %v", err)
+	}
+
+	// Уже активное событие восстанавливать нечего
+	var activeID uuid.UUID
+	if err := db.Get(&activeID, `SELECT id FROM user_behaviors WHERE deleted_at IS NULL`); err != nil {
+		t.Fatalf("failed to get active behavior: %v", err)
+	}
+	if err := repo.Restore(context.Background(), activeID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+}
+
+func TestUserBehaviorBatchCreateReturnsInsertedRows(t *testing.T) {
+	db := openBehaviorsTestDB(t)
+	repo := NewUserBehaviorRepository(db)
+
+	statements := []string{
+		`ALTER TABLE user_behaviors
+			ADD COLUMN user_name VARCHAR(255),
+			ADD COLUMN x INTEGER,
+			ADD COLUMN y INTEGER,
+			ADD COLUMN key VARCHAR(255),
+			ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT NOW()`,
+		`CREATE UNIQUE INDEX idx_user_behaviors_dedup
+			ON user_behaviors (session_id, timestamp, event_type, md5(url))
+			WHERE deleted_at IS NULL`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("failed to prepare test table: %v", err)
+		}
+	}
+
+	ts := time.Date(2025, 7, 10, 12, 0, 0, 0, time.UTC)
+	insertTestBehavior(t, db, ts, "click", "a.com")
+
+	newBehavior := func(ts time.Time, domain string) entity.UserBehavior {
+		return entity.UserBehavior{
+			SessionID: "session_1",
+			Timestamp: ts,
+			Type:      "click",
+			URL:       "https://" + domain + "/",
+			Domain:    domain,
+			CreatedAt: ts,
+			UpdatedAt: ts,
+		}
+	}
+
+	// Первое событие уже сохранено, третье повторяет второе внутри батча
+	inserted, err := repo.BatchCreate(context.Background(), []entity.UserBehavior{
+		newBehavior(ts, "a.com"),
+		newBehavior(ts.Add(time.Minute), "b.com"),
+		newBehavior(ts.Add(time.Minute), "b.com"),
+	})
+	if err != nil {
+		t.Fatalf("BatchCreate failed: %v", err)
+	}
+
+	if len(inserted) != 1 {
+		t.Fatalf("expected 1 inserted behavior, got %d: %+v", len(inserted), inserted)
+	}
+	if inserted[0].ID == uuid.Nil || inserted[0].Domain != "b.com" || !inserted[0].Timestamp.Equal(ts.Add(time.Minute)) {
+		t.Errorf("unexpected inserted behavior %+v", inserted[0])
+	}
+}
@@ -2,12 +2,21 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
 	"github.com/dinerozz/web-behavior-backend/internal/model/request"
 	"github.com/dinerozz/web-behavior-backend/internal/model/response"
 	"github.com/gofrs/uuid"
 	"github.com/jmoiron/sqlx"
 )
 
+var (
+	ErrUserNotFound = errors.New("user not found")
+	// ErrLastSuperAdmin - снятие прав оставило бы систему без супер админов
+	ErrLastSuperAdmin = errors.New("cannot demote the only super admin")
+)
+
 type UserRepository struct {
 	db *sqlx.DB
 }
@@ -107,16 +116,77 @@ func (r *UserRepository) IsUserSuperAdmin(userID uuid.UUID) (bool, error) {
 	return isSuperAdmin.Valid && isSuperAdmin.Bool, nil
 }
 
-func (r *UserRepository) GetAllUsers() ([]response.User, error) {
-	query := `SELECT id, username, is_super_admin, created_at, updated_at FROM users ORDER BY created_at DESC`
+// buildUserConditions собирает условия фильтра списка пользователей
+func buildUserConditions(filter entity.UserFilter) (string, []interface{}) {
+	conditions := ""
+	args := []interface{}{}
+
+	if filter.Search != "" {
+		args = append(args, "%"+filter.Search+"%")
+		conditions += fmt.Sprintf(" AND username ILIKE $%d", len(args))
+	}
+
+	if filter.IsSuperAdmin != nil {
+		args = append(args, *filter.IsSuperAdmin)
+		conditions += fmt.Sprintf(" AND COALESCE(is_super_admin, false) = $%d", len(args))
+	}
+
+	return conditions, args
+}
+
+// SetSuperAdmin выставляет флаг is_super_admin пользователю. Снятие прав выполняется в транзакции
+// с блокировкой строк супер админов, поэтому параллельные снятия не могут оставить систему без
+// супер админов: следующая транзакция ждет коммита и видит уже обновленные строки
+func (r *UserRepository) SetSuperAdmin(userID uuid.UUID, isSuperAdmin bool) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if !isSuperAdmin {
+		var superAdminIDs []uuid.UUID
+		err = tx.Select(&superAdminIDs, `SELECT id FROM users WHERE is_super_admin = true FOR UPDATE`)
+		if err != nil {
+			return err
+		}
+
+		if len(superAdminIDs) == 1 && superAdminIDs[0] == userID {
+			return ErrLastSuperAdmin
+		}
+	}
+
+	result, err := tx.Exec(`UPDATE users SET is_super_admin = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, isSuperAdmin, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return tx.Commit()
+}
+
+func (r *UserRepository) GetAllUsers(filter entity.UserFilter, limit, offset int) ([]response.User, error) {
+	conditions, args := buildUserConditions(filter)
 
-	rows, err := r.db.Query(query)
+	query := `SELECT id, username, is_super_admin, created_at, updated_at FROM users WHERE 1=1` + conditions +
+		fmt.Sprintf(" ORDER BY created_at DESC, id LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, limit, offset)
+
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var users []response.User
+	users := make([]response.User, 0)
 	for rows.Next() {
 		var user response.User
 		var isSuperAdmin sql.NullBool
@@ -148,3 +218,11 @@ func (r *UserRepository) GetAllUsers() ([]response.User, error) {
 
 	return users, nil
 }
+
+func (r *UserRepository) CountUsers(filter entity.UserFilter) (int, error) {
+	conditions, args := buildUserConditions(filter)
+
+	var total int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM users WHERE 1=1`+conditions, args...).Scan(&total)
+	return total, err
+}
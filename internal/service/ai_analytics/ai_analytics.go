@@ -6,15 +6,65 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/dinerozz/web-behavior-backend/pkg/telemetry"
+	"math/rand"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
 
+const (
+	defaultOpenAIModel   = "gpt-4o"
+	defaultOpenAIBaseURL = "https://api.openai.com/v1/chat/completions"
+	defaultOpenAITimeout = 30 * time.Second
+
+	defaultOpenAITemperature    = 0.1
+	defaultDomainUsageMaxTokens = 500
+	defaultFocusLevelMaxTokens  = 200
+
+	defaultRetryBaseDelay = 500 * time.Millisecond
+	maxRetryDelay         = 30 * time.Second
+)
+
+type OpenAIConfig struct {
+	APIKey  string
+	Model   string
+	BaseURL string
+	Timeout time.Duration
+
+	// Повторы при 429/5xx и сетевых ошибках (0 = без повторов)
+	RetryAttempts  int
+	RetryBaseDelay time.Duration
+
+	// Общая температура генерации (отрицательная = по умолчанию 0.1)
+	Temperature float64
+	// Параметры отдельных запросов, незаданные поля берутся из Model/Temperature
+	DomainUsage CallConfig
+	FocusLevel  CallConfig
+}
+
+// CallConfig - модель и параметры генерации для одного вида запроса к OpenAI.
+// Пустая модель и MaxTokens <= 0 заменяются общими настройками, отрицательная температура - общей
+type CallConfig struct {
+	Model       string
+	Temperature float64
+	MaxTokens   int
+}
+
 type AIAnalyticsService struct {
-	apiKey     string
-	baseURL    string
-	httpClient *http.Client
+	apiKey         string
+	model          string
+	domainUsage    CallConfig
+	focusLevel     CallConfig
+	baseURL        string
+	httpClient     *http.Client
+	retryAttempts  int
+	retryBaseDelay time.Duration
+	categoryRepo   DomainCategoryStore
+	usageStore     UsageStore
+	// Границы уровня фокуса по числу доменов для fallback без AI
+	focusThresholds entity.FocusThresholds
 }
 
 type OpenAIRequest struct {
@@ -30,43 +80,110 @@ type Message struct {
 }
 
 type OpenAIResponse struct {
-	Choices []Choice `json:"choices"`
+	Choices []Choice    `json:"choices"`
+	Usage   OpenAIUsage `json:"usage"`
 }
 
 type Choice struct {
 	Message Message `json:"message"`
 }
 
-func NewAIAnalyticsService(apiKey string) *AIAnalyticsService {
+func NewAIAnalyticsService(config OpenAIConfig, categoryRepo DomainCategoryStore, usageStore UsageStore, focusThresholds entity.FocusThresholds) *AIAnalyticsService {
+	if config.Model == "" {
+		config.Model = defaultOpenAIModel
+	}
+	if config.BaseURL == "" {
+		config.BaseURL = defaultOpenAIBaseURL
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultOpenAITimeout
+	}
+	if config.RetryAttempts < 0 {
+		config.RetryAttempts = 0
+	}
+	if config.RetryBaseDelay <= 0 {
+		config.RetryBaseDelay = defaultRetryBaseDelay
+	}
+	if config.Temperature < 0 {
+		config.Temperature = defaultOpenAITemperature
+	}
+
 	return &AIAnalyticsService{
-		apiKey:  apiKey,
-		baseURL: "https://api.openai.com/v1/chat/completions",
+		apiKey:      config.APIKey,
+		model:       config.Model,
+		domainUsage: config.DomainUsage.withDefaults(config.Model, config.Temperature, defaultDomainUsageMaxTokens),
+		focusLevel:  config.FocusLevel.withDefaults(config.Model, config.Temperature, defaultFocusLevelMaxTokens),
+		baseURL:     config.BaseURL,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: config.Timeout,
 		},
+		retryAttempts:   config.RetryAttempts,
+		retryBaseDelay:  config.RetryBaseDelay,
+		categoryRepo:    categoryRepo,
+		usageStore:      usageStore,
+		focusThresholds: focusThresholds.Normalize(),
+	}
+}
+
+func (c CallConfig) withDefaults(model string, temperature float64, maxTokens int) CallConfig {
+	if c.Model == "" {
+		c.Model = model
+	}
+	if c.Temperature < 0 {
+		c.Temperature = temperature
 	}
+	if c.MaxTokens <= 0 {
+		c.MaxTokens = maxTokens
+	}
+	return c
+}
+
+// Model возвращает общую модель OpenAI
+func (s *AIAnalyticsService) Model() string {
+	return s.model
+}
+
+// DomainUsageModel - модель анализа доменов, входит в ключ кеша анализа
+func (s *AIAnalyticsService) DomainUsageModel() string {
+	return s.domainUsage.Model
+}
+
+// FocusLevelModel - модель оценки уровня фокуса, входит в ключ кеша фокуса
+func (s *AIAnalyticsService) FocusLevelModel() string {
+	return s.focusLevel.Model
+}
+
+// IsEnabled - без API ключа AI анализ отключен и используется fallback
+func (s *AIAnalyticsService) IsEnabled() bool {
+	return s.apiKey != ""
 }
 
-func (s *AIAnalyticsService) AnalyzeDomainUsage(ctx context.Context, domainsCount int, domains []string, deepWorkData entity.DeepWorkData, engagementRate float64, trackedHours float64) (*entity.DomainAnalysis, error) {
-	prompt := s.buildPrompt(domainsCount, domains, deepWorkData, engagementRate, trackedHours)
+// AnalyzeDomainUsage - lang выбирает язык промпта и ответа (пустой = русский)
+func (s *AIAnalyticsService) AnalyzeDomainUsage(ctx context.Context, domainsCount int, domains []string, deepWorkData entity.DeepWorkData, engagementRate float64, trackedHours float64, lang string) (*entity.DomainAnalysis, error) {
+	if !s.IsEnabled() {
+		return nil, fmt.Errorf("AI analytics is disabled: OpenAI API key is not configured")
+	}
+
+	overrides := s.getDomainCategoryOverrides(ctx, domains)
+	prompt := s.buildPrompt(domainsCount, domains, deepWorkData, engagementRate, trackedHours, overrides, lang)
 
 	request := OpenAIRequest{
-		Model: "gpt-4o",
+		Model: s.domainUsage.Model,
 		Messages: []Message{
 			{
 				Role:    "system",
-				Content: s.getSystemPrompt(),
+				Content: s.getSystemPrompt(lang),
 			},
 			{
 				Role:    "user",
 				Content: prompt,
 			},
 		},
-		Temperature: 0.1,
-		MaxTokens:   500,
+		Temperature: s.domainUsage.Temperature,
+		MaxTokens:   s.domainUsage.MaxTokens,
 	}
 
-	response, err := s.callOpenAI(ctx, request)
+	response, err := s.callOpenAI(ctx, request, "domain_usage")
 	if err != nil {
 		return nil, fmt.Errorf("failed to call OpenAI: %w", err)
 	}
@@ -77,7 +194,8 @@ func (s *AIAnalyticsService) AnalyzeDomainUsage(ctx context.Context, domainsCoun
 	if err := json.Unmarshal([]byte(cleanResponse), &analysis); err != nil {
 		fmt.Printf("Failed to parse AI response: %v\nRaw response: %s\n", err, response)
 
-		return &entity.DomainAnalysis{
+		l := localeFor(lang)
+		fallback := &entity.DomainAnalysis{
 			FocusLevel:      s.DetermineFocusLevelFallback(domainsCount),
 			WorkPattern:     "unknown",
 			Recommendations: []string{},
@@ -94,14 +212,19 @@ func (s *AIAnalyticsService) AnalyzeDomainUsage(ctx context.Context, domainsCoun
 					Focus:       0,
 					Efficiency:  0,
 					Balance:     0,
-					Explanation: "AI анализ недоступен",
+					Explanation: l.parseFailedExplanation,
 				},
-				BehaviorInsights: []string{"Анализ не выполнен из-за ошибки"},
-				KeyFindings:      []string{"Базовые данные доступны без AI"},
+				BehaviorInsights: []string{l.parseFailedInsight},
+				KeyFindings:      []string{l.parseFailedFinding},
 			},
-		}, nil
+		}
+		applyDomainCategoryOverrides(&fallback.Analysis.DomainBreakdown, overrides)
+
+		return fallback, nil
 	}
 
+	applyDomainCategoryOverrides(&analysis.Analysis.DomainBreakdown, overrides)
+
 	return &analysis, nil
 }
 
@@ -143,76 +266,13 @@ func (s *AIAnalyticsService) fixIncompleteJSON(jsonStr string) string {
 	return jsonStr
 }
 
-func (s *AIAnalyticsService) getSystemPrompt() string {
-	return `Ты эксперт по анализу цифрового поведения и продуктивности. 
-
-ЗАДАЧА: Дать детальный, но краткий анализ на основе конкретных данных.
-
-КАТЕГОРИИ ДОМЕНОВ:
-- work_tools: Jira, Slack, корпоративные системы, CRM
-- development: localhost, GitHub, CodeSandbox, IDE, облачные платформы  
-- research: Stack Overflow, документация, курсы, блоги разработчиков
-- communication: Gmail, Telegram, LinkedIn, мессенджеры
-- distractions: YouTube, соцсети, новости, развлекательный контент
-
-ОЦЕНКИ (0-100):
-- overall: общая продуктивность (engagement + deep work + focus)
-- focus: на основе deep work rate и количества доменов
-- efficiency: на основе engagement rate
-- balance: баланс рабочих/отвлекающих доменов
-
-ИНСАЙТЫ: Конкретные наблюдения с цифрами и пояснениями.
-
-ФОРМАТ JSON (без markdown):
-{
-  "focus_level": "high|medium|low",
-  "focus_insight": "Краткий вывод с цифрами",
-  "work_pattern": "deep_focused|task_switching|research_heavy|communication_intensive|distracted",
-  "recommendations": ["рекомендация с обоснованием"],
-  "analysis": {
-    "domain_breakdown": {
-      "work_tools": ["список доменов"],
-      "development": ["список доменов"],
-      "research": ["список доменов"], 
-      "communication": ["список доменов"],
-      "distractions": ["список доменов"]
-    },
-    "productivity_score": {
-      "overall": 85,
-      "focus": 90,
-      "efficiency": 80,
-      "balance": 85,
-      "explanation": "Высокие показатели благодаря X, но снижены из-за Y"
-    },
-    "behavior_insights": [
-      "93% времени deep work на localhost - отличная концентрация",
-      "22 домена за 4+ часа - высокая фрагментация внимания"
-    ],
-    "key_findings": [
-      "Преобладает разработка (localhost + dev инструменты)",
-      "Минимальные отвлечения на развлекательный контент"
-    ]
-  }
-}`
+func (s *AIAnalyticsService) getSystemPrompt(lang string) string {
+	return localeFor(lang).systemPrompt
 }
 
-func (s *AIAnalyticsService) buildPrompt(domainsCount int, domains []string, deepWorkData entity.DeepWorkData, engagementRate float64, trackedHours float64) string {
-	return fmt.Sprintf(`ДАННЫЕ ДЛЯ АНАЛИЗА:
-
-📊 ОСНОВНЫЕ МЕТРИКИ:
-- Время работы: %.2f часов
-- Engagement rate: %.1f%% (активность в минутах)
-- Уникальных доменов: %d
-- Deep work: %d сессий (%.1f часов, %.1f%% времени)
-- Средняя deep work сессия: %.1f мин (макс: %.1f мин)
-
-🌐 ПОСЕЩЕННЫЕ ДОМЕНЫ:
-%s
-
-🎯 DEEP WORK ДОМЕНЫ:
-%s
-
-ЗАДАЧА: Проанализируй паттерн работы, дай конкретные инсайты с цифрами и практичные рекомендации.`,
+func (s *AIAnalyticsService) buildPrompt(domainsCount int, domains []string, deepWorkData entity.DeepWorkData, engagementRate float64, trackedHours float64, overrides map[string]string, lang string) string {
+	l := localeFor(lang)
+	return fmt.Sprintf(l.analysisPrompt,
 		trackedHours,
 		engagementRate,
 		domainsCount,
@@ -221,13 +281,14 @@ func (s *AIAnalyticsService) buildPrompt(domainsCount int, domains []string, dee
 		deepWorkData.DeepWorkRate,
 		deepWorkData.AverageMinutes,
 		deepWorkData.LongestMinutes,
-		formatDomainsForPrompt(domains),
-		formatTopDomainsForPrompt(deepWorkData.TopDomains))
+		formatDomainsForPrompt(domains, l),
+		formatTopDomainsForPrompt(deepWorkData.TopDomains, l),
+		formatDomainOverridesForPrompt(overrides, l))
 }
 
-func formatDomainsForPrompt(domains []string) string {
+func formatDomainsForPrompt(domains []string, l localeStrings) string {
 	if len(domains) == 0 {
-		return "Нет данных"
+		return l.noData
 	}
 
 	var result strings.Builder
@@ -244,9 +305,9 @@ func formatDomainsForPrompt(domains []string) string {
 	return result.String()
 }
 
-func formatTopDomainsForPrompt(topDomains []entity.DeepWorkDomain) string {
+func formatTopDomainsForPrompt(topDomains []entity.DeepWorkDomain, l localeStrings) string {
 	if len(topDomains) == 0 {
-		return "Нет deep work сессий"
+		return l.noDeepWork
 	}
 
 	var result strings.Builder
@@ -254,7 +315,7 @@ func formatTopDomainsForPrompt(topDomains []entity.DeepWorkDomain) string {
 		if i > 0 {
 			result.WriteString(", ")
 		}
-		result.WriteString(fmt.Sprintf("%s (%.1f мин)", domain.Domain, domain.Minutes))
+		result.WriteString(fmt.Sprintf(l.domainMinutes, domain.Domain, domain.Minutes))
 	}
 	return result.String()
 }
@@ -266,35 +327,30 @@ func getTopDomain(topDomains []entity.DeepWorkDomain) string {
 	return "не определен"
 }
 
-func (s *AIAnalyticsService) callOpenAI(ctx context.Context, request OpenAIRequest) (string, error) {
+// callOpenAI - общий путь для всех запросов к OpenAI: один http.Client (таймаут и транспорт из конфига),
+// повторы через doWithRetry и отмена по контексту запроса. operation - метка для метрик.
+// Расход токенов из usage ответа копится в дневных счетчиках
+func (s *AIAnalyticsService) callOpenAI(ctx context.Context, request OpenAIRequest, operation string) (string, error) {
 	jsonData, err := json.Marshal(request)
 	if err != nil {
 		return "", err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", s.baseURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+s.apiKey)
-
-	resp, err := s.httpClient.Do(req)
+	resp, err := s.doWithRetry(ctx, jsonData)
 	if err != nil {
+		telemetry.AICallsTotal.Inc(operation, "error")
 		return "", err
 	}
+	telemetry.AICallsTotal.Inc(operation, "success")
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("OpenAI API returned status %d", resp.StatusCode)
-	}
-
 	var openAIResp OpenAIResponse
 	if err := json.NewDecoder(resp.Body).Decode(&openAIResp); err != nil {
 		return "", err
 	}
 
+	s.recordUsage(ctx, request.Model, openAIResp.Usage)
+
 	if len(openAIResp.Choices) == 0 {
 		return "", fmt.Errorf("no response from OpenAI")
 	}
@@ -302,29 +358,21 @@ func (s *AIAnalyticsService) callOpenAI(ctx context.Context, request OpenAIReque
 	return openAIResp.Choices[0].Message.Content, nil
 }
 
-func (s *AIAnalyticsService) AnalyzeFocusWithAI(ctx context.Context, domainsCount int) (*entity.FocusLevelResponse, error) {
-	prompt := fmt.Sprintf(`Проанализируй уровень фокуса пользователя:
-
-ДАННЫЕ:
-- Количество уникальных доменов: %d
-- Домены: %s
-
-ЗАДАЧА: Определи уровень фокуса и дай краткий инсайт.
-
-ОТВЕТ в JSON формате:
-{
-  "focus_level": "high|medium|low",
-  "insight": "Краткое объяснение с конкретными наблюдениями",
-  "method": "ai"
-}
+func (s *AIAnalyticsService) AnalyzeFocusWithAI(ctx context.Context, domainsCount int, lang string) (*entity.FocusLevelResponse, error) {
+	if !s.IsEnabled() {
+		return &entity.FocusLevelResponse{
+			FocusLevel: s.DetermineFocusLevelFallback(domainsCount),
+			Insight:    localeFor(lang).aiDisabledInsight,
+			Method:     "fallback",
+			Timestamp:  time.Now(),
+		}, nil
+	}
 
-ПРАВИЛА:
-- high: ≤5 доменов, фокусированная работа
-- medium: 6-15 доменов, умеренная многозадачность  
-- low: >15 доменов, высокая фрагментация
-- Учитывай типы доменов (рабочие vs развлекательные)`, domainsCount)
+	t := s.focusThresholds
+	prompt := fmt.Sprintf(localeFor(lang).focusPrompt, domainsCount,
+		t.HighMaxDomains, t.HighMaxDomains+1, t.MediumMaxDomains, t.MediumMaxDomains)
 
-	response, err := s.callOpenAIForFocus(ctx, prompt)
+	response, err := s.callOpenAIForFocus(ctx, prompt, lang)
 	if err != nil {
 		return nil, err
 	}
@@ -348,73 +396,143 @@ func (s *AIAnalyticsService) AnalyzeFocusWithAI(ctx context.Context, domainsCoun
 	}, nil
 }
 
-func (s *AIAnalyticsService) callOpenAIForFocus(ctx context.Context, prompt string) (string, error) {
-	request := map[string]interface{}{
-		"model": "gpt-4o",
-		"messages": []map[string]string{
+func (s *AIAnalyticsService) callOpenAIForFocus(ctx context.Context, prompt, lang string) (string, error) {
+	request := OpenAIRequest{
+		Model: s.focusLevel.Model,
+		Messages: []Message{
 			{
-				"role":    "system",
-				"content": "Ты эксперт по анализу цифрового поведения. Отвечай только в JSON формате без markdown.",
+				Role:    "system",
+				Content: localeFor(lang).focusSystemPrompt,
 			},
 			{
-				"role":    "user",
-				"content": prompt,
+				Role:    "user",
+				Content: prompt,
 			},
 		},
-		"temperature": 0.1,
-		"max_tokens":  200,
+		Temperature: s.focusLevel.Temperature,
+		MaxTokens:   s.focusLevel.MaxTokens,
 	}
 
-	jsonData, err := json.Marshal(request)
-	if err != nil {
-		return "", err
-	}
+	return s.callOpenAI(ctx, request, "focus_level")
+}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", err
+// doWithRetry отправляет запрос в OpenAI, повторяя его при 429/5xx и сетевых ошибках
+// с экспоненциальной задержкой и jitter. Retry-After от OpenAI имеет приоритет.
+// Возвращает ответ со статусом 200 либо последнюю ошибку.
+func (s *AIAnalyticsService) doWithRetry(ctx context.Context, body []byte) (*http.Response, error) {
+	var lastErr error
+
+	for attempt := 0; attempt <= s.retryAttempts; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "POST", s.baseURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+		var retryAfter time.Duration
+
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = err
+		} else {
+			if resp.StatusCode == http.StatusOK {
+				return resp, nil
+			}
+
+			resp.Body.Close()
+			lastErr = fmt.Errorf("OpenAI API returned status %d", resp.StatusCode)
+
+			if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < http.StatusInternalServerError {
+				return nil, lastErr
+			}
+
+			if resp.StatusCode == http.StatusTooManyRequests {
+				retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+			}
+		}
+
+		if attempt == s.retryAttempts {
+			break
+		}
+
+		delay := s.retryDelay(attempt, retryAfter)
+
+		// Не ждем, если повтор все равно не уложится в дедлайн запроса
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	return nil, lastErr
+}
 
-	client := &http.Client{Timeout: 15 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
+func (s *AIAnalyticsService) retryDelay(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		if retryAfter > maxRetryDelay {
+			return maxRetryDelay
+		}
+		return retryAfter
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("OpenAI API returned status %d", resp.StatusCode)
+	delay := s.retryBaseDelay << attempt
+	delay += time.Duration(rand.Int63n(int64(s.retryBaseDelay)))
+
+	if delay > maxRetryDelay {
+		return maxRetryDelay
 	}
+	return delay
+}
 
-	var openAIResp struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
+// parseRetryAfter поддерживает оба формата заголовка: секунды и HTTP-дату
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&openAIResp); err != nil {
-		return "", err
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
 	}
 
-	if len(openAIResp.Choices) == 0 {
-		return "", fmt.Errorf("no response from OpenAI")
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
 	}
 
-	return openAIResp.Choices[0].Message.Content, nil
+	return 0
 }
 
 func (s *AIAnalyticsService) DetermineFocusLevelFallback(domainsCount int) string {
-	switch {
-	case domainsCount <= 5:
-		return "high"
-	case domainsCount <= 15:
-		return "medium"
-	default:
-		return "low"
+	return s.focusThresholds.DomainsLevel(domainsCount)
+}
+
+// FallbackAnalysis - анализ без AI на языке запроса: домены раскладываются локальными правилами
+// (с учетом закрепленных категорий), оценки считаются из engagement и deep work
+func (s *AIAnalyticsService) FallbackAnalysis(ctx context.Context, req entity.AIAnalyticsRequest) *entity.DomainAnalysis {
+	l := localeFor(req.Lang)
+	breakdown := buildLocalDomainBreakdown(req.Domains, s.getDomainCategoryOverrides(ctx, req.Domains))
+
+	return &entity.DomainAnalysis{
+		FocusLevel:      s.DetermineFocusLevelFallback(req.DomainsCount),
+		FocusInsight:    s.FallbackFocusInsight(req.DomainsCount, req.Lang),
+		WorkPattern:     localWorkPattern(req, breakdown, s.focusThresholds),
+		Recommendations: []string{l.fallbackRecommendation},
+		Analysis: entity.DetailedAnalysis{
+			DomainBreakdown:   breakdown,
+			ProductivityScore: localProductivityScore(req, breakdown, s.focusThresholds, l),
+			BehaviorInsights:  []string{l.fallbackBehaviorInsight},
+			KeyFindings:       []string{l.fallbackKeyFinding},
+		},
 	}
 }
@@ -0,0 +1,92 @@
+package ai_analytics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+)
+
+// newBlockingOpenAIServer отвечает только после отмены запроса клиентом и считает попытки
+func newBlockingOpenAIServer(t *testing.T) (*httptest.Server, *int32, chan struct{}) {
+	t.Helper()
+
+	var calls int32
+	started := make(chan struct{}, 10)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		// Пока тело не прочитано, сервер не замечает закрытия соединения клиентом
+		io.Copy(io.Discard, r.Body)
+		started <- struct{}{}
+
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &calls, started
+}
+
+func newTestAIAnalyticsService(baseURL string) *AIAnalyticsService {
+	return NewAIAnalyticsService(OpenAIConfig{
+		APIKey:         "test-key",
+		BaseURL:        baseURL,
+		Timeout:        10 * time.Second,
+		RetryAttempts:  3,
+		RetryBaseDelay: 10 * time.Millisecond,
+	}, nil, nil, entity.FocusThresholds{})
+}
+
+func TestAnalyzeFocusWithAICancelledContext(t *testing.T) {
+	server, calls, _ := newBlockingOpenAIServer(t)
+	s := newTestAIAnalyticsService(server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := s.AnalyzeFocusWithAI(ctx, 5, "en")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if n := atomic.LoadInt32(calls); n != 0 {
+		t.Fatalf("expected no OpenAI calls with a cancelled context, got %d", n)
+	}
+}
+
+func TestAnalyzeFocusWithAICancelledInFlight(t *testing.T) {
+	server, calls, started := newBlockingOpenAIServer(t)
+	s := newTestAIAnalyticsService(server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Отмена клиентом во время запроса к OpenAI
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	begin := time.Now()
+	_, err := s.AnalyzeFocusWithAI(ctx, 5, "en")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(begin); elapsed > 2*time.Second {
+		t.Fatalf("call returned %s after cancellation, expected it to abort immediately", elapsed)
+	}
+
+	// Отмененный запрос не повторяется, хотя RetryAttempts = 3
+	if n := atomic.LoadInt32(calls); n != 1 {
+		t.Fatalf("expected exactly one OpenAI call, got %d", n)
+	}
+}
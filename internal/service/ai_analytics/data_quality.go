@@ -0,0 +1,75 @@
+package ai_analytics
+
+import (
+	"math"
+	"time"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/dinerozz/web-behavior-backend/pkg/utils"
+)
+
+// Пороги, при которых соответствующая часть данных считается полной
+const (
+	qualityFullTrackedHours = 4.0
+	qualityFullEvents       = 500
+	qualityFullSessions     = 3
+
+	// Меньше 30 минут трекинга - инсайты строятся на слишком тонких данных
+	qualityMinTrackedHours = 0.5
+)
+
+// AssessDataQuality оценивает объем входных данных: каждая составляющая (часы трекинга, события,
+// deep work сессии) дает долю от 0 до 1, взвешенная сумма - confidence score.
+// Если число событий не передано, его вес распределяется между остальными
+func AssessDataQuality(req entity.AIAnalyticsRequest) (string, float64, []string) {
+	hoursScore := math.Min(req.TrackedHours/qualityFullTrackedHours, 1)
+	sessionsScore := math.Min(float64(req.DeepWork.SessionsCount)/qualityFullSessions, 1)
+
+	var score float64
+	if req.EventsCount > 0 {
+		eventsScore := math.Min(float64(req.EventsCount)/qualityFullEvents, 1)
+		score = hoursScore*0.5 + eventsScore*0.25 + sessionsScore*0.25
+	} else {
+		score = hoursScore*0.65 + sessionsScore*0.35
+	}
+
+	var warnings []string
+	if req.TrackedHours < qualityMinTrackedHours {
+		warnings = append(warnings, "less than 30 minutes tracked, insights may be unreliable")
+		score = math.Min(score, 0.3)
+	}
+	if req.DeepWork.SessionsCount == 0 {
+		warnings = append(warnings, "no deep work sessions in the period")
+	}
+
+	quality := "low"
+	switch {
+	case score >= 0.7:
+		quality = "high"
+	case score >= 0.4:
+		quality = "medium"
+	}
+
+	return quality, utils.RoundToTwoDecimals(score), warnings
+}
+
+// BuildAnalyticsMeta собирает метаданные ответа; без AI (fallback) уверенность снижается вдвое
+func (s *AIAnalyticsService) BuildAnalyticsMeta(req entity.AIAnalyticsRequest, startedAt time.Time, usedAI bool) *entity.AnalyticsMeta {
+	quality, confidence, warnings := AssessDataQuality(req)
+
+	meta := &entity.AnalyticsMeta{
+		ProcessedAt:     time.Now(),
+		ProcessingTime:  time.Since(startedAt).Milliseconds(),
+		DataQuality:     quality,
+		ConfidenceScore: confidence,
+		Warnings:        warnings,
+	}
+
+	if usedAI {
+		meta.AIModel = s.domainUsage.Model
+	} else {
+		meta.ConfidenceScore = utils.RoundToTwoDecimals(confidence / 2)
+	}
+
+	return meta
+}
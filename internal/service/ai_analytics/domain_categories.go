@@ -0,0 +1,173 @@
+package ai_analytics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/gofrs/uuid"
+)
+
+// DomainCategoryStore описывает хранилище закрепленных категорий доменов
+// (реализуется repository.DomainCategoryRepository, пакет repository импортирует config,
+// поэтому интерфейс объявлен здесь, чтобы избежать циклического импорта)
+type DomainCategoryStore interface {
+	GetAll(ctx context.Context) ([]entity.DomainCategory, error)
+	ExistsByDomain(ctx context.Context, domain string, excludeID *uuid.UUID) (bool, error)
+	Create(ctx context.Context, category *entity.DomainCategory) error
+	Update(ctx context.Context, category *entity.DomainCategory) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+func normalizeDomain(domain string) string {
+	return strings.ToLower(strings.TrimSpace(domain))
+}
+
+func (s *AIAnalyticsService) ListDomainCategories(ctx context.Context) ([]entity.DomainCategory, error) {
+	return s.categoryRepo.GetAll(ctx)
+}
+
+func (s *AIAnalyticsService) CreateDomainCategory(ctx context.Context, req entity.DomainCategoryRequest) (*entity.DomainCategory, error) {
+	domain := normalizeDomain(req.Domain)
+
+	exists, err := s.categoryRepo.ExistsByDomain(ctx, domain, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check domain existence: %w", err)
+	}
+
+	if exists {
+		return nil, fmt.Errorf("domain category already exists")
+	}
+
+	category := &entity.DomainCategory{
+		ID:       uuid.Must(uuid.NewV4()),
+		Domain:   domain,
+		Category: req.Category,
+	}
+
+	if err := s.categoryRepo.Create(ctx, category); err != nil {
+		return nil, fmt.Errorf("failed to create domain category: %w", err)
+	}
+
+	return category, nil
+}
+
+func (s *AIAnalyticsService) UpdateDomainCategory(ctx context.Context, id uuid.UUID, req entity.DomainCategoryRequest) (*entity.DomainCategory, error) {
+	domain := normalizeDomain(req.Domain)
+
+	exists, err := s.categoryRepo.ExistsByDomain(ctx, domain, &id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check domain existence: %w", err)
+	}
+
+	if exists {
+		return nil, fmt.Errorf("domain category already exists")
+	}
+
+	category := &entity.DomainCategory{
+		ID:       id,
+		Domain:   domain,
+		Category: req.Category,
+	}
+
+	if err := s.categoryRepo.Update(ctx, category); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("domain category not found")
+		}
+		return nil, fmt.Errorf("failed to update domain category: %w", err)
+	}
+
+	return category, nil
+}
+
+func (s *AIAnalyticsService) DeleteDomainCategory(ctx context.Context, id uuid.UUID) error {
+	if err := s.categoryRepo.Delete(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("domain category not found")
+		}
+		return fmt.Errorf("failed to delete domain category: %w", err)
+	}
+
+	return nil
+}
+
+// getDomainCategoryOverrides возвращает закрепленные категории только для посещенных доменов
+func (s *AIAnalyticsService) getDomainCategoryOverrides(ctx context.Context, domains []string) map[string]string {
+	overrides := make(map[string]string)
+
+	if s.categoryRepo == nil {
+		return overrides
+	}
+
+	categories, err := s.categoryRepo.GetAll(ctx)
+	if err != nil {
+		fmt.Printf("Failed to load domain category overrides: %v\n", err)
+		return overrides
+	}
+
+	pinned := make(map[string]string, len(categories))
+	for _, category := range categories {
+		pinned[category.Domain] = category.Category
+	}
+
+	for _, domain := range domains {
+		if category, ok := pinned[normalizeDomain(domain)]; ok {
+			overrides[domain] = category
+		}
+	}
+
+	return overrides
+}
+
+func formatDomainOverridesForPrompt(overrides map[string]string, l localeStrings) string {
+	if len(overrides) == 0 {
+		return ""
+	}
+
+	var result strings.Builder
+	result.WriteString(l.pinnedCategories)
+	for domain, category := range overrides {
+		result.WriteString(fmt.Sprintf("- %s → %s\n", domain, category))
+	}
+	return strings.TrimRight(result.String(), "\n")
+}
+
+// applyDomainCategoryOverrides переносит закрепленные домены в их категорию,
+// даже если AI отнес их к другой
+func applyDomainCategoryOverrides(breakdown *entity.DomainBreakdown, overrides map[string]string) {
+	if len(overrides) == 0 {
+		return
+	}
+
+	buckets := map[string]*[]string{
+		entity.DomainCategoryWorkTools:     &breakdown.WorkTools,
+		entity.DomainCategoryDevelopment:   &breakdown.Development,
+		entity.DomainCategoryResearch:      &breakdown.Research,
+		entity.DomainCategoryCommunication: &breakdown.Communication,
+		entity.DomainCategoryDistractions:  &breakdown.Distractions,
+	}
+
+	isOverridden := make(map[string]bool, len(overrides))
+	for domain := range overrides {
+		isOverridden[normalizeDomain(domain)] = true
+	}
+
+	for _, bucket := range buckets {
+		filtered := make([]string, 0, len(*bucket))
+		for _, domain := range *bucket {
+			if !isOverridden[normalizeDomain(domain)] {
+				filtered = append(filtered, domain)
+			}
+		}
+		*bucket = filtered
+	}
+
+	for domain, category := range overrides {
+		if bucket, ok := buckets[category]; ok {
+			*bucket = append(*bucket, domain)
+		}
+	}
+}
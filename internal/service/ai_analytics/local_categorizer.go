@@ -0,0 +1,285 @@
+package ai_analytics
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/dinerozz/web-behavior-backend/pkg/utils"
+)
+
+// knownDomainCategories - категории популярных доменов для анализа без AI.
+// Совпадение ищется по домену и всем его родительским доменам (mail.google.com -> google.com)
+var knownDomainCategories = map[string]string{
+	// work_tools
+	"atlassian.net":     entity.DomainCategoryWorkTools,
+	"atlassian.com":     entity.DomainCategoryWorkTools,
+	"trello.com":        entity.DomainCategoryWorkTools,
+	"asana.com":         entity.DomainCategoryWorkTools,
+	"notion.so":         entity.DomainCategoryWorkTools,
+	"clickup.com":       entity.DomainCategoryWorkTools,
+	"linear.app":        entity.DomainCategoryWorkTools,
+	"monday.com":        entity.DomainCategoryWorkTools,
+	"figma.com":         entity.DomainCategoryWorkTools,
+	"miro.com":          entity.DomainCategoryWorkTools,
+	"docs.google.com":   entity.DomainCategoryWorkTools,
+	"drive.google.com":  entity.DomainCategoryWorkTools,
+	"sheets.google.com": entity.DomainCategoryWorkTools,
+	"salesforce.com":    entity.DomainCategoryWorkTools,
+	"hubspot.com":       entity.DomainCategoryWorkTools,
+	"amocrm.ru":         entity.DomainCategoryWorkTools,
+	"bitrix24.ru":       entity.DomainCategoryWorkTools,
+	"yougile.com":       entity.DomainCategoryWorkTools,
+
+	// development
+	"localhost":                entity.DomainCategoryDevelopment,
+	"127.0.0.1":                entity.DomainCategoryDevelopment,
+	"github.com":               entity.DomainCategoryDevelopment,
+	"gitlab.com":               entity.DomainCategoryDevelopment,
+	"bitbucket.org":            entity.DomainCategoryDevelopment,
+	"codesandbox.io":           entity.DomainCategoryDevelopment,
+	"codepen.io":               entity.DomainCategoryDevelopment,
+	"replit.com":               entity.DomainCategoryDevelopment,
+	"vercel.com":               entity.DomainCategoryDevelopment,
+	"netlify.com":              entity.DomainCategoryDevelopment,
+	"console.aws.amazon.com":   entity.DomainCategoryDevelopment,
+	"console.cloud.google.com": entity.DomainCategoryDevelopment,
+	"portal.azure.com":         entity.DomainCategoryDevelopment,
+	"hub.docker.com":           entity.DomainCategoryDevelopment,
+	"npmjs.com":                entity.DomainCategoryDevelopment,
+	"pkg.go.dev":               entity.DomainCategoryDevelopment,
+	"postman.com":              entity.DomainCategoryDevelopment,
+
+	// research
+	"stackoverflow.com":     entity.DomainCategoryResearch,
+	"stackexchange.com":     entity.DomainCategoryResearch,
+	"developer.mozilla.org": entity.DomainCategoryResearch,
+	"habr.com":              entity.DomainCategoryResearch,
+	"medium.com":            entity.DomainCategoryResearch,
+	"dev.to":                entity.DomainCategoryResearch,
+	"wikipedia.org":         entity.DomainCategoryResearch,
+	"coursera.org":          entity.DomainCategoryResearch,
+	"udemy.com":             entity.DomainCategoryResearch,
+	"stepik.org":            entity.DomainCategoryResearch,
+	"chatgpt.com":           entity.DomainCategoryResearch,
+	"claude.ai":             entity.DomainCategoryResearch,
+	"google.com":            entity.DomainCategoryResearch,
+	"bing.com":              entity.DomainCategoryResearch,
+	"duckduckgo.com":        entity.DomainCategoryResearch,
+	"ya.ru":                 entity.DomainCategoryResearch,
+
+	// communication
+	"mail.google.com":     entity.DomainCategoryCommunication,
+	"gmail.com":           entity.DomainCategoryCommunication,
+	"outlook.com":         entity.DomainCategoryCommunication,
+	"outlook.office.com":  entity.DomainCategoryCommunication,
+	"mail.ru":             entity.DomainCategoryCommunication,
+	"mail.yandex.ru":      entity.DomainCategoryCommunication,
+	"slack.com":           entity.DomainCategoryCommunication,
+	"web.telegram.org":    entity.DomainCategoryCommunication,
+	"telegram.org":        entity.DomainCategoryCommunication,
+	"web.whatsapp.com":    entity.DomainCategoryCommunication,
+	"teams.microsoft.com": entity.DomainCategoryCommunication,
+	"zoom.us":             entity.DomainCategoryCommunication,
+	"meet.google.com":     entity.DomainCategoryCommunication,
+	"discord.com":         entity.DomainCategoryCommunication,
+	"linkedin.com":        entity.DomainCategoryCommunication,
+
+	// distractions
+	"youtube.com":          entity.DomainCategoryDistractions,
+	"netflix.com":          entity.DomainCategoryDistractions,
+	"twitch.tv":            entity.DomainCategoryDistractions,
+	"tiktok.com":           entity.DomainCategoryDistractions,
+	"instagram.com":        entity.DomainCategoryDistractions,
+	"facebook.com":         entity.DomainCategoryDistractions,
+	"x.com":                entity.DomainCategoryDistractions,
+	"twitter.com":          entity.DomainCategoryDistractions,
+	"reddit.com":           entity.DomainCategoryDistractions,
+	"vk.com":               entity.DomainCategoryDistractions,
+	"ok.ru":                entity.DomainCategoryDistractions,
+	"pinterest.com":        entity.DomainCategoryDistractions,
+	"9gag.com":             entity.DomainCategoryDistractions,
+	"kinopoisk.ru":         entity.DomainCategoryDistractions,
+	"dzen.ru":              entity.DomainCategoryDistractions,
+	"news.ycombinator.com": entity.DomainCategoryDistractions,
+	"aliexpress.com":       entity.DomainCategoryDistractions,
+	"amazon.com":           entity.DomainCategoryDistractions,
+	"ozon.ru":              entity.DomainCategoryDistractions,
+	"wildberries.ru":       entity.DomainCategoryDistractions,
+}
+
+// domainKeywordCategories - запасные правила по подстроке для корпоративных и неизвестных доменов,
+// проверяются по порядку
+var domainKeywordCategories = []struct {
+	keyword  string
+	category string
+}{
+	{"jira", entity.DomainCategoryWorkTools},
+	{"confluence", entity.DomainCategoryWorkTools},
+	{"crm", entity.DomainCategoryWorkTools},
+	{"gitlab", entity.DomainCategoryDevelopment},
+	{"git.", entity.DomainCategoryDevelopment},
+	{"jenkins", entity.DomainCategoryDevelopment},
+	{"grafana", entity.DomainCategoryDevelopment},
+	{"sentry", entity.DomainCategoryDevelopment},
+	{"swagger", entity.DomainCategoryDevelopment},
+	{"dev.", entity.DomainCategoryDevelopment},
+	{"staging", entity.DomainCategoryDevelopment},
+	{"docs.", entity.DomainCategoryResearch},
+	{"wiki", entity.DomainCategoryResearch},
+	{"learn", entity.DomainCategoryResearch},
+	{"mail.", entity.DomainCategoryCommunication},
+	{"chat", entity.DomainCategoryCommunication},
+	{"meet", entity.DomainCategoryCommunication},
+	{"news", entity.DomainCategoryDistractions},
+	{"game", entity.DomainCategoryDistractions},
+	{"shop", entity.DomainCategoryDistractions},
+}
+
+// CategorizeDomainLocally определяет категорию домена по правилам; false - домен неизвестен
+func CategorizeDomainLocally(domain string) (string, bool) {
+	host := utils.NormalizeDomain(normalizeDomain(domain))
+	if host == "" {
+		return "", false
+	}
+
+	for candidate := host; candidate != ""; {
+		if category, ok := knownDomainCategories[candidate]; ok {
+			return category, true
+		}
+		dot := strings.Index(candidate, ".")
+		if dot < 0 {
+			break
+		}
+		candidate = candidate[dot+1:]
+	}
+
+	for _, rule := range domainKeywordCategories {
+		if strings.Contains(host, rule.keyword) {
+			return rule.category, true
+		}
+	}
+
+	return "", false
+}
+
+// buildLocalDomainBreakdown раскладывает домены по категориям правилами,
+// закрепленные админом категории имеют приоритет. Неизвестные домены не попадают ни в одну категорию
+func buildLocalDomainBreakdown(domains []string, overrides map[string]string) entity.DomainBreakdown {
+	breakdown := entity.DomainBreakdown{
+		WorkTools:     []string{},
+		Development:   []string{},
+		Research:      []string{},
+		Communication: []string{},
+		Distractions:  []string{},
+	}
+
+	buckets := map[string]*[]string{
+		entity.DomainCategoryWorkTools:     &breakdown.WorkTools,
+		entity.DomainCategoryDevelopment:   &breakdown.Development,
+		entity.DomainCategoryResearch:      &breakdown.Research,
+		entity.DomainCategoryCommunication: &breakdown.Communication,
+		entity.DomainCategoryDistractions:  &breakdown.Distractions,
+	}
+
+	for _, domain := range domains {
+		if category, ok := resolveDomainCategory(domain, overrides); ok {
+			if bucket, known := buckets[category]; known {
+				*bucket = append(*bucket, domain)
+			}
+		}
+	}
+
+	return breakdown
+}
+
+// resolveDomainCategory - закрепленная админом категория домена, иначе категория по правилам
+func resolveDomainCategory(domain string, overrides map[string]string) (string, bool) {
+	if category, ok := overrides[domain]; ok {
+		return category, true
+	}
+	return CategorizeDomainLocally(domain)
+}
+
+// CategorizeDomains определяет категории доменов без AI по тем же правилам, что и fallback анализ.
+// Неизвестные домены в результат не попадают
+func (s *AIAnalyticsService) CategorizeDomains(ctx context.Context, domains []string) map[string]string {
+	overrides := s.getDomainCategoryOverrides(ctx, domains)
+
+	categories := make(map[string]string, len(domains))
+	for _, domain := range domains {
+		if category, ok := resolveDomainCategory(domain, overrides); ok {
+			categories[domain] = category
+		}
+	}
+
+	return categories
+}
+
+// domainCountFocusScore - оценка фокуса по количеству доменов, границы как в DetermineFocusLevelFallback
+func domainCountFocusScore(domainsCount int, thresholds entity.FocusThresholds) float64 {
+	switch {
+	case domainsCount <= thresholds.HighMaxDomains:
+		return 100
+	case domainsCount <= thresholds.MediumMaxDomains:
+		return 70
+	case domainsCount <= lowFocusMaxDomains:
+		return 40
+	default:
+		return 20
+	}
+}
+
+func clampScore(value float64) int {
+	return int(math.Round(math.Max(0, math.Min(100, value))))
+}
+
+// localProductivityScore считает оценки без AI:
+// focus - deep work rate (70%) и количество доменов (30%), efficiency - engagement rate,
+// balance - доля рабочих доменов среди распознанных, overall - взвешенная сумма
+func localProductivityScore(req entity.AIAnalyticsRequest, breakdown entity.DomainBreakdown, thresholds entity.FocusThresholds, l localeStrings) entity.ProductivityScore {
+	focus := clampScore(req.DeepWork.DeepWorkRate*0.7 + domainCountFocusScore(req.DomainsCount, thresholds)*0.3)
+	efficiency := clampScore(req.EngagementRate)
+
+	productive := len(breakdown.WorkTools) + len(breakdown.Development) + len(breakdown.Research) + len(breakdown.Communication)
+	distractions := len(breakdown.Distractions)
+
+	balance := 50
+	if productive+distractions > 0 {
+		balance = clampScore(float64(productive) / float64(productive+distractions) * 100)
+	}
+
+	overall := clampScore(float64(focus)*0.4 + float64(efficiency)*0.4 + float64(balance)*0.2)
+
+	return entity.ProductivityScore{
+		Overall:    overall,
+		Focus:      focus,
+		Efficiency: efficiency,
+		Balance:    balance,
+		Explanation: fmt.Sprintf(l.localScoreExplanation,
+			req.DeepWork.DeepWorkRate, req.EngagementRate, distractions, productive+distractions),
+	}
+}
+
+// localWorkPattern определяет паттерн работы по deep work и преобладающей категории доменов
+func localWorkPattern(req entity.AIAnalyticsRequest, breakdown entity.DomainBreakdown, thresholds entity.FocusThresholds) string {
+	categorized := len(breakdown.WorkTools) + len(breakdown.Development) + len(breakdown.Research) +
+		len(breakdown.Communication) + len(breakdown.Distractions)
+
+	switch {
+	case req.DeepWork.DeepWorkRate >= 50:
+		return "deep_focused"
+	case categorized > 0 && float64(len(breakdown.Distractions))/float64(categorized) > 0.3:
+		return "distracted"
+	case len(breakdown.Communication) > len(breakdown.Development) && len(breakdown.Communication) > len(breakdown.WorkTools):
+		return "communication_intensive"
+	case len(breakdown.Research) > len(breakdown.Development) && len(breakdown.Research) > len(breakdown.WorkTools):
+		return "research_heavy"
+	case req.DomainsCount > thresholds.MediumMaxDomains:
+		return "task_switching"
+	default:
+		return "unknown"
+	}
+}
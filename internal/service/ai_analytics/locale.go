@@ -0,0 +1,295 @@
+package ai_analytics
+
+import "fmt"
+
+// Поддерживаемые языки AI инсайтов; русский - по умолчанию для обратной совместимости
+const (
+	LangRU      = "ru"
+	LangEN      = "en"
+	DefaultLang = LangRU
+)
+
+// localeStrings - промпты и fallback тексты для одного языка
+type localeStrings struct {
+	systemPrompt      string
+	analysisPrompt    string // формат: часы, engagement, домены, сессии, часы deep work, rate, средняя, макс, домены, топ домены, закрепленные
+	focusSystemPrompt string
+	focusPrompt       string // формат: количество доменов, затем границы high, medium (от, до) и low из FocusThresholds
+
+	noData           string
+	noDeepWork       string
+	domainMinutes    string // формат: домен, минуты
+	pinnedCategories string
+
+	focusInsightHigh    string // формат: количество доменов
+	focusInsightMedium  string
+	focusInsightLow     string
+	focusInsightVeryLow string
+	aiDisabledInsight   string
+
+	fallbackRecommendation  string
+	localScoreExplanation   string // формат: deep work rate, engagement rate, отвлекающие домены, распознанные домены
+	fallbackBehaviorInsight string
+	fallbackKeyFinding      string
+
+	parseFailedExplanation string
+	parseFailedInsight     string
+	parseFailedFinding     string
+}
+
+var locales = map[string]localeStrings{
+	LangRU: {
+		systemPrompt: `Ты эксперт по анализу цифрового поведения и продуктивности. 
+
+ЗАДАЧА: Дать детальный, но краткий анализ на основе конкретных данных.
+
+КАТЕГОРИИ ДОМЕНОВ:
+- work_tools: Jira, Slack, корпоративные системы, CRM
+- development: localhost, GitHub, CodeSandbox, IDE, облачные платформы  
+- research: Stack Overflow, документация, курсы, блоги разработчиков
+- communication: Gmail, Telegram, LinkedIn, мессенджеры
+- distractions: YouTube, соцсети, новости, развлекательный контент
+
+ОЦЕНКИ (0-100):
+- overall: общая продуктивность (engagement + deep work + focus)
+- focus: на основе deep work rate и количества доменов
+- efficiency: на основе engagement rate
+- balance: баланс рабочих/отвлекающих доменов
+
+ИНСАЙТЫ: Конкретные наблюдения с цифрами и пояснениями.
+
+ФОРМАТ JSON (без markdown):
+{
+  "focus_level": "high|medium|low",
+  "focus_insight": "Краткий вывод с цифрами",
+  "work_pattern": "deep_focused|task_switching|research_heavy|communication_intensive|distracted",
+  "recommendations": ["рекомендация с обоснованием"],
+  "analysis": {
+    "domain_breakdown": {
+      "work_tools": ["список доменов"],
+      "development": ["список доменов"],
+      "research": ["список доменов"], 
+      "communication": ["список доменов"],
+      "distractions": ["список доменов"]
+    },
+    "productivity_score": {
+      "overall": 85,
+      "focus": 90,
+      "efficiency": 80,
+      "balance": 85,
+      "explanation": "Высокие показатели благодаря X, но снижены из-за Y"
+    },
+    "behavior_insights": [
+      "93% времени deep work на localhost - отличная концентрация",
+      "22 домена за 4+ часа - высокая фрагментация внимания"
+    ],
+    "key_findings": [
+      "Преобладает разработка (localhost + dev инструменты)",
+      "Минимальные отвлечения на развлекательный контент"
+    ]
+  }
+}`,
+		analysisPrompt: `ДАННЫЕ ДЛЯ АНАЛИЗА:
+
+📊 ОСНОВНЫЕ МЕТРИКИ:
+- Время работы: %.2f часов
+- Engagement rate: %.1f%% (активность в минутах)
+- Уникальных доменов: %d
+- Deep work: %d сессий (%.1f часов, %.1f%% времени)
+- Средняя deep work сессия: %.1f мин (макс: %.1f мин)
+
+🌐 ПОСЕЩЕННЫЕ ДОМЕНЫ:
+%s
+
+🎯 DEEP WORK ДОМЕНЫ:
+%s%s
+
+ЗАДАЧА: Проанализируй паттерн работы, дай конкретные инсайты с цифрами и практичные рекомендации.`,
+		focusSystemPrompt: "Ты эксперт по анализу цифрового поведения. Отвечай только в JSON формате без markdown.",
+		focusPrompt: `Проанализируй уровень фокуса пользователя:
+
+ДАННЫЕ:
+- Количество уникальных доменов: %d
+
+ЗАДАЧА: Определи уровень фокуса и дай краткий инсайт.
+
+ОТВЕТ в JSON формате:
+{
+  "focus_level": "high|medium|low",
+  "insight": "Краткое объяснение с конкретными наблюдениями",
+  "method": "ai"
+}
+
+ПРАВИЛА:
+- high: ≤%d доменов, фокусированная работа
+- medium: %d-%d доменов, умеренная многозадачность  
+- low: >%d доменов, высокая фрагментация
+- Учитывай типы доменов (рабочие vs развлекательные)`,
+
+		noData:           "Нет данных",
+		noDeepWork:       "Нет deep work сессий",
+		domainMinutes:    "%s (%.1f мин)",
+		pinnedCategories: "\n\n📌 ЗАКРЕПЛЕННЫЕ КАТЕГОРИИ (используй их без изменений):\n",
+
+		focusInsightHigh:    "Высокая концентрация: работа в %d доменах указывает на фокусированную деятельность",
+		focusInsightMedium:  "Средняя концентрация: %d доменов говорит о сбалансированной многозадачности",
+		focusInsightLow:     "Низкая концентрация: %d доменов может указывать на частые переключения контекста",
+		focusInsightVeryLow: "Очень низкая концентрация: %d доменов указывает на высокую фрагментацию внимания",
+		aiDisabledInsight:   "AI анализ отключен, используется базовая оценка по количеству доменов",
+
+		fallbackRecommendation:  "AI анализ временно недоступен",
+		localScoreExplanation:   "Базовая оценка без AI: deep work %.1f%%, engagement %.1f%%, отвлекающих доменов %d из %d распознанных",
+		fallbackBehaviorInsight: "Базовая оценка без AI анализа",
+		fallbackKeyFinding:      "Детальный анализ требует AI сервиса",
+
+		parseFailedExplanation: "AI анализ недоступен",
+		parseFailedInsight:     "Анализ не выполнен из-за ошибки",
+		parseFailedFinding:     "Базовые данные доступны без AI",
+	},
+	LangEN: {
+		systemPrompt: `You are an expert in digital behavior and productivity analysis.
+TASK: Give a detailed but concise analysis based on the specific data. Write all text fields in English.
+
+DOMAIN CATEGORIES:
+- work_tools: Jira, Slack, corporate systems, CRM
+- development: localhost, GitHub, CodeSandbox, IDE, cloud platforms
+- research: Stack Overflow, documentation, courses, developer blogs
+- communication: Gmail, Telegram, LinkedIn, messengers
+- distractions: YouTube, social networks, news, entertainment
+
+SCORES (0-100):
+- overall: overall productivity (engagement + deep work + focus)
+- focus: based on deep work rate and number of domains
+- efficiency: based on engagement rate
+- balance: balance of work/distracting domains
+
+INSIGHTS: Specific observations with numbers and explanations.
+
+JSON FORMAT (no markdown):
+{
+  "focus_level": "high|medium|low",
+  "focus_insight": "Short conclusion with numbers",
+  "work_pattern": "deep_focused|task_switching|research_heavy|communication_intensive|distracted",
+  "recommendations": ["recommendation with reasoning"],
+  "analysis": {
+    "domain_breakdown": {
+      "work_tools": ["list of domains"],
+      "development": ["list of domains"],
+      "research": ["list of domains"],
+      "communication": ["list of domains"],
+      "distractions": ["list of domains"]
+    },
+    "productivity_score": {
+      "overall": 85,
+      "focus": 90,
+      "efficiency": 80,
+      "balance": 85,
+      "explanation": "High scores thanks to X, but lowered by Y"
+    },
+    "behavior_insights": [
+      "93% of deep work time on localhost - excellent concentration",
+      "22 domains over 4+ hours - high attention fragmentation"
+    ],
+    "key_findings": [
+      "Development dominates (localhost + dev tools)",
+      "Minimal distractions from entertainment content"
+    ]
+  }
+}`,
+		analysisPrompt: `DATA FOR ANALYSIS:
+
+📊 KEY METRICS:
+- Working time: %.2f hours
+- Engagement rate: %.1f%% (activity in minutes)
+- Unique domains: %d
+- Deep work: %d sessions (%.1f hours, %.1f%% of time)
+- Average deep work session: %.1f min (max: %.1f min)
+
+🌐 VISITED DOMAINS:
+%s
+
+🎯 DEEP WORK DOMAINS:
+%s%s
+
+TASK: Analyze the work pattern, give specific insights with numbers and practical recommendations.`,
+		focusSystemPrompt: "You are an expert in digital behavior analysis. Answer only in JSON format without markdown.",
+		focusPrompt: `Analyze the user's focus level:
+
+DATA:
+- Number of unique domains: %d
+
+TASK: Determine the focus level and give a short insight in English.
+
+ANSWER in JSON format:
+{
+  "focus_level": "high|medium|low",
+  "insight": "Short explanation with specific observations",
+  "method": "ai"
+}
+
+RULES:
+- high: ≤%d domains, focused work
+- medium: %d-%d domains, moderate multitasking
+- low: >%d domains, high fragmentation
+- Consider domain types (work vs entertainment)`,
+
+		noData:           "No data",
+		noDeepWork:       "No deep work sessions",
+		domainMinutes:    "%s (%.1f min)",
+		pinnedCategories: "\n\n📌 PINNED CATEGORIES (use them as is):\n",
+
+		focusInsightHigh:    "High focus: working across %d domains indicates focused activity",
+		focusInsightMedium:  "Medium focus: %d domains suggest balanced multitasking",
+		focusInsightLow:     "Low focus: %d domains may indicate frequent context switching",
+		focusInsightVeryLow: "Very low focus: %d domains indicate highly fragmented attention",
+		aiDisabledInsight:   "AI analysis is disabled, using a basic estimate based on the number of domains",
+
+		fallbackRecommendation:  "AI analysis is temporarily unavailable",
+		localScoreExplanation:   "Basic estimate without AI: deep work %.1f%%, engagement %.1f%%, %d of %d recognized domains are distractions",
+		fallbackBehaviorInsight: "Basic estimate without AI analysis",
+		fallbackKeyFinding:      "Detailed analysis requires the AI service",
+
+		parseFailedExplanation: "AI analysis is unavailable",
+		parseFailedInsight:     "Analysis failed due to an error",
+		parseFailedFinding:     "Basic data is available without AI",
+	},
+}
+
+// IsSupportedLang проверяет язык; пустая строка означает язык по умолчанию
+func IsSupportedLang(lang string) bool {
+	if lang == "" {
+		return true
+	}
+	_, ok := locales[lang]
+	return ok
+}
+
+// NormalizeLang возвращает язык по умолчанию для пустого или неизвестного значения
+func NormalizeLang(lang string) string {
+	if _, ok := locales[lang]; ok {
+		return lang
+	}
+	return DefaultLang
+}
+
+func localeFor(lang string) localeStrings {
+	return locales[NormalizeLang(lang)]
+}
+
+// Больше этого числа доменов - очень низкий фокус (отдельный текст инсайта и оценка в local score)
+const lowFocusMaxDomains = 25
+
+// FallbackFocusInsight - инсайт по количеству доменов, когда AI недоступен
+func (s *AIAnalyticsService) FallbackFocusInsight(domainsCount int, lang string) string {
+	l := localeFor(lang)
+	switch {
+	case domainsCount <= s.focusThresholds.HighMaxDomains:
+		return fmt.Sprintf(l.focusInsightHigh, domainsCount)
+	case domainsCount <= s.focusThresholds.MediumMaxDomains:
+		return fmt.Sprintf(l.focusInsightMedium, domainsCount)
+	case domainsCount <= lowFocusMaxDomains:
+		return fmt.Sprintf(l.focusInsightLow, domainsCount)
+	default:
+		return fmt.Sprintf(l.focusInsightVeryLow, domainsCount)
+	}
+}
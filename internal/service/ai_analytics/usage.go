@@ -0,0 +1,192 @@
+package ai_analytics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+)
+
+// Дневные счетчики живут дольше месяца, чтобы месячная сумма собиралась из дневных ключей
+const aiUsageKeyTTL = 40 * 24 * time.Hour
+
+// Поля дневного хеша расхода; стоимость хранится в микродолларах, чтобы считать целыми числами
+const (
+	usageFieldRequests         = "requests"
+	usageFieldPromptTokens     = "prompt_tokens"
+	usageFieldCompletionTokens = "completion_tokens"
+	usageFieldTotalTokens      = "total_tokens"
+	usageFieldCostMicroUSD     = "cost_micro_usd"
+)
+
+// UsageStore - хранилище дневных счетчиков расхода (реализуется redis.ServiceInterface)
+type UsageStore interface {
+	IncrementHash(ctx context.Context, key string, fields map[string]int64, ttl time.Duration) error
+	GetAllHash(ctx context.Context, key string) (map[string]string, error)
+}
+
+// OpenAIUsage - поле usage ответа OpenAI
+type OpenAIUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+// modelPrice - цена в долларах за 1M токенов
+type modelPrice struct {
+	prompt     float64
+	completion float64
+}
+
+// Прайс OpenAI для оценки расходов. Модель ищется по самому длинному префиксу
+// (gpt-4o-2024-08-06 -> gpt-4o), неизвестные модели считаются по цене gpt-4o
+var openAIPrices = map[string]modelPrice{
+	"gpt-4o":        {prompt: 2.50, completion: 10.00},
+	"gpt-4o-mini":   {prompt: 0.15, completion: 0.60},
+	"gpt-4.1":       {prompt: 2.00, completion: 8.00},
+	"gpt-4.1-mini":  {prompt: 0.40, completion: 1.60},
+	"gpt-4.1-nano":  {prompt: 0.10, completion: 0.40},
+	"gpt-4-turbo":   {prompt: 10.00, completion: 30.00},
+	"gpt-3.5-turbo": {prompt: 0.50, completion: 1.50},
+}
+
+func priceForModel(model string) modelPrice {
+	best, bestLen := openAIPrices[defaultOpenAIModel], 0
+	for prefix, price := range openAIPrices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > bestLen {
+			best, bestLen = price, len(prefix)
+		}
+	}
+	return best
+}
+
+func estimateCostMicroUSD(model string, usage OpenAIUsage) int64 {
+	price := priceForModel(model)
+	// цена за 1M токенов в долларах = цена за токен в микродолларах
+	return int64(float64(usage.PromptTokens)*price.prompt + float64(usage.CompletionTokens)*price.completion)
+}
+
+func aiUsageKey(day time.Time) string {
+	return fmt.Sprintf("ai_analytics:usage:%s", day.Format("2006-01-02"))
+}
+
+// recordUsage добавляет расход запроса в дневной счетчик. Ошибки Redis только логируются:
+// учет расходов не должен ломать ответ AI
+func (s *AIAnalyticsService) recordUsage(ctx context.Context, model string, usage OpenAIUsage) {
+	if s.usageStore == nil {
+		return
+	}
+
+	fields := map[string]int64{
+		usageFieldRequests:         1,
+		usageFieldPromptTokens:     usage.PromptTokens,
+		usageFieldCompletionTokens: usage.CompletionTokens,
+		usageFieldTotalTokens:      usage.TotalTokens,
+		usageFieldCostMicroUSD:     estimateCostMicroUSD(model, usage),
+	}
+
+	key := aiUsageKey(time.Now().UTC())
+	if err := s.usageStore.IncrementHash(ctx, key, fields, aiUsageKeyTTL); err != nil {
+		log.Printf("Failed to record OpenAI usage in %s: %v", key, err)
+	}
+}
+
+// GetUsage возвращает расход токенов и оценочную стоимость за сегодня и текущий месяц (UTC)
+func (s *AIAnalyticsService) GetUsage(ctx context.Context) (*entity.AIUsageStats, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	monthStart := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	stats := &entity.AIUsageStats{
+		Date:       today.Format("2006-01-02"),
+		MonthStart: monthStart.Format("2006-01-02"),
+	}
+	if s.usageStore == nil {
+		return stats, nil
+	}
+
+	var month map[string]int64
+	for day := monthStart; !day.After(today); day = day.AddDate(0, 0, 1) {
+		counters, err := s.usageStore.GetAllHash(ctx, aiUsageKey(day))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get AI usage for %s: %w", day.Format("2006-01-02"), err)
+		}
+
+		dayTotals := parseUsageCounters(counters)
+		if day.Equal(today) {
+			stats.Today = usageTotals(dayTotals)
+		}
+
+		if month == nil {
+			month = dayTotals
+			continue
+		}
+		for field, value := range dayTotals {
+			month[field] += value
+		}
+	}
+	stats.Month = usageTotals(month)
+
+	return stats, nil
+}
+
+// RequestsToday - число запросов к OpenAI за текущий день (UTC)
+func (s *AIAnalyticsService) RequestsToday(ctx context.Context) (int, error) {
+	if s.usageStore == nil {
+		return 0, nil
+	}
+
+	counters, err := s.usageStore.GetAllHash(ctx, aiUsageKey(time.Now().UTC()))
+	if err != nil {
+		return 0, err
+	}
+
+	return int(parseUsageCounters(counters)[usageFieldRequests]), nil
+}
+
+func parseUsageCounters(counters map[string]string) map[string]int64 {
+	result := make(map[string]int64, len(counters))
+	for field, raw := range counters {
+		if value, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			result[field] = value
+		}
+	}
+	return result
+}
+
+func usageTotals(counters map[string]int64) entity.AIUsageTotals {
+	return entity.AIUsageTotals{
+		Requests:         counters[usageFieldRequests],
+		PromptTokens:     counters[usageFieldPromptTokens],
+		CompletionTokens: counters[usageFieldCompletionTokens],
+		TotalTokens:      counters[usageFieldTotalTokens],
+		EstimatedCostUSD: math.Round(float64(counters[usageFieldCostMicroUSD])/100) / 1e4,
+	}
+}
+
+// HealthCheck - состояние AI сервиса без обращения к OpenAI: наличие ключа, модель и число запросов за сегодня
+func (s *AIAnalyticsService) HealthCheck(ctx context.Context) *entity.AIAnalyticsHealthCheck {
+	health := &entity.AIAnalyticsHealthCheck{
+		Available: s.IsEnabled(),
+		Model:     s.domainUsage.Model,
+		LastCheck: time.Now(),
+	}
+	var problems []string
+	if !health.Available {
+		problems = append(problems, "OpenAI API key is not configured")
+	}
+
+	// Ошибка чтения счетчиков дополняет, а не заменяет сообщение о ненастроенном ключе
+	requestsToday, err := s.RequestsToday(ctx)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("failed to read AI usage: %v", err))
+	}
+	health.RequestsToday = requestsToday
+	health.ErrorMessage = strings.Join(problems, "; ")
+
+	return health
+}
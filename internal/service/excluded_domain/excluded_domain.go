@@ -0,0 +1,203 @@
+package excluded_domain
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/dinerozz/web-behavior-backend/internal/repository"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// Время жизни кеша списка исключений; изменения через API сбрасывают кеш сразу,
+	// на других инстансах они применяются по истечении TTL
+	excludedDomainsCacheTTL = time.Minute
+	// Через сколько повторить чтение таблицы после ошибки: до этого отдается запасной список,
+	// чтобы при недоступной БД каждый запрос не ходил в нее заново
+	excludedDomainsRetryTTL = 10 * time.Second
+)
+
+type ExcludedDomainService struct {
+	repo repository.ExcludedDomainRepository
+	// Домены из EXCLUDED_DOMAINS, действуют вместе с таблицей excluded_domains
+	configured []string
+
+	mu        sync.RWMutex
+	cached    []string
+	expiresAt time.Time
+	// Последний успешно прочитанный список - запасной вариант при ошибке чтения таблицы
+	lastLoaded []string
+}
+
+func NewExcludedDomainService(repo repository.ExcludedDomainRepository, configured []string) *ExcludedDomainService {
+	normalized := make([]string, 0, len(configured))
+	for _, domain := range configured {
+		if domain = normalizeDomain(domain); domain != "" {
+			normalized = append(normalized, domain)
+		}
+	}
+
+	return &ExcludedDomainService{
+		repo:       repo,
+		configured: normalized,
+	}
+}
+
+func normalizeDomain(domain string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "www.")
+}
+
+// Domains возвращает отсортированный список исключенных доменов (конфиг + таблица).
+// При ошибке чтения таблицы используется последний прочитанный список (или только домены из конфига),
+// чтобы метрики и прием событий не падали; он кешируется на excludedDomainsRetryTTL
+func (s *ExcludedDomainService) Domains(ctx context.Context) []string {
+	s.mu.RLock()
+	if s.cached != nil && time.Now().Before(s.expiresAt) {
+		domains := s.cached
+		s.mu.RUnlock()
+		return domains
+	}
+	s.mu.RUnlock()
+
+	stored, err := s.repo.GetAll(ctx)
+	if err != nil {
+		fmt.Printf("Failed to load excluded domains: %v\n", err)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		fallback := s.lastLoaded
+		if fallback == nil {
+			// Не nil, чтобы пустой список тоже считался закешированным
+			fallback = append([]string{}, s.configured...)
+		}
+		s.cached = fallback
+		s.expiresAt = time.Now().Add(excludedDomainsRetryTTL)
+		return fallback
+	}
+
+	seen := make(map[string]bool, len(stored)+len(s.configured))
+	domains := make([]string, 0, len(stored)+len(s.configured))
+	for _, domain := range s.configured {
+		if !seen[domain] {
+			seen[domain] = true
+			domains = append(domains, domain)
+		}
+	}
+	for _, excluded := range stored {
+		if !seen[excluded.Domain] {
+			seen[excluded.Domain] = true
+			domains = append(domains, excluded.Domain)
+		}
+	}
+	sort.Strings(domains)
+
+	s.mu.Lock()
+	s.cached = domains
+	s.lastLoaded = domains
+	s.expiresAt = time.Now().Add(excludedDomainsCacheTTL)
+	s.mu.Unlock()
+
+	return domains
+}
+
+// IsExcluded сравнивает уже нормализованный домен события (utils.NormalizeDomain) со списком исключений
+func (s *ExcludedDomainService) IsExcluded(ctx context.Context, domain string) bool {
+	for _, excluded := range s.Domains(ctx) {
+		if excluded == domain {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *ExcludedDomainService) invalidate() {
+	s.mu.Lock()
+	s.cached = nil
+	s.mu.Unlock()
+}
+
+func (s *ExcludedDomainService) List(ctx context.Context) ([]entity.ExcludedDomain, error) {
+	return s.repo.GetAll(ctx)
+}
+
+func (s *ExcludedDomainService) Create(ctx context.Context, req entity.ExcludedDomainRequest) (*entity.ExcludedDomain, error) {
+	domain := normalizeDomain(req.Domain)
+	if domain == "" {
+		return nil, fmt.Errorf("domain is required")
+	}
+
+	exists, err := s.repo.ExistsByDomain(ctx, domain, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check domain existence: %w", err)
+	}
+
+	if exists {
+		return nil, fmt.Errorf("excluded domain already exists")
+	}
+
+	excluded := &entity.ExcludedDomain{
+		ID:     uuid.Must(uuid.NewV4()),
+		Domain: domain,
+	}
+
+	if err := s.repo.Create(ctx, excluded); err != nil {
+		return nil, fmt.Errorf("failed to create excluded domain: %w", err)
+	}
+
+	s.invalidate()
+
+	return excluded, nil
+}
+
+func (s *ExcludedDomainService) Update(ctx context.Context, id uuid.UUID, req entity.ExcludedDomainRequest) (*entity.ExcludedDomain, error) {
+	domain := normalizeDomain(req.Domain)
+	if domain == "" {
+		return nil, fmt.Errorf("domain is required")
+	}
+
+	exists, err := s.repo.ExistsByDomain(ctx, domain, &id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check domain existence: %w", err)
+	}
+
+	if exists {
+		return nil, fmt.Errorf("excluded domain already exists")
+	}
+
+	excluded := &entity.ExcludedDomain{
+		ID:     id,
+		Domain: domain,
+	}
+
+	if err := s.repo.Update(ctx, excluded); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("excluded domain not found")
+		}
+		return nil, fmt.Errorf("failed to update excluded domain: %w", err)
+	}
+
+	s.invalidate()
+
+	return excluded, nil
+}
+
+func (s *ExcludedDomainService) Delete(ctx context.Context, id uuid.UUID) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("excluded domain not found")
+		}
+		return fmt.Errorf("failed to delete excluded domain: %w", err)
+	}
+
+	s.invalidate()
+
+	return nil
+}
@@ -3,10 +3,13 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/dinerozz/web-behavior-backend/internal/entity"
 	"github.com/dinerozz/web-behavior-backend/internal/repository"
 	"github.com/gofrs/uuid"
+	"strings"
+	"time"
 )
 
 type ExtensionUserService interface {
@@ -18,22 +21,31 @@ type ExtensionUserService interface {
 	UpdateUser(ctx context.Context, id uuid.UUID, req entity.UpdateExtensionUserRequest) (*entity.ExtensionUserPublic, error)
 	RegenerateAPIKey(ctx context.Context, id uuid.UUID) (*entity.RegenerateAPIKeyResponse, error)
 	DeleteUser(ctx context.Context, id uuid.UUID) error
+	DeactivateStaleUsers(ctx context.Context, days int) ([]uuid.UUID, error)
 	ValidateAPIKey(ctx context.Context, apiKey string) (*entity.ExtensionUser, error)
 	GetStats(ctx context.Context) (*entity.ExtensionUserStats, error)
 }
 
+// ErrOwnerNotOrgMember - owner_user_id не состоит в организации пользователя расширения
+var ErrOwnerNotOrgMember = errors.New("owner user is not a member of the extension user's organization")
+
 type extensionUserService struct {
-	repo    repository.ExtensionUserRepository
-	orgRepo repository.OrganizationRepository
+	repo       repository.ExtensionUserRepository
+	orgRepo    repository.OrganizationRepository
+	pagination entity.PaginationLimits
 }
 
-func NewExtensionUserService(repo repository.ExtensionUserRepository, orgRepo repository.OrganizationRepository) ExtensionUserService {
+func NewExtensionUserService(repo repository.ExtensionUserRepository, orgRepo repository.OrganizationRepository, pagination entity.PaginationLimits) ExtensionUserService {
 	return &extensionUserService{
-		repo:    repo,
-		orgRepo: orgRepo,
+		repo:       repo,
+		orgRepo:    orgRepo,
+		pagination: pagination,
 	}
 }
 
+// Размер выборки для старых limit/offset запросов без limit
+const legacyExtensionUsersLimit = 50
+
 func (s *extensionUserService) CreateUser(ctx context.Context, req entity.CreateExtensionUserRequest) (*entity.ExtensionUser, error) {
 	exists, err := s.repo.ExistsByUsername(ctx, req.Username)
 	if err != nil {
@@ -48,6 +60,12 @@ func (s *extensionUserService) CreateUser(ctx context.Context, req entity.Create
 		return nil, fmt.Errorf("username already exists")
 	}
 
+	if req.OwnerUserID != nil {
+		if err := s.checkOwnerMembership(*req.OrganizationID, *req.OwnerUserID); err != nil {
+			return nil, err
+		}
+	}
+
 	apiKey, err := s.repo.GenerateAPIKey()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate API key: %w", err)
@@ -59,6 +77,7 @@ func (s *extensionUserService) CreateUser(ctx context.Context, req entity.Create
 		APIKey:         apiKey,
 		IsActive:       true,
 		OrganizationID: *req.OrganizationID,
+		OwnerUserID:    req.OwnerUserID,
 	}
 
 	err = s.repo.Create(ctx, user)
@@ -117,20 +136,21 @@ func (s *extensionUserService) GetUserByUsername(ctx context.Context, username s
 }
 
 func (s *extensionUserService) GetAllUsers(ctx context.Context, filter entity.ExtensionUserFilter) ([]entity.ExtensionUserPublic, *entity.PaginationInfo, error) {
-	if filter.Page > 0 && filter.PerPage > 0 {
-		if filter.PerPage > 200 {
-			filter.PerPage = 200
-		}
-		if filter.Page < 1 {
-			filter.Page = 1
-		}
+	if filter.SortBy != "" && !entity.ExtensionUserSortFields[filter.SortBy] {
+		return nil, nil, fmt.Errorf("invalid sort_by: must be one of username, created_at, updated_at, last_used_at")
+	}
+
+	if filter.Order != "" && !strings.EqualFold(filter.Order, "asc") && !strings.EqualFold(filter.Order, "desc") {
+		return nil, nil, fmt.Errorf("invalid order: must be asc or desc")
+	}
+
+	if filter.Page > 0 {
+		filter.PerPage = s.pagination.Clamp(filter.PerPage)
 	} else {
 		if filter.Limit <= 0 {
-			filter.Limit = 50
-		}
-		if filter.Limit > 200 {
-			filter.Limit = 200
+			filter.Limit = legacyExtensionUsersLimit
 		}
+		filter.Limit = s.pagination.Clamp(filter.Limit)
 	}
 
 	users, err := s.repo.GetAllWithOrganization(ctx, filter)
@@ -138,20 +158,23 @@ func (s *extensionUserService) GetAllUsers(ctx context.Context, filter entity.Ex
 		return nil, nil, fmt.Errorf("failed to get users: %w", err)
 	}
 
-	var paginationInfo *entity.PaginationInfo
-	if filter.Page > 0 && filter.PerPage > 0 {
-		total, err := s.repo.CountByFilter(ctx, filter)
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to count users: %w", err)
-		}
+	total, err := s.repo.CountByFilter(ctx, filter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to count users: %w", err)
+	}
 
-		totalPages := (total + filter.PerPage - 1) / filter.PerPage
-		paginationInfo = &entity.PaginationInfo{
-			Page:       filter.Page,
-			PerPage:    filter.PerPage,
-			Total:      total,
-			TotalPages: totalPages,
-		}
+	// Для старых limit/offset параметров страница вычисляется из смещения
+	page, perPage := filter.Page, filter.PerPage
+	if page <= 0 || perPage <= 0 {
+		perPage = filter.Limit
+		page = filter.Offset/filter.Limit + 1
+	}
+
+	paginationInfo := &entity.PaginationInfo{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: (total + perPage - 1) / perPage,
 	}
 
 	return users, paginationInfo, nil
@@ -176,6 +199,21 @@ func (s *extensionUserService) UpdateUser(ctx context.Context, id uuid.UUID, req
 		}
 	}
 
+	// Владелец должен состоять в организации, в том числе после переноса расширения в другую
+	ownerUserID := existingUser.OwnerUserID
+	if req.OwnerUserID.Set {
+		ownerUserID = req.OwnerUserID.Value
+	}
+	organizationID := existingUser.OrganizationID
+	if req.OrganizationID != nil {
+		organizationID = *req.OrganizationID
+	}
+	if ownerUserID != nil && (req.OwnerUserID.Set || req.OrganizationID != nil) {
+		if err := s.checkOwnerMembership(organizationID, *ownerUserID); err != nil {
+			return nil, err
+		}
+	}
+
 	updatedUser, err := s.repo.Update(ctx, id, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update user: %w", err)
@@ -187,6 +225,17 @@ func (s *extensionUserService) UpdateUser(ctx context.Context, id uuid.UUID, req
 	return s.toPublicUser(updatedUser), nil
 }
 
+func (s *extensionUserService) checkOwnerMembership(organizationID, ownerUserID uuid.UUID) error {
+	_, err := s.orgRepo.CheckUserAccess(organizationID, ownerUserID)
+	if errors.Is(err, repository.ErrNoOrganizationAccess) {
+		return ErrOwnerNotOrgMember
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check owner membership: %w", err)
+	}
+	return nil
+}
+
 func (s *extensionUserService) RegenerateAPIKey(ctx context.Context, id uuid.UUID) (*entity.RegenerateAPIKeyResponse, error) {
 	existingUser, err := s.repo.GetByID(ctx, id)
 	if err != nil {
@@ -220,6 +269,14 @@ func (s *extensionUserService) DeleteUser(ctx context.Context, id uuid.UUID) err
 	return nil
 }
 
+func (s *extensionUserService) DeactivateStaleUsers(ctx context.Context, days int) ([]uuid.UUID, error) {
+	if days <= 0 {
+		return nil, fmt.Errorf("days must be a positive number")
+	}
+
+	return s.repo.DeactivateStaleUsers(ctx, time.Duration(days)*24*time.Hour)
+}
+
 func (s *extensionUserService) ValidateAPIKey(ctx context.Context, apiKey string) (*entity.ExtensionUser, error) {
 	if apiKey == "" {
 		return nil, fmt.Errorf("API key is required")
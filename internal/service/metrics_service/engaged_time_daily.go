@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/dinerozz/web-behavior-backend/pkg/utils"
+)
+
+const (
+	dailySourceRollup = "rollup"
+	dailySourceRaw    = "raw"
+)
+
+// GetEngagedTimeDaily читает полные прошедшие дни из rollup таблицы daily_engagement,
+// а неполные крайние дни, текущий день и дни без строки rollup считает по сырым событиям
+func (s *MetricsService) GetEngagedTimeDaily(ctx context.Context, filter entity.EngagedTimeDailyFilter) (*entity.EngagedTimeDailyMetric, error) {
+	if filter.UserID == "" {
+		return nil, errors.New("user_id is required")
+	}
+
+	if filter.EndTime.Before(filter.StartTime) {
+		return nil, fmt.Errorf("end_time must be after start_time")
+	}
+
+	if s.dailyRepo == nil {
+		return nil, fmt.Errorf("daily engagement rollup is not configured")
+	}
+
+	filter.ActiveEvents = s.activeEventsForUser(filter.UserID)
+
+	start := filter.StartTime.UTC()
+	// end_time включителен, как и в остальных метриках
+	end := filter.EndTime.UTC().Add(time.Microsecond)
+	firstDay := start.Truncate(24 * time.Hour)
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	rollupRows, err := s.dailyRepo.GetRange(ctx, filter.UserID, firstDay, end)
+	if err != nil {
+		return nil, err
+	}
+
+	rollupByDay := make(map[string]entity.DailyEngagement, len(rollupRows))
+	for _, row := range rollupRows {
+		rollupByDay[row.Day.Format("2006-01-02")] = row
+	}
+
+	metric := &entity.EngagedTimeDailyMetric{
+		UserID:    filter.UserID,
+		StartTime: filter.StartTime,
+		EndTime:   filter.EndTime,
+		Days:      []entity.EngagedTimeDay{},
+	}
+
+	for day := firstDay; day.Before(end); day = day.Add(24 * time.Hour) {
+		nextDay := day.Add(24 * time.Hour)
+		segmentStart := maxTime(start, day)
+		segmentEnd := minTime(end, nextDay)
+
+		point := entity.EngagedTimeDay{Date: day.Format("2006-01-02")}
+
+		isWholeDay := segmentStart.Equal(day) && !segmentEnd.Before(nextDay.Add(-time.Second))
+		// День без строки rollup (rollup еще не запускался) считается по сырым событиям, а не как 0
+		row, hasRollup := rollupByDay[point.Date]
+		if isWholeDay && day.Before(today) && hasRollup {
+			point.ActiveMinutes = row.ActiveMinutes
+			point.TrackedMinutes = row.TrackedMinutes
+			point.Source = dailySourceRollup
+		} else {
+			point.ActiveMinutes, point.TrackedMinutes, err = s.dailyRepo.ComputeRaw(ctx, filter.UserID, segmentStart, segmentEnd, filter.ActiveEvents)
+			if err != nil {
+				return nil, err
+			}
+			point.Source = dailySourceRaw
+		}
+
+		point.EngagementRate = engagementRate(point.ActiveMinutes, point.TrackedMinutes)
+		metric.ActiveMinutes += point.ActiveMinutes
+		metric.TrackedMinutes += point.TrackedMinutes
+		metric.Days = append(metric.Days, point)
+	}
+
+	metric.EngagementRate = engagementRate(metric.ActiveMinutes, metric.TrackedMinutes)
+
+	return metric, nil
+}
+
+func engagementRate(activeMinutes, trackedMinutes int) float64 {
+	if trackedMinutes <= 0 {
+		return 0
+	}
+	return utils.RoundToTwoDecimals(float64(activeMinutes) / float64(trackedMinutes) * 100)
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
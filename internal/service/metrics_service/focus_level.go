@@ -0,0 +1,40 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+)
+
+// applyFocusLevel заполняет FocusLevel/FocusInsight по порогам из конфига. Для switches (по умолчанию)
+// используются переключения в час из deep work блоков; если блоков нет, считаем по числу доменов
+func (s *MetricsService) applyFocusLevel(metric *entity.EngagedTimeMetric, method string) {
+	if method != entity.FocusMethodDomains && metric.DeepWork.SessionsCount > 0 {
+		switchesPerHour := metric.DeepWork.AvgSwitchesPerHour
+
+		metric.FocusMethod = entity.FocusMethodSwitches
+		metric.FocusLevel = s.focusThresholds.SwitchesLevel(switchesPerHour)
+		switch metric.FocusLevel {
+		case entity.FocusLevelHigh:
+			metric.FocusInsight = fmt.Sprintf("Высокая концентрация: в среднем %.1f переключений контекста в час в deep work блоках", switchesPerHour)
+		case entity.FocusLevelMedium:
+			metric.FocusInsight = fmt.Sprintf("Средняя концентрация: %.1f переключений контекста в час говорит о сбалансированной многозадачности", switchesPerHour)
+		default:
+			metric.FocusInsight = fmt.Sprintf("Низкая концентрация: %.1f переключений контекста в час указывает на фрагментацию внимания", switchesPerHour)
+		}
+		return
+	}
+
+	domainsCount := metric.UniqueDomainsCount
+
+	metric.FocusMethod = entity.FocusMethodDomains
+	metric.FocusLevel = s.focusThresholds.DomainsLevel(domainsCount)
+	switch metric.FocusLevel {
+	case entity.FocusLevelHigh:
+		metric.FocusInsight = fmt.Sprintf("Высокая концентрация: работа в %d доменах указывает на фокусированную деятельность", domainsCount)
+	case entity.FocusLevelMedium:
+		metric.FocusInsight = fmt.Sprintf("Средняя концентрация: %d доменов говорит о сбалансированной многозадачности", domainsCount)
+	default:
+		metric.FocusInsight = fmt.Sprintf("Низкая концентрация: %d доменов может указывать на частые переключения контекста", domainsCount)
+	}
+}
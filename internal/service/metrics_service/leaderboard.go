@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+)
+
+// IsValidLeaderboardMetric проверяет метрику рейтинга
+func IsValidLeaderboardMetric(metric string) bool {
+	switch metric {
+	case entity.LeaderboardMetricDeepWork, entity.LeaderboardMetricEngagementRate, entity.LeaderboardMetricTrackedHours:
+		return true
+	}
+	return false
+}
+
+func leaderboardValue(entry entity.LeaderboardEntry, metric string) float64 {
+	switch metric {
+	case entity.LeaderboardMetricEngagementRate:
+		return entry.EngagementRate
+	case entity.LeaderboardMetricTrackedHours:
+		return entry.TrackedHours
+	default:
+		return entry.DeepWorkMinutes
+	}
+}
+
+// GetOrganizationLeaderboard считает метрики всех активных пользователей расширения организации одним запросом
+// и сортирует их по выбранной метрике. Проверка доступа к организации - на стороне вызывающего
+func (s *MetricsService) GetOrganizationLeaderboard(ctx context.Context, filter entity.LeaderboardFilter) (*entity.Leaderboard, error) {
+	if !IsValidLeaderboardMetric(filter.Metric) {
+		return nil, fmt.Errorf("invalid metric: %s", filter.Metric)
+	}
+
+	if filter.EndTime.Before(filter.StartTime) {
+		return nil, fmt.Errorf("end_time must be after start_time")
+	}
+
+	if s.orgRepo != nil {
+		activeEvents, err := s.orgRepo.GetActiveEvents(filter.OrganizationID)
+		if err != nil {
+			return nil, err
+		}
+		filter.ActiveEvents = activeEvents
+	}
+	filter.ExcludedDomains = s.excludedDomainsList(ctx)
+
+	entries, err := s.repo.GetLeaderboard(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		entries[i].Value = leaderboardValue(entries[i], filter.Metric)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Value != entries[j].Value {
+			return entries[i].Value > entries[j].Value
+		}
+		return entries[i].Username < entries[j].Username
+	})
+
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+
+	if entries == nil {
+		entries = []entity.LeaderboardEntry{}
+	}
+
+	return &entity.Leaderboard{
+		OrganizationID: filter.OrganizationID,
+		Metric:         filter.Metric,
+		StartTime:      filter.StartTime,
+		EndTime:        filter.EndTime,
+		Entries:        entries,
+	}, nil
+}
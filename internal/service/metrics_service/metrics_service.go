@@ -1,23 +1,141 @@
 package service
 
 import (
+	"container/list"
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/dinerozz/web-behavior-backend/internal/entity"
 	"github.com/dinerozz/web-behavior-backend/internal/repository"
 	"github.com/dinerozz/web-behavior-backend/internal/service/ai_analytics"
+	"github.com/dinerozz/web-behavior-backend/internal/service/excluded_domain"
+	userBehaviorService "github.com/dinerozz/web-behavior-backend/internal/service/user_behavior"
+	"github.com/dinerozz/web-behavior-backend/pkg/utils"
 )
 
+const (
+	// Время жизни кеша набора активных событий организации
+	activeEventsCacheTTL = 5 * time.Minute
+	// Сколько пользователей держать в кеше; самые давно запрошенные вытесняются
+	activeEventsCacheSize = 10000
+)
+
+type cachedActiveEvents struct {
+	userID    string
+	events    []string
+	expiresAt time.Time
+}
+
 type MetricsService struct {
 	repo      repository.UserMetricsRepository
 	aiService *ai_analytics.AIAnalyticsService
+	orgRepo   *repository.OrganizationRepository
+	dailyRepo repository.DailyEngagementRepository
+	// Список исключенных доменов; nil - без исключений
+	excludedDomains *excluded_domain.ExcludedDomainService
+	// Границы high/medium/low для уровня фокуса и productivity score
+	focusThresholds entity.FocusThresholds
+
+	deepWorkSessionsLimits entity.PaginationLimits
+
+	activeEventsMu    sync.Mutex
+	activeEventsCache map[string]*list.Element // ключ - user_id пользователя расширения
+	activeEventsLRU   *list.List               // элементы *cachedActiveEvents, в начале - последние запрошенные
+}
+
+func NewMetricsService(repo repository.UserMetricsRepository, aiService *ai_analytics.AIAnalyticsService, orgRepo *repository.OrganizationRepository, dailyRepo repository.DailyEngagementRepository, excludedDomains *excluded_domain.ExcludedDomainService, focusThresholds entity.FocusThresholds, deepWorkSessionsLimits entity.PaginationLimits) *MetricsService {
+	return &MetricsService{
+		repo:                   repo,
+		aiService:              aiService,
+		orgRepo:                orgRepo,
+		dailyRepo:              dailyRepo,
+		excludedDomains:        excludedDomains,
+		focusThresholds:        focusThresholds.Normalize(),
+		deepWorkSessionsLimits: deepWorkSessionsLimits,
+		activeEventsCache:      make(map[string]*list.Element),
+		activeEventsLRU:        list.New(),
+	}
 }
 
-func NewMetricsService(repo repository.UserMetricsRepository, aiService *ai_analytics.AIAnalyticsService) *MetricsService {
-	return &MetricsService{repo: repo, aiService: aiService}
+// excludedDomainsList возвращает домены, которые не учитываются в метриках
+func (s *MetricsService) excludedDomainsList(ctx context.Context) []string {
+	if s.excludedDomains == nil {
+		return nil
+	}
+	return s.excludedDomains.Domains(ctx)
+}
+
+// activeEventsForUser возвращает набор активных событий организации пользователя.
+// nil означает набор по умолчанию (repository.ActiveEvents)
+func (s *MetricsService) activeEventsForUser(userID string) []string {
+	if s.orgRepo == nil {
+		return nil
+	}
+
+	s.activeEventsMu.Lock()
+	if elem, ok := s.activeEventsCache[userID]; ok {
+		cached := elem.Value.(*cachedActiveEvents)
+		if time.Now().Before(cached.expiresAt) {
+			s.activeEventsLRU.MoveToFront(elem)
+			s.activeEventsMu.Unlock()
+			return cached.events
+		}
+	}
+	s.activeEventsMu.Unlock()
+
+	events, err := s.orgRepo.GetActiveEventsByExtensionUserID(userID)
+	if err != nil {
+		fmt.Printf("Failed to load organization active events for user %s: %v\n", userID, err)
+		return nil
+	}
+
+	s.activeEventsMu.Lock()
+	defer s.activeEventsMu.Unlock()
+
+	entry := &cachedActiveEvents{
+		userID:    userID,
+		events:    events,
+		expiresAt: time.Now().Add(activeEventsCacheTTL),
+	}
+
+	if elem, ok := s.activeEventsCache[userID]; ok {
+		elem.Value = entry
+		s.activeEventsLRU.MoveToFront(elem)
+		return events
+	}
+
+	s.activeEventsCache[userID] = s.activeEventsLRU.PushFront(entry)
+	for s.activeEventsLRU.Len() > activeEventsCacheSize {
+		oldest := s.activeEventsLRU.Back()
+		s.activeEventsLRU.Remove(oldest)
+		delete(s.activeEventsCache, oldest.Value.(*cachedActiveEvents).userID)
+	}
+
+	return events
+}
+
+// ExcludedDomainsCacheKey описывает текущий список исключенных доменов для ключей кеша метрик,
+// чтобы после изменения списка не отдавались результаты, посчитанные по старому
+func (s *MetricsService) ExcludedDomainsCacheKey(ctx context.Context) string {
+	return strings.Join(s.excludedDomainsList(ctx), ",")
+}
+
+// ActiveEventsCacheKey описывает набор активных событий организации пользователя для ключей кеша метрик,
+// чтобы после смены набора не отдавались результаты, посчитанные по старому
+func (s *MetricsService) ActiveEventsCacheKey(userID string) string {
+	events := s.activeEventsForUser(userID)
+	if len(events) == 0 {
+		return "default"
+	}
+
+	sorted := append([]string(nil), events...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
 }
 
 func (s *MetricsService) GetTrackedTime(ctx context.Context, filter entity.TrackedTimeFilter) (*entity.TrackedTimeMetric, error) {
@@ -37,6 +155,8 @@ func (s *MetricsService) GetTrackedTime(ctx context.Context, filter entity.Track
 		return nil, fmt.Errorf("period cannot exceed 90 days")
 	}
 
+	filter.ExcludedDomains = s.excludedDomainsList(ctx)
+
 	metric, err := s.repo.GetTrackedTime(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate tracked time: %w", err)
@@ -50,6 +170,8 @@ func (s *MetricsService) GetTrackedTimeTotal(ctx context.Context, filter entity.
 		return nil, fmt.Errorf("user_id is required")
 	}
 
+	filter.ExcludedDomains = s.excludedDomainsList(ctx)
+
 	metric, err := s.repo.GetTrackedTimeTotal(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate total tracked time: %w", err)
@@ -58,39 +180,244 @@ func (s *MetricsService) GetTrackedTimeTotal(ctx context.Context, filter entity.
 	return metric, nil
 }
 
+func (s *MetricsService) GetTrackedTimeTotalByUsers(ctx context.Context, filter entity.TrackedTimeTotalByUsersFilter) (map[string]*entity.TrackedTimeMetric, error) {
+	if len(filter.UserIDs) == 0 {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	if len(filter.UserIDs) > entity.MaxTrackedTimeTotalUsers {
+		return nil, fmt.Errorf("no more than %d user_id values are allowed", entity.MaxTrackedTimeTotalUsers)
+	}
+
+	filter.ExcludedDomains = s.excludedDomainsList(ctx)
+
+	metrics, err := s.repo.GetTrackedTimeTotalByUsers(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate total tracked time: %w", err)
+	}
+
+	return metrics, nil
+}
+
 func (s *MetricsService) GetEngagedTime(ctx context.Context, filter entity.EngagedTimeFilter) (*entity.EngagedTimeMetric, error) {
 	if filter.UserID == "" {
 		return nil, fmt.Errorf("user_id is required")
 	}
 
-	//if filter.StartTime.IsZero() || filter.EndTime.IsZero() {
-	//	return nil, fmt.Errorf("start_time and end_time are required")
-	//}
-	//
-	//if filter.EndTime.Before(filter.StartTime) {
-	//	return nil, fmt.Errorf("end_time must be after start_time")
-	//}
+	// Максимальный период проверяется в хендлере (ENGAGED_TIME_MAX_RANGE_DAYS)
+	if filter.EndTime.Before(filter.StartTime) {
+		return nil, fmt.Errorf("end_time must be after start_time")
+	}
 
-	if filter.EndTime.Sub(filter.StartTime) > 90*24*time.Hour {
-		return nil, fmt.Errorf("period cannot exceed 90 days")
+	switch filter.FocusMethod {
+	case "", entity.FocusMethodDomains, entity.FocusMethodSwitches:
+	default:
+		return nil, fmt.Errorf("invalid focus_method: must be one of domains, switches")
+	}
+
+	filter.ActiveEvents = s.activeEventsForUser(filter.UserID)
+	if len(filter.CustomActiveEvents) > 0 {
+		filter.ActiveEvents = filter.CustomActiveEvents
 	}
+	filter.ExcludedDomains = s.excludedDomainsList(ctx)
 
 	metric, err := s.repo.GetEngagedTime(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate engaged time: %w", err)
 	}
 
+	s.applyFocusLevel(metric, filter.FocusMethod)
+	s.applyProductivityScore(metric)
+
+	if filter.ComparePrevious {
+		previousFilter := filter
+		previousFilter.EndTime = filter.StartTime
+		previousFilter.StartTime = filter.StartTime.Add(-filter.EndTime.Sub(filter.StartTime))
+
+		previous, err := s.repo.GetEngagedTime(ctx, previousFilter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate engaged time for previous period: %w", err)
+		}
+
+		metric.Comparison = buildEngagementComparison(metric, previous, previousFilter)
+	}
+
 	return metric, nil
 }
 
+func buildEngagementComparison(current, previous *entity.EngagedTimeMetric, previousFilter entity.EngagedTimeFilter) *entity.EngagementComparison {
+	return &entity.EngagementComparison{
+		PreviousStartTime: previousFilter.StartTime,
+		PreviousEndTime:   previousFilter.EndTime,
+
+		PreviousEngagementRate: previous.EngagementRate,
+		EngagementRateDelta:    utils.RoundToTwoDecimals(current.EngagementRate - previous.EngagementRate),
+		EngagementRateChange:   percentChange(current.EngagementRate, previous.EngagementRate),
+
+		PreviousDeepWorkMinutes: previous.DeepWork.TotalMinutes,
+		DeepWorkMinutesDelta:    utils.RoundToTwoDecimals(current.DeepWork.TotalMinutes - previous.DeepWork.TotalMinutes),
+		DeepWorkMinutesChange:   percentChange(current.DeepWork.TotalMinutes, previous.DeepWork.TotalMinutes),
+
+		PreviousActiveMinutes: previous.ActiveMinutes,
+		ActiveMinutesDelta:    current.ActiveMinutes - previous.ActiveMinutes,
+		ActiveMinutesChange:   percentChange(float64(current.ActiveMinutes), float64(previous.ActiveMinutes)),
+	}
+}
+
+// percentChange возвращает изменение в процентах; если в предыдущем периоде данных нет - 0
+func percentChange(current, previous float64) float64 {
+	if previous == 0 {
+		return 0
+	}
+	return utils.RoundToTwoDecimals((current - previous) / previous * 100)
+}
+
 func (s *MetricsService) GetTopDomains(ctx context.Context, filter entity.TopDomainsFilter) (*entity.TopDomainsResponse, error) {
 	if filter.UserID == "" {
 		return nil, errors.New("user_id is required")
 	}
 
-	return s.repo.GetTopDomains(ctx, filter)
+	for _, eventType := range filter.EventTypes {
+		if !userBehaviorService.IsValidEventType(eventType) {
+			return nil, fmt.Errorf("%w: %s", userBehaviorService.ErrInvalidEventType, eventType)
+		}
+	}
+
+	filter.ExcludedDomains = s.excludedDomainsList(ctx)
+
+	result, err := s.repo.GetTopDomains(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if filter.Categorize {
+		s.categorizeTopDomains(ctx, result)
+	}
+
+	return result, nil
+}
+
+func (s *MetricsService) GetActivityHeatmap(ctx context.Context, filter entity.ActivityHeatmapFilter) (*entity.ActivityHeatmap, error) {
+	if filter.UserID == "" {
+		return nil, errors.New("user_id is required")
+	}
+
+	filter.ActiveEvents = s.activeEventsForUser(filter.UserID)
+	filter.ExcludedDomains = s.excludedDomainsList(ctx)
+
+	return s.repo.GetActivityHeatmap(ctx, filter)
+}
+
+func (s *MetricsService) GetSessionEngagement(ctx context.Context, filter entity.SessionEngagementFilter) (*entity.SessionEngagementMetric, error) {
+	if filter.UserID == "" {
+		return nil, errors.New("user_id is required")
+	}
+
+	filter.ActiveEvents = s.activeEventsForUser(filter.UserID)
+
+	return s.repo.GetSessionEngagement(ctx, filter)
+}
+
+func (s *MetricsService) GetTypingActivity(ctx context.Context, filter entity.TypingActivityFilter) (*entity.TypingActivity, error) {
+	if filter.UserID == "" {
+		return nil, errors.New("user_id is required")
+	}
+
+	return s.repo.GetTypingActivity(ctx, filter)
+}
+
+func (s *MetricsService) GetScrollActivity(ctx context.Context, filter entity.ScrollActivityFilter) (*entity.ScrollActivity, error) {
+	if filter.UserID == "" {
+		return nil, errors.New("user_id is required")
+	}
+
+	return s.repo.GetScrollActivity(ctx, filter)
+}
+
+func (s *MetricsService) GetTabSwitchStats(ctx context.Context, filter entity.TabSwitchFilter) (*entity.TabSwitchStats, error) {
+	if filter.UserID == "" {
+		return nil, errors.New("user_id is required")
+	}
+
+	return s.repo.GetTabSwitchStats(ctx, filter)
+}
+
+// GetMinuteActivity возвращает сырую минутную серию с тем же набором активных событий и исключениями, что и engaged time
+func (s *MetricsService) GetMinuteActivity(ctx context.Context, filter entity.EngagedTimeFilter) (*entity.MinuteActivitySeries, error) {
+	if filter.UserID == "" {
+		return nil, errors.New("user_id is required")
+	}
+
+	filter.ActiveEvents = s.activeEventsForUser(filter.UserID)
+	filter.ExcludedDomains = s.excludedDomainsList(ctx)
+
+	minutes, err := s.repo.GetMinuteActivity(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.MinuteActivitySeries{
+		UserID:    filter.UserID,
+		StartTime: filter.StartTime,
+		EndTime:   filter.EndTime,
+		Minutes:   minutes,
+	}, nil
+}
+
+func (s *MetricsService) GetClickHeatmap(ctx context.Context, userID, url string, gridSize int) (*entity.ClickHeatmap, error) {
+	if userID == "" {
+		return nil, errors.New("user_id is required")
+	}
+
+	if url == "" {
+		return nil, errors.New("url is required")
+	}
+
+	if gridSize <= 0 {
+		return nil, errors.New("grid_size must be positive")
+	}
+
+	return s.repo.GetClickHeatmap(ctx, userID, url, gridSize)
+}
+
+func (s *MetricsService) GetDeepWorkBlockEvents(ctx context.Context, filter entity.DeepWorkSessionsFilter, blockID int) (*entity.DeepWorkBlockEventsResponse, error) {
+	filter.ActiveEvents = s.activeEventsForUser(filter.UserID)
+	filter.ExcludedDomains = s.excludedDomainsList(ctx)
+
+	events, err := s.repo.GetDeepWorkBlockEvents(ctx, filter, blockID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Блок не существует или не проходит пороги deep work
+	if len(events) == 0 {
+		return nil, errors.New("deep work block not found")
+	}
+
+	return &entity.DeepWorkBlockEventsResponse{
+		BlockID:     blockID,
+		UserID:      filter.UserID,
+		StartTime:   events[0].Timestamp,
+		EndTime:     events[len(events)-1].Timestamp,
+		TotalEvents: len(events),
+		Events:      events,
+	}, nil
 }
 
 func (s *MetricsService) GetDeepWorkSessions(ctx context.Context, filter entity.DeepWorkSessionsFilter) (*entity.DeepWorkSessionsResponse, error) {
+	filter.ActiveEvents = s.activeEventsForUser(filter.UserID)
+	filter.ExcludedDomains = s.excludedDomainsList(ctx)
+	filter.Limit = s.deepWorkSessionsLimits.Clamp(filter.Limit)
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
 	return s.repo.GetDeepWorkSessions(ctx, filter)
 }
+
+func (s *MetricsService) GetDeepWorkBySession(ctx context.Context, filter entity.DeepWorkSessionsFilter) (*entity.DeepWorkBySessionResponse, error) {
+	filter.ActiveEvents = s.activeEventsForUser(filter.UserID)
+	filter.ExcludedDomains = s.excludedDomainsList(ctx)
+
+	return s.repo.GetDeepWorkBySession(ctx, filter)
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+)
+
+// Параметры детерминированного productivity score (формула описана в README)
+const (
+	// Доля deep work от отслеживаемого времени, при которой компонент deep work дает 100
+	targetDeepWorkRate = 50.0
+	// Во сколько раз выше порога medium focus компонент переключений падает до 0
+	focusScoreZeroFactor = 2
+	// Целевая доля deep work в активном времени для balance
+	targetDeepWorkShare = 50.0
+
+	overallFocusWeight      = 0.4
+	overallEfficiencyWeight = 0.4
+	overallBalanceWeight    = 0.2
+)
+
+// applyProductivityScore считает productivity score без AI по engagement rate, deep work rate
+// и частоте переключений контекста и записывает его в metric.Analysis
+func (s *MetricsService) applyProductivityScore(metric *entity.EngagedTimeMetric) {
+	metric.Analysis = &entity.EngagedTimeAnalysis{
+		ProductivityScore: computeProductivityScore(metric, s.focusThresholds),
+		Source:            entity.AnalysisSourceRules,
+	}
+}
+
+func computeProductivityScore(metric *entity.EngagedTimeMetric, thresholds entity.FocusThresholds) entity.ProductivityScore {
+	if metric.TrackedMinutes == 0 {
+		return entity.ProductivityScore{
+			Explanation: "Нет отслеживаемой активности за период",
+		}
+	}
+
+	efficiency := clampScore(metric.EngagementRate)
+
+	deepWorkScore := clampScore(metric.DeepWork.DeepWorkRate / targetDeepWorkRate * 100)
+
+	// Переключения в час есть только при наличии deep work блоков, иначе оцениваем по числу доменов
+	var switchScore float64
+	var switchesText string
+	if metric.DeepWork.SessionsCount > 0 {
+		switchScore = linearScore(metric.DeepWork.AvgSwitchesPerHour,
+			float64(thresholds.HighMaxSwitchesPerHour), float64(focusScoreZeroFactor*thresholds.MediumMaxSwitchesPerHour))
+		switchesText = fmt.Sprintf("%.1f переключений контекста в час", metric.DeepWork.AvgSwitchesPerHour)
+	} else {
+		switchScore = linearScore(float64(metric.UniqueDomainsCount),
+			float64(thresholds.HighMaxDomains), float64(focusScoreZeroFactor*thresholds.MediumMaxDomains))
+		switchesText = fmt.Sprintf("%d доменов за период", metric.UniqueDomainsCount)
+	}
+
+	focus := (deepWorkScore + switchScore) / 2
+
+	var deepWorkShare float64
+	if metric.ActiveMinutes > 0 {
+		deepWorkShare = math.Min(metric.DeepWork.TotalMinutes/float64(metric.ActiveMinutes)*100, 100)
+	}
+	balance := clampScore(100 - math.Abs(deepWorkShare-targetDeepWorkShare)*2)
+
+	overall := overallFocusWeight*focus + overallEfficiencyWeight*efficiency + overallBalanceWeight*balance
+
+	return entity.ProductivityScore{
+		Overall:    roundScore(overall),
+		Focus:      roundScore(focus),
+		Efficiency: roundScore(efficiency),
+		Balance:    roundScore(balance),
+		Explanation: fmt.Sprintf(
+			"Вовлеченность %.0f%% отслеживаемого времени, deep work %.0f%% (%.0f%% активного времени), %s",
+			metric.EngagementRate, metric.DeepWork.DeepWorkRate, deepWorkShare, switchesText,
+		),
+	}
+}
+
+// linearScore дает 100 при value <= best и линейно снижается до 0 при value >= worst
+func linearScore(value, best, worst float64) float64 {
+	if value <= best {
+		return 100
+	}
+	if value >= worst {
+		return 0
+	}
+	return (worst - value) / (worst - best) * 100
+}
+
+func clampScore(value float64) float64 {
+	return math.Max(0, math.Min(100, value))
+}
+
+func roundScore(value float64) int {
+	return int(math.Round(clampScore(value)))
+}
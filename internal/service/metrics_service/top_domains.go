@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/dinerozz/web-behavior-backend/internal/service/ai_analytics"
+	"github.com/dinerozz/web-behavior-backend/pkg/utils"
+)
+
+// categorizeTopDomains проставляет категории возвращенным доменам тем же категоризатором, что и AI fallback,
+// и собирает сводку по категориям. Нераспознанные домены попадают в other
+func (s *MetricsService) categorizeTopDomains(ctx context.Context, result *entity.TopDomainsResponse) {
+	domains := make([]string, len(result.Domains))
+	for i, domain := range result.Domains {
+		domains[i] = domain.Domain
+	}
+
+	var categories map[string]string
+	if s.aiService != nil {
+		categories = s.aiService.CategorizeDomains(ctx, domains)
+	} else {
+		categories = make(map[string]string, len(domains))
+		for _, domain := range domains {
+			if category, ok := ai_analytics.CategorizeDomainLocally(domain); ok {
+				categories[domain] = category
+			}
+		}
+	}
+
+	byCategory := make(map[string]*entity.DomainCategoryStats)
+	for i := range result.Domains {
+		domain := &result.Domains[i]
+
+		category, ok := categories[domain.Domain]
+		if !ok {
+			category = entity.DomainCategoryOther
+		}
+		domain.Category = category
+
+		stats, exists := byCategory[category]
+		if !exists {
+			stats = &entity.DomainCategoryStats{Category: category}
+			byCategory[category] = stats
+		}
+		stats.DomainsCount++
+		stats.EventsCount += domain.EventsCount
+		stats.ActiveMinutes += domain.ActiveMinutes
+		stats.Percentage += domain.Percentage
+	}
+
+	result.Categories = make([]entity.DomainCategoryStats, 0, len(byCategory))
+	for _, stats := range byCategory {
+		stats.Percentage = utils.RoundToTwoDecimals(stats.Percentage)
+		result.Categories = append(result.Categories, *stats)
+	}
+
+	sort.Slice(result.Categories, func(i, j int) bool {
+		if result.Categories[i].ActiveMinutes != result.Categories[j].ActiveMinutes {
+			return result.Categories[i].ActiveMinutes > result.Categories[j].ActiveMinutes
+		}
+		return result.Categories[i].Category < result.Categories[j].Category
+	})
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+)
+
+const (
+	weeklyDigestTopDomains      = 3
+	weeklyDigestRecommendations = 2
+	// Изменение focus меньше порога считается стабильным
+	weeklyFocusTrendThreshold = 5
+	// Ниже этой вовлеченности советуем сократить пассивное время
+	weeklyLowEngagementRate = 50.0
+)
+
+// GetWeeklyDigest собирает недельную сводку из engaged time за эту и прошлую неделю и топ доменов
+func (s *MetricsService) GetWeeklyDigest(ctx context.Context, filter entity.WeeklyDigestFilter) (*entity.WeeklyDigest, error) {
+	if filter.UserID == "" {
+		return nil, errors.New("user_id is required")
+	}
+
+	weekStart := filter.WeekStart.UTC().Truncate(24 * time.Hour)
+	// end_time включителен, как и в остальных метриках
+	weekEnd := weekStart.AddDate(0, 0, 7).Add(-time.Microsecond)
+
+	current, err := s.GetEngagedTime(ctx, entity.EngagedTimeFilter{
+		UserID:    filter.UserID,
+		StartTime: weekStart,
+		EndTime:   weekEnd,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := s.GetEngagedTime(ctx, entity.EngagedTimeFilter{
+		UserID:    filter.UserID,
+		StartTime: weekStart.AddDate(0, 0, -7),
+		EndTime:   weekStart.Add(-time.Microsecond),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate engaged time for previous week: %w", err)
+	}
+
+	topDomains, err := s.GetTopDomains(ctx, entity.TopDomainsFilter{
+		UserID:    filter.UserID,
+		Limit:     weeklyDigestTopDomains,
+		StartTime: &weekStart,
+		EndTime:   &weekEnd,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top domains: %w", err)
+	}
+
+	trend := buildWeeklyFocusTrend(current, previous)
+
+	return &entity.WeeklyDigest{
+		UserID:    filter.UserID,
+		WeekStart: weekStart,
+		WeekEnd:   weekEnd,
+
+		TrackedHours:   current.TrackedHours,
+		ActiveHours:    current.ActiveHours,
+		EngagementRate: current.EngagementRate,
+
+		DeepWorkSessions: current.DeepWork.SessionsCount,
+		DeepWorkHours:    current.DeepWork.TotalHours,
+
+		TopDomains: topDomains.Domains,
+
+		FocusLevel: current.FocusLevel,
+		FocusTrend: trend,
+
+		Recommendations: weeklyRecommendations(current, trend, s.focusThresholds),
+
+		GeneratedAt: time.Now().UTC(),
+	}, nil
+}
+
+func buildWeeklyFocusTrend(current, previous *entity.EngagedTimeMetric) entity.WeeklyFocusTrend {
+	trend := entity.WeeklyFocusTrend{Direction: entity.FocusTrendFlat}
+	if current.Analysis != nil {
+		trend.Focus = current.Analysis.ProductivityScore.Focus
+	}
+	if previous.Analysis != nil {
+		trend.PreviousFocus = previous.Analysis.ProductivityScore.Focus
+	}
+	trend.Delta = trend.Focus - trend.PreviousFocus
+
+	switch {
+	case trend.Delta >= weeklyFocusTrendThreshold:
+		trend.Direction = entity.FocusTrendUp
+	case trend.Delta <= -weeklyFocusTrendThreshold:
+		trend.Direction = entity.FocusTrendDown
+	}
+
+	return trend
+}
+
+// weeklyRecommendations выбирает две рекомендации по правилам в порядке приоритета
+func weeklyRecommendations(metric *entity.EngagedTimeMetric, trend entity.WeeklyFocusTrend, thresholds entity.FocusThresholds) []string {
+	var candidates []string
+
+	if metric.TrackedMinutes == 0 {
+		candidates = append(candidates,
+			"За неделю нет отслеживаемой активности: проверьте, что расширение установлено и включено",
+		)
+	}
+
+	if metric.DeepWork.SessionsCount == 0 {
+		candidates = append(candidates,
+			"Запланируйте хотя бы один непрерывный блок работы без переключений, чтобы появились deep work сессии",
+		)
+	} else if metric.DeepWork.AvgSwitchesPerHour > float64(thresholds.MediumMaxSwitchesPerHour) {
+		candidates = append(candidates, fmt.Sprintf(
+			"В deep work блоках в среднем %.1f переключений контекста в час: закройте лишние вкладки и уведомления на время фокусной работы",
+			metric.DeepWork.AvgSwitchesPerHour,
+		))
+	}
+
+	if trend.Direction == entity.FocusTrendDown {
+		candidates = append(candidates, fmt.Sprintf(
+			"Фокус снизился на %d пунктов по сравнению с прошлой неделей: верните в расписание время без встреч и переписки",
+			-trend.Delta,
+		))
+	}
+
+	if metric.TrackedMinutes > 0 && metric.EngagementRate < weeklyLowEngagementRate {
+		candidates = append(candidates, fmt.Sprintf(
+			"Активность занимает только %.0f%% отслеживаемого времени: сократите пассивный просмотр и фоновые вкладки",
+			metric.EngagementRate,
+		))
+	}
+
+	if metric.DeepWork.SessionsCount > 0 && metric.DeepWork.DeepWorkRate < targetDeepWorkRate {
+		candidates = append(candidates, fmt.Sprintf(
+			"Deep work занимает %.0f%% времени: увеличьте длительность фокусных блоков, чтобы приблизиться к %.0f%%",
+			metric.DeepWork.DeepWorkRate, targetDeepWorkRate,
+		))
+	}
+
+	candidates = append(candidates,
+		fmt.Sprintf("Сохраняйте текущий ритм: %.1f ч активной работы за неделю", metric.ActiveHours),
+		"Выделяйте время под сложные задачи в начале дня, пока внимание максимально",
+	)
+
+	return candidates[:weeklyDigestRecommendations]
+}
@@ -0,0 +1,102 @@
+package organization
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dinerozz/web-behavior-backend/internal/model/request"
+	"github.com/dinerozz/web-behavior-backend/internal/model/response"
+	"github.com/dinerozz/web-behavior-backend/pkg/utils"
+	"github.com/gofrs/uuid"
+)
+
+// Срок действия приглашения в организацию
+const InviteTTL = 7 * 24 * time.Hour
+
+func generateInviteToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return "inv_" + hex.EncodeToString(bytes), nil
+}
+
+func (s *OrganizationService) checkInviteAdmin(orgID, userID uuid.UUID) error {
+	hasAccess, role, err := s.checkAccess(orgID, userID)
+	if err != nil {
+		return fmt.Errorf("access check failed: %w", err)
+	}
+	if !hasAccess {
+		return fmt.Errorf("access denied")
+	}
+
+	if role != "admin" && role != "super_admin" {
+		return fmt.Errorf("only admins can manage invites")
+	}
+
+	return nil
+}
+
+// CreateInvite создает приглашение и возвращает токен; отправка письма остается на стороне клиента
+func (s *OrganizationService) CreateInvite(orgID uuid.UUID, inviteReq *request.CreateOrganizationInvite, adminUserID uuid.UUID) (response.OrganizationInvite, error) {
+	if err := s.checkInviteAdmin(orgID, adminUserID); err != nil {
+		return response.OrganizationInvite{}, err
+	}
+
+	if inviteReq.Role != "admin" && inviteReq.Role != "member" && inviteReq.Role != "viewer" {
+		return response.OrganizationInvite{}, fmt.Errorf("invalid role: must be 'admin', 'member', or 'viewer'")
+	}
+
+	email := strings.ToLower(strings.TrimSpace(inviteReq.Email))
+	username := strings.TrimSpace(inviteReq.Username)
+	if username == "" {
+		return response.OrganizationInvite{}, fmt.Errorf("username is required")
+	}
+
+	pending, err := s.Repo.HasPendingInvite(orgID, email)
+	if err != nil {
+		return response.OrganizationInvite{}, fmt.Errorf("failed to check pending invites: %w", err)
+	}
+	if pending {
+		return response.OrganizationInvite{}, fmt.Errorf("invite already pending for this email")
+	}
+
+	token, err := generateInviteToken()
+	if err != nil {
+		return response.OrganizationInvite{}, fmt.Errorf("failed to generate invite token: %w", err)
+	}
+
+	invite, err := s.Repo.CreateInvite(orgID, email, username, inviteReq.Role, utils.HashToken(token), adminUserID, time.Now().Add(InviteTTL))
+	if err != nil {
+		return response.OrganizationInvite{}, fmt.Errorf("failed to create invite: %w", err)
+	}
+	invite.Token = token
+
+	return invite, nil
+}
+
+func (s *OrganizationService) GetPendingInvites(orgID, adminUserID uuid.UUID) ([]response.OrganizationInvite, error) {
+	if err := s.checkInviteAdmin(orgID, adminUserID); err != nil {
+		return nil, err
+	}
+
+	return s.Repo.GetPendingInvites(orgID)
+}
+
+func (s *OrganizationService) RevokeInvite(orgID, inviteID, adminUserID uuid.UUID) error {
+	if err := s.checkInviteAdmin(orgID, adminUserID); err != nil {
+		return err
+	}
+
+	return s.Repo.RevokeInvite(orgID, inviteID)
+}
+
+// AcceptInvite добавляет аутентифицированного пользователя в организацию с ролью из приглашения,
+// если приглашение выписано на его username
+func (s *OrganizationService) AcceptInvite(acceptReq *request.AcceptOrganizationInvite, userID uuid.UUID) (response.OrganizationInvite, error) {
+	return s.Repo.AcceptInvite(utils.HashToken(strings.TrimSpace(acceptReq.Token)), userID)
+}
@@ -1,22 +1,32 @@
 package organization
 
 import (
+	"errors"
 	"fmt"
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
 	"github.com/dinerozz/web-behavior-backend/internal/model/request"
 	"github.com/dinerozz/web-behavior-backend/internal/model/response"
 	"github.com/dinerozz/web-behavior-backend/internal/repository"
+	userBehaviorService "github.com/dinerozz/web-behavior-backend/internal/service/user_behavior"
 	"github.com/gofrs/uuid"
 )
 
+var (
+	ErrActiveEventsAdminOnly  = errors.New("only admins can update active events")
+	ErrInvalidActiveEventType = errors.New("invalid event type")
+)
+
 type OrganizationService struct {
-	Repo     *repository.OrganizationRepository
-	UserRepo *repository.UserRepository
+	Repo            *repository.OrganizationRepository
+	UserRepo        *repository.UserRepository
+	AuditPagination entity.PaginationLimits
 }
 
-func NewOrganizationService(repo *repository.OrganizationRepository, userRepo *repository.UserRepository) *OrganizationService {
+func NewOrganizationService(repo *repository.OrganizationRepository, userRepo *repository.UserRepository, auditPagination entity.PaginationLimits) *OrganizationService {
 	return &OrganizationService{
-		Repo:     repo,
-		UserRepo: userRepo,
+		Repo:            repo,
+		UserRepo:        userRepo,
+		AuditPagination: auditPagination,
 	}
 }
 
@@ -96,7 +106,8 @@ func (s *OrganizationService) UpdateOrganization(orgID uuid.UUID, org *request.U
 	return s.Repo.UpdateOrganization(orgID, org)
 }
 
-func (s *OrganizationService) DeleteOrganization(orgID uuid.UUID, userID uuid.UUID) error {
+// DeleteOrganization без force отказывает, пока в организации есть другие участники или пользователи расширения
+func (s *OrganizationService) DeleteOrganization(orgID uuid.UUID, userID uuid.UUID, force bool) error {
 	hasAccess, role, err := s.checkAccess(orgID, userID)
 	if err != nil {
 		return fmt.Errorf("access check failed: %w", err)
@@ -109,7 +120,18 @@ func (s *OrganizationService) DeleteOrganization(orgID uuid.UUID, userID uuid.UU
 		return fmt.Errorf("only admins can delete organization")
 	}
 
-	return s.Repo.DeleteOrganization(orgID)
+	if !force {
+		members, extensionUsers, err := s.Repo.CountOrganizationDependents(orgID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to count organization dependents: %w", err)
+		}
+
+		if members > 0 || extensionUsers > 0 {
+			return fmt.Errorf("organization still has %d other members and %d extension users, use force=true to delete it", members, extensionUsers)
+		}
+	}
+
+	return s.Repo.DeleteOrganization(orgID, force)
 }
 
 func (s *OrganizationService) GetUserOrganizations(userID uuid.UUID) (response.UserOrganizations, error) {
@@ -148,7 +170,7 @@ func (s *OrganizationService) AddUserToOrganization(orgID uuid.UUID, addUserReq
 		return fmt.Errorf("invalid role: must be 'admin', 'member', or 'viewer'")
 	}
 
-	return s.Repo.AddUserToOrganization(orgID, userToAddID, addUserReq.Role)
+	return s.Repo.AddUserToOrganization(orgID, userToAddID, addUserReq.Role, adminUserID)
 }
 
 func (s *OrganizationService) RemoveUserFromOrganization(orgID, userToRemoveID, adminUserID uuid.UUID) error {
@@ -182,7 +204,7 @@ func (s *OrganizationService) RemoveUserFromOrganization(orgID, userToRemoveID,
 		}
 	}
 
-	return s.Repo.RemoveUserFromOrganization(orgID, userToRemoveID)
+	return s.Repo.RemoveUserFromOrganization(orgID, userToRemoveID, adminUserID)
 }
 
 func (s *OrganizationService) UpdateUserRole(orgID, userToUpdateID uuid.UUID, role string, adminUserID uuid.UUID) error {
@@ -220,7 +242,44 @@ func (s *OrganizationService) UpdateUserRole(orgID, userToUpdateID uuid.UUID, ro
 		}
 	}
 
-	return s.Repo.UpdateUserRole(orgID, userToUpdateID, role)
+	return s.Repo.UpdateUserRole(orgID, userToUpdateID, role, adminUserID)
+}
+
+// GetAuditLog возвращает журнал изменений состава организации, новые записи первыми (только для админов)
+func (s *OrganizationService) GetAuditLog(orgID, adminUserID uuid.UUID, page, perPage int) ([]response.OrgAuditEntry, *entity.PaginationInfo, error) {
+	hasAccess, role, err := s.checkAccess(orgID, adminUserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("access check failed: %w", err)
+	}
+	if !hasAccess {
+		return nil, nil, fmt.Errorf("access denied")
+	}
+
+	if role != "admin" && role != "super_admin" {
+		return nil, nil, fmt.Errorf("only admins can view audit log")
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	perPage = s.AuditPagination.Clamp(perPage)
+
+	entries, err := s.Repo.GetAuditLog(orgID, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get audit log: %w", err)
+	}
+
+	total, err := s.Repo.CountAuditLog(orgID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to count audit log: %w", err)
+	}
+
+	return entries, &entity.PaginationInfo{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: (total + perPage - 1) / perPage,
+	}, nil
 }
 
 func (s *OrganizationService) CheckUserAccess(orgID, userID uuid.UUID) (string, error) {
@@ -235,3 +294,72 @@ func (s *OrganizationService) IsUserOrgAdmin(orgID, userID uuid.UUID) (bool, err
 	}
 	return role == "admin" || role == "super_admin", nil
 }
+
+func (s *OrganizationService) GetActiveEvents(orgID uuid.UUID, userID uuid.UUID) (response.OrganizationActiveEvents, error) {
+	hasAccess, _, err := s.checkAccess(orgID, userID)
+	if err != nil {
+		return response.OrganizationActiveEvents{}, fmt.Errorf("access check failed: %w", err)
+	}
+	if !hasAccess {
+		return response.OrganizationActiveEvents{}, fmt.Errorf("access denied")
+	}
+
+	activeEvents, err := s.Repo.GetActiveEvents(orgID)
+	if err != nil {
+		return response.OrganizationActiveEvents{}, err
+	}
+
+	result := response.OrganizationActiveEvents{
+		OrganizationID: orgID,
+		ActiveEvents:   activeEvents,
+	}
+
+	if len(activeEvents) == 0 {
+		result.ActiveEvents = repository.ActiveEvents
+		result.IsDefault = true
+	}
+
+	return result, nil
+}
+
+func (s *OrganizationService) UpdateActiveEvents(orgID uuid.UUID, req *request.UpdateOrganizationActiveEvents, userID uuid.UUID) (response.OrganizationActiveEvents, error) {
+	hasAccess, role, err := s.checkAccess(orgID, userID)
+	if err != nil {
+		return response.OrganizationActiveEvents{}, fmt.Errorf("access check failed: %w", err)
+	}
+	if !hasAccess {
+		return response.OrganizationActiveEvents{}, fmt.Errorf("access denied")
+	}
+
+	if role != "admin" && role != "super_admin" {
+		return response.OrganizationActiveEvents{}, ErrActiveEventsAdminOnly
+	}
+
+	seen := make(map[string]bool, len(req.ActiveEvents))
+	activeEvents := make([]string, 0, len(req.ActiveEvents))
+	for _, eventType := range req.ActiveEvents {
+		if !userBehaviorService.IsValidEventType(eventType) {
+			return response.OrganizationActiveEvents{}, fmt.Errorf("%w: %s", ErrInvalidActiveEventType, eventType)
+		}
+		if !seen[eventType] {
+			seen[eventType] = true
+			activeEvents = append(activeEvents, eventType)
+		}
+	}
+
+	if err := s.Repo.UpdateActiveEvents(orgID, activeEvents); err != nil {
+		return response.OrganizationActiveEvents{}, err
+	}
+
+	result := response.OrganizationActiveEvents{
+		OrganizationID: orgID,
+		ActiveEvents:   activeEvents,
+	}
+
+	if len(activeEvents) == 0 {
+		result.ActiveEvents = repository.ActiveEvents
+		result.IsDefault = true
+	}
+
+	return result, nil
+}
@@ -0,0 +1,408 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultHealthCheckInterval = 15 * time.Second
+
+// ErrUnavailable возвращают операции чтения, пока Redis недоступен; для кеша это равносильно промаху
+var ErrUnavailable = errors.New("redis is unavailable")
+
+// ErrKeyNotFound - ключа нет (или он истек); остальные ошибки чтения означают сбой Redis
+var ErrKeyNotFound = errors.New("key not found")
+
+// DegradableService делегирует в Service, пока Redis доступен. Если Redis недоступен (при старте или позже),
+// сервис работает как пустой кеш: чтение - промах, запись - no-op, rate limit пропускает запросы.
+// Удаление ключей возвращает ErrUnavailable: отзыв refresh токена не должен молча пропускаться.
+// Доступность периодически проверяется через Health, после восстановления запросы снова идут в Redis,
+// а кеш метрик пользователей, чья инвалидация была пропущена, сбрасывается.
+// Refresh токены, выданные без Redis, не сохраняются: после истечения access токена нужен повторный логин
+type DegradableService struct {
+	service   *Service
+	available atomic.Bool
+	interval  time.Duration
+	stop      chan struct{}
+	stopOnce  sync.Once
+
+	// Пользователи, чей кеш метрик не удалось сбросить, пока Redis был недоступен
+	pendingMu           sync.Mutex
+	pendingInvalidation map[string]struct{}
+}
+
+var _ ServiceInterface = (*DegradableService)(nil)
+
+func NewDegradableRedisService(config RedisConfig) *DegradableService {
+	interval := config.HealthCheckInterval
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+
+	d := &DegradableService{
+		service:  &Service{client: newClient(config)},
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := d.service.Health(ctx); err != nil {
+		log.Printf("⚠️ Redis is unavailable at %s:%s, running without cache: %v", config.Host, config.Port, err)
+	} else {
+		d.available.Store(true)
+		log.Printf("✅ Connected to Redis at %s:%s", config.Host, config.Port)
+	}
+
+	go d.monitor()
+
+	return d
+}
+
+// monitor переключает режим по результату периодического Health
+func (d *DegradableService) monitor() {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), d.interval/2)
+			err := d.service.Health(ctx)
+			cancel()
+
+			if err != nil {
+				if d.available.Swap(false) {
+					log.Printf("⚠️ Redis became unavailable, running without cache: %v", err)
+				}
+				continue
+			}
+
+			if !d.available.Swap(true) {
+				log.Println("✅ Redis connection restored")
+				d.flushPendingInvalidations()
+			}
+		}
+	}
+}
+
+// flushPendingInvalidations сбрасывает кеш метрик, инвалидация которого была пропущена во время недоступности
+func (d *DegradableService) flushPendingInvalidations() {
+	d.pendingMu.Lock()
+	pending := d.pendingInvalidation
+	d.pendingInvalidation = nil
+	d.pendingMu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var deleted int64
+	for userID := range pending {
+		n, err := d.service.InvalidateUserMetricCache(ctx, userID)
+		if err != nil {
+			log.Printf("Failed to invalidate metric cache of user %s after Redis recovery: %v", userID, err)
+			d.addPendingInvalidation(userID)
+			continue
+		}
+		deleted += n
+	}
+
+	log.Printf("Invalidated %d metric cache keys of %d users after Redis recovery", deleted, len(pending))
+}
+
+func (d *DegradableService) addPendingInvalidation(userID string) {
+	d.pendingMu.Lock()
+	defer d.pendingMu.Unlock()
+
+	if d.pendingInvalidation == nil {
+		d.pendingInvalidation = make(map[string]struct{})
+	}
+	d.pendingInvalidation[userID] = struct{}{}
+}
+
+// Available - доступен ли Redis по последней проверке
+func (d *DegradableService) Available() bool {
+	return d.available.Load()
+}
+
+func (d *DegradableService) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if !d.Available() {
+		return nil
+	}
+	return d.service.Set(ctx, key, value, ttl)
+}
+
+// SetNX без Redis не может ничего зарезервировать, поэтому возвращает ErrUnavailable
+func (d *DegradableService) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	if !d.Available() {
+		return false, ErrUnavailable
+	}
+	return d.service.SetNX(ctx, key, value, ttl)
+}
+
+func (d *DegradableService) Get(ctx context.Context, key string, dest interface{}) error {
+	if !d.Available() {
+		return ErrUnavailable
+	}
+	return d.service.Get(ctx, key, dest)
+}
+
+func (d *DegradableService) GetAndDelete(ctx context.Context, key string, dest interface{}) error {
+	if !d.Available() {
+		return ErrUnavailable
+	}
+	return d.service.GetAndDelete(ctx, key, dest)
+}
+
+func (d *DegradableService) Delete(ctx context.Context, key string) error {
+	if !d.Available() {
+		return ErrUnavailable
+	}
+	return d.service.Delete(ctx, key)
+}
+
+func (d *DegradableService) Exists(ctx context.Context, key string) (bool, error) {
+	if !d.Available() {
+		return false, nil
+	}
+	return d.service.Exists(ctx, key)
+}
+
+func (d *DegradableService) SetExpire(ctx context.Context, key string, ttl time.Duration) error {
+	if !d.Available() {
+		return nil
+	}
+	return d.service.SetExpire(ctx, key, ttl)
+}
+
+func (d *DegradableService) GetTTL(ctx context.Context, key string) (time.Duration, error) {
+	if !d.Available() {
+		return 0, ErrUnavailable
+	}
+	return d.service.GetTTL(ctx, key)
+}
+
+func (d *DegradableService) CacheUserSession(ctx context.Context, sessionID string, userID int, ttl time.Duration) error {
+	if !d.Available() {
+		return nil
+	}
+	return d.service.CacheUserSession(ctx, sessionID, userID, ttl)
+}
+
+func (d *DegradableService) GetUserSession(ctx context.Context, sessionID string) (int, error) {
+	if !d.Available() {
+		return 0, ErrUnavailable
+	}
+	return d.service.GetUserSession(ctx, sessionID)
+}
+
+func (d *DegradableService) DeleteUserSession(ctx context.Context, sessionID string) error {
+	if !d.Available() {
+		return ErrUnavailable
+	}
+	return d.service.DeleteUserSession(ctx, sessionID)
+}
+
+func (d *DegradableService) CheckRateLimit(ctx context.Context, key string, limit int, window time.Duration) (bool, error) {
+	if !d.Available() {
+		return true, nil
+	}
+	return d.service.CheckRateLimit(ctx, key, limit, window)
+}
+
+func (d *DegradableService) IncrementFixedWindow(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	if !d.Available() {
+		return 0, 0, ErrUnavailable
+	}
+	return d.service.IncrementFixedWindow(ctx, key, window)
+}
+
+func (d *DegradableService) CacheUserBehavior(ctx context.Context, userID int, data interface{}, ttl time.Duration) error {
+	if !d.Available() {
+		return nil
+	}
+	return d.service.CacheUserBehavior(ctx, userID, data, ttl)
+}
+
+func (d *DegradableService) GetUserBehavior(ctx context.Context, userID int, dest interface{}) error {
+	if !d.Available() {
+		return ErrUnavailable
+	}
+	return d.service.GetUserBehavior(ctx, userID, dest)
+}
+
+func (d *DegradableService) CacheMetrics(ctx context.Context, metricType string, data interface{}, ttl time.Duration) error {
+	if !d.Available() {
+		return nil
+	}
+	return d.service.CacheMetrics(ctx, metricType, data, ttl)
+}
+
+func (d *DegradableService) GetMetrics(ctx context.Context, metricType string, dest interface{}) error {
+	if !d.Available() {
+		return ErrUnavailable
+	}
+	return d.service.GetMetrics(ctx, metricType, dest)
+}
+
+func (d *DegradableService) AddToSet(ctx context.Context, key string, values ...interface{}) error {
+	if !d.Available() {
+		return nil
+	}
+	return d.service.AddToSet(ctx, key, values...)
+}
+
+func (d *DegradableService) GetSet(ctx context.Context, key string) ([]string, error) {
+	if !d.Available() {
+		return nil, nil
+	}
+	return d.service.GetSet(ctx, key)
+}
+
+func (d *DegradableService) IsInSet(ctx context.Context, key string, value interface{}) (bool, error) {
+	if !d.Available() {
+		return false, nil
+	}
+	return d.service.IsInSet(ctx, key, value)
+}
+
+func (d *DegradableService) AddToSortedSet(ctx context.Context, key string, score float64, member interface{}) error {
+	if !d.Available() {
+		return nil
+	}
+	return d.service.AddToSortedSet(ctx, key, score, member)
+}
+
+func (d *DegradableService) GetTopFromSortedSet(ctx context.Context, key string, count int64) ([]string, error) {
+	if !d.Available() {
+		return nil, nil
+	}
+	return d.service.GetTopFromSortedSet(ctx, key, count)
+}
+
+func (d *DegradableService) SetHash(ctx context.Context, key, field string, value interface{}) error {
+	if !d.Available() {
+		return nil
+	}
+	return d.service.SetHash(ctx, key, field, value)
+}
+
+func (d *DegradableService) GetHash(ctx context.Context, key, field string, dest interface{}) error {
+	if !d.Available() {
+		return ErrUnavailable
+	}
+	return d.service.GetHash(ctx, key, field, dest)
+}
+
+func (d *DegradableService) GetAllHash(ctx context.Context, key string) (map[string]string, error) {
+	if !d.Available() {
+		return map[string]string{}, nil
+	}
+	return d.service.GetAllHash(ctx, key)
+}
+
+func (d *DegradableService) IncrementHash(ctx context.Context, key string, fields map[string]int64, ttl time.Duration) error {
+	if !d.Available() {
+		return nil
+	}
+	return d.service.IncrementHash(ctx, key, fields, ttl)
+}
+
+func (d *DegradableService) SetUserMetricCache(ctx context.Context, userID, key string, period MetricCachePeriod, value interface{}, ttl time.Duration) error {
+	if !d.Available() {
+		return nil
+	}
+	return d.service.SetUserMetricCache(ctx, userID, key, period, value, ttl)
+}
+
+// GetOrCompute без Redis всегда вычисляет значение
+func (d *DegradableService) GetOrCompute(ctx context.Context, key string, ttl time.Duration, dest interface{}, compute func() (interface{}, error)) (bool, error) {
+	if !d.Available() {
+		return false, computeInto(dest, compute)
+	}
+	return d.service.GetOrCompute(ctx, key, ttl, dest, compute)
+}
+
+func (d *DegradableService) GetOrComputeUserMetric(ctx context.Context, userID, key string, period MetricCachePeriod, ttl time.Duration, dest interface{}, compute func() (interface{}, error)) (bool, error) {
+	if !d.Available() {
+		return false, computeInto(dest, compute)
+	}
+	return d.service.GetOrComputeUserMetric(ctx, userID, key, period, ttl, dest, compute)
+}
+
+func computeInto(dest interface{}, compute func() (interface{}, error)) error {
+	value, err := compute()
+	if err != nil {
+		return err
+	}
+	return fillDest(value, dest)
+}
+
+// InvalidateUserMetricCache без Redis запоминает пользователя и сбрасывает его кеш после восстановления
+func (d *DegradableService) InvalidateUserMetricCache(ctx context.Context, userID string) (int64, error) {
+	if !d.Available() {
+		d.addPendingInvalidation(userID)
+		return 0, nil
+	}
+	return d.service.InvalidateUserMetricCache(ctx, userID)
+}
+
+// InvalidateUserMetricCacheRange без Redis запоминает пользователя: после восстановления его кеш
+// сбрасывается целиком, так как периоды пропущенных событий не сохраняются
+func (d *DegradableService) InvalidateUserMetricCacheRange(ctx context.Context, userID string, from, to time.Time) (int64, error) {
+	if !d.Available() {
+		d.addPendingInvalidation(userID)
+		return 0, nil
+	}
+	return d.service.InvalidateUserMetricCacheRange(ctx, userID, from, to)
+}
+
+func (d *DegradableService) Publish(ctx context.Context, channel string, message interface{}) error {
+	if !d.Available() {
+		return ErrUnavailable
+	}
+	return d.service.Publish(ctx, channel, message)
+}
+
+// Subscribe отдает подписку клиента и без Redis: go-redis переподключается сам,
+// а подписчик просто не получит сообщений до восстановления
+func (d *DegradableService) Subscribe(ctx context.Context, channel string) *redis.PubSub {
+	return d.service.Subscribe(ctx, channel)
+}
+
+func (d *DegradableService) Keys(ctx context.Context, pattern string) ([]string, error) {
+	if !d.Available() {
+		return nil, nil
+	}
+	return d.service.Keys(ctx, pattern)
+}
+
+func (d *DegradableService) FlushDB(ctx context.Context) error {
+	if !d.Available() {
+		return nil
+	}
+	return d.service.FlushDB(ctx)
+}
+
+// Health всегда пингует Redis, чтобы readiness probe видел реальное состояние
+func (d *DegradableService) Health(ctx context.Context) error {
+	return d.service.Health(ctx)
+}
+
+func (d *DegradableService) Close() error {
+	d.stopOnce.Do(func() { close(d.stop) })
+	return d.service.Close()
+}
@@ -3,6 +3,8 @@ package redis
 import (
 	"context"
 	"time"
+
+	"github.com/redis/go-redis/v9"
 )
 
 type RedisConfig struct {
@@ -10,11 +12,16 @@ type RedisConfig struct {
 	Port     string
 	Password string
 	DB       int
+
+	// Как часто DegradableService проверяет доступность Redis
+	HealthCheckInterval time.Duration
 }
 
 type ServiceInterface interface {
 	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error)
 	Get(ctx context.Context, key string, dest interface{}) error
+	GetAndDelete(ctx context.Context, key string, dest interface{}) error
 	Delete(ctx context.Context, key string) error
 	Exists(ctx context.Context, key string) (bool, error)
 	SetExpire(ctx context.Context, key string, ttl time.Duration) error
@@ -25,6 +32,7 @@ type ServiceInterface interface {
 	DeleteUserSession(ctx context.Context, sessionID string) error
 
 	CheckRateLimit(ctx context.Context, key string, limit int, window time.Duration) (bool, error)
+	IncrementFixedWindow(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error)
 
 	CacheUserBehavior(ctx context.Context, userID int, data interface{}, ttl time.Duration) error
 	GetUserBehavior(ctx context.Context, userID int, dest interface{}) error
@@ -41,6 +49,16 @@ type ServiceInterface interface {
 	SetHash(ctx context.Context, key, field string, value interface{}) error
 	GetHash(ctx context.Context, key, field string, dest interface{}) error
 	GetAllHash(ctx context.Context, key string) (map[string]string, error)
+	IncrementHash(ctx context.Context, key string, fields map[string]int64, ttl time.Duration) error
+
+	SetUserMetricCache(ctx context.Context, userID, key string, period MetricCachePeriod, value interface{}, ttl time.Duration) error
+	GetOrCompute(ctx context.Context, key string, ttl time.Duration, dest interface{}, compute func() (interface{}, error)) (bool, error)
+	GetOrComputeUserMetric(ctx context.Context, userID, key string, period MetricCachePeriod, ttl time.Duration, dest interface{}, compute func() (interface{}, error)) (bool, error)
+	InvalidateUserMetricCache(ctx context.Context, userID string) (int64, error)
+	InvalidateUserMetricCacheRange(ctx context.Context, userID string, from, to time.Time) (int64, error)
+
+	Publish(ctx context.Context, channel string, message interface{}) error
+	Subscribe(ctx context.Context, channel string) *redis.PubSub
 
 	Keys(ctx context.Context, pattern string) ([]string, error)
 	FlushDB(ctx context.Context) error
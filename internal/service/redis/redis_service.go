@@ -8,6 +8,8 @@ import (
 	"github.com/redis/go-redis/v9"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,12 +17,16 @@ type Service struct {
 	client *redis.Client
 }
 
-func NewRedisService(config RedisConfig) *Service {
-	client := redis.NewClient(&redis.Options{
+func newClient(config RedisConfig) *redis.Client {
+	return redis.NewClient(&redis.Options{
 		Addr:     fmt.Sprintf("%s:%s", config.Host, config.Port),
 		Password: config.Password,
 		DB:       config.DB,
 	})
+}
+
+func NewRedisService(config RedisConfig) *Service {
+	client := newClient(config)
 
 	ctx := context.Background()
 	_, err := client.Ping(ctx).Result()
@@ -42,11 +48,34 @@ func (r *Service) Set(ctx context.Context, key string, value interface{}, ttl ti
 	return r.client.Set(ctx, key, jsonValue, ttl).Err()
 }
 
+// SetNX записывает значение, только если ключа еще нет; false - ключ уже существует
+func (r *Service) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	jsonValue, err := json.Marshal(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	return r.client.SetNX(ctx, key, jsonValue, ttl).Result()
+}
+
 func (r *Service) Get(ctx context.Context, key string, dest interface{}) error {
 	val, err := r.client.Get(ctx, key).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
-			return fmt.Errorf("key not found: %s", key)
+			return fmt.Errorf("%w: %s", ErrKeyNotFound, key)
+		}
+		return fmt.Errorf("failed to get value: %w", err)
+	}
+
+	return json.Unmarshal([]byte(val), dest)
+}
+
+// GetAndDelete атомарно читает и удаляет ключ (GETDEL)
+func (r *Service) GetAndDelete(ctx context.Context, key string, dest interface{}) error {
+	val, err := r.client.GetDel(ctx, key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return fmt.Errorf("%w: %s", ErrKeyNotFound, key)
 		}
 		return fmt.Errorf("failed to get value: %w", err)
 	}
@@ -119,6 +148,38 @@ func (r *Service) CheckRateLimit(ctx context.Context, key string, limit int, win
 	return count <= int64(limit), nil
 }
 
+// IncrementFixedWindow увеличивает счетчик окна и возвращает его значение и время до сброса.
+// TTL ставится только при создании ключа (счетчик стал 1), поэтому окно не продлевается каждым запросом.
+// EXPIRE NX не используется: он есть только с Redis 7
+func (r *Service) IncrementFixedWindow(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	count, err := r.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if count == 1 {
+		if err := r.client.Expire(ctx, key, window).Err(); err != nil {
+			return 0, 0, err
+		}
+		return count, window, nil
+	}
+
+	ttl, err := r.client.TTL(ctx, key).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Ключ без TTL (EXPIRE после первого INCR не выполнился) никогда бы не сбросился
+	if ttl < 0 {
+		if err := r.client.Expire(ctx, key, window).Err(); err != nil {
+			return 0, 0, err
+		}
+		ttl = window
+	}
+
+	return count, ttl, nil
+}
+
 func (r *Service) CacheUserBehavior(ctx context.Context, userID int, data interface{}, ttl time.Duration) error {
 	key := fmt.Sprintf("user_behavior:%d", userID)
 	return r.Set(ctx, key, data, ttl)
@@ -187,6 +248,21 @@ func (r *Service) GetAllHash(ctx context.Context, key string) (map[string]string
 	return r.client.HGetAll(ctx, key).Result()
 }
 
+// IncrementHash атомарно увеличивает числовые поля хеша и обновляет TTL ключа.
+// Обычный EXPIRE вместо EXPIRE NX (только Redis 7+): хеш дневных счетчиков все равно
+// перестает обновляться после своего дня, так что продление TTL лишь откладывает удаление
+func (r *Service) IncrementHash(ctx context.Context, key string, fields map[string]int64, ttl time.Duration) error {
+	pipe := r.client.TxPipeline()
+
+	for field, delta := range fields {
+		pipe.HIncrBy(ctx, key, field, delta)
+	}
+	pipe.Expire(ctx, key, ttl)
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
 func (r *Service) Keys(ctx context.Context, pattern string) ([]string, error) {
 	return r.client.Keys(ctx, pattern).Result()
 }
@@ -195,6 +271,218 @@ func (r *Service) FlushDB(ctx context.Context) error {
 	return r.client.FlushDB(ctx).Err()
 }
 
+// MetricCachePeriod - период событий, по которым посчитана закешированная метрика.
+// Нулевая граница - период не ограничен с этой стороны (например, топ доменов за все время)
+type MetricCachePeriod struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Оценка для неограниченного конца периода: больше любого unix времени событий
+const unboundedPeriodScore = 1 << 53
+
+// UserMetricKeysSet - sorted set ключей кеша метрик пользователя. Оценка - конец периода метрики (unix),
+// элемент - "<начало периода (unix)>|<ключ>", см. MetricCachePeriod
+func UserMetricKeysSet(userID string) string {
+	return fmt.Sprintf("user:%s:metric_periods", userID)
+}
+
+func metricKeysSetMember(period MetricCachePeriod, key string) redis.Z {
+	var start int64
+	if !period.Start.IsZero() {
+		start = period.Start.Unix()
+	}
+
+	score := float64(unboundedPeriodScore)
+	if !period.End.IsZero() {
+		score = float64(period.End.Unix())
+	}
+
+	return redis.Z{Score: score, Member: strconv.FormatInt(start, 10) + "|" + key}
+}
+
+// parseMetricKeysSetMember возвращает начало периода и ключ кеша из элемента UserMetricKeysSet
+func parseMetricKeysSetMember(member string) (int64, string, bool) {
+	startStr, key, ok := strings.Cut(member, "|")
+	if !ok {
+		return 0, "", false
+	}
+
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+
+	return start, key, true
+}
+
+// SetUserMetricCache кеширует значение и запоминает ключ с периодом метрики в sorted set пользователя,
+// чтобы InvalidateUserMetricCacheRange мог удалить его при поступлении событий из этого периода.
+// TTL множества не меньше TTL самого долгоживущего ключа в нем
+func (r *Service) SetUserMetricCache(ctx context.Context, userID, key string, period MetricCachePeriod, value interface{}, ttl time.Duration) error {
+	if err := r.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+
+	setKey := UserMetricKeysSet(userID)
+	if err := r.client.ZAdd(ctx, setKey, metricKeysSetMember(period, key)).Err(); err != nil {
+		return fmt.Errorf("failed to track metric cache key: %w", err)
+	}
+
+	currentTTL, err := r.client.TTL(ctx, setKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to get metric keys set ttl: %w", err)
+	}
+
+	if currentTTL < ttl {
+		return r.client.Expire(ctx, setKey, ttl).Err()
+	}
+
+	return nil
+}
+
+// GetOrCompute читает key в dest; при промахе вызывает compute, кеширует результат на ttl и кладет его в dest.
+// Возвращает true при попадании в кеш. Ошибка compute возвращается как есть и не кешируется,
+// ошибка записи в кеш только логируется
+func (r *Service) GetOrCompute(ctx context.Context, key string, ttl time.Duration, dest interface{}, compute func() (interface{}, error)) (bool, error) {
+	return r.getOrCompute(ctx, key, dest, compute, func(value interface{}) error {
+		return r.Set(ctx, key, value, ttl)
+	})
+}
+
+// GetOrComputeUserMetric - GetOrCompute с записью через SetUserMetricCache, чтобы ключ сбрасывался
+// при новых событиях пользователя из периода метрики
+func (r *Service) GetOrComputeUserMetric(ctx context.Context, userID, key string, period MetricCachePeriod, ttl time.Duration, dest interface{}, compute func() (interface{}, error)) (bool, error) {
+	return r.getOrCompute(ctx, key, dest, compute, func(value interface{}) error {
+		return r.SetUserMetricCache(ctx, userID, key, period, value, ttl)
+	})
+}
+
+func (r *Service) getOrCompute(ctx context.Context, key string, dest interface{}, compute func() (interface{}, error), store func(value interface{}) error) (bool, error) {
+	if err := r.Get(ctx, key, dest); err == nil {
+		return true, nil
+	}
+
+	value, err := compute()
+	if err != nil {
+		return false, err
+	}
+
+	if err := store(value); err != nil {
+		log.Printf("Failed to cache %s: %v", key, err)
+	}
+
+	return false, fillDest(value, dest)
+}
+
+// fillDest заполняет dest так же, как при попадании в кеш, чтобы вызывающий код не различал источники
+func fillDest(value, dest interface{}) error {
+	jsonValue, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	return json.Unmarshal(jsonValue, dest)
+}
+
+// InvalidateUserMetricCache удаляет все закешированные метрики пользователя.
+// Гарантия слабая: запрос, посчитавший метрику до вставки и записавший ее после инвалидации,
+// оставит устаревшее значение до истечения TTL. Для дашбордов это приемлемо.
+// Возвращает число удаленных ключей кеша (ключи, уже истекшие по TTL, не считаются)
+func (r *Service) InvalidateUserMetricCache(ctx context.Context, userID string) (int64, error) {
+	setKey := UserMetricKeysSet(userID)
+
+	members, err := r.client.ZRange(ctx, setKey, 0, -1).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get metric cache keys: %w", err)
+	}
+
+	deleted, err := r.deleteMetricCacheKeys(ctx, members)
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, r.client.Del(ctx, setKey).Err()
+}
+
+// InvalidateUserMetricCacheRange удаляет только метрики пользователя, период которых пересекается
+// с [from, to] - временем новых событий. Метрики за прошедшие периоды остаются в кеше.
+// Границы сравниваются с точностью до секунды, поэтому на границе ключ может быть сброшен лишний раз, но не пропущен
+func (r *Service) InvalidateUserMetricCacheRange(ctx context.Context, userID string, from, to time.Time) (int64, error) {
+	setKey := UserMetricKeysSet(userID)
+
+	members, err := r.client.ZRangeByScore(ctx, setKey, &redis.ZRangeBy{
+		Min: strconv.FormatInt(from.Unix(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get metric cache keys: %w", err)
+	}
+
+	// Конец периода не раньше from отобран по оценке, здесь - начало периода не позже to
+	var affected []string
+	for _, member := range members {
+		start, _, ok := parseMetricKeysSetMember(member)
+		if !ok || start <= to.Unix() {
+			affected = append(affected, member)
+		}
+	}
+
+	if len(affected) == 0 {
+		return 0, nil
+	}
+
+	deleted, err := r.deleteMetricCacheKeys(ctx, affected)
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, r.client.ZRem(ctx, setKey, stringsToInterfaces(affected)...).Err()
+}
+
+// deleteMetricCacheKeys удаляет ключи кеша, перечисленные элементами UserMetricKeysSet
+func (r *Service) deleteMetricCacheKeys(ctx context.Context, members []string) (int64, error) {
+	keys := make([]string, 0, len(members))
+	for _, member := range members {
+		if _, key, ok := parseMetricKeysSetMember(member); ok {
+			keys = append(keys, key)
+		}
+	}
+
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	deleted, err := r.client.Del(ctx, keys...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete metric cache keys: %w", err)
+	}
+
+	return deleted, nil
+}
+
+func stringsToInterfaces(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
+
+// Publish сериализует сообщение в JSON и публикует в канал
+func (r *Service) Publish(ctx context.Context, channel string, message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	return r.client.Publish(ctx, channel, data).Err()
+}
+
+func (r *Service) Subscribe(ctx context.Context, channel string) *redis.PubSub {
+	return r.client.Subscribe(ctx, channel)
+}
+
 func (r *Service) Close() error {
 	return r.client.Close()
 }
@@ -0,0 +1,97 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/dinerozz/web-behavior-backend/internal/service/redis"
+	"github.com/dinerozz/web-behavior-backend/pkg/utils"
+	"github.com/gofrs/uuid"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshUnavailable - хранилище refresh токенов недоступно, токен не проверен и не погашен
+	ErrRefreshUnavailable = errors.New("refresh token storage is unavailable")
+)
+
+type refreshTokenData struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// В Redis хранится только хэш refresh токена
+func refreshTokenKey(token string) string {
+	return fmt.Sprintf("refresh_token:%s", utils.HashToken(token))
+}
+
+func (s *UserService) IssueRefreshToken(ctx context.Context, userID uuid.UUID) (string, error) {
+	token, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	if err := s.redisService.Set(ctx, refreshTokenKey(token), refreshTokenData{UserID: userID}, utils.RefreshTokenTTL); err != nil {
+		return "", fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken погашает переданный refresh токен и выдает новую пару токенов.
+// Пользователь проверяется до погашения, чтобы сбой поиска не сжег рабочий токен;
+// само погашение атомарно (GETDEL), поэтому токен используется не больше одного раза
+func (s *UserService) RotateRefreshToken(ctx context.Context, refreshToken string) (accessToken string, newRefreshToken string, err error) {
+	key := refreshTokenKey(refreshToken)
+
+	var data refreshTokenData
+	if err := s.redisService.Get(ctx, key, &data); err != nil {
+		return "", "", refreshStoreError(err)
+	}
+
+	user, err := s.Repo.GetUserById(data.UserID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && user.ID == uuid.Nil) {
+		// пользователь удален: токен больше не нужен
+		if delErr := s.redisService.Delete(ctx, key); delErr != nil {
+			fmt.Printf("Failed to delete refresh token of missing user: %v\n", delErr)
+		}
+		return "", "", ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get user: %w", err)
+	}
+
+	var consumed refreshTokenData
+	if err := s.redisService.GetAndDelete(ctx, key, &consumed); err != nil {
+		// токен успели погасить параллельным запросом
+		return "", "", refreshStoreError(err)
+	}
+	if consumed.UserID != data.UserID {
+		return "", "", ErrInvalidRefreshToken
+	}
+
+	accessToken, err = utils.GenerateToken(user.ID, user.Username)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	newRefreshToken, err = s.IssueRefreshToken(ctx, user.ID)
+	if err != nil {
+		return "", "", err
+	}
+
+	return accessToken, newRefreshToken, nil
+}
+
+// refreshStoreError отличает отсутствующий токен (401) от сбоя Redis (503)
+func refreshStoreError(err error) error {
+	if errors.Is(err, redis.ErrKeyNotFound) {
+		return ErrInvalidRefreshToken
+	}
+	return fmt.Errorf("%w: %v", ErrRefreshUnavailable, err)
+}
+
+func (s *UserService) RevokeRefreshToken(ctx context.Context, refreshToken string) error {
+	return s.redisService.Delete(ctx, refreshTokenKey(refreshToken))
+}
@@ -1,18 +1,24 @@
 package user
 
 import (
+	"fmt"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
 	"github.com/dinerozz/web-behavior-backend/internal/model/request"
 	"github.com/dinerozz/web-behavior-backend/internal/model/response"
 	"github.com/dinerozz/web-behavior-backend/internal/repository"
+	"github.com/dinerozz/web-behavior-backend/internal/service/redis"
 	"github.com/gofrs/uuid"
 )
 
 type UserService struct {
-	Repo *repository.UserRepository
+	Repo            *repository.UserRepository
+	redisService    redis.ServiceInterface
+	UsersPagination entity.PaginationLimits
 }
 
-func NewUserService(repo *repository.UserRepository) *UserService {
-	return &UserService{Repo: repo}
+func NewUserService(repo *repository.UserRepository, redisService redis.ServiceInterface, usersPagination entity.PaginationLimits) *UserService {
+	return &UserService{Repo: repo, redisService: redisService, UsersPagination: usersPagination}
 }
 
 func (s *UserService) CheckIfUserExistsByUsername(username string) bool {
@@ -35,8 +41,34 @@ func (s *UserService) CreateUserWithPassword(user *request.CreateUserWithPasswor
 	return s.Repo.CreateUserWithPassword(user)
 }
 
-func (s *UserService) GetAllUsers() ([]response.User, error) {
-	return s.Repo.GetAllUsers()
+// GetAllUsers возвращает страницу пользователей, новые первыми
+func (s *UserService) GetAllUsers(filter entity.UserFilter, page, perPage int) ([]response.User, *entity.PaginationInfo, error) {
+	if page < 1 {
+		page = 1
+	}
+	perPage = s.UsersPagination.Clamp(perPage)
+
+	users, err := s.Repo.GetAllUsers(filter, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	total, err := s.Repo.CountUsers(filter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	return users, &entity.PaginationInfo{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: (total + perPage - 1) / perPage,
+	}, nil
+}
+
+// SetSuperAdmin выдает или снимает права супер админа; последнего супер админа разжаловать нельзя
+func (s *UserService) SetSuperAdmin(userToUpdateID uuid.UUID, isSuperAdmin bool) error {
+	return s.Repo.SetSuperAdmin(userToUpdateID, isSuperAdmin)
 }
 
 // Deprecated
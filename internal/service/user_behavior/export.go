@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+)
+
+// Максимальный период одной выгрузки, более длинные периоды выгружаются частями
+const maxBehaviorExportRange = 31 * 24 * time.Hour
+
+// ValidateBehaviorExport проверяет фильтр выгрузки до начала записи ответа
+func ValidateBehaviorExport(filter entity.BehaviorExportFilter) error {
+	if filter.UserID == nil && filter.SessionID == nil {
+		return fmt.Errorf("userId or sessionId is required")
+	}
+
+	if filter.StartTime.IsZero() || filter.EndTime.IsZero() {
+		return fmt.Errorf("start and end are required")
+	}
+
+	if filter.EndTime.Before(filter.StartTime) {
+		return fmt.Errorf("end must be after start")
+	}
+
+	if filter.EndTime.Sub(filter.StartTime) > maxBehaviorExportRange {
+		return fmt.Errorf("time range cannot exceed %d days", int(maxBehaviorExportRange.Hours()/24))
+	}
+
+	return nil
+}
+
+// ExportBehaviors передает события в fn по одному в порядке timestamp, память не растет с размером выгрузки
+func (s *userBehaviorService) ExportBehaviors(ctx context.Context, filter entity.BehaviorExportFilter, fn func(entity.UserBehavior) error) error {
+	if err := ValidateBehaviorExport(filter); err != nil {
+		return err
+	}
+
+	return s.repo.StreamForExport(ctx, filter, fn)
+}
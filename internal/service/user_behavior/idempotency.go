@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+)
+
+// IdempotencyKeyTTL - сколько хранится результат батча для повторов с тем же Idempotency-Key
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// Сколько живет резерв ключа, пока батч обрабатывается: упавший запрос не блокирует ключ на сутки
+const idempotencyReservationTTL = time.Minute
+
+const maxIdempotencyKeyLength = 255
+
+var (
+	ErrIdempotencyKeyMismatch   = errors.New("idempotency key was already used with a different payload")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still in progress")
+	ErrInvalidIdempotencyKey    = errors.New("invalid idempotency key")
+)
+
+type idempotencyRecord struct {
+	PayloadHash string                               `json:"payload_hash"`
+	Pending     bool                                 `json:"pending,omitempty"` // ключ зарезервирован, батч еще обрабатывается
+	Result      entity.BatchCreateUserBehaviorResult `json:"result"`
+}
+
+// idempotencyKey - ключ Redis в пространстве клиента (пользователь расширения или IP),
+// чтобы одинаковые Idempotency-Key разных клиентов не пересекались
+func idempotencyKey(scope, key string) string {
+	return fmt.Sprintf("idempotency:behaviors_batch:%s:%s", scope, key)
+}
+
+func batchPayloadHash(req entity.BatchCreateUserBehaviorRequest, partial bool) (string, error) {
+	payload, err := json.Marshal(struct {
+		Request entity.BatchCreateUserBehaviorRequest `json:"request"`
+		Partial bool                                  `json:"partial"`
+	}{req, partial})
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(payload)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// BatchCreateBehaviorsIdempotent повторяет BatchCreateBehaviors, но для уже обработанного ключа
+// возвращает сохраненный результат без повторной вставки. Ключ резервируется через SET NX до вставки,
+// поэтому из параллельных повторов батч вставляет только один. scope - пространство ключей клиента.
+// Без Redis или без ключа работает как обычный батч
+func (s *userBehaviorService) BatchCreateBehaviorsIdempotent(ctx context.Context, req entity.BatchCreateUserBehaviorRequest, partial bool, scope, key string) (*entity.BatchCreateUserBehaviorResult, error) {
+	if key == "" || s.redisService == nil {
+		return s.BatchCreateBehaviors(ctx, req, partial)
+	}
+
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, ErrInvalidIdempotencyKey
+	}
+
+	payloadHash, err := batchPayloadHash(req, partial)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash batch payload: %w", err)
+	}
+
+	redisKey := idempotencyKey(scope, key)
+	reserved, err := s.redisService.SetNX(ctx, redisKey, idempotencyRecord{PayloadHash: payloadHash, Pending: true}, idempotencyReservationTTL)
+	if err != nil {
+		fmt.Printf("Failed to reserve idempotency key, processing batch without it: %v\n", err)
+		return s.BatchCreateBehaviors(ctx, req, partial)
+	}
+
+	if !reserved {
+		var record idempotencyRecord
+		if err := s.redisService.Get(ctx, redisKey, &record); err != nil {
+			// резерв истек между SET NX и GET - считаем, что запрос с этим ключом еще идет
+			return nil, ErrIdempotencyKeyInProgress
+		}
+
+		if record.PayloadHash != payloadHash {
+			return nil, ErrIdempotencyKeyMismatch
+		}
+		if record.Pending {
+			return nil, ErrIdempotencyKeyInProgress
+		}
+
+		record.Result.Replayed = true
+		return &record.Result, nil
+	}
+
+	result, err := s.BatchCreateBehaviors(ctx, req, partial)
+	if err != nil {
+		// Ошибочный батч не запоминаем, чтобы клиент мог повторить его с тем же ключом
+		if delErr := s.redisService.Delete(ctx, redisKey); delErr != nil {
+			fmt.Printf("Failed to release idempotency key: %v\n", delErr)
+		}
+		return nil, err
+	}
+
+	record := idempotencyRecord{PayloadHash: payloadHash, Result: *result}
+	if err := s.redisService.Set(ctx, redisKey, record, IdempotencyKeyTTL); err != nil {
+		fmt.Printf("Failed to store idempotency key: %v\n", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dinerozz/web-behavior-backend/internal/entity"
+	"github.com/dinerozz/web-behavior-backend/internal/repository"
+	"github.com/gofrs/uuid"
+)
+
+var (
+	ErrSessionNotFound            = errors.New("session not found")
+	ErrSessionAnnotationNotFound  = errors.New("session annotation not found")
+	ErrSessionAnnotationForbidden = errors.New("only the author or a super admin can delete the annotation")
+)
+
+type SessionAnnotationService interface {
+	CreateAnnotation(ctx context.Context, sessionID string, authorID uuid.UUID, req entity.CreateSessionAnnotationRequest) (*entity.SessionAnnotation, error)
+	GetAnnotations(ctx context.Context, sessionID string) ([]entity.SessionAnnotation, error)
+	DeleteAnnotation(ctx context.Context, sessionID string, annotationID, userID uuid.UUID) error
+}
+
+type sessionAnnotationService struct {
+	repo         repository.SessionAnnotationRepository
+	behaviorRepo repository.UserBehaviorRepository
+	userRepo     *repository.UserRepository
+}
+
+func NewSessionAnnotationService(repo repository.SessionAnnotationRepository, behaviorRepo repository.UserBehaviorRepository, userRepo *repository.UserRepository) SessionAnnotationService {
+	return &sessionAnnotationService{
+		repo:         repo,
+		behaviorRepo: behaviorRepo,
+		userRepo:     userRepo,
+	}
+}
+
+// CreateAnnotation добавляет метку к сессии, в которой есть хотя бы одно неудаленное событие
+func (s *sessionAnnotationService) CreateAnnotation(ctx context.Context, sessionID string, authorID uuid.UUID, req entity.CreateSessionAnnotationRequest) (*entity.SessionAnnotation, error) {
+	if sessionID == "" {
+		return nil, fmt.Errorf("session ID is required")
+	}
+
+	tag := strings.TrimSpace(req.Tag)
+	if tag == "" {
+		return nil, fmt.Errorf("tag is required")
+	}
+
+	summary, err := s.behaviorRepo.GetSessionSummary(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if summary == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	annotation := &entity.SessionAnnotation{
+		ID:        uuid.Must(uuid.NewV4()),
+		SessionID: sessionID,
+		Tag:       tag,
+		AuthorID:  &authorID,
+	}
+
+	if err := s.repo.Create(ctx, annotation); err != nil {
+		return nil, fmt.Errorf("failed to create session annotation: %w", err)
+	}
+
+	// Перечитываем, чтобы вернуть author_name
+	created, err := s.repo.GetByID(ctx, annotation.ID)
+	if err != nil {
+		return nil, err
+	}
+	if created == nil {
+		return annotation, nil
+	}
+
+	return created, nil
+}
+
+// GetAnnotations возвращает метки сессии в порядке создания
+func (s *sessionAnnotationService) GetAnnotations(ctx context.Context, sessionID string) ([]entity.SessionAnnotation, error) {
+	if sessionID == "" {
+		return nil, fmt.Errorf("session ID is required")
+	}
+
+	return s.repo.GetBySession(ctx, sessionID)
+}
+
+// DeleteAnnotation удаляет метку; удалить может только ее автор или супер админ
+func (s *sessionAnnotationService) DeleteAnnotation(ctx context.Context, sessionID string, annotationID, userID uuid.UUID) error {
+	annotation, err := s.repo.GetByID(ctx, annotationID)
+	if err != nil {
+		return err
+	}
+	if annotation == nil || annotation.SessionID != sessionID {
+		return ErrSessionAnnotationNotFound
+	}
+
+	if annotation.AuthorID == nil || *annotation.AuthorID != userID {
+		isSuperAdmin, err := s.userRepo.IsUserSuperAdmin(userID)
+		if err != nil {
+			return fmt.Errorf("failed to check admin status: %w", err)
+		}
+		if !isSuperAdmin {
+			return ErrSessionAnnotationForbidden
+		}
+	}
+
+	if err := s.repo.Delete(ctx, annotationID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSessionAnnotationNotFound
+		}
+		return fmt.Errorf("failed to delete session annotation: %w", err)
+	}
+
+	return nil
+}
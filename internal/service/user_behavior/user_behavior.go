@@ -3,34 +3,178 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/dinerozz/web-behavior-backend/internal/entity"
 	"github.com/dinerozz/web-behavior-backend/internal/repository"
+	"github.com/dinerozz/web-behavior-backend/internal/service/excluded_domain"
+	"github.com/dinerozz/web-behavior-backend/internal/service/redis"
+	"github.com/dinerozz/web-behavior-backend/pkg/telemetry"
+	"github.com/dinerozz/web-behavior-backend/pkg/utils"
 	"github.com/gofrs/uuid"
 )
 
+var (
+	ErrDeletedBehaviorNotFound = errors.New("deleted behavior not found")
+	// ErrBehaviorRestoreConflict - после удаления расширение повторно прислало то же событие,
+	// восстановление создало бы дубликат
+	ErrBehaviorRestoreConflict = errors.New("an identical active behavior already exists, restore would create a duplicate")
+)
+
+// ErrInvalidEventType - тип события не входит в список поддерживаемых
+var ErrInvalidEventType = errors.New("invalid event type")
+
+// ErrExcludedDomain - событие домена из списка исключений: оно не сохраняется, расширению отвечаем 202
+var ErrExcludedDomain = errors.New("event ignored: domain is excluded")
+
 type UserBehaviorService interface {
 	CreateBehavior(ctx context.Context, req entity.CreateUserBehaviorRequest) (*entity.UserBehavior, error)
-	BatchCreateBehaviors(ctx context.Context, req entity.BatchCreateUserBehaviorRequest) error
+	BatchCreateBehaviors(ctx context.Context, req entity.BatchCreateUserBehaviorRequest, partial bool) (*entity.BatchCreateUserBehaviorResult, error)
+	BatchCreateBehaviorsIdempotent(ctx context.Context, req entity.BatchCreateUserBehaviorRequest, partial bool, scope, key string) (*entity.BatchCreateUserBehaviorResult, error)
 	GetBehaviorByID(ctx context.Context, id uuid.UUID) (*entity.UserBehavior, error)
 	GetBehaviors(ctx context.Context, filter entity.UserBehaviorFilter) ([]entity.UserBehavior, *entity.PaginationInfo, error)
+	GetBehaviorsByCursor(ctx context.Context, filter entity.UserBehaviorFilter) ([]entity.UserBehavior, *entity.CursorPaginationInfo, error)
 	GetStats(ctx context.Context, filter entity.UserBehaviorFilter) (*entity.UserBehaviorStats, error)
+	GetStatsTimeline(ctx context.Context, filter entity.UserBehaviorFilter, groupBy string) ([]entity.StatsBucket, error)
 	GetSessionSummary(ctx context.Context, sessionID string) (*entity.SessionSummary, error)
 	GetUserSessions(ctx context.Context, userID string, page, perPage int) ([]entity.SessionSummary, *entity.PaginationInfo, error)
 	DeleteBehavior(ctx context.Context, id uuid.UUID) error
+	RestoreBehavior(ctx context.Context, id uuid.UUID) error
 	ValidateEventType(eventType string) bool
 	ValidateCoordinates(x, y *int, eventType string) error
+	ValidateTimestamp(ts time.Time) error
 	GetUserEventsCount(ctx context.Context, filter entity.UserEventsCount) (*entity.UserEventsCountResponse, error)
+	GetDistinctEventTypes(ctx context.Context, filter entity.UserEventsCount) (*entity.DistinctEventTypesResponse, error)
+	GetUserActivitySpan(ctx context.Context, userID string) (*entity.UserActivitySpan, error)
+	ExportBehaviors(ctx context.Context, filter entity.BehaviorExportFilter, fn func(entity.UserBehavior) error) error
 }
 
 type userBehaviorService struct {
-	repo repository.UserBehaviorRepository
+	repo                repository.UserBehaviorRepository
+	redisService        redis.ServiceInterface
+	behaviorsPagination entity.PaginationLimits
+	sessionsPagination  entity.PaginationLimits
+	timestampBounds     entity.TimestampBounds
+	maxBatchEvents      int
+	// События исключенных доменов не сохраняются; nil - без исключений
+	excludedDomains *excluded_domain.ExcludedDomainService
+	// Очередь публикации в live-стрим сессий; nil без Redis
+	sessionEvents chan entity.UserBehavior
+}
+
+func NewUserBehaviorService(repo repository.UserBehaviorRepository, redisService redis.ServiceInterface, behaviorsPagination, sessionsPagination entity.PaginationLimits, timestampBounds entity.TimestampBounds, maxBatchEvents int, excludedDomains *excluded_domain.ExcludedDomainService) UserBehaviorService {
+	s := &userBehaviorService{
+		repo:                repo,
+		redisService:        redisService,
+		behaviorsPagination: behaviorsPagination,
+		sessionsPagination:  sessionsPagination,
+		timestampBounds:     timestampBounds,
+		maxBatchEvents:      maxBatchEvents,
+		excludedDomains:     excludedDomains,
+	}
+
+	if redisService != nil {
+		s.sessionEvents = make(chan entity.UserBehavior, sessionEventsQueueSize)
+		go s.runSessionEventsPublisher()
+	}
+
+	return s
+}
+
+func (s *userBehaviorService) isExcludedDomain(ctx context.Context, domain string) bool {
+	return s.excludedDomains != nil && s.excludedDomains.IsExcluded(ctx, domain)
+}
+
+// excludedDomainsList - домены, которые не учитываются в подсчете уникальных доменов
+func (s *userBehaviorService) excludedDomainsList(ctx context.Context) []string {
+	if s.excludedDomains == nil {
+		return nil
+	}
+	return s.excludedDomains.Domains(ctx)
+}
+
+// Размер выборки для старых limit/offset запросов без limit
+const legacyBehaviorsLimit = 100
+
+// SessionEventsChannel - канал Redis pub/sub с новыми событиями сессии
+func SessionEventsChannel(sessionID string) string {
+	return fmt.Sprintf("session:%s:events", sessionID)
 }
 
-func NewUserBehaviorService(repo repository.UserBehaviorRepository) UserBehaviorService {
-	return &userBehaviorService{
-		repo: repo,
+// Очередь событий для live-стрима и таймаут одной публикации в Redis
+const (
+	sessionEventsQueueSize      = 4096
+	sessionEventsPublishTimeout = 2 * time.Second
+)
+
+// publishBehaviors ставит созданные события в очередь live-стрима сессии без ожидания:
+// при переполненной очереди события в стрим не попадают, сохранение событий не тормозится
+func (s *userBehaviorService) publishBehaviors(_ context.Context, behaviors ...entity.UserBehavior) {
+	if s.sessionEvents == nil {
+		return
+	}
+
+	for i, behavior := range behaviors {
+		select {
+		case s.sessionEvents <- behavior:
+		default:
+			fmt.Printf("Session stream queue is full, dropped %d behaviors\n", len(behaviors)-i)
+			return
+		}
+	}
+}
+
+// runSessionEventsPublisher публикует события из очереди в Redis pub/sub в фоне
+func (s *userBehaviorService) runSessionEventsPublisher() {
+	for behavior := range s.sessionEvents {
+		ctx, cancel := context.WithTimeout(context.Background(), sessionEventsPublishTimeout)
+		if err := s.redisService.Publish(ctx, SessionEventsChannel(behavior.SessionID), behavior); err != nil {
+			fmt.Printf("Failed to publish behavior to session stream: %v\n", err)
+		}
+		cancel()
+	}
+}
+
+// invalidateMetricCaches сбрасывает закешированные метрики пользователей, период которых пересекается
+// с временем новых событий (от самого раннего до самого позднего события пользователя в пачке).
+// AI-анализ кешируется по входным данным, а не по пользователю, и здесь не сбрасывается
+func (s *userBehaviorService) invalidateMetricCaches(ctx context.Context, behaviors ...entity.UserBehavior) {
+	if s.redisService == nil {
+		return
+	}
+
+	type eventsRange struct{ from, to time.Time }
+	ranges := make(map[uuid.UUID]*eventsRange)
+	var order []uuid.UUID
+
+	for _, behavior := range behaviors {
+		if behavior.UserID == nil {
+			continue
+		}
+
+		r, ok := ranges[*behavior.UserID]
+		if !ok {
+			ranges[*behavior.UserID] = &eventsRange{from: behavior.Timestamp, to: behavior.Timestamp}
+			order = append(order, *behavior.UserID)
+			continue
+		}
+		if behavior.Timestamp.Before(r.from) {
+			r.from = behavior.Timestamp
+		}
+		if behavior.Timestamp.After(r.to) {
+			r.to = behavior.Timestamp
+		}
+	}
+
+	for _, userID := range order {
+		r := ranges[userID]
+		if _, err := s.redisService.InvalidateUserMetricCacheRange(ctx, userID.String(), r.from, r.to); err != nil {
+			fmt.Printf("Failed to invalidate metric cache for user %s: %v\n", userID, err)
+		}
 	}
 }
 
@@ -50,70 +194,137 @@ var validEventTypes = map[string]bool{
 
 func (s *userBehaviorService) CreateBehavior(ctx context.Context, req entity.CreateUserBehaviorRequest) (*entity.UserBehavior, error) {
 	if !s.ValidateEventType(req.Type) {
-		return nil, fmt.Errorf("invalid event type: %s", req.Type)
+		return nil, fmt.Errorf("%w: %s", ErrInvalidEventType, req.Type)
 	}
 
 	if err := s.ValidateCoordinates(req.X, req.Y, req.Type); err != nil {
 		return nil, err
 	}
 
+	if err := s.ValidateTimestamp(req.Timestamp); err != nil {
+		return nil, err
+	}
+
 	behavior := &entity.UserBehavior{
 		SessionID: req.SessionID,
 		Timestamp: req.Timestamp,
 		Type:      req.Type,
 		URL:       req.URL,
+		Domain:    utils.NormalizeDomain(req.URL),
 		UserID:    req.UserID,
+		UserName:  req.UserName,
 		X:         req.X,
 		Y:         req.Y,
 		//Key:       req.Key,
 	}
 
-	if err := s.repo.Create(ctx, behavior); err != nil {
+	// Шум исключенных доменов (chrome://, about:blank и т.п.) не сохраняем
+	if s.isExcludedDomain(ctx, behavior.Domain) {
+		return nil, ErrExcludedDomain
+	}
+
+	inserted, err := s.repo.Create(ctx, behavior)
+	if err != nil {
 		return nil, fmt.Errorf("failed to create behavior: %w", err)
 	}
 
+	// Повторная отправка того же события: ответ идемпотентный, метрики не меняются
+	if inserted == 0 {
+		return behavior, nil
+	}
+
+	telemetry.BehaviorsIngestedTotal.Inc("single")
+	s.publishBehaviors(ctx, *behavior)
+	s.invalidateMetricCaches(ctx, *behavior)
+
 	return behavior, nil
 }
 
-func (s *userBehaviorService) BatchCreateBehaviors(ctx context.Context, req entity.BatchCreateUserBehaviorRequest) error {
+// BatchCreateBehaviors в режиме partial пропускает невалидные события и возвращает их индексы с причинами,
+// иначе весь батч отклоняется при первой ошибке. Дубликаты ранее сохраненных событий считаются принятыми, но не вставляются
+func (s *userBehaviorService) BatchCreateBehaviors(ctx context.Context, req entity.BatchCreateUserBehaviorRequest, partial bool) (*entity.BatchCreateUserBehaviorResult, error) {
 	if len(req.Events) == 0 {
-		return fmt.Errorf("no events provided")
+		return nil, fmt.Errorf("no events provided")
 	}
 
-	if len(req.Events) > 1000 {
-		return fmt.Errorf("too many events, maximum is 1000")
+	if len(req.Events) > s.maxBatchEvents {
+		return nil, fmt.Errorf("too many events, maximum is %d", s.maxBatchEvents)
 	}
 
 	var behaviors []entity.UserBehavior
+	rejected := []entity.RejectedBehaviorEvent{}
+	excluded := 0
 
 	for i, event := range req.Events {
 		if !s.ValidateEventType(event.Type) {
-			return fmt.Errorf("invalid event type at index %d: %s", i, event.Type)
+			if !partial {
+				return nil, fmt.Errorf("invalid event type at index %d: %s", i, event.Type)
+			}
+
+			rejected = append(rejected, entity.RejectedBehaviorEvent{
+				Index:  i,
+				Reason: fmt.Sprintf("invalid event type: %s", event.Type),
+			})
+			continue
 		}
 
 		//if err := s.ValidateCoordinates(event.X, event.Y, event.Type); err != nil {
 		//	return fmt.Errorf("validation error at index %d: %w", i, err)
 		//}
 
+		if err := s.ValidateTimestamp(event.Timestamp); err != nil {
+			if !partial {
+				return nil, fmt.Errorf("invalid timestamp at index %d: %w", i, err)
+			}
+
+			rejected = append(rejected, entity.RejectedBehaviorEvent{
+				Index:  i,
+				Reason: err.Error(),
+			})
+			continue
+		}
+
 		behavior := entity.UserBehavior{
 			SessionID: event.SessionID,
 			Timestamp: event.Timestamp,
 			Type:      event.Type,
 			URL:       event.URL,
+			Domain:    utils.NormalizeDomain(event.URL),
 			UserID:    event.UserID,
+			UserName:  event.UserName,
 			X:         event.X,
 			Y:         event.Y,
 			//Key:       event.Key,
 		}
 
+		if s.isExcludedDomain(ctx, behavior.Domain) {
+			excluded++
+			continue
+		}
+
 		behaviors = append(behaviors, behavior)
 	}
 
-	if err := s.repo.BatchCreate(ctx, behaviors); err != nil {
-		return fmt.Errorf("failed to batch create behaviors: %w", err)
+	inserted, err := s.repo.BatchCreate(ctx, behaviors)
+	if err != nil {
+		return nil, fmt.Errorf("failed to batch create behaviors: %w", err)
 	}
 
-	return nil
+	// Дубликаты уже были опубликованы и учтены в кешах при первой вставке
+	if len(inserted) > 0 {
+		telemetry.BehaviorsIngestedTotal.Add(float64(len(inserted)), "batch")
+		s.publishBehaviors(ctx, inserted...)
+		s.invalidateMetricCaches(ctx, inserted...)
+	}
+
+	return &entity.BatchCreateUserBehaviorResult{
+		Accepted:       len(behaviors),
+		Inserted:       len(inserted),
+		Duplicates:     len(behaviors) - len(inserted),
+		Rejected:       len(rejected),
+		RejectedEvents: rejected,
+		Excluded:       excluded,
+	}, nil
 }
 
 func (s *userBehaviorService) GetBehaviorByID(ctx context.Context, id uuid.UUID) (*entity.UserBehavior, error) {
@@ -129,22 +340,29 @@ func (s *userBehaviorService) GetBehaviorByID(ctx context.Context, id uuid.UUID)
 	return behavior, nil
 }
 
+// ErrInvalidBehaviorOrder - order списка событий не asc и не desc (400)
+var ErrInvalidBehaviorOrder = errors.New("invalid order: must be asc or desc")
+
+func validateBehaviorOrder(order string) error {
+	if order != "" && !strings.EqualFold(order, "asc") && !strings.EqualFold(order, "desc") {
+		return ErrInvalidBehaviorOrder
+	}
+	return nil
+}
+
 func (s *userBehaviorService) GetBehaviors(ctx context.Context, filter entity.UserBehaviorFilter) ([]entity.UserBehavior, *entity.PaginationInfo, error) {
-	if filter.Page > 0 && filter.PerPage > 0 {
-		if filter.PerPage > 1000 {
-			filter.PerPage = 1000
-		}
-		if filter.Page < 1 {
-			filter.Page = 1
-		}
+	if err := validateBehaviorOrder(filter.Order); err != nil {
+		return nil, nil, err
+	}
+
+	if filter.Page > 0 {
+		filter.PerPage = s.behaviorsPagination.Clamp(filter.PerPage)
 	} else {
 		// Старая логика для совместимости
 		if filter.Limit <= 0 {
-			filter.Limit = 100
-		}
-		if filter.Limit > 1000 {
-			filter.Limit = 1000
+			filter.Limit = legacyBehaviorsLimit
 		}
+		filter.Limit = s.behaviorsPagination.Clamp(filter.Limit)
 	}
 
 	behaviors, err := s.repo.GetByFilter(ctx, filter)
@@ -171,7 +389,38 @@ func (s *userBehaviorService) GetBehaviors(ctx context.Context, filter entity.Us
 	return behaviors, paginationInfo, nil
 }
 
+// GetBehaviorsByCursor - курсор не хранит направление, при смене order нужно начинать с пустого курсора
+func (s *userBehaviorService) GetBehaviorsByCursor(ctx context.Context, filter entity.UserBehaviorFilter) ([]entity.UserBehavior, *entity.CursorPaginationInfo, error) {
+	if err := validateBehaviorOrder(filter.Order); err != nil {
+		return nil, nil, err
+	}
+
+	filter.PerPage = s.behaviorsPagination.Clamp(filter.PerPage)
+
+	// Берем на одну запись больше, чтобы понять, есть ли следующая страница
+	filter.CursorMode = true
+	filter.Limit = filter.PerPage + 1
+
+	behaviors, err := s.repo.GetByFilter(ctx, filter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get behaviors: %w", err)
+	}
+
+	paginationInfo := &entity.CursorPaginationInfo{PerPage: filter.PerPage}
+	if len(behaviors) > filter.PerPage {
+		behaviors = behaviors[:filter.PerPage]
+		last := behaviors[len(behaviors)-1]
+
+		paginationInfo.HasMore = true
+		paginationInfo.NextCursor = entity.BehaviorCursor{Timestamp: last.Timestamp, ID: last.ID}.Encode()
+	}
+
+	return behaviors, paginationInfo, nil
+}
+
 func (s *userBehaviorService) GetStats(ctx context.Context, filter entity.UserBehaviorFilter) (*entity.UserBehaviorStats, error) {
+	filter.ExcludedDomains = s.excludedDomainsList(ctx)
+
 	stats, err := s.repo.GetStats(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stats: %w", err)
@@ -180,6 +429,56 @@ func (s *userBehaviorService) GetStats(ctx context.Context, filter entity.UserBe
 	return stats, nil
 }
 
+// ErrInvalidStatsGroupBy - некорректные параметры разбивки статистики по времени (400)
+var ErrInvalidStatsGroupBy = errors.New("invalid group_by")
+
+// Ограничение на число бакетов, чтобы group_by=hour не отдавал годовой ряд
+const maxStatsBuckets = 744
+
+func (s *userBehaviorService) GetStatsTimeline(ctx context.Context, filter entity.UserBehaviorFilter, groupBy string) ([]entity.StatsBucket, error) {
+	step, ok := entity.StatsGroupByUnits[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("%w: must be one of day, hour", ErrInvalidStatsGroupBy)
+	}
+
+	if filter.StartTime == nil || filter.EndTime == nil {
+		return nil, fmt.Errorf("%w: startTime and endTime are required", ErrInvalidStatsGroupBy)
+	}
+
+	if filter.EndTime.Before(*filter.StartTime) {
+		return nil, fmt.Errorf("%w: endTime must be after startTime", ErrInvalidStatsGroupBy)
+	}
+
+	first := filter.StartTime.UTC().Truncate(step)
+	last := filter.EndTime.UTC().Truncate(step)
+	if int(last.Sub(first)/step)+1 > maxStatsBuckets {
+		return nil, fmt.Errorf("%w: too many buckets, max %d for group_by=%s", ErrInvalidStatsGroupBy, maxStatsBuckets, groupBy)
+	}
+
+	filter.ExcludedDomains = s.excludedDomainsList(ctx)
+	buckets, err := s.repo.GetStatsTimeline(ctx, filter, groupBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats timeline: %w", err)
+	}
+
+	// Заполняем пустые бакеты нулями, чтобы фронт рисовал ряд без пропусков
+	byBucket := make(map[time.Time]entity.StatsBucket, len(buckets))
+	for _, bucket := range buckets {
+		byBucket[bucket.Bucket] = bucket
+	}
+
+	timeline := make([]entity.StatsBucket, 0, int(last.Sub(first)/step)+1)
+	for bucket := first; !bucket.After(last); bucket = bucket.Add(step) {
+		if existing, ok := byBucket[bucket]; ok {
+			timeline = append(timeline, existing)
+			continue
+		}
+		timeline = append(timeline, entity.StatsBucket{Bucket: bucket, ByType: map[string]int64{}})
+	}
+
+	return timeline, nil
+}
+
 func (s *userBehaviorService) GetSessionSummary(ctx context.Context, sessionID string) (*entity.SessionSummary, error) {
 	if sessionID == "" {
 		return nil, fmt.Errorf("session ID is required")
@@ -206,12 +505,7 @@ func (s *userBehaviorService) GetUserSessions(ctx context.Context, userID string
 	if page < 1 {
 		page = 1
 	}
-	if perPage <= 0 {
-		perPage = 50
-	}
-	if perPage > 200 {
-		perPage = 200
-	}
+	perPage = s.sessionsPagination.Clamp(perPage)
 
 	sessions, err := s.repo.GetUserSessions(ctx, userID, page, perPage)
 	if err != nil {
@@ -241,6 +535,20 @@ func (s *userBehaviorService) DeleteBehavior(ctx context.Context, id uuid.UUID)
 	return nil
 }
 
+func (s *userBehaviorService) RestoreBehavior(ctx context.Context, id uuid.UUID) error {
+	if err := s.repo.Restore(ctx, id); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrDeletedBehaviorNotFound
+		case errors.Is(err, repository.ErrDuplicateBehavior):
+			return ErrBehaviorRestoreConflict
+		}
+		return fmt.Errorf("failed to restore behavior: %w", err)
+	}
+
+	return nil
+}
+
 func (s *userBehaviorService) GetUserEventsCount(ctx context.Context, filter entity.UserEventsCount) (*entity.UserEventsCountResponse, error) {
 	events, err := s.repo.GetUserEventsCount(ctx, filter)
 	if err != nil {
@@ -252,7 +560,35 @@ func (s *userBehaviorService) GetUserEventsCount(ctx context.Context, filter ent
 	return events, nil
 }
 
+func (s *userBehaviorService) GetDistinctEventTypes(ctx context.Context, filter entity.UserEventsCount) (*entity.DistinctEventTypesResponse, error) {
+	eventTypes, err := s.repo.GetDistinctEventTypes(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get distinct event types: %w", err)
+	}
+
+	return &entity.DistinctEventTypesResponse{
+		UserID:     filter.UserID,
+		StartTime:  filter.StartTime,
+		EndTime:    filter.EndTime,
+		EventTypes: eventTypes,
+	}, nil
+}
+
+func (s *userBehaviorService) GetUserActivitySpan(ctx context.Context, userID string) (*entity.UserActivitySpan, error) {
+	span, err := s.repo.GetUserActivitySpan(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user activity span: %w", err)
+	}
+
+	return span, nil
+}
+
 func (s *userBehaviorService) ValidateEventType(eventType string) bool {
+	return IsValidEventType(eventType)
+}
+
+// IsValidEventType проверяет тип события по общему списку допустимых типов
+func IsValidEventType(eventType string) bool {
 	return validEventTypes[eventType]
 }
 
@@ -278,3 +614,16 @@ func (s *userBehaviorService) ValidateCoordinates(x, y *int, eventType string) e
 
 	return nil
 }
+
+// ValidateTimestamp отклоняет события из будущего (с допуском MaxFutureSkew) и раньше Floor
+func (s *userBehaviorService) ValidateTimestamp(ts time.Time) error {
+	if ts.After(time.Now().Add(s.timestampBounds.MaxFutureSkew)) {
+		return fmt.Errorf("timestamp %s is too far in the future", ts.Format(time.RFC3339))
+	}
+
+	if ts.Before(s.timestampBounds.Floor) {
+		return fmt.Errorf("timestamp %s is before %s", ts.Format(time.RFC3339), s.timestampBounds.Floor.Format(time.RFC3339))
+	}
+
+	return nil
+}
@@ -1,15 +1,24 @@
 package middleware
 
 import (
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"github.com/dinerozz/web-behavior-backend/internal/model/response/wrapper"
 	"github.com/dinerozz/web-behavior-backend/internal/repository"
 	service "github.com/dinerozz/web-behavior-backend/internal/service/extension_user"
+	"github.com/dinerozz/web-behavior-backend/internal/service/redis"
+	"github.com/dinerozz/web-behavior-backend/pkg/logging"
+	"github.com/dinerozz/web-behavior-backend/pkg/telemetry"
 	"github.com/dinerozz/web-behavior-backend/pkg/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/gofrs/uuid"
+	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 func AuthenticationMiddleware() gin.HandlerFunc {
@@ -22,6 +31,11 @@ func AuthenticationMiddleware() gin.HandlerFunc {
 		}
 
 		claims, err := utils.ValidateToken(tokenString)
+		if errors.Is(err, utils.ErrTokenExpired) {
+			c.JSON(http.StatusUnauthorized, wrapper.ErrorWrapper{Message: "Authentication token expired", Success: false})
+			c.Abort()
+			return
+		}
 		if err != nil {
 			fmt.Println("Error validating token", err)
 			c.JSON(http.StatusUnauthorized, wrapper.ErrorWrapper{Message: "Invalid authentication token", Success: false})
@@ -130,3 +144,290 @@ func SuperAdminMiddleware(userRepo *repository.UserRepository) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// MetricsUserAccessMiddleware пускает к метрикам пользователя расширения (query user_id, в том числе
+// несколько значений) только супер админа или админа, состоящего в той же организации
+func MetricsUserAccessMiddleware(userRepo *repository.UserRepository, orgRepo *repository.OrganizationRepository) gin.HandlerFunc {
+	return extensionUserAccessMiddleware(userRepo, orgRepo, "user_id", false)
+}
+
+// BehaviorExportAccessMiddleware применяет ту же проверку к выгрузке событий (query userId).
+// Выгрузка только по sessionId без userId доступна лишь супер админу
+func BehaviorExportAccessMiddleware(userRepo *repository.UserRepository, orgRepo *repository.OrganizationRepository) gin.HandlerFunc {
+	return extensionUserAccessMiddleware(userRepo, orgRepo, "userId", true)
+}
+
+func extensionUserAccessMiddleware(userRepo *repository.UserRepository, orgRepo *repository.OrganizationRepository, queryParam string, requireTarget bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var targetIDs []string
+		for _, raw := range c.QueryArray(queryParam) {
+			for _, id := range strings.Split(raw, ",") {
+				if id = strings.TrimSpace(id); id != "" {
+					targetIDs = append(targetIDs, id)
+				}
+			}
+		}
+
+		// Без user_id проверять нечего, обязательность параметра проверяет обработчик
+		if len(targetIDs) == 0 && !requireTarget {
+			c.Next()
+			return
+		}
+
+		userID, exists := c.Get("user_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, wrapper.ErrorWrapper{Message: "User ID not found", Success: false})
+			c.Abort()
+			return
+		}
+
+		userUUID, err := uuid.FromString(userID.(string))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{Message: "Invalid user ID", Success: false})
+			c.Abort()
+			return
+		}
+
+		isSuperAdmin, err := userRepo.IsUserSuperAdmin(userUUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{Message: "Failed to check admin status", Success: false})
+			c.Abort()
+			return
+		}
+
+		if isSuperAdmin {
+			c.Set("is_super_admin", true)
+			c.Next()
+			return
+		}
+
+		if len(targetIDs) == 0 {
+			c.JSON(http.StatusForbidden, wrapper.ErrorWrapper{Message: queryParam + " is required", Success: false})
+			c.Abort()
+			return
+		}
+
+		for _, targetID := range targetIDs {
+			targetUUID, err := uuid.FromString(targetID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, wrapper.ErrorWrapper{Message: "Invalid " + queryParam + " format", Success: false})
+				c.Abort()
+				return
+			}
+
+			allowed, err := orgRepo.CanAccessExtensionUser(userUUID, targetUUID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, wrapper.ErrorWrapper{Message: "Failed to check organization access", Success: false})
+				c.Abort()
+				return
+			}
+
+			if !allowed {
+				c.JSON(http.StatusForbidden, wrapper.ErrorWrapper{Message: "Access to this user's metrics is denied", Success: false})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// RateLimitMiddleware ограничивает число запросов в минуту по extension_user_id,
+// а для неаутентифицированных клиентов - по IP. При недоступности Redis запросы пропускаются.
+func RateLimitMiddleware(redisService redis.ServiceInterface, scope string, limitPerMinute int) gin.HandlerFunc {
+	const window = time.Minute
+
+	return func(c *gin.Context) {
+		if limitPerMinute <= 0 {
+			c.Next()
+			return
+		}
+
+		identity := "ip:" + c.ClientIP()
+		if extensionUserID, exists := c.Get("extension_user_id"); exists {
+			identity = fmt.Sprintf("user:%v", extensionUserID)
+		}
+
+		key := fmt.Sprintf("rate_limit:%s:%s", scope, identity)
+
+		allowed, err := redisService.CheckRateLimit(c.Request.Context(), key, limitPerMinute, window)
+		if err != nil {
+			log.Printf("Rate limit check failed for %s: %v", key, err)
+			c.Next()
+			return
+		}
+
+		if !allowed {
+			retryAfter := window
+			if ttl, err := redisService.GetTTL(c.Request.Context(), key); err == nil && ttl > 0 {
+				retryAfter = ttl
+			}
+
+			c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+			c.JSON(http.StatusTooManyRequests, wrapper.ErrorWrapper{
+				Message: "Too many requests, please retry later",
+				Success: false,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// BodySizeLimitMiddleware ограничивает размер тела запроса: запрос с большим Content-Length отклоняется
+// с 413 до обработчика, а чтение тела без Content-Length обрывается на лимите через http.MaxBytesReader.
+// routeLimits задает лимит для отдельных маршрутов по шаблону (c.FullPath)
+func BodySizeLimitMiddleware(defaultLimit int64, routeLimits map[string]int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := defaultLimit
+		if routeLimit, ok := routeLimits[c.FullPath()]; ok {
+			limit = routeLimit
+		}
+
+		if limit <= 0 || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, wrapper.ErrorWrapper{
+				Message: fmt.Sprintf("Request body too large, maximum is %d bytes", limit),
+				Success: false,
+			})
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+// PrometheusMiddleware считает запросы и их длительность по шаблону маршрута (c.FullPath),
+// чтобы path-параметры не раздували количество серий
+func PrometheusMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		telemetry.HTTPRequestDuration.ObserveSince(start, c.Request.Method, route)
+		telemetry.HTTPRequestsTotal.Inc(c.Request.Method, route, strconv.Itoa(c.Writer.Status()))
+	}
+}
+
+// MetricsTokenMiddleware закрывает эндпоинт метрик bearer-токеном; с пустым токеном доступ закрыт
+func MetricsTokenMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := []byte(c.GetHeader("Authorization"))
+		if token == "" || subtle.ConstantTimeCompare(provided, []byte("Bearer "+token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, wrapper.ErrorWrapper{Message: "Unauthorized", Success: false})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequestLoggingMiddleware присваивает запросу ID (заголовок X-Request-ID и контекст)
+// и пишет одну строку лога на запрос через slog
+func RequestLoggingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID, err := uuid.NewV4()
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		c.Header("X-Request-ID", requestID.String())
+		c.Set("request_id", requestID.String())
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), requestID.String()))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("request_id", requestID.String()),
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+
+		slog.LogAttrs(c.Request.Context(), level, "http request", attrs...)
+	}
+}
+
+// CORSMiddleware разрешает только origin из списка: точное совпадение или wildcard поддомен (https://*.example.com).
+// Недоверенный origin никогда не возвращается в Access-Control-Allow-Origin
+func CORSMiddleware(allowedOrigins []string, allowLocalhost bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.Request.Header.Get("Origin")
+
+		c.Writer.Header().Add("Vary", "Origin")
+		if origin != "" && IsOriginAllowed(origin, allowedOrigins, allowLocalhost) {
+			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+			c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Idempotency-Key")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, Idempotent-Replayed, Warning, X-API-Deprecation")
+
+		if c.Request.Method == "OPTIONS" {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// IsOriginAllowed проверяет origin по списку CORS: точное совпадение, wildcard поддомен или localhost
+func IsOriginAllowed(origin string, allowedOrigins []string, allowLocalhost bool) bool {
+	if allowLocalhost && (strings.HasPrefix(origin, "http://localhost:") ||
+		strings.HasPrefix(origin, "http://127.0.0.1:")) {
+		return true
+	}
+
+	for _, allowed := range allowedOrigins {
+		if allowed == origin {
+			return true
+		}
+
+		// https://*.example.com -> origin должен быть https://<sub>.example.com
+		if scheme, host, ok := strings.Cut(allowed, "://*."); ok {
+			prefix := scheme + "://"
+			suffix := "." + host
+			if strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				sub := strings.TrimSuffix(strings.TrimPrefix(origin, prefix), suffix)
+				if sub != "" && !strings.ContainsAny(sub, "/:@") {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
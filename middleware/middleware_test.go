@@ -0,0 +1,195 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testBatchRoute = "/api/v1/inayla/behaviors/batch"
+
+func newBodyLimitRouter(t *testing.T, handler gin.HandlerFunc) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(BodySizeLimitMiddleware(16, map[string]int64{testBatchRoute: 64}))
+	router.POST("/api/v1/inayla/behaviors", handler)
+	router.POST(testBatchRoute, handler)
+	return router
+}
+
+func failIfCalled(t *testing.T) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t.Errorf("handler called for %s", c.FullPath())
+		c.Status(http.StatusOK)
+	}
+}
+
+func TestBodySizeLimitMiddlewareRejectsOversizeBody(t *testing.T) {
+	cases := []struct {
+		name string
+		path string
+		size int
+	}{
+		{name: "default limit", path: "/api/v1/inayla/behaviors", size: 17},
+		{name: "batch route override", path: testBatchRoute, size: 65},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := newBodyLimitRouter(t, failIfCalled(t))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(strings.Repeat("a", tc.size))))
+
+			if recorder.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("expected 413, got %d", recorder.Code)
+			}
+		})
+	}
+}
+
+func TestBodySizeLimitMiddlewareAllowsBodyWithinLimit(t *testing.T) {
+	cases := []struct {
+		name string
+		path string
+		size int
+	}{
+		{name: "default limit", path: "/api/v1/inayla/behaviors", size: 16},
+		// Больше общего лимита, но в пределах лимита batch маршрута
+		{name: "batch route override", path: testBatchRoute, size: 64},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			called := false
+			router := newBodyLimitRouter(t, func(c *gin.Context) {
+				called = true
+				body, err := io.ReadAll(c.Request.Body)
+				if err != nil || len(body) != tc.size {
+					t.Errorf("expected %d bytes of body, got %d, %v", tc.size, len(body), err)
+				}
+				c.Status(http.StatusOK)
+			})
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(strings.Repeat("a", tc.size))))
+
+			if recorder.Code != http.StatusOK || !called {
+				t.Fatalf("expected handler to run with 200, got %d (called: %t)", recorder.Code, called)
+			}
+		})
+	}
+}
+
+func TestBodySizeLimitMiddlewareWithoutContentLength(t *testing.T) {
+	router := newBodyLimitRouter(t, func(c *gin.Context) {
+		// Без Content-Length запрос доходит до обработчика, но чтение обрывается на лимите
+		_, err := io.ReadAll(c.Request.Body)
+		var maxBytesErr *http.MaxBytesError
+		if !errors.As(err, &maxBytesErr) {
+			t.Errorf("expected http.MaxBytesError, got %v", err)
+		}
+		c.Status(http.StatusRequestEntityTooLarge)
+	})
+
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/inayla/behaviors", strings.NewReader(strings.Repeat("a", 17)))
+	request.ContentLength = -1
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", recorder.Code)
+	}
+}
+
+func TestIsOriginAllowed(t *testing.T) {
+	allowed := []string{"https://inayla.com", "https://*.inayla.com"}
+
+	cases := []struct {
+		origin         string
+		allowLocalhost bool
+		want           bool
+	}{
+		{origin: "https://inayla.com", want: true},
+		{origin: "https://app.inayla.com", want: true},
+		{origin: "https://a.b.inayla.com", want: true},
+		{origin: "http://app.inayla.com", want: false},
+		{origin: "https://evilinayla.com", want: false},
+		{origin: "https://inayla.com.evil.com", want: false},
+		{origin: "https://evil.com/.inayla.com", want: false},
+		{origin: "https://user@app.inayla.com", want: false},
+		{origin: "https://.inayla.com", want: false},
+		{origin: "https://example.com", want: false},
+		// localhost разрешен только вне prod (allowLocalhost = ENV != prod)
+		{origin: "http://localhost:3000", allowLocalhost: true, want: true},
+		{origin: "http://127.0.0.1:5173", allowLocalhost: true, want: true},
+		{origin: "http://localhost:3000", allowLocalhost: false, want: false},
+		{origin: "http://127.0.0.1:5173", allowLocalhost: false, want: false},
+		{origin: "http://localhost.evil.com", allowLocalhost: true, want: false},
+	}
+
+	for _, tc := range cases {
+		if got := IsOriginAllowed(tc.origin, allowed, tc.allowLocalhost); got != tc.want {
+			t.Errorf("IsOriginAllowed(%q, allowLocalhost=%t) = %t, want %t", tc.origin, tc.allowLocalhost, got, tc.want)
+		}
+	}
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name       string
+		method     string
+		origin     string
+		wantOrigin string
+		wantStatus int
+	}{
+		{name: "allowed origin", method: http.MethodGet, origin: "https://app.inayla.com", wantOrigin: "https://app.inayla.com", wantStatus: http.StatusOK},
+		{name: "disallowed origin", method: http.MethodGet, origin: "https://evil.com", wantStatus: http.StatusOK},
+		{name: "localhost in prod", method: http.MethodGet, origin: "http://localhost:3000", wantStatus: http.StatusOK},
+		{name: "preflight allowed", method: http.MethodOptions, origin: "https://inayla.com", wantOrigin: "https://inayla.com", wantStatus: http.StatusNoContent},
+		{name: "preflight disallowed", method: http.MethodOptions, origin: "https://evil.com", wantStatus: http.StatusNoContent},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(CORSMiddleware([]string{"https://inayla.com", "https://*.inayla.com"}, false))
+			router.GET("/resource", func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+			router.OPTIONS("/resource", failIfCalled(t))
+
+			request := httptest.NewRequest(tc.method, "/resource", nil)
+			request.Header.Set("Origin", tc.origin)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != tc.wantStatus {
+				t.Fatalf("expected %d, got %d", tc.wantStatus, recorder.Code)
+			}
+			if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != tc.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tc.wantOrigin)
+			}
+			wantCredentials := ""
+			if tc.wantOrigin != "" {
+				wantCredentials = "true"
+			}
+			if got := recorder.Header().Get("Access-Control-Allow-Credentials"); got != wantCredentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, wantCredentials)
+			}
+			if vary := recorder.Header().Values("Vary"); len(vary) == 0 || vary[0] != "Origin" {
+				t.Errorf("expected Vary: Origin, got %v", vary)
+			}
+		})
+	}
+}
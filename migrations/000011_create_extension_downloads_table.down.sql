@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_extension_downloads_downloaded_at;
+
+DROP TABLE IF EXISTS extension_downloads;
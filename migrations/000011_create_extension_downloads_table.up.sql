@@ -0,0 +1,9 @@
+-- up migration: create_extension_downloads_table
+CREATE TABLE IF NOT EXISTS extension_downloads (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_agent TEXT NULL,
+    downloaded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+-- Индекс для быстрого получения последней загрузки
+CREATE INDEX IF NOT EXISTS idx_extension_downloads_downloaded_at ON extension_downloads(downloaded_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_user_behaviors_not_deleted;
+
+ALTER TABLE user_behaviors DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,7 @@
+-- up migration: add_deleted_at_user_behaviors
+ALTER TABLE user_behaviors ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP NULL;
+
+-- Частичный индекс: большинство запросов читает только не удаленные события
+CREATE INDEX IF NOT EXISTS idx_user_behaviors_not_deleted
+    ON user_behaviors(user_id, timestamp)
+    WHERE deleted_at IS NULL;
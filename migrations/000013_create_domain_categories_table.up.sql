@@ -0,0 +1,8 @@
+-- up migration: create_domain_categories_table
+CREATE TABLE IF NOT EXISTS domain_categories (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    domain VARCHAR(255) NOT NULL UNIQUE,
+    category VARCHAR(50) NOT NULL CHECK (category IN ('work_tools', 'development', 'research', 'communication', 'distractions')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
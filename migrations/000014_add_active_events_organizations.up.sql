@@ -0,0 +1,3 @@
+-- up migration: add_active_events_organizations
+-- NULL = используется набор активных событий по умолчанию
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS active_events TEXT[] NULL;
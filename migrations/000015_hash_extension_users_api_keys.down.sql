@@ -0,0 +1,4 @@
+-- down migration: hash_extension_users_api_keys
+-- Хэш необратим: исходные ключи восстановить нельзя.
+-- После отката все ключи нужно перевыпустить через POST /extension/users/:id/regenerate-key.
+SELECT 1;
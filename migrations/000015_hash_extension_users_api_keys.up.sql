@@ -0,0 +1,7 @@
+-- up migration: hash_extension_users_api_keys
+-- Ключи хранятся как SHA-256 (hex). Хэшируются все существующие ключи, включая заданные вручную
+-- и старые ключи без префикса wb_: приложение сравнивает только хэши, поэтому нехэшированный ключ
+-- перестал бы работать. Выданные ранее ключи продолжают работать без перевыпуска.
+-- Миграция выполняется один раз (версия фиксируется в schema_migrations), повторно ключи не хэшируются
+UPDATE extension_users
+SET api_key = encode(sha256(convert_to(api_key, 'UTF8')), 'hex');
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_user_behaviors_user_domain;
+ALTER TABLE user_behaviors DROP COLUMN IF EXISTS domain;
@@ -0,0 +1,7 @@
+-- up migration: add_domain_user_behaviors
+-- Нормализованный домен (lowercase, без порта и www.) заполняется при вставке событий.
+-- Константный DEFAULT не переписывает таблицу; старые строки заполняет команда backfill-domains
+-- пачками в отдельных транзакциях, а не один UPDATE на всю таблицу
+ALTER TABLE user_behaviors ADD COLUMN IF NOT EXISTS domain VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_user_behaviors_user_domain ON user_behaviors(user_id, domain);
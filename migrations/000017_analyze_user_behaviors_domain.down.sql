@@ -0,0 +1,3 @@
+-- Rollback migration: statistics refresh has nothing to revert
+
+ANALYZE user_behaviors;
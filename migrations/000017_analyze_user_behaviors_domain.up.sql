@@ -0,0 +1,6 @@
+-- Migration: Update user_behaviors statistics for the domain column
+-- Description: Only refreshes planner statistics; rows with an empty domain are filled by the
+-- backfill-domains command in batches, each committed separately, after the deploy.
+-- Составной индекс (user_id, timestamp, domain) создается отдельно в 000025 через CREATE INDEX CONCURRENTLY.
+-- Индекс (user_id, domain) из 000016 остается для запросов по домену без диапазона времени
+ANALYZE user_behaviors;
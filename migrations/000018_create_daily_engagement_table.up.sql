@@ -0,0 +1,10 @@
+-- up migration: create_daily_engagement_table
+-- Дневной rollup активных/отслеживаемых минут, заполняется командой rollup
+CREATE TABLE IF NOT EXISTS daily_engagement (
+    user_id VARCHAR(255) NOT NULL,
+    day DATE NOT NULL,
+    active_minutes INTEGER NOT NULL DEFAULT 0,
+    tracked_minutes INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, day)
+    );
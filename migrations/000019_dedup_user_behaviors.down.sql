@@ -0,0 +1,3 @@
+-- Rollback migration: Drop dedup index (удаленные дубликаты не восстанавливаются)
+
+DROP INDEX IF EXISTS idx_user_behaviors_dedup;
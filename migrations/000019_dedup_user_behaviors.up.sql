@@ -0,0 +1,25 @@
+-- Migration: Deduplicate user_behaviors
+-- Description: Remove events resent by the extension on reconnect and prevent new duplicates at ingestion
+
+-- Step 1: One-time cleanup of existing duplicates (same session_id, timestamp, event_type, url).
+-- Оставляем самую раннюю запись, удаленные через deleted_at не трогаем
+DELETE FROM user_behaviors ub
+USING (
+    SELECT id,
+           ROW_NUMBER() OVER (
+               PARTITION BY session_id, timestamp, event_type, url
+               ORDER BY created_at, id
+           ) AS rn
+    FROM user_behaviors
+    WHERE deleted_at IS NULL
+) duplicates
+WHERE ub.id = duplicates.id
+  AND duplicates.rn > 1;
+
+-- Step 2: Unique partial index used by INSERT ... ON CONFLICT DO NOTHING.
+-- url хранится как md5, чтобы длинные URL не превышали лимит размера строки btree индекса
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_behaviors_dedup
+    ON user_behaviors (session_id, timestamp, event_type, md5(url))
+    WHERE deleted_at IS NULL;
+
+ANALYZE user_behaviors;
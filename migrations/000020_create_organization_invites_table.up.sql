@@ -0,0 +1,19 @@
+-- up migration: create_organization_invites_table
+-- Приглашения в организацию по email; хранится только SHA-256 хэш токена
+CREATE TABLE IF NOT EXISTS organization_invites (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(50) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    invited_by uuid REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP NULL,
+    accepted_by uuid REFERENCES users(id) ON DELETE SET NULL,
+    revoked_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE INDEX IF NOT EXISTS idx_organization_invites_pending
+    ON organization_invites(organization_id, email)
+    WHERE accepted_at IS NULL AND revoked_at IS NULL;
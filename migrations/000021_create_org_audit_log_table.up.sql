@@ -0,0 +1,14 @@
+-- up migration: create_org_audit_log_table
+-- Журнал изменений состава организации. Без внешних ключей, чтобы записи переживали удаление пользователей и организаций
+CREATE TABLE IF NOT EXISTS org_audit_log (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id uuid NOT NULL,
+    actor_id uuid NOT NULL,
+    target_user_id uuid NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    old_role VARCHAR(50) NULL,
+    new_role VARCHAR(50) NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE INDEX IF NOT EXISTS idx_org_audit_log_org_created ON org_audit_log(organization_id, created_at DESC);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_extension_users_owner_user_id;
+
+ALTER TABLE extension_users DROP COLUMN IF EXISTS owner_user_id;
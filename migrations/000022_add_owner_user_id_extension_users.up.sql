@@ -0,0 +1,6 @@
+-- up migration: add_owner_user_id_extension_users
+-- Привязка пользователя расширения к участнику организации (users), нужна для last_active_at участников
+ALTER TABLE extension_users
+    ADD COLUMN owner_user_id uuid NULL REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_extension_users_owner_user_id ON extension_users(owner_user_id);
@@ -0,0 +1,11 @@
+-- up migration: create_session_annotations_table
+-- Метки сессий от аналитиков ("pairing", "meeting", "focus block"). Сессии отдельной таблицы нет, поэтому session_id без внешнего ключа
+CREATE TABLE IF NOT EXISTS session_annotations (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    session_id VARCHAR(255) NOT NULL,
+    tag VARCHAR(255) NOT NULL,
+    author_id uuid REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE INDEX IF NOT EXISTS idx_session_annotations_session_created ON session_annotations(session_id, created_at);
@@ -0,0 +1,13 @@
+-- up migration: create_excluded_domains_table
+-- Домены, которые не учитываются в метриках и не сохраняются при приеме событий (служебные страницы браузера и т.п.).
+-- Значения совпадают с user_behaviors.domain, например chrome://newtab -> chrome, about:blank -> about
+CREATE TABLE IF NOT EXISTS excluded_domains (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    domain VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+INSERT INTO excluded_domains (domain)
+VALUES ('chrome'), ('chrome-extension'), ('about'), ('edge'), ('moz-extension')
+ON CONFLICT (domain) DO NOTHING;
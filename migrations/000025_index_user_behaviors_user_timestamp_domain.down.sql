@@ -0,0 +1,2 @@
+-- down migration: index_user_behaviors_user_timestamp_domain
+DROP INDEX CONCURRENTLY IF EXISTS idx_user_behaviors_user_timestamp_domain;
@@ -0,0 +1,6 @@
+-- up migration: index_user_behaviors_user_timestamp_domain
+-- Составной индекс для engaged time, deep work и top domains (диапазон по user_id + timestamp с доменом).
+-- CONCURRENTLY не блокирует запись в user_behaviors во время деплоя и не работает внутри транзакции,
+-- поэтому команда в файле единственная
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_user_behaviors_user_timestamp_domain
+    ON user_behaviors (user_id, timestamp, domain);
@@ -0,0 +1 @@
+ALTER TABLE organization_invites DROP COLUMN IF EXISTS username;
@@ -0,0 +1,4 @@
+-- up migration: add_username_organization_invites
+-- Приглашение принимает только пользователь с этим username; приглашения без username принять нельзя
+ALTER TABLE organization_invites
+    ADD COLUMN IF NOT EXISTS username VARCHAR(100) NULL;
@@ -0,0 +1,31 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID кладет request ID в контекст, чтобы сервисы и репозитории могли его логировать
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID возвращает request ID из контекста или пустую строку
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// FromContext возвращает slog логгер с request_id, если он есть в контексте
+func FromContext(ctx context.Context) *slog.Logger {
+	logger := slog.Default()
+	if requestID := RequestID(ctx); requestID != "" {
+		return logger.With(slog.String("request_id", requestID))
+	}
+	return logger
+}
@@ -0,0 +1,32 @@
+package telemetry
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// CounterVec - монотонно растущий счетчик с метками
+type CounterVec struct {
+	name string
+	vec  *prometheus.CounterVec
+}
+
+func NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	vec := prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labelNames)
+	registry.MustRegister(vec)
+	return &CounterVec{name: name, vec: vec}
+}
+
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		return
+	}
+
+	counter, err := c.vec.GetMetricWithLabelValues(labelValues...)
+	if err != nil {
+		logLabelsError(c.name, err)
+		return
+	}
+	counter.Add(delta)
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
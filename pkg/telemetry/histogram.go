@@ -0,0 +1,37 @@
+package telemetry
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// HistogramVec - гистограмма с метками
+type HistogramVec struct {
+	name string
+	vec  *prometheus.HistogramVec
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	vec := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: help, Buckets: buckets}, labelNames)
+	registry.MustRegister(vec)
+	return &HistogramVec{name: name, vec: vec}
+}
+
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	observer, err := h.vec.GetMetricWithLabelValues(labelValues...)
+	if err != nil {
+		logLabelsError(h.name, err)
+		return
+	}
+	observer.Observe(value)
+}
+
+// ObserveSince записывает длительность с момента start в секундах
+func (h *HistogramVec) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
@@ -0,0 +1,36 @@
+package telemetry
+
+// Метрики приложения
+var (
+	HTTPRequestsTotal = NewCounterVec(
+		"http_requests_total",
+		"Total number of HTTP requests by route and status.",
+		"method", "route", "status",
+	)
+
+	HTTPRequestDuration = NewHistogramVec(
+		"http_request_duration_seconds",
+		"HTTP request latency in seconds.",
+		DefaultBuckets,
+		"method", "route",
+	)
+
+	DBQueryDuration = NewHistogramVec(
+		"db_query_duration_seconds",
+		"Database query duration in seconds.",
+		DefaultBuckets,
+		"query",
+	)
+
+	BehaviorsIngestedTotal = NewCounterVec(
+		"behaviors_ingested_total",
+		"Total number of behavior events stored.",
+		"source",
+	)
+
+	AICallsTotal = NewCounterVec(
+		"ai_calls_total",
+		"Total number of OpenAI API calls by type and result.",
+		"type", "status",
+	)
+)
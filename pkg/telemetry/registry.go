@@ -0,0 +1,34 @@
+// Package telemetry - метрики приложения на prometheus/client_golang. Используется собственный реестр
+// вместо глобального: в нем только метрики приложения, Go runtime и процесса.
+package telemetry
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// DefaultBuckets - границы гистограмм в секундах, как в prometheus client_golang
+var DefaultBuckets = prometheus.DefBuckets
+
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler отдает метрики реестра в формате Prometheus
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// logLabelsError - неверное число меток не должно ронять запрос: наблюдение пропускается
+func logLabelsError(name string, err error) {
+	log.Printf("telemetry: skip %s observation: %v", name, err)
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+var httpSchemeRegexp = regexp.MustCompile(`^https?://`)
+
+// NormalizeDomain извлекает домен из URL: lowercase, без порта и префикса www.
+// Логика совпадает с backfill-запросом в миграции 000016_add_domain_user_behaviors.
+func NormalizeDomain(rawURL string) string {
+	host := rawURL
+	if httpSchemeRegexp.MatchString(host) {
+		host = host[strings.Index(host, "://")+3:]
+	}
+
+	if i := strings.Index(host, "/"); i >= 0 {
+		host = host[:i]
+	}
+
+	if i := strings.Index(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+
+	host = strings.ToLower(host)
+
+	return strings.TrimPrefix(host, "www.")
+}
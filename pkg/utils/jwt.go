@@ -1,42 +1,108 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret = []byte("SECRET")
+// DevJWTSecret - секрет для локального запуска без JWT_SECRET, в prod не используется
+const DevJWTSecret = "SECRET"
+
+// Настройки подписи задаются ConfigureJWT при старте сервера
+var (
+	jwtSecret                          = []byte(DevJWTSecret)
+	jwtSigningMethod jwt.SigningMethod = jwt.SigningMethodHS256
+)
+
+var (
+	AccessTokenTTL  = 24 * time.Hour
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+var (
+	ErrTokenExpired = errors.New("token expired")
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+// ConfigureJWT задает секрет, HMAC алгоритм (HS256, HS384, HS512) и время жизни токенов.
+// Вызывается один раз при старте, до обработки запросов
+func ConfigureJWT(secret, algorithm string, accessTTL, refreshTTL time.Duration) error {
+	if secret == "" {
+		return errors.New("jwt secret is empty")
+	}
+
+	method, ok := jwt.GetSigningMethod(algorithm).(*jwt.SigningMethodHMAC)
+	if !ok {
+		return fmt.Errorf("unsupported jwt algorithm %q, use HS256, HS384 or HS512", algorithm)
+	}
+
+	if accessTTL <= 0 || refreshTTL <= 0 {
+		return errors.New("jwt token ttl must be positive")
+	}
+
+	jwtSecret = []byte(secret)
+	jwtSigningMethod = method
+	AccessTokenTTL = accessTTL
+	RefreshTokenTTL = refreshTTL
+
+	return nil
+}
 
 func GenerateToken(userID uuid.UUID, username string) (string, error) {
 	claims := jwt.MapClaims{
 		"username": username,
 		"user_id":  userID.String(),
-		"exp":      time.Now().Add(time.Hour * 72).Unix(),
+		"exp":      time.Now().Add(AccessTokenTTL).Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token := jwt.NewWithClaims(jwtSigningMethod, claims)
 	return token.SignedString(jwtSecret)
 }
 
+// ValidateToken возвращает ErrTokenExpired для просроченного токена и ErrInvalidToken для остальных ошибок
 func ValidateToken(tokenString string) (jwt.MapClaims, error) {
+	// Токены, подписанные другим алгоритмом, отклоняются, даже если это тоже HMAC
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("invalid signing method")
 		}
 
 		return jwtSecret, nil
-	})
+	}, jwt.WithValidMethods([]string{jwtSigningMethod.Alg()}))
 
 	if err != nil {
-		return nil, err
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrTokenExpired
+		}
+		return nil, ErrInvalidToken
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
 		return claims, nil
 	}
 
-	return nil, errors.New("invalid token")
+	return nil, ErrInvalidToken
+}
+
+// GenerateRefreshToken возвращает случайный непрозрачный refresh токен
+func GenerateRefreshToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(bytes), nil
+}
+
+// HashToken возвращает SHA-256 хэш токена для хранения
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
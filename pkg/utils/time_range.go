@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Пресеты period в порядке отображения
+var PeriodPresets = []string{"today", "week", "month", "year"}
+
+// TimeRangeError - ошибка в параметрах периода запроса; хендлеры отвечают на нее 400
+type TimeRangeError struct {
+	Message string
+}
+
+func (e *TimeRangeError) Error() string {
+	return e.Message
+}
+
+func timeRangeErrorf(format string, args ...interface{}) error {
+	return &TimeRangeError{Message: fmt.Sprintf(format, args...)}
+}
+
+// ParseTimeRange читает обязательный период из start_time/end_time (RFC3339) или из period
+// (today, week, month, year) в таймзоне tz (по умолчанию UTC). Явные start_time/end_time
+// имеют приоритет над period. Все ошибки - *TimeRangeError
+func ParseTimeRange(c *gin.Context) (start, end time.Time, err error) {
+	startPtr, endPtr, err := ParseOptionalTimeRange(c)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if startPtr == nil {
+		return time.Time{}, time.Time{}, timeRangeErrorf("start_time is required (RFC3339 format) or use period (%s)", strings.Join(PeriodPresets, ", "))
+	}
+	if endPtr == nil {
+		return time.Time{}, time.Time{}, timeRangeErrorf("end_time is required (RFC3339 format)")
+	}
+
+	return *startPtr, *endPtr, nil
+}
+
+// ParseOptionalTimeRange - как ParseTimeRange, но каждая граница может отсутствовать (nil)
+func ParseOptionalTimeRange(c *gin.Context) (start, end *time.Time, err error) {
+	startStr, endStr := c.Query("start_time"), c.Query("end_time")
+
+	if startStr == "" && endStr == "" {
+		period := c.Query("period")
+		if period == "" {
+			return nil, nil, nil
+		}
+
+		loc, err := LoadTimezone(c.Query("tz"))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		startTime, endTime, err := PeriodTimeRange(period, loc)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &startTime, &endTime, nil
+	}
+
+	if startStr != "" {
+		startTime, err := time.Parse(time.RFC3339, startStr)
+		if err != nil {
+			return nil, nil, timeRangeErrorf("Invalid start_time format, use RFC3339 (e.g., 2025-07-10T08:00:00Z)")
+		}
+		start = &startTime
+	}
+
+	if endStr != "" {
+		endTime, err := time.Parse(time.RFC3339, endStr)
+		if err != nil {
+			return nil, nil, timeRangeErrorf("Invalid end_time format, use RFC3339 (e.g., 2025-07-11T19:59:59Z)")
+		}
+		end = &endTime
+	}
+
+	if start != nil && end != nil && end.Before(*start) {
+		return nil, nil, timeRangeErrorf("end_time must be after start_time")
+	}
+
+	return start, end, nil
+}
+
+// LoadTimezone загружает IANA таймзону, пустая строка - UTC
+func LoadTimezone(tz string) (*time.Location, error) {
+	if tz == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, timeRangeErrorf("Invalid timezone '%s'", tz)
+	}
+	return loc, nil
+}
+
+// PeriodTimeRange считает границы календарного периода (today, week, month, year), в который
+// попадает текущий момент, в указанной таймзоне. Неделя начинается с понедельника
+func PeriodTimeRange(period string, loc *time.Location) (time.Time, time.Time, error) {
+	return PeriodTimeRangeAt(period, loc, time.Now())
+}
+
+// PeriodTimeRangeAt - PeriodTimeRange для момента now
+func PeriodTimeRangeAt(period string, loc *time.Location, now time.Time) (time.Time, time.Time, error) {
+	now = now.In(loc)
+	var startTime, endTime time.Time
+
+	switch strings.ToLower(period) {
+	case "today":
+		startTime = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+		endTime = startTime.AddDate(0, 0, 1)
+
+	case "week":
+		weekday := int(now.Weekday())
+		if weekday == 0 { // воскресенье в Go = 0, делаем его 7
+			weekday = 7
+		}
+		startTime = time.Date(now.Year(), now.Month(), now.Day()-(weekday-1), 0, 0, 0, 0, loc)
+		endTime = startTime.AddDate(0, 0, 7)
+
+	case "month":
+		startTime = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+		endTime = startTime.AddDate(0, 1, 0)
+
+	case "year":
+		startTime = time.Date(now.Year(), 1, 1, 0, 0, 0, 0, loc)
+		endTime = startTime.AddDate(1, 0, 0)
+
+	default:
+		return time.Time{}, time.Time{}, timeRangeErrorf("Invalid period '%s'. Valid values: %s", period, strings.Join(PeriodPresets, ", "))
+	}
+
+	// Конец периода включительно: последняя наносекунда перед началом следующего
+	return startTime, endTime.Add(-time.Nanosecond), nil
+}
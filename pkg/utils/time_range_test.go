@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeriodTimeRangeAtAcrossDayBoundary(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("failed to load Asia/Tokyo: %v", err)
+	}
+	losAngeles, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatalf("failed to load America/Los_Angeles: %v", err)
+	}
+
+	date := func(loc *time.Location, year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, loc)
+	}
+
+	cases := []struct {
+		name   string
+		now    time.Time
+		loc    *time.Location
+		period string
+		start  time.Time
+		end    time.Time
+	}{
+		// 2025-03-31 20:30 UTC (понедельник) - в Токио уже вторник 1 апреля
+		{name: "tokyo today", now: time.Date(2025, 3, 31, 20, 30, 0, 0, time.UTC), loc: tokyo, period: "today", start: date(tokyo, 2025, 4, 1), end: date(tokyo, 2025, 4, 2)},
+		{name: "tokyo week", now: time.Date(2025, 3, 31, 20, 30, 0, 0, time.UTC), loc: tokyo, period: "week", start: date(tokyo, 2025, 3, 31), end: date(tokyo, 2025, 4, 7)},
+		{name: "tokyo month", now: time.Date(2025, 3, 31, 20, 30, 0, 0, time.UTC), loc: tokyo, period: "month", start: date(tokyo, 2025, 4, 1), end: date(tokyo, 2025, 5, 1)},
+		{name: "tokyo year", now: time.Date(2024, 12, 31, 16, 0, 0, 0, time.UTC), loc: tokyo, period: "year", start: date(tokyo, 2025, 1, 1), end: date(tokyo, 2026, 1, 1)},
+		// 2025-03-30 16:00 UTC - воскресенье, а в Токио уже понедельник: новая неделя
+		{name: "tokyo week starts before UTC", now: time.Date(2025, 3, 30, 16, 0, 0, 0, time.UTC), loc: tokyo, period: "week", start: date(tokyo, 2025, 3, 31), end: date(tokyo, 2025, 4, 7)},
+		// 2025-01-01 03:00 UTC - в Лос-Анджелесе еще вторник 31 декабря 2024
+		{name: "los angeles today", now: time.Date(2025, 1, 1, 3, 0, 0, 0, time.UTC), loc: losAngeles, period: "today", start: date(losAngeles, 2024, 12, 31), end: date(losAngeles, 2025, 1, 1)},
+		{name: "los angeles week", now: time.Date(2025, 1, 1, 3, 0, 0, 0, time.UTC), loc: losAngeles, period: "week", start: date(losAngeles, 2024, 12, 30), end: date(losAngeles, 2025, 1, 6)},
+		{name: "los angeles month", now: time.Date(2025, 1, 1, 3, 0, 0, 0, time.UTC), loc: losAngeles, period: "month", start: date(losAngeles, 2024, 12, 1), end: date(losAngeles, 2025, 1, 1)},
+		{name: "los angeles year", now: time.Date(2025, 1, 1, 3, 0, 0, 0, time.UTC), loc: losAngeles, period: "year", start: date(losAngeles, 2024, 1, 1), end: date(losAngeles, 2025, 1, 1)},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			start, end, err := PeriodTimeRangeAt(tc.period, tc.loc, tc.now)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !start.Equal(tc.start) {
+				t.Errorf("start = %s, want %s", start, tc.start)
+			}
+			// Конец периода включительно
+			if want := tc.end.Add(-time.Nanosecond); !end.Equal(want) {
+				t.Errorf("end = %s, want %s", end, want)
+			}
+		})
+	}
+}
+
+func TestPeriodTimeRangeAtInvalidPeriod(t *testing.T) {
+	_, _, err := PeriodTimeRangeAt("decade", time.UTC, time.Now())
+	if _, ok := err.(*TimeRangeError); !ok {
+		t.Fatalf("expected *TimeRangeError, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/dinerozz/web-behavior-backend/internal/service/redis"
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+// Таймаут одной проверки зависимости в readiness probe
+const readinessCheckTimeout = 2 * time.Second
+
+// livenessHandler - процесс жив, зависимости не проверяются
+func livenessHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "healthy",
+		"timestamp": time.Now().Unix(),
+		"service":   "web-behavior-app",
+	})
+}
+
+// readinessHandler пингует Postgres и Redis. Без Postgres под не готов (503); без Redis сервис
+// работает в режиме деградации (DegradableService), поэтому под остается в ротации со статусом degraded.
+// Тексты ошибок только логируются: эндпоинт публичный, а они содержат хосты и параметры подключения
+func readinessHandler(db *sqlx.DB, redisService redis.ServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		checks := map[string]string{
+			"database": "ok",
+			"redis":    "ok",
+		}
+		status, statusText := http.StatusOK, "ready"
+
+		dbCtx, cancel := context.WithTimeout(c.Request.Context(), readinessCheckTimeout)
+		defer cancel()
+		if err := db.PingContext(dbCtx); err != nil {
+			log.Printf("Readiness check: database is unavailable: %v", err)
+			checks["database"] = "unavailable"
+			status, statusText = http.StatusServiceUnavailable, "not_ready"
+		}
+
+		redisCtx, cancelRedis := context.WithTimeout(c.Request.Context(), readinessCheckTimeout)
+		defer cancelRedis()
+		if err := redisService.Health(redisCtx); err != nil {
+			log.Printf("Readiness check: redis is unavailable: %v", err)
+			checks["redis"] = "degraded"
+			if status == http.StatusOK {
+				statusText = "degraded"
+			}
+		}
+
+		c.JSON(status, gin.H{
+			"status":    statusText,
+			"checks":    checks,
+			"timestamp": time.Now().Unix(),
+			"service":   "web-behavior-app",
+		})
+	}
+}